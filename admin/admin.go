@@ -1,8 +1,10 @@
 package admin
 
 import (
+	"encoding/json"
 	"net"
 	"net/http"
+	"sync"
 
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
 	"github.com/gorilla/mux"
@@ -10,6 +12,9 @@ import (
 
 type AdminApi struct {
 	http.Server
+
+	statusLock sync.Mutex
+	status     map[string]func() any
 }
 
 func (a *AdminApi) Init(name string) error {
@@ -21,13 +26,35 @@ func (a *AdminApi) Init(name string) error {
 	router := mux.NewRouter()
 
 	a.Handler = router
+	a.status = make(map[string]func() any)
 
 	// Add admin handlers to the admin only http server and start it
 	router.Path("/metrics").Handler(metricsHandler)
+	router.Path("/status").HandlerFunc(a.serveStatus)
 
 	return err
 }
 
+// AddStatus adds the value returned by f under name to /status, which is served as json
+func (a *AdminApi) AddStatus(name string, f func() any) {
+	a.statusLock.Lock()
+	defer a.statusLock.Unlock()
+
+	a.status[name] = f
+}
+
+func (a *AdminApi) serveStatus(w http.ResponseWriter, r *http.Request) {
+	a.statusLock.Lock()
+	out := make(map[string]any, len(a.status))
+	for name, f := range a.status {
+		out[name] = f()
+	}
+	a.statusLock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
 func (a *AdminApi) Serve(l net.Listener) error {
 	a.Addr = l.Addr().String()
 	return a.Server.Serve(l)
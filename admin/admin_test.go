@@ -2,6 +2,7 @@ package admin
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net"
 	"net/http"
@@ -63,3 +64,45 @@ func TestAdminStartStop(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestAdminStatus(t *testing.T) {
+
+	ctx := setup(t)
+	a := AdminApi{}
+	err := a.Init("admin_test_status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.AddStatus("beacon_node", func() any {
+		return map[string]string{"unfit": "syncing"}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = a.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		_ = a.Shutdown(ctx)
+	})
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatal("Non-200 status code received", resp.StatusCode)
+	}
+
+	var status map[string]map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status["beacon_node"]["unfit"] != "syncing" {
+		t.Fatalf("unexpected status %v", status)
+	}
+}
@@ -1,38 +1,52 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"math/big"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/Rocket-Rescue-Node/rescue-proxy/consensuslayer"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/executionlayer"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/pb"
-	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
 	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// The most signatures a single ValidateEIP1271Batch request may contain
+const maxEIP1271BatchSize = 256
+
 type API struct {
 	pb.UnimplementedApiServer
 	EL     executionlayer.ExecutionLayer
 	CL     consensuslayer.ConsensusLayer
 	Logger *zap.Logger
+	// Changes to the node set, for StreamNodeSet
+	NodeSet *nodeset.Feed
+
+	// Serve TLS with this certificate and key, if set
+	TLSCertFile string
+	TLSKeyFile  string
+	// Require clients to present a certificate signed by a CA in this file, if set. Requires TLS.
+	TLSClientCAFile string
+	// Require clients to send this token in their authorization metadata, as "Bearer <token>", if set
+	Token string
+
 	server *grpc.Server
 	m      *metrics.MetricsRegistry
 
-	soloValidatorCache     map[common.Address]interface{}
-	soloValidatorCacheLock sync.RWMutex
-	ticker                 struct {
-		t    *time.Ticker
-		done chan bool
-	}
-	ctx context.Context
+	// Open streams, which Stop cancels but doesn't wait for
+	streams sync.WaitGroup
 }
 
 func (a *API) GetRocketPoolNodes(ctx context.Context, request *pb.RocketPoolNodesRequest) (*pb.RocketPoolNodes, error) {
@@ -72,13 +86,12 @@ func (a *API) GetOdaoNodes(ctx context.Context, request *pb.OdaoNodesRequest) (*
 }
 
 func (a *API) GetSoloValidators(ctx context.Context, request *pb.SoloValidatorsRequest) (*pb.SoloValidators, error) {
-	a.soloValidatorCacheLock.RLock()
-	defer a.soloValidatorCacheLock.RUnlock()
+	addrs := a.CL.GetWithdrawalAddresses()
 
 	out := &pb.SoloValidators{}
-	out.WithdrawalAddresses = make([][]byte, 0, len(a.soloValidatorCache))
+	out.WithdrawalAddresses = make([][]byte, 0, len(addrs))
 
-	for addr := range a.soloValidatorCache {
+	for _, addr := range addrs {
 		out.WithdrawalAddresses = append(out.WithdrawalAddresses, addr.Bytes())
 	}
 
@@ -106,90 +119,452 @@ func (a *API) ValidateEIP1271(ctx context.Context, request *pb.ValidateEIP1271Re
 	return &pb.ValidateEIP1271Response{Valid: valid}, nil
 }
 
-func (a *API) updateCache() error {
-	a.soloValidatorCacheLock.Lock()
-	defer a.soloValidatorCacheLock.Unlock()
+func (a *API) ValidateEIP1271Batch(ctx context.Context, request *pb.ValidateEIP1271BatchRequest) (*pb.ValidateEIP1271BatchResponse, error) {
+	if len(request.Requests) > maxEIP1271BatchSize {
+		return &pb.ValidateEIP1271BatchResponse{Error: fmt.Sprintf("too many signatures: expected at most %d, got %d", maxEIP1271BatchSize, len(request.Requests))}, nil
+	}
 
-	validators, err := a.CL.GetValidators()
-	if err != nil {
-		a.m.Counter("solo_validator_cache_update_failed").Inc()
-		return err
+	out := &pb.ValidateEIP1271BatchResponse{
+		Responses: make([]*pb.ValidateEIP1271Response, len(request.Requests)),
 	}
 
-	newMap := make(map[common.Address]interface{})
-	for _, validator := range validators {
-		if validator.Status == apiv1.ValidatorStateUnknown ||
-			validator.Status == apiv1.ValidatorStateExitedUnslashed ||
-			validator.Status == apiv1.ValidatorStateExitedSlashed ||
-			validator.Status == apiv1.ValidatorStateWithdrawalPossible ||
-			validator.Status == apiv1.ValidatorStateWithdrawalDone {
+	// Reject malformed requests up front, and validate the rest together
+	requests := make([]executionlayer.EIP1271Request, 0, len(request.Requests))
+	indices := make([]int, 0, len(request.Requests))
+	for i, r := range request.Requests {
+		if len(r.DataHash) != 32 {
+			out.Responses[i] = &pb.ValidateEIP1271Response{Error: fmt.Sprintf("invalid DataHash length: expected 32 bytes, got %d", len(r.DataHash))}
+			continue
+		}
+		if len(r.Address) != 20 {
+			out.Responses[i] = &pb.ValidateEIP1271Response{Error: fmt.Sprintf("invalid Address length: expected 20 bytes, got %d", len(r.Address))}
 			continue
 		}
 
-		creds := validator.Validator.WithdrawalCredentials
+		requests = append(requests, executionlayer.EIP1271Request{
+			DataHash:  common.BytesToHash(r.DataHash),
+			Signature: r.Signature,
+			Address:   common.BytesToAddress(r.Address),
+		})
+		indices = append(indices, i)
+	}
 
-		// Check that the creds are 0x01
-		if !bytes.HasPrefix(creds, []byte{0x01}) {
+	for i, result := range a.EL.ValidateEIP1271Batch(ctx, requests) {
+		if result.Err != nil {
+			a.m.Counter("validate_eip1271_error").Inc()
+			out.Responses[indices[i]] = &pb.ValidateEIP1271Response{Error: result.Err.Error()}
 			continue
 		}
 
-		// The address is the last 20 bytes of the credential.
-		address := common.BytesToAddress(creds[len(creds)-common.AddressLength:])
+		a.m.Counter("validate_eip1271_ok").Inc()
+		out.Responses[indices[i]] = &pb.ValidateEIP1271Response{Valid: result.Valid}
+	}
 
-		// Add to the new map
-		newMap[address] = struct{}{}
+	a.m.Counter("validate_eip1271_batch").Inc()
+	return out, nil
+}
+
+var signatureMethods = map[executionlayer.SignatureMethod]pb.SignatureMethod{
+	executionlayer.SignatureMethodNone:    pb.SignatureMethod_SIGNATURE_METHOD_NONE,
+	executionlayer.SignatureMethodEOA:     pb.SignatureMethod_SIGNATURE_METHOD_EOA,
+	executionlayer.SignatureMethodEIP1271: pb.SignatureMethod_SIGNATURE_METHOD_EIP1271,
+	executionlayer.SignatureMethodEIP6492: pb.SignatureMethod_SIGNATURE_METHOD_EIP6492,
+}
+
+func (a *API) ValidateSignature(ctx context.Context, request *pb.ValidateSignatureRequest) (*pb.ValidateSignatureResponse, error) {
+	if len(request.DataHash) != 32 {
+		return &pb.ValidateSignatureResponse{Error: fmt.Sprintf("invalid DataHash length: expected 32 bytes, got %d", len(request.DataHash))}, nil
+	}
+	if len(request.Address) != 20 {
+		return &pb.ValidateSignatureResponse{Error: fmt.Sprintf("invalid Address length: expected 20 bytes, got %d", len(request.Address))}, nil
 	}
+	dataHash := common.BytesToHash(request.DataHash)
+	address := common.BytesToAddress(request.Address)
 
-	// swap out the cache
-	a.m.Gauge("solo_validator_cache_update_size").Set(float64(len(newMap)))
-	a.m.Counter("solo_validator_cache_update_success").Inc()
-	a.soloValidatorCache = newMap
+	var block *big.Int
+	if request.Block != 0 {
+		block = new(big.Int).SetUint64(request.Block)
+	}
 
-	return nil
+	method, err := a.EL.ValidateSignature(ctx, dataHash, request.Signature, address, block)
+	if err != nil {
+		a.m.Counter("validate_signature_error").Inc()
+		return &pb.ValidateSignatureResponse{Error: err.Error()}, nil
+	}
+
+	a.m.Counter("validate_signature_" + method.String()).Inc()
+	return &pb.ValidateSignatureResponse{
+		Valid:  method != executionlayer.SignatureMethodNone,
+		Method: signatureMethods[method],
+	}, nil
 }
 
-func (a *API) Init(listener net.Listener) error {
+func (a *API) GetRPInfoAt(ctx context.Context, request *pb.RPInfoAtRequest) (*pb.RPInfoAtResponse, error) {
+	if len(request.Pubkey) != rptypes.ValidatorPubkeyLength {
+		return &pb.RPInfoAtResponse{Error: fmt.Sprintf("invalid Pubkey length: expected %d bytes, got %d", rptypes.ValidatorPubkeyLength, len(request.Pubkey))}, nil
+	}
+	pubkey := rptypes.BytesToValidatorPubkey(request.Pubkey)
 
-	a.m = metrics.NewMetricsRegistry("api")
+	rpInfo, err := a.EL.GetRPInfoAt(pubkey, request.Block)
+	if err != nil {
+		a.m.Counter("get_rp_info_at_error").Inc()
+		return &pb.RPInfoAtResponse{Error: err.Error()}, nil
+	}
 
-	a.server = grpc.NewServer()
+	a.m.Counter("get_rp_info_at_ok").Inc()
+	if rpInfo == nil {
+		return &pb.RPInfoAtResponse{}, nil
+	}
 
-	pb.RegisterApiServer(a.server, a)
+	out := &pb.RPInfoAtResponse{
+		IsMinipool:              true,
+		NodeAddress:             rpInfo.NodeAddress.Bytes(),
+		ExpectedFeeRecipient:    rpInfo.ExpectedFeeRecipient.Bytes(),
+		AcceptableFeeRecipients: make([][]byte, 0, len(rpInfo.AcceptableFeeRecipients)),
+	}
+	for _, addr := range rpInfo.AcceptableFeeRecipients {
+		out.AcceptableFeeRecipients = append(out.AcceptableFeeRecipients, addr.Bytes())
+	}
 
-	a.Logger.Info("Starting grpc server", zap.String("url", listener.Addr().String()))
-	go func() {
-		if err := a.server.Serve(listener); err != nil {
-			a.Logger.Panic("gRPC server stopped", zap.Error(err))
+	return out, nil
+}
+
+func (a *API) GetWithdrawalAddresses(ctx context.Context, request *pb.WithdrawalAddressesRequest) (*pb.WithdrawalAddressesResponse, error) {
+	var nodes []*executionlayer.WithdrawalAddresses
+	var err error
+
+	switch address := request.Address.(type) {
+	case *pb.WithdrawalAddressesRequest_WithdrawalAddress:
+		if len(address.WithdrawalAddress) != 20 {
+			return &pb.WithdrawalAddressesResponse{Error: fmt.Sprintf("invalid WithdrawalAddress length: expected 20 bytes, got %d", len(address.WithdrawalAddress))}, nil
 		}
-	}()
 
-	a.Logger.Info("Seeding the solo validator cache")
-	err := a.updateCache()
+		nodes, err = a.EL.GetWithdrawalAddressNodes(common.BytesToAddress(address.WithdrawalAddress))
+	case *pb.WithdrawalAddressesRequest_NodeAddress:
+		if len(address.NodeAddress) != 20 {
+			return &pb.WithdrawalAddressesResponse{Error: fmt.Sprintf("invalid NodeAddress length: expected 20 bytes, got %d", len(address.NodeAddress))}, nil
+		}
+
+		var node *executionlayer.WithdrawalAddresses
+		node, err = a.EL.GetNodeWithdrawalAddresses(common.BytesToAddress(address.NodeAddress))
+		if node != nil {
+			nodes = []*executionlayer.WithdrawalAddresses{node}
+		}
+	default:
+		return &pb.WithdrawalAddressesResponse{Error: "either WithdrawalAddress or NodeAddress is required"}, nil
+	}
+
+	if err != nil {
+		a.m.Counter("get_withdrawal_addresses_error").Inc()
+		return &pb.WithdrawalAddressesResponse{Error: err.Error()}, nil
+	}
+
+	out := &pb.WithdrawalAddressesResponse{
+		Nodes: make([]*pb.NodeWithdrawalAddresses, 0, len(nodes)),
+	}
+	for _, node := range nodes {
+		n := &pb.NodeWithdrawalAddresses{
+			NodeAddress:       node.NodeAddress.Bytes(),
+			WithdrawalAddress: node.WithdrawalAddress.Bytes(),
+		}
+		if node.RPLWithdrawalAddress != nil {
+			n.RplWithdrawalAddress = node.RPLWithdrawalAddress.Bytes()
+		}
+		out.Nodes = append(out.Nodes, n)
+	}
+
+	a.m.Counter("get_withdrawal_addresses_ok").Inc()
+	return out, nil
+}
+
+func (a *API) GetExpectedFeeRecipient(ctx context.Context, request *pb.ExpectedFeeRecipientRequest) (*pb.ExpectedFeeRecipientResponse, error) {
+	var validatorInfo *consensuslayer.ValidatorInfo
+	var pubkey rptypes.ValidatorPubkey
+
+	switch validator := request.Validator.(type) {
+	case *pb.ExpectedFeeRecipientRequest_Pubkey:
+		if len(validator.Pubkey) != rptypes.ValidatorPubkeyLength {
+			return &pb.ExpectedFeeRecipientResponse{Error: fmt.Sprintf("invalid Pubkey length: expected %d bytes, got %d", rptypes.ValidatorPubkeyLength, len(validator.Pubkey))}, nil
+		}
+		pubkey = rptypes.BytesToValidatorPubkey(validator.Pubkey)
+
+		validators, err := a.CL.GetValidatorInfoByPubkey([]rptypes.ValidatorPubkey{pubkey})
+		if err != nil {
+			a.m.Counter("get_expected_fee_recipient_error").Inc()
+			return &pb.ExpectedFeeRecipientResponse{Error: err.Error()}, nil
+		}
+		validatorInfo = validators[pubkey]
+	case *pb.ExpectedFeeRecipientRequest_Index:
+		index := strconv.FormatUint(validator.Index, 10)
+		validators, err := a.CL.GetValidatorInfo([]string{index})
+		if err != nil {
+			a.m.Counter("get_expected_fee_recipient_error").Inc()
+			return &pb.ExpectedFeeRecipientResponse{Error: err.Error()}, nil
+		}
+
+		// Without a pubkey there's nothing more to look the validator up by
+		validatorInfo = validators[index]
+		if validatorInfo == nil {
+			a.m.Counter("get_expected_fee_recipient_unknown").Inc()
+			return &pb.ExpectedFeeRecipientResponse{}, nil
+		}
+		pubkey = validatorInfo.Pubkey
+	default:
+		return &pb.ExpectedFeeRecipientResponse{Error: "either Pubkey or Index is required"}, nil
+	}
+
+	out := &pb.ExpectedFeeRecipientResponse{
+		Pubkey: pubkey.Bytes(),
+	}
+	if validatorInfo != nil {
+		out.Index = uint64(validatorInfo.Index)
+		out.InBeaconState = !validatorInfo.Pending
+		out.Pending = validatorInfo.Pending
+		if validatorInfo.HasExecutionCredentials {
+			out.WithdrawalAddress = validatorInfo.WithdrawalAddress.Bytes()
+		}
+	}
+
+	// Minipools are classified by the execution layer, so the beacon node needn't have them yet
+	rpInfo, err := a.EL.GetRPInfo(pubkey)
+	if err != nil {
+		a.m.Counter("get_expected_fee_recipient_error").Inc()
+		return &pb.ExpectedFeeRecipientResponse{Error: err.Error()}, nil
+	}
+
+	switch {
+	case rpInfo != nil:
+		out.Type = pb.ValidatorType_VALIDATOR_TYPE_MINIPOOL
+		out.NodeAddress = rpInfo.NodeAddress.Bytes()
+		out.InSmoothingPool = rpInfo.InSmoothingPool
+		out.ExpectedFeeRecipient = rpInfo.ExpectedFeeRecipient.Bytes()
+		out.AcceptableFeeRecipients = make([][]byte, 0, len(rpInfo.AcceptableFeeRecipients))
+		for _, addr := range rpInfo.AcceptableFeeRecipients {
+			out.AcceptableFeeRecipients = append(out.AcceptableFeeRecipients, addr.Bytes())
+		}
+		out.RethFallback = true
+		out.RethAddress = a.EL.REthAddress().Bytes()
+	case validatorInfo == nil:
+		out.Type = pb.ValidatorType_VALIDATOR_TYPE_UNKNOWN
+	case validatorInfo.HasExecutionCredentials:
+		// Solo validators may only use their withdrawal address
+		out.Type = pb.ValidatorType_VALIDATOR_TYPE_SOLO_0X01
+		if validatorInfo.Compounding {
+			out.Type = pb.ValidatorType_VALIDATOR_TYPE_SOLO_0X02
+		}
+		out.ExpectedFeeRecipient = validatorInfo.WithdrawalAddress.Bytes()
+		out.AcceptableFeeRecipients = [][]byte{validatorInfo.WithdrawalAddress.Bytes()}
+	default:
+		out.Type = pb.ValidatorType_VALIDATOR_TYPE_0X00
+	}
+
+	a.m.Counter("get_expected_fee_recipient_" + strings.ToLower(strings.TrimPrefix(out.Type.String(), "VALIDATOR_TYPE_"))).Inc()
+	return out, nil
+}
+
+// Converts a change time to unix time, leaving unknown times as 0
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+func (a *API) GetNodeInfo(ctx context.Context, request *pb.NodeInfoRequest) (*pb.NodeInfoResponse, error) {
+	if len(request.NodeAddress) != 20 {
+		return &pb.NodeInfoResponse{Error: fmt.Sprintf("invalid NodeAddress length: expected 20 bytes, got %d", len(request.NodeAddress))}, nil
+	}
+	nodeAddr := common.BytesToAddress(request.NodeAddress)
+
+	details, err := a.EL.GetNodeDetails(nodeAddr)
+	if err != nil {
+		a.m.Counter("get_node_info_error").Inc()
+		return &pb.NodeInfoResponse{Error: err.Error()}, nil
+	}
+
+	out := &pb.NodeInfoResponse{
+		NodeAddress: nodeAddr.Bytes(),
+	}
+	if details == nil {
+		a.m.Counter("get_node_info_unknown").Inc()
+		return out, nil
+	}
+
+	out.Registered = true
+	out.InSmoothingPool = details.InSmoothingPool
+	out.FeeDistributor = details.FeeDistributor.Bytes()
+	out.MinipoolPubkeys = make([][]byte, 0, len(details.Minipools))
+	for _, pubkey := range details.Minipools {
+		out.MinipoolPubkeys = append(out.MinipoolPubkeys, pubkey.Bytes())
+	}
+	out.OdaoMember = details.OdaoMember
+	out.SmoothingPoolChanged = unixTime(details.SmoothingPoolChanged)
+	out.FeeDistributorChanged = unixTime(details.FeeDistributorChanged)
+	out.MinipoolsChanged = unixTime(details.MinipoolsChanged)
+	out.OdaoChanged = unixTime(details.OdaoChanged)
+
+	a.m.Counter("get_node_info_ok").Inc()
+	return out, nil
+}
+
+var nodeSetChangeTypes = map[nodeset.ChangeType]pb.NodeSetChangeType{
+	nodeset.NodeRegistered: pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_NODE_REGISTERED,
+	nodeset.OdaoJoined:     pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_ODAO_JOINED,
+	nodeset.OdaoLeft:       pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_ODAO_LEFT,
+	nodeset.OdaoKicked:     pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_ODAO_KICKED,
+	nodeset.SoloAdded:      pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_SOLO_ADDED,
+	nodeset.SoloRemoved:    pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_SOLO_REMOVED,
+}
+
+func nodeSetCursor(cursor nodeset.Cursor) *pb.NodeSetCursor {
+	return &pb.NodeSetCursor{
+		Block:    cursor.Block,
+		Index:    cursor.Index,
+		Instance: cursor.Instance,
+	}
+}
+
+func (a *API) nodeSetSnapshot() (*pb.NodeSetSnapshot, error) {
+	out := &pb.NodeSetSnapshot{
+		NodeIds:     make([][]byte, 0, 1024),
+		OdaoNodeIds: make([][]byte, 0, 8),
+	}
+
+	err := a.EL.ForEachNode(func(addr common.Address) bool {
+		out.NodeIds = append(out.NodeIds, addr.Bytes())
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.EL.ForEachOdaoNode(func(addr common.Address) bool {
+		out.OdaoNodeIds = append(out.OdaoNodeIds, addr.Bytes())
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := a.CL.GetWithdrawalAddresses()
+	out.SoloWithdrawalAddresses = make([][]byte, 0, len(addrs))
+	for _, addr := range addrs {
+		out.SoloWithdrawalAddresses = append(out.SoloWithdrawalAddresses, addr.Bytes())
+	}
+
+	return out, nil
+}
+
+// Sends a snapshot unless resuming, then the missed changes, then changes from the subscription until it ends.
+// Returns the cursor of the last update sent.
+func (a *API) sendNodeSet(stream pb.Api_StreamNodeSetServer, sub *nodeset.Subscription, missed []nodeset.Change, cursor nodeset.Cursor, resumed bool) (nodeset.Cursor, error) {
+	if resumed {
+		a.m.Counter("stream_node_set_resumed").Inc()
+	} else {
+		// Taken after subscribing, so that no change is missed between the two
+		snapshot, err := a.nodeSetSnapshot()
+		if err != nil {
+			return cursor, err
+		}
+
+		err = stream.Send(&pb.NodeSetUpdate{
+			Cursor: nodeSetCursor(cursor),
+			Update: &pb.NodeSetUpdate_Snapshot{Snapshot: snapshot},
+		})
+		if err != nil {
+			return cursor, err
+		}
+		a.m.Counter("stream_node_set_snapshot").Inc()
+	}
+
+	send := func(change nodeset.Change) error {
+		err := stream.Send(&pb.NodeSetUpdate{
+			Cursor: nodeSetCursor(change.Cursor),
+			Update: &pb.NodeSetUpdate_Change{Change: &pb.NodeSetChange{
+				Type:    nodeSetChangeTypes[change.Type],
+				Address: change.Address.Bytes(),
+			}},
+		})
+		if err != nil {
+			return err
+		}
+
+		cursor = change.Cursor
+		a.m.Counter("stream_node_set_change").Inc()
+		return nil
+	}
+
+	for _, change := range missed {
+		if err := send(change); err != nil {
+			return cursor, err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return cursor, stream.Context().Err()
+		case <-sub.Done():
+			return cursor, nil
+		case change := <-sub.Changes():
+			if err := send(change); err != nil {
+				return cursor, err
+			}
+		}
+	}
+}
+
+// StreamNodeSet sends a snapshot of the node set, then changes to it as they happen. Streams that
+// pass the cursor of the last update they received resume from it, if the proxy still can.
+func (a *API) StreamNodeSet(request *pb.NodeSetRequest, stream pb.Api_StreamNodeSetServer) error {
+	if a.NodeSet == nil {
+		return status.Error(codes.Unimplemented, "the node set isn't being followed")
+	}
+
+	var after *nodeset.Cursor
+	if request.Cursor != nil {
+		after = &nodeset.Cursor{
+			Instance: request.Cursor.Instance,
+			Block:    request.Cursor.Block,
+			Index:    request.Cursor.Index,
+		}
+	}
+
+	a.streams.Add(1)
+	defer a.streams.Done()
+
+	a.m.Counter("stream_node_set_started").Inc()
+	for {
+		sub, missed, cursor, resumed := a.NodeSet.Subscribe(after)
+		last, err := a.sendNodeSet(stream, sub, missed, cursor, resumed)
+		sub.Close()
+		if err != nil {
+			a.m.Counter("stream_node_set_ended").Inc()
+			return err
+		}
+
+		// The feed ended the subscription, so carry on from the history, or a new snapshot
+		after = &last
+	}
+}
+
+func (a *API) Init(listener net.Listener) error {
+
+	a.m = metrics.NewMetricsRegistry("api")
+
+	opts, err := a.serverOptions()
 	if err != nil {
 		return err
 	}
+	a.server = grpc.NewServer(opts...)
 
-	// Updates to the validator set are taxing on the bn to query, so do it every 16 epochs (about once every hour and a half)
-	a.ticker.t = time.NewTicker(time.Second * 32 * 12 * 16)
-	a.ticker.done = make(chan bool)
-	a.Logger.Info("Starting solo validator background worker")
+	pb.RegisterApiServer(a.server, a)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	a.ctx = ctx
+	a.Logger.Info("Starting grpc server", zap.String("url", listener.Addr().String()))
 	go func() {
-		for {
-			select {
-			case <-a.ticker.t.C:
-				a.Logger.Debug("Updating solo validator worker")
-				err := a.updateCache()
-				if err != nil {
-					a.Logger.Warn("Error updating solo validator cache", zap.Error(err))
-				}
-			case <-a.ticker.done:
-				a.Logger.Info("Stopped background solo validator worker")
-				cancel()
-				return
-			}
+		if err := a.server.Serve(listener); err != nil {
+			a.Logger.Panic("gRPC server stopped", zap.Error(err))
 		}
 	}()
 
@@ -197,8 +572,11 @@ func (a *API) Init(listener net.Listener) error {
 }
 
 func (a *API) Deinit() {
+	if a.server == nil {
+		// Init failed
+		return
+	}
+
 	a.server.Stop()
-	a.ticker.t.Stop()
-	close(a.ticker.done)
-	<-a.ctx.Done()
+	a.streams.Wait()
 }
@@ -1,18 +1,39 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/Rocket-Rescue-Node/rescue-proxy/consensuslayer"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/executionlayer"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/pb"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/test"
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -38,25 +59,29 @@ func setup(t *testing.T) apiTest {
 		_ = out.Close()
 	})
 
+	return apiTest{
+		listener: out,
+		logger:   zaptest.NewLogger(t),
+		client:   dial(t, out, grpc.WithTransportCredentials(insecure.NewCredentials())),
+		ctx:      ctx,
+	}
+}
+
+func dial(t *testing.T, listener *bufconn.Listener, opts ...grpc.DialOption) pb.ApiClient {
 	conn, err := grpc.NewClient(
 		"passthrough:bufconn",
-		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
-			return out.Dial()
-		}),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		append(opts, grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}))...,
 	)
 	if err != nil {
 		t.Fatal("couldn't dial mock api listener", err)
 	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
 
-	client := pb.NewApiClient(conn)
-
-	return apiTest{
-		listener: out,
-		logger:   zaptest.NewLogger(t),
-		client:   client,
-		ctx:      ctx,
-	}
+	return pb.NewApiClient(conn)
 }
 
 func TestApiStartStop(t *testing.T) {
@@ -75,11 +100,6 @@ func TestApiStartStop(t *testing.T) {
 	}
 	t.Cleanup(a.Deinit)
 
-	// Force a ticker tick
-	a.ticker.t.Reset(100 * time.Millisecond)
-	time.Sleep(110 * time.Millisecond)
-	a.ticker.t.Reset(1 * time.Second)
-
 	resp, err := at.client.GetOdaoNodes(at.ctx, &pb.OdaoNodesRequest{})
 	if err != nil {
 		t.Fatal(err)
@@ -107,3 +127,692 @@ func TestApiStartStop(t *testing.T) {
 		t.Fatal("api didn't return any odao nodes")
 	}
 }
+
+func TestApiGetRPInfoAt(t *testing.T) {
+
+	at := setup(t)
+	el := test.NewMockExecutionLayer(50, 5, 200, t.Name())
+	cl := test.NewMockConsensusLayer(400, t.Name())
+	a := API{
+		EL:     el,
+		CL:     cl,
+		Logger: at.logger,
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	for pubkey, rpInfo := range el.VMap {
+		resp, err := at.client.GetRPInfoAt(at.ctx, &pb.RPInfoAtRequest{Pubkey: pubkey.Bytes(), Block: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.GetError() != "" || !resp.GetIsMinipool() {
+			t.Fatalf("expected %s to be a minipool, got %v", pubkey.String(), resp)
+		}
+
+		if !bytes.Equal(resp.GetNodeAddress(), rpInfo.NodeAddress.Bytes()) ||
+			!bytes.Equal(resp.GetExpectedFeeRecipient(), rpInfo.ExpectedFeeRecipient.Bytes()) {
+			t.Fatalf("unexpected response for %s: %v", pubkey.String(), resp)
+		}
+		break
+	}
+
+	resp, err := at.client.GetRPInfoAt(at.ctx, &pb.RPInfoAtRequest{Pubkey: make([]byte, 48), Block: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" || resp.GetIsMinipool() {
+		t.Fatalf("expected an unknown validator not to be a minipool, got %v", resp)
+	}
+
+	resp, err = at.client.GetRPInfoAt(at.ctx, &pb.RPInfoAtRequest{Pubkey: []byte{0x01}, Block: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() == "" {
+		t.Fatal("expected a short pubkey to be rejected")
+	}
+}
+
+func TestApiValidateSignature(t *testing.T) {
+
+	at := setup(t)
+	el := test.NewMockExecutionLayer(50, 5, 200, t.Name())
+	cl := test.NewMockConsensusLayer(400, t.Name())
+	a := API{
+		EL:     el,
+		CL:     cl,
+		Logger: at.logger,
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	resp, err := at.client.ValidateSignature(at.ctx, &pb.ValidateSignatureRequest{
+		DataHash:  make([]byte, 32),
+		Signature: []byte{0x01},
+		Address:   make([]byte, 20),
+		Block:     100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" || !resp.GetValid() || resp.GetMethod() != pb.SignatureMethod_SIGNATURE_METHOD_EIP1271 {
+		t.Fatalf("expected the signature to be valid by EIP-1271, got %v", resp)
+	}
+
+	resp, err = at.client.ValidateSignature(at.ctx, &pb.ValidateSignatureRequest{
+		DataHash:  make([]byte, 32),
+		Signature: []byte{0x01},
+		Address:   []byte{0x01},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() == "" || resp.GetValid() {
+		t.Fatal("expected a short address to be rejected")
+	}
+}
+
+func TestApiValidateEIP1271Batch(t *testing.T) {
+
+	at := setup(t)
+	el := test.NewMockExecutionLayer(50, 5, 200, t.Name())
+	cl := test.NewMockConsensusLayer(400, t.Name())
+	a := API{
+		EL:     el,
+		CL:     cl,
+		Logger: at.logger,
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	valid := &pb.ValidateEIP1271Request{DataHash: make([]byte, 32), Signature: []byte{0x01}, Address: make([]byte, 20)}
+	malformed := &pb.ValidateEIP1271Request{DataHash: make([]byte, 32), Signature: []byte{0x01}, Address: []byte{0x01}}
+
+	resp, err := at.client.ValidateEIP1271Batch(at.ctx, &pb.ValidateEIP1271BatchRequest{
+		Requests: []*pb.ValidateEIP1271Request{valid, malformed, valid},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" || len(resp.GetResponses()) != 3 {
+		t.Fatalf("expected 3 responses, got %v", resp)
+	}
+	for i, r := range resp.GetResponses() {
+		if i == 1 {
+			if r.GetError() == "" || r.GetValid() {
+				t.Fatal("expected a short address to be rejected")
+			}
+			continue
+		}
+
+		if r.GetError() != "" || !r.GetValid() {
+			t.Fatalf("expected response %d to be valid, got %v", i, r)
+		}
+	}
+
+	requests := make([]*pb.ValidateEIP1271Request, maxEIP1271BatchSize+1)
+	for i := range requests {
+		requests[i] = valid
+	}
+	resp, err = at.client.ValidateEIP1271Batch(at.ctx, &pb.ValidateEIP1271BatchRequest{Requests: requests})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() == "" || len(resp.GetResponses()) != 0 {
+		t.Fatal("expected an oversized batch to be rejected")
+	}
+}
+
+func TestApiGetWithdrawalAddresses(t *testing.T) {
+
+	at := setup(t)
+	el := test.NewMockExecutionLayer(50, 5, 200, t.Name())
+	cl := test.NewMockConsensusLayer(400, t.Name())
+	a := API{
+		EL:     el,
+		CL:     cl,
+		Logger: at.logger,
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	// Two nodes share a withdrawal address, and one of them withdraws RPL elsewhere
+	nodes := make([]common.Address, 0, 2)
+	err = el.ForEachNode(func(addr common.Address) bool {
+		nodes = append(nodes, addr)
+		return len(nodes) < 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := common.HexToAddress("0x0a010a")
+	rpl := common.HexToAddress("0x0a020a")
+	el.WithdrawalAddresses[nodes[0]].WithdrawalAddress = shared
+	el.WithdrawalAddresses[nodes[1]].WithdrawalAddress = shared
+	el.WithdrawalAddresses[nodes[1]].RPLWithdrawalAddress = &rpl
+
+	resp, err := at.client.GetWithdrawalAddresses(at.ctx, &pb.WithdrawalAddressesRequest{
+		Address: &pb.WithdrawalAddressesRequest_WithdrawalAddress{WithdrawalAddress: shared.Bytes()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" || len(resp.GetNodes()) != 2 {
+		t.Fatalf("expected 2 nodes, got %v", resp)
+	}
+
+	resp, err = at.client.GetWithdrawalAddresses(at.ctx, &pb.WithdrawalAddressesRequest{
+		Address: &pb.WithdrawalAddressesRequest_NodeAddress{NodeAddress: nodes[1].Bytes()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" || len(resp.GetNodes()) != 1 {
+		t.Fatalf("expected 1 node, got %v", resp)
+	}
+	node := resp.GetNodes()[0]
+	if !bytes.Equal(node.GetNodeAddress(), nodes[1].Bytes()) ||
+		!bytes.Equal(node.GetWithdrawalAddress(), shared.Bytes()) ||
+		!bytes.Equal(node.GetRplWithdrawalAddress(), rpl.Bytes()) {
+		t.Fatalf("unexpected withdrawal addresses %v", node)
+	}
+
+	resp, err = at.client.GetWithdrawalAddresses(at.ctx, &pb.WithdrawalAddressesRequest{
+		Address: &pb.WithdrawalAddressesRequest_NodeAddress{NodeAddress: nodes[0].Bytes()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" || len(resp.GetNodes()) != 1 || len(resp.GetNodes()[0].GetRplWithdrawalAddress()) != 0 {
+		t.Fatalf("expected a node without an RPL withdrawal address, got %v", resp)
+	}
+
+	// Addresses that aren't nodes have no withdrawal addresses
+	resp, err = at.client.GetWithdrawalAddresses(at.ctx, &pb.WithdrawalAddressesRequest{
+		Address: &pb.WithdrawalAddressesRequest_NodeAddress{NodeAddress: shared.Bytes()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" || len(resp.GetNodes()) != 0 {
+		t.Fatalf("expected no nodes, got %v", resp)
+	}
+
+	for _, request := range []*pb.WithdrawalAddressesRequest{
+		{},
+		{Address: &pb.WithdrawalAddressesRequest_WithdrawalAddress{WithdrawalAddress: []byte{0x01}}},
+		{Address: &pb.WithdrawalAddressesRequest_NodeAddress{NodeAddress: []byte{0x01}}},
+	} {
+		resp, err = at.client.GetWithdrawalAddresses(at.ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetError() == "" {
+			t.Fatalf("expected %v to be rejected", request)
+		}
+	}
+}
+
+func TestApiStreamNodeSet(t *testing.T) {
+
+	at := setup(t)
+	el := test.NewMockExecutionLayer(50, 5, 200, t.Name())
+	cl := test.NewMockConsensusLayer(400, t.Name())
+	feed := nodeset.NewFeed(nodeset.DefaultHistory)
+	feed.Advance(100)
+	a := API{
+		EL:      el,
+		CL:      cl,
+		Logger:  at.logger,
+		NodeSet: feed,
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	recvSnapshot := func(stream pb.Api_StreamNodeSetClient) *pb.NodeSetUpdate {
+		t.Helper()
+
+		update, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot := update.GetSnapshot()
+		if snapshot == nil {
+			t.Fatalf("expected a snapshot, got %v", update)
+		}
+		if len(snapshot.GetNodeIds()) != 50 || len(snapshot.GetOdaoNodeIds()) != 5 || len(snapshot.GetSoloWithdrawalAddresses()) == 0 {
+			t.Fatalf("unexpected snapshot %d %d %d", len(snapshot.GetNodeIds()), len(snapshot.GetOdaoNodeIds()), len(snapshot.GetSoloWithdrawalAddresses()))
+		}
+		return update
+	}
+	recvChange := func(stream pb.Api_StreamNodeSetClient, changeType pb.NodeSetChangeType, addr common.Address) *pb.NodeSetUpdate {
+		t.Helper()
+
+		update, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		change := update.GetChange()
+		if change.GetType() != changeType || !bytes.Equal(change.GetAddress(), addr.Bytes()) {
+			t.Fatalf("expected %s %s, got %v", changeType, addr, update)
+		}
+		return update
+	}
+
+	ctx, cancel := context.WithCancel(at.ctx)
+	stream, err := at.client.StreamNodeSet(ctx, &pb.NodeSetRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := recvSnapshot(stream)
+	if update.GetCursor().GetBlock() != 100 {
+		t.Fatalf("unexpected snapshot cursor %v", update.GetCursor())
+	}
+
+	node := common.HexToAddress("0x0f010f")
+	feed.Publish(101, nodeset.NodeRegistered, node)
+	update = recvChange(stream, pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_NODE_REGISTERED, node)
+	cancel()
+
+	// Resuming picks up the changes made while disconnected, without a snapshot
+	feed.Publish(102, nodeset.OdaoJoined, node)
+	stream, err = at.client.StreamNodeSet(at.ctx, &pb.NodeSetRequest{Cursor: update.GetCursor()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update = recvChange(stream, pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_ODAO_JOINED, node)
+	if update.GetCursor().GetBlock() != 102 || update.GetCursor().GetIndex() != 1 {
+		t.Fatalf("unexpected change cursor %v", update.GetCursor())
+	}
+
+	// Changes that can't be expressed as deltas are followed by a new snapshot
+	feed.Reset()
+	recvSnapshot(stream)
+
+	solo := common.HexToAddress("0x1f101f")
+	feed.Publish(0, nodeset.SoloAdded, solo)
+	recvChange(stream, pb.NodeSetChangeType_NODE_SET_CHANGE_TYPE_SOLO_ADDED, solo)
+
+	// Cursors the proxy can't resume from, like those from another instance, get a snapshot
+	stream, err = at.client.StreamNodeSet(at.ctx, &pb.NodeSetRequest{Cursor: &pb.NodeSetCursor{Block: 99}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recvSnapshot(stream)
+}
+
+func TestApiGetExpectedFeeRecipient(t *testing.T) {
+
+	at := setup(t)
+	el := test.NewMockExecutionLayer(50, 5, 200, t.Name())
+	cl := test.NewMockConsensusLayer(400, t.Name())
+	a := API{
+		EL:     el,
+		CL:     cl,
+		Logger: at.logger,
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	get := func(request *pb.ExpectedFeeRecipientRequest) *pb.ExpectedFeeRecipientResponse {
+		t.Helper()
+
+		resp, err := at.client.GetExpectedFeeRecipient(at.ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetError() != "" {
+			t.Fatal(resp.GetError())
+		}
+		return resp
+	}
+
+	// Minipools are known to the execution layer, even before the beacon node has them
+	for pubkey, rpInfo := range el.VMap {
+		resp := get(&pb.ExpectedFeeRecipientRequest{Validator: &pb.ExpectedFeeRecipientRequest_Pubkey{Pubkey: pubkey.Bytes()}})
+		if resp.GetType() != pb.ValidatorType_VALIDATOR_TYPE_MINIPOOL || resp.GetInBeaconState() ||
+			!bytes.Equal(resp.GetNodeAddress(), rpInfo.NodeAddress.Bytes()) ||
+			!bytes.Equal(resp.GetExpectedFeeRecipient(), rpInfo.ExpectedFeeRecipient.Bytes()) ||
+			len(resp.GetAcceptableFeeRecipients()) != 1 ||
+			!resp.GetRethFallback() || !bytes.Equal(resp.GetRethAddress(), el.REth.Bytes()) {
+			t.Fatalf("unexpected response for minipool %s: %v", pubkey.String(), resp)
+		}
+		break
+	}
+
+	validators, err := cl.GetValidators()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var solo, bls, compounding *apiv1.Validator
+	for _, v := range validators {
+		if v.Validator.WithdrawalCredentials[0] == 0x00 {
+			bls = v
+		} else if solo == nil {
+			solo = v
+		} else {
+			compounding = v
+		}
+	}
+	compounding.Validator.WithdrawalCredentials[0] = 0x02
+
+	resp := get(&pb.ExpectedFeeRecipientRequest{Validator: &pb.ExpectedFeeRecipientRequest_Index{Index: uint64(solo.Index)}})
+	withdrawalAddress := solo.Validator.WithdrawalCredentials[12:]
+	if resp.GetType() != pb.ValidatorType_VALIDATOR_TYPE_SOLO_0X01 || !resp.GetInBeaconState() || resp.GetIndex() != uint64(solo.Index) ||
+		!bytes.Equal(resp.GetPubkey(), solo.Validator.PublicKey[:]) ||
+		!bytes.Equal(resp.GetWithdrawalAddress(), withdrawalAddress) ||
+		!bytes.Equal(resp.GetExpectedFeeRecipient(), withdrawalAddress) ||
+		resp.GetRethFallback() {
+		t.Fatalf("unexpected response for solo validator %d: %v", solo.Index, resp)
+	}
+
+	resp = get(&pb.ExpectedFeeRecipientRequest{Validator: &pb.ExpectedFeeRecipientRequest_Pubkey{Pubkey: compounding.Validator.PublicKey[:]}})
+	if resp.GetType() != pb.ValidatorType_VALIDATOR_TYPE_SOLO_0X02 || resp.GetIndex() != uint64(compounding.Index) {
+		t.Fatalf("unexpected response for 0x02 validator %d: %v", compounding.Index, resp)
+	}
+
+	resp = get(&pb.ExpectedFeeRecipientRequest{Validator: &pb.ExpectedFeeRecipientRequest_Pubkey{Pubkey: bls.Validator.PublicKey[:]}})
+	if resp.GetType() != pb.ValidatorType_VALIDATOR_TYPE_0X00 || len(resp.GetExpectedFeeRecipient()) != 0 || len(resp.GetWithdrawalAddress()) != 0 {
+		t.Fatalf("unexpected response for 0x00 validator %d: %v", bls.Index, resp)
+	}
+
+	// Pending validators have no index yet
+	pending := rptypes.BytesToValidatorPubkey(bytes.Repeat([]byte{0x01}, rptypes.ValidatorPubkeyLength))
+	pendingAddress := common.HexToAddress("0x0f010f")
+	cl.Pending[pending] = &consensuslayer.ValidatorInfo{
+		Pubkey:                  pending,
+		WithdrawalAddress:       pendingAddress,
+		HasExecutionCredentials: true,
+		Pending:                 true,
+	}
+	resp = get(&pb.ExpectedFeeRecipientRequest{Validator: &pb.ExpectedFeeRecipientRequest_Pubkey{Pubkey: pending.Bytes()}})
+	if resp.GetType() != pb.ValidatorType_VALIDATOR_TYPE_SOLO_0X01 || resp.GetInBeaconState() || !resp.GetPending() ||
+		!bytes.Equal(resp.GetExpectedFeeRecipient(), pendingAddress.Bytes()) {
+		t.Fatalf("unexpected response for pending validator: %v", resp)
+	}
+
+	resp = get(&pb.ExpectedFeeRecipientRequest{Validator: &pb.ExpectedFeeRecipientRequest_Pubkey{Pubkey: make([]byte, 48)}})
+	if resp.GetType() != pb.ValidatorType_VALIDATOR_TYPE_UNKNOWN || len(resp.GetExpectedFeeRecipient()) != 0 {
+		t.Fatalf("unexpected response for an unknown pubkey: %v", resp)
+	}
+	resp = get(&pb.ExpectedFeeRecipientRequest{Validator: &pb.ExpectedFeeRecipientRequest_Index{Index: 1 << 40}})
+	if resp.GetType() != pb.ValidatorType_VALIDATOR_TYPE_UNKNOWN {
+		t.Fatalf("unexpected response for an unknown index: %v", resp)
+	}
+
+	for _, request := range []*pb.ExpectedFeeRecipientRequest{
+		{Validator: &pb.ExpectedFeeRecipientRequest_Pubkey{Pubkey: []byte{0x01}}},
+		{},
+	} {
+		resp, err := at.client.GetExpectedFeeRecipient(at.ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetError() == "" {
+			t.Fatalf("expected %v to be rejected", request)
+		}
+	}
+}
+
+func TestApiGetNodeInfo(t *testing.T) {
+
+	at := setup(t)
+	el := test.NewMockExecutionLayer(50, 5, 200, t.Name())
+	a := API{
+		EL:     el,
+		CL:     test.NewMockConsensusLayer(400, t.Name()),
+		Logger: at.logger,
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	// Count the minipools of one of the nodes
+	var node *executionlayer.RPInfo
+	minipools := 0
+	for _, rpInfo := range el.VMap {
+		if node == nil {
+			node = rpInfo
+		}
+		if rpInfo == node {
+			minipools++
+		}
+	}
+
+	resp, err := at.client.GetNodeInfo(at.ctx, &pb.NodeInfoRequest{NodeAddress: node.NodeAddress.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" {
+		t.Fatal(resp.GetError())
+	}
+	if !resp.GetRegistered() || !bytes.Equal(resp.GetNodeAddress(), node.NodeAddress.Bytes()) ||
+		!bytes.Equal(resp.GetFeeDistributor(), node.ExpectedFeeRecipient.Bytes()) ||
+		len(resp.GetMinipoolPubkeys()) != minipools || resp.GetOdaoMember() {
+		t.Fatalf("unexpected response for node %s: %v", node.NodeAddress.String(), resp)
+	}
+	for _, pubkey := range resp.GetMinipoolPubkeys() {
+		if el.VMap[rptypes.BytesToValidatorPubkey(pubkey)] != node {
+			t.Fatalf("unexpected minipool %x", pubkey)
+		}
+	}
+	// The mock doesn't know when anything changed
+	if resp.GetSmoothingPoolChanged() != 0 || resp.GetMinipoolsChanged() != 0 || resp.GetOdaoChanged() != 0 {
+		t.Fatalf("unexpected change times %v", resp)
+	}
+
+	resp, err = at.client.GetNodeInfo(at.ctx, &pb.NodeInfoRequest{NodeAddress: make([]byte, 20)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() != "" || resp.GetRegistered() || len(resp.GetFeeDistributor()) != 0 {
+		t.Fatalf("unexpected response for an unregistered node: %v", resp)
+	}
+
+	resp, err = at.client.GetNodeInfo(at.ctx, &pb.NodeInfoRequest{NodeAddress: []byte{0x01}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetError() == "" {
+		t.Fatal("expected an invalid address to be rejected")
+	}
+}
+
+func TestApiToken(t *testing.T) {
+
+	at := setup(t)
+	a := API{
+		EL:     test.NewMockExecutionLayer(50, 5, 200, t.Name()),
+		CL:     test.NewMockConsensusLayer(400, t.Name()),
+		Logger: at.logger,
+		Token:  "sesame",
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	for _, md := range []metadata.MD{
+		nil,
+		metadata.Pairs("authorization", "Bearer open"),
+		metadata.Pairs("authorization", "sesame"),
+	} {
+		ctx := metadata.NewOutgoingContext(at.ctx, md)
+		_, err := at.client.GetRocketPoolNodes(ctx, &pb.RocketPoolNodesRequest{})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("expected %v to be unauthenticated, got %v", md, err)
+		}
+
+		// Streams are authenticated before they start
+		stream, err := at.client.StreamNodeSet(ctx, &pb.NodeSetRequest{})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("expected stream with %v to be unauthenticated, got %v", md, err)
+		}
+	}
+
+	ctx := metadata.NewOutgoingContext(at.ctx, metadata.Pairs("authorization", "Bearer sesame"))
+	nodes, err := at.client.GetRocketPoolNodes(ctx, &pb.RocketPoolNodesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes.GetNodeIds()) != 50 {
+		t.Fatalf("expected 50 nodes, got %d", len(nodes.GetNodeIds()))
+	}
+}
+
+// Writes a certificate for localhost, signed by parent, or self-signed if parent is nil,
+// and its key to dir, returning their paths
+func writeCert(t *testing.T, dir string, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (string, string, *x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent = template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certPath, keyPath, cert, key
+}
+
+func TestApiTLS(t *testing.T) {
+
+	at := setup(t)
+	dir := t.TempDir()
+	caPath, _, ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	certPath, keyPath, _, _ := writeCert(t, dir, "server", ca, caKey)
+	clientCertPath, clientKeyPath, _, _ := writeCert(t, dir, "client", ca, caKey)
+	_, _, strangerCA, strangerKey := writeCert(t, dir, "stranger-ca", nil, nil)
+	strangerCertPath, strangerKeyPath, _, _ := writeCert(t, dir, "stranger", strangerCA, strangerKey)
+
+	a := API{
+		EL:              test.NewMockExecutionLayer(50, 5, 200, t.Name()),
+		CL:              test.NewMockConsensusLayer(400, t.Name()),
+		Logger:          at.logger,
+		TLSCertFile:     certPath,
+		TLSKeyFile:      keyPath,
+		TLSClientCAFile: caPath,
+	}
+	err := a.Init(at.listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.Deinit)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	clientWith := func(certPath, keyPath string) pb.ApiClient {
+		config := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+		if certPath != "" {
+			cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		return dial(t, at.listener, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	}
+
+	nodes, err := clientWith(clientCertPath, clientKeyPath).GetRocketPoolNodes(at.ctx, &pb.RocketPoolNodesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes.GetNodeIds()) != 50 {
+		t.Fatalf("expected 50 nodes, got %d", len(nodes.GetNodeIds()))
+	}
+
+	// Clients without a certificate from the CA, or without TLS, are refused
+	for name, client := range map[string]pb.ApiClient{
+		"no certificate":       clientWith("", ""),
+		"stranger certificate": clientWith(strangerCertPath, strangerKeyPath),
+		"plaintext":            at.client,
+	} {
+		_, err := client.GetRocketPoolNodes(at.ctx, &pb.RocketPoolNodesRequest{})
+		if err == nil {
+			t.Fatalf("expected a client with %s to be refused", name)
+		}
+	}
+}
+
+func TestApiTLSMisconfigured(t *testing.T) {
+
+	at := setup(t)
+	dir := t.TempDir()
+	caPath, _, _, _ := writeCert(t, dir, "ca", nil, nil)
+
+	for _, a := range []*API{
+		// Client certificates can't be verified without TLS
+		{TLSClientCAFile: caPath},
+		{TLSCertFile: filepath.Join(dir, "missing.crt"), TLSKeyFile: filepath.Join(dir, "missing.key")},
+	} {
+		a.Logger = at.logger
+		if err := a.Init(at.listener); err == nil {
+			a.Deinit()
+			t.Fatalf("expected %+v to fail", a)
+		}
+		// Deinit is harmless after a failed Init
+		a.Deinit()
+	}
+}
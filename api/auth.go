@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The metadata key clients send the shared token in, as "Bearer <token>"
+const tokenMetadataKey = "authorization"
+
+// Builds the server's TLS config from TLSCertFile and TLSKeyFile, verifying client
+// certificates against TLSClientCAFile if it's set
+func (a *API) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(a.TLSCertFile, a.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load api tls certificate: %w", err)
+	}
+
+	out := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if a.TLSClientCAFile == "" {
+		return out, nil
+	}
+
+	pem, err := os.ReadFile(a.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read api tls client ca: %w", err)
+	}
+	out.ClientCAs = x509.NewCertPool()
+	if !out.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in api tls client ca %s", a.TLSClientCAFile)
+	}
+	out.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return out, nil
+}
+
+// Checks that the request carries the shared token, if one is configured
+func (a *API) authenticate(ctx context.Context) error {
+	if a.Token == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get(tokenMetadataKey) {
+		token, ok := strings.CutPrefix(value, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) == 1 {
+			return nil
+		}
+	}
+
+	a.m.Counter("unauthenticated").Inc()
+	return status.Error(codes.Unauthenticated, "missing or invalid api token")
+}
+
+func (a *API) unaryAuthInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authenticate(ctx); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (a *API) streamAuthInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authenticate(ss.Context()); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// The options securing the server, per the API's TLS and token settings
+func (a *API) serverOptions() ([]grpc.ServerOption, error) {
+	out := []grpc.ServerOption{
+		grpc.UnaryInterceptor(a.unaryAuthInterceptor),
+		grpc.StreamInterceptor(a.streamAuthInterceptor),
+	}
+
+	if a.TLSCertFile == "" {
+		if a.TLSClientCAFile != "" {
+			return nil, fmt.Errorf("verifying api client certificates requires a tls certificate and key")
+		}
+		if a.Token != "" {
+			a.Logger.Warn("The api token is sent in the clear, as TLS is not enabled")
+		}
+		return out, nil
+	}
+
+	tlsConfig, err := a.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, grpc.Creds(credentials.NewTLS(tlsConfig))), nil
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Rocket-Rescue-Node/rescue-proxy/pb"
@@ -16,27 +19,91 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// tokenCredentials sends the api token with every request
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// The token may also be sent without TLS, to an api that doesn't have it enabled
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
 func main() {
 	addr := flag.String("addr", "0.0.0.0:8080", "the address where the api is responding to grpc requests")
 	odao := flag.Bool("odao", false, "pass this to get the list of odao nodes")
 	solo := flag.Bool("solo", false, "pass this to get the list of solo validator withdrawal addresses")
 	validateEIP1271 := flag.Bool("validate-eip1271", false, "pass this to validate an EIP-1271 signature")
-	dataHash := flag.String("data-hash", "", "data hash for EIP-1271 validation (32 bytes in hex)")
-	signature := flag.String("signature", "", "signature for EIP-1271 validation (hex)")
-	signerAddress := flag.String("signer-address", "", "signer address for EIP-1271 validation (20 bytes in hex)")
+	validateEIP1271Batch := flag.String("validate-eip1271-batch", "", "a file, or - for stdin, of EIP-1271 signatures to validate together, one data-hash,signature,signer-address per line in hex")
+	validateSignature := flag.Bool("validate-signature", false, "pass this to validate an EOA, EIP-1271 or EIP-6492 signature")
+	dataHash := flag.String("data-hash", "", "data hash for signature validation (32 bytes in hex)")
+	signature := flag.String("signature", "", "signature for signature validation (hex)")
+	signerAddress := flag.String("signer-address", "", "signer address for signature validation (20 bytes in hex)")
+	rpInfoAt := flag.Bool("rp-info-at", false, "pass this to get a minipool validator's fee recipients as of a past block")
+	pubkey := flag.String("pubkey", "", "validator pubkey for rp-info-at or expected-fee-recipient (48 bytes in hex)")
+	index := flag.Int64("index", -1, "validator index for expected-fee-recipient, instead of pubkey")
+	expectedFeeRecipient := flag.Bool("expected-fee-recipient", false, "pass this with pubkey or index to get a validator's classification and expected fee recipient")
+	block := flag.Uint64("block", 0, "block number for rp-info-at, or validate-signature (0 for the latest)")
+	withdrawalAddress := flag.String("withdrawal-address", "", "pass a withdrawal address (20 bytes in hex) to find the nodes using it for ETH or RPL")
+	nodeWithdrawalAddresses := flag.String("node-withdrawal-addresses", "", "pass a node address (20 bytes in hex) to get its withdrawal addresses")
+	nodeInfo := flag.String("node-info", "", "pass a node address (20 bytes in hex) to get everything the proxy knows about the node")
+	streamNodeSet := flag.Bool("stream-node-set", false, "pass this to follow the node set, printing a snapshot and then each change as a line of json")
+	cursor := flag.String("cursor", "", "the instance:block:index cursor of the last update received, for stream-node-set to resume from")
 	useTLS := flag.Bool("tls", false, "use TLS to connect to the api")
+	tlsCAFile := flag.String("tls-ca-file", "", "CA certificates to verify the api's certificate against, instead of the system's. Implies tls.")
+	tlsCertFile := flag.String("tls-cert-file", "", "a client certificate to present to the api, for apis that verify them. Implies tls.")
+	tlsKeyFile := flag.String("tls-key-file", "", "the key for tls-cert-file")
+	token := flag.String("token", "", "the token to send the api, for apis that require one. Other users can read it from the command line, so prefer token-file.")
+	tokenFile := flag.String("token-file", "", "a file to read token from, ignoring surrounding whitespace")
 
 	flag.Parse()
 
 	var tc credentials.TransportCredentials
-	if *useTLS {
+	if *useTLS || *tlsCAFile != "" || *tlsCertFile != "" {
 		// An empty tls.Config{} will use the system's root CA set.
-		tc = credentials.NewTLS(&tls.Config{})
+		tlsConfig := &tls.Config{}
+		if *tlsCAFile != "" {
+			pem, err := os.ReadFile(*tlsCAFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				fmt.Fprintf(os.Stderr, "No certificates found in %s\n", *tlsCAFile)
+				os.Exit(1)
+			}
+		}
+		if *tlsCertFile != "" {
+			cert, err := tls.LoadX509KeyPair(*tlsCertFile, *tlsKeyFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		tc = credentials.NewTLS(tlsConfig)
 	} else {
 		tc = insecure.NewCredentials()
 	}
 
-	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(tc))
+	if *tokenFile != "" {
+		b, err := os.ReadFile(*tokenFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		*token = strings.TrimSpace(string(b))
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(tc)}
+	if *token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(*token)))
+	}
+
+	conn, err := grpc.NewClient(*addr, opts...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
@@ -46,12 +113,140 @@ func main() {
 
 	c := pb.NewApiClient(conn)
 
+	if *streamNodeSet {
+		request := &pb.NodeSetRequest{}
+		if *cursor != "" {
+			request.Cursor = &pb.NodeSetCursor{}
+			if _, err := fmt.Sscanf(*cursor, "%d:%d:%d", &request.Cursor.Instance, &request.Cursor.Block, &request.Cursor.Index); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid cursor: must be instance:block:index\n")
+				os.Exit(1)
+			}
+		}
+
+		// Streams until interrupted
+		stream, err := c.StreamNodeSet(context.Background(), request)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+		addrs := func(in [][]byte) []string {
+			out := make([]string, 0, len(in))
+			for _, addr := range in {
+				out = append(out, "0x"+hex.EncodeToString(addr))
+			}
+			return out
+		}
+
+		for {
+			update, err := stream.Recv()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+
+			out := map[string]any{
+				"cursor": fmt.Sprintf("%d:%d:%d", update.GetCursor().GetInstance(), update.GetCursor().GetBlock(), update.GetCursor().GetIndex()),
+			}
+			if snapshot := update.GetSnapshot(); snapshot != nil {
+				out["snapshot"] = map[string]any{
+					"node_ids":                  addrs(snapshot.NodeIds),
+					"odao_node_ids":             addrs(snapshot.OdaoNodeIds),
+					"solo_withdrawal_addresses": addrs(snapshot.SoloWithdrawalAddresses),
+				}
+			} else {
+				out["change"] = update.GetChange().GetType().String()
+				out["address"] = "0x" + hex.EncodeToString(update.GetChange().GetAddress())
+			}
+
+			j, err := json.Marshal(out)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s\n", j)
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if *validateEIP1271 {
+	if *validateEIP1271Batch != "" {
+		in := os.Stdin
+		if *validateEIP1271Batch != "-" {
+			in, err = os.Open(*validateEIP1271Batch)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			defer in.Close()
+		}
+
+		request := &pb.ValidateEIP1271BatchRequest{}
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			fields := strings.Split(line, ",")
+			if len(fields) != 3 {
+				fmt.Fprintf(os.Stderr, "Invalid line %q: expected data-hash,signature,signer-address\n", line)
+				os.Exit(1)
+			}
+
+			decoded := make([][]byte, 3)
+			for i, field := range fields {
+				decoded[i], err = hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(field), "0x"))
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Invalid line %q: %v\n", line, err)
+					os.Exit(1)
+				}
+			}
+
+			request.Requests = append(request.Requests, &pb.ValidateEIP1271Request{
+				DataHash:  decoded[0],
+				Signature: decoded[1],
+				Address:   decoded[2],
+			})
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+
+		r, err := c.ValidateEIP1271Batch(ctx, request)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s\n", r.Error)
+			os.Exit(1)
+		}
+
+		out := make([]map[string]any, 0, len(r.Responses))
+		for _, response := range r.Responses {
+			result := map[string]any{"valid": response.Valid}
+			if response.Error != "" {
+				result["error"] = response.Error
+			}
+			out = append(out, result)
+		}
+
+		j, err := json.Marshal(out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s\n", j)
+		return
+	}
+
+	if *validateEIP1271 || *validateSignature {
 		if *dataHash == "" || *signature == "" || *signerAddress == "" {
-			fmt.Fprintf(os.Stderr, "For EIP-1271 validation, data-hash, signature, and signer-address are required\n")
+			fmt.Fprintf(os.Stderr, "For signature validation, data-hash, signature, and signer-address are required\n")
 			os.Exit(1)
 		}
 
@@ -73,6 +268,24 @@ func main() {
 			os.Exit(1)
 		}
 
+		if *validateSignature {
+			r, err := c.ValidateSignature(ctx, &pb.ValidateSignatureRequest{
+				DataHash:  dataHashBytes,
+				Signature: signatureBytes,
+				Address:   signerAddressBytes,
+				Block:     *block,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+			if r.Error != "" {
+				fmt.Printf("Validation error: %s\n", r.Error)
+			}
+			fmt.Printf("Signature Validation Result: %v (%s)\n", r.Valid, r.Method)
+			return
+		}
+
 		r, err := c.ValidateEIP1271(ctx, &pb.ValidateEIP1271Request{
 			DataHash:  dataHashBytes,
 			Signature: signatureBytes,
@@ -89,6 +302,209 @@ func main() {
 		return
 	}
 
+	if *rpInfoAt {
+		pubkeyBytes, err := hex.DecodeString(strings.TrimPrefix(*pubkey, "0x"))
+		if err != nil || len(pubkeyBytes) != 48 {
+			fmt.Fprintf(os.Stderr, "Invalid pubkey: must be 48 bytes in hex\n")
+			os.Exit(1)
+		}
+
+		r, err := c.GetRPInfoAt(ctx, &pb.RPInfoAtRequest{
+			Pubkey: pubkeyBytes,
+			Block:  *block,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s\n", r.Error)
+			os.Exit(1)
+		}
+		if !r.IsMinipool {
+			fmt.Printf("Not a minipool at block %d\n", *block)
+			return
+		}
+
+		acceptable := make([]string, 0, len(r.AcceptableFeeRecipients))
+		for _, addr := range r.AcceptableFeeRecipients {
+			acceptable = append(acceptable, "0x"+hex.EncodeToString(addr))
+		}
+
+		j, err := json.Marshal(map[string]any{
+			"node_address":              "0x" + hex.EncodeToString(r.NodeAddress),
+			"expected_fee_recipient":    "0x" + hex.EncodeToString(r.ExpectedFeeRecipient),
+			"acceptable_fee_recipients": acceptable,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s\n", j)
+		return
+	}
+
+	if *expectedFeeRecipient {
+		request := &pb.ExpectedFeeRecipientRequest{}
+		if *index >= 0 {
+			request.Validator = &pb.ExpectedFeeRecipientRequest_Index{Index: uint64(*index)}
+		} else {
+			pubkeyBytes, err := hex.DecodeString(strings.TrimPrefix(*pubkey, "0x"))
+			if err != nil || len(pubkeyBytes) != 48 {
+				fmt.Fprintf(os.Stderr, "Invalid pubkey: must be 48 bytes in hex\n")
+				os.Exit(1)
+			}
+			request.Validator = &pb.ExpectedFeeRecipientRequest_Pubkey{Pubkey: pubkeyBytes}
+		}
+
+		r, err := c.GetExpectedFeeRecipient(ctx, request)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s\n", r.Error)
+			os.Exit(1)
+		}
+
+		hexOrEmpty := func(b []byte) string {
+			if len(b) == 0 {
+				return ""
+			}
+			return "0x" + hex.EncodeToString(b)
+		}
+		acceptable := make([]string, 0, len(r.AcceptableFeeRecipients))
+		for _, addr := range r.AcceptableFeeRecipients {
+			acceptable = append(acceptable, hexOrEmpty(addr))
+		}
+
+		out := map[string]any{
+			"type":                      r.Type.String(),
+			"pubkey":                    hexOrEmpty(r.Pubkey),
+			"pending":                   r.Pending,
+			"withdrawal_address":        hexOrEmpty(r.WithdrawalAddress),
+			"node_address":              hexOrEmpty(r.NodeAddress),
+			"in_smoothing_pool":         r.InSmoothingPool,
+			"expected_fee_recipient":    hexOrEmpty(r.ExpectedFeeRecipient),
+			"acceptable_fee_recipients": acceptable,
+			"reth_fallback":             r.RethFallback,
+			"reth_address":              hexOrEmpty(r.RethAddress),
+		}
+		if r.InBeaconState {
+			out["index"] = r.Index
+		}
+
+		j, err := json.Marshal(out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s\n", j)
+		return
+	}
+
+	if *nodeInfo != "" {
+		addrBytes, err := hex.DecodeString(strings.TrimPrefix(*nodeInfo, "0x"))
+		if err != nil || len(addrBytes) != 20 {
+			fmt.Fprintf(os.Stderr, "Invalid node address: must be 20 bytes in hex\n")
+			os.Exit(1)
+		}
+
+		r, err := c.GetNodeInfo(ctx, &pb.NodeInfoRequest{NodeAddress: addrBytes})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s\n", r.Error)
+			os.Exit(1)
+		}
+
+		// Change times are printed as RFC 3339, or null if unknown
+		changed := func(unix int64) any {
+			if unix == 0 {
+				return nil
+			}
+			return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+		}
+		minipools := make([]string, 0, len(r.MinipoolPubkeys))
+		for _, pubkey := range r.MinipoolPubkeys {
+			minipools = append(minipools, "0x"+hex.EncodeToString(pubkey))
+		}
+
+		out := map[string]any{
+			"node_address": "0x" + hex.EncodeToString(r.NodeAddress),
+			"registered":   r.Registered,
+		}
+		if r.Registered {
+			out["in_smoothing_pool"] = r.InSmoothingPool
+			out["fee_distributor"] = "0x" + hex.EncodeToString(r.FeeDistributor)
+			out["minipool_pubkeys"] = minipools
+			out["odao_member"] = r.OdaoMember
+			out["smoothing_pool_changed"] = changed(r.SmoothingPoolChanged)
+			out["fee_distributor_changed"] = changed(r.FeeDistributorChanged)
+			out["minipools_changed"] = changed(r.MinipoolsChanged)
+			out["odao_changed"] = changed(r.OdaoChanged)
+		}
+
+		j, err := json.Marshal(out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s\n", j)
+		return
+	}
+
+	if *withdrawalAddress != "" || *nodeWithdrawalAddresses != "" {
+		request := &pb.WithdrawalAddressesRequest{}
+		if *withdrawalAddress != "" {
+			addrBytes, err := hex.DecodeString(strings.TrimPrefix(*withdrawalAddress, "0x"))
+			if err != nil || len(addrBytes) != 20 {
+				fmt.Fprintf(os.Stderr, "Invalid withdrawal address: must be 20 bytes in hex\n")
+				os.Exit(1)
+			}
+			request.Address = &pb.WithdrawalAddressesRequest_WithdrawalAddress{WithdrawalAddress: addrBytes}
+		} else {
+			addrBytes, err := hex.DecodeString(strings.TrimPrefix(*nodeWithdrawalAddresses, "0x"))
+			if err != nil || len(addrBytes) != 20 {
+				fmt.Fprintf(os.Stderr, "Invalid node address: must be 20 bytes in hex\n")
+				os.Exit(1)
+			}
+			request.Address = &pb.WithdrawalAddressesRequest_NodeAddress{NodeAddress: addrBytes}
+		}
+
+		r, err := c.GetWithdrawalAddresses(ctx, request)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "%s\n", r.Error)
+			os.Exit(1)
+		}
+
+		out := make([]map[string]any, 0, len(r.Nodes))
+		for _, node := range r.Nodes {
+			result := map[string]any{
+				"node_address":       "0x" + hex.EncodeToString(node.NodeAddress),
+				"withdrawal_address": "0x" + hex.EncodeToString(node.WithdrawalAddress),
+			}
+			if len(node.RplWithdrawalAddress) > 0 {
+				result["rpl_withdrawal_address"] = "0x" + hex.EncodeToString(node.RplWithdrawalAddress)
+			}
+			out = append(out, result)
+		}
+
+		j, err := json.Marshal(out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s\n", j)
+		return
+	}
+
 	var nodeIds [][]byte
 
 	if *odao {
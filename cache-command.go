@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Rocket-Rescue-Node/rescue-proxy/executionlayer"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+)
+
+const cacheUsage = `Usage: rescue-proxy cache <command> [options]
+
+Inspects or seeds the execution layer cache snapshot in a -cache-path directory.
+The snapshot must not be in use by a running rescue-proxy.
+
+Commands:
+  dump    Write every cached node, minipool and odao node as json or csv
+  lookup  Show what the cache holds for a validator pubkey or node address
+  import  Create a snapshot from a dump, so a new proxy can skip warming up
+
+Run rescue-proxy cache <command> -h for the options of each command.
+`
+
+// runCacheCommand implements the cache subcommand, with args being everything after "cache"
+func runCacheCommand(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, cacheUsage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "dump":
+		err = cacheDump(args[1:], stdout, stderr)
+	case "lookup":
+		err = cacheLookup(args[1:], stdout, stderr)
+	case "import":
+		err = cacheImport(args[1:], stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, cacheUsage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown cache command %q\n\n%s", args[0], cacheUsage)
+		return 2
+	}
+
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "rescue-proxy cache %s: %v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+func newCacheFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("rescue-proxy cache "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cachePath := fs.String("cache-path", "", "The directory holding the cache snapshot (required)")
+	return fs, cachePath
+}
+
+func validateFormat(format string) error {
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("invalid -format %q, must be json or csv", format)
+	}
+
+	return nil
+}
+
+// The cache registers metrics as it opens, so they must be initialized even though nothing serves them
+func openCacheSnapshot(cachePath string) (*executionlayer.CacheSnapshot, error) {
+	if cachePath == "" {
+		return nil, fmt.Errorf("-cache-path is required")
+	}
+
+	if _, err := metrics.Init("rescue_proxy"); err != nil {
+		return nil, err
+	}
+
+	return executionlayer.OpenCacheSnapshot(cachePath)
+}
+
+func cacheDump(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs, cachePath := newCacheFlagSet("dump", stderr)
+	format := fs.String("format", "json", "The output format, json or csv")
+	output := fs.String("o", "", "The file to write the dump to (defaults to stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+
+	snapshot, err := openCacheSnapshot(*cachePath)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	dump, err := snapshot.Dump()
+	if err != nil {
+		return err
+	}
+
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "csv" {
+		return dump.WriteCSV(w)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dump)
+}
+
+type cacheLookupNode struct {
+	*executionlayer.CacheDumpNode
+	IsOdao    bool                      `json:"is_odao"`
+	Minipools []rptypes.ValidatorPubkey `json:"minipools"`
+}
+
+type cacheLookupValidator struct {
+	Pubkey                  rptypes.ValidatorPubkey `json:"pubkey"`
+	NodeAddress             common.Address          `json:"node_address"`
+	ExpectedFeeRecipient    common.Address          `json:"expected_fee_recipient"`
+	AcceptableFeeRecipients []common.Address        `json:"acceptable_fee_recipients"`
+	Node                    *cacheLookupNode        `json:"node"`
+}
+
+func lookupNode(snapshot *executionlayer.CacheSnapshot, addr common.Address) (*cacheLookupNode, error) {
+	n, err := snapshot.GetNode(addr)
+	if err != nil || n == nil {
+		return nil, err
+	}
+
+	out := &cacheLookupNode{CacheDumpNode: n}
+	out.IsOdao, err = snapshot.IsOdaoNode(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Minipools, err = snapshot.GetNodeMinipools(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func cacheLookup(args []string, stdout io.Writer, stderr io.Writer) error {
+	fs, cachePath := newCacheFlagSet("lookup", stderr)
+	smoothingPoolAddr := fs.String("smoothing-pool-addr", "0xd4E96eF8eee8678dBFf4d535E033Ed1a4F7605b7", "The smoothing pool address of the network the cache was built from")
+	spGraceEpochs := fs.Uint64("sp-grace-epochs", 4, "The -sp-grace-epochs the proxy runs with")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rescue-proxy cache lookup [options] <validator pubkey|node address>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one validator pubkey or node address")
+	}
+
+	if !common.IsHexAddress(*smoothingPoolAddr) {
+		return fmt.Errorf("invalid -smoothing-pool-addr %q", *smoothingPoolAddr)
+	}
+
+	key := fs.Arg(0)
+	var pubkey rptypes.ValidatorPubkey
+	var nodeAddr common.Address
+	isAddress := common.IsHexAddress(key)
+	if isAddress {
+		nodeAddr = common.HexToAddress(key)
+	} else {
+		var err error
+		pubkey, err = rptypes.HexToValidatorPubkey(strings.TrimPrefix(key, "0x"))
+		if err != nil {
+			return fmt.Errorf("%q is neither a validator pubkey nor a node address", key)
+		}
+	}
+
+	snapshot, err := openCacheSnapshot(*cachePath)
+	if err != nil {
+		return err
+	}
+	defer snapshot.Close()
+
+	var out interface{}
+	if isAddress {
+		node, err := lookupNode(snapshot, nodeAddr)
+		if err != nil {
+			return err
+		}
+		if node == nil {
+			return fmt.Errorf("node %s not found in the cache", nodeAddr.String())
+		}
+		out = node
+	} else {
+		rpInfo, err := snapshot.GetRPInfo(pubkey, common.HexToAddress(*smoothingPoolAddr), *spGraceEpochs)
+		if err != nil {
+			return err
+		}
+		if rpInfo == nil {
+			return fmt.Errorf("validator %s is not a minipool in the cache", pubkey.String())
+		}
+
+		node, err := lookupNode(snapshot, rpInfo.NodeAddress)
+		if err != nil {
+			return err
+		}
+
+		out = &cacheLookupValidator{
+			Pubkey:                  pubkey,
+			NodeAddress:             rpInfo.NodeAddress,
+			ExpectedFeeRecipient:    *rpInfo.ExpectedFeeRecipient,
+			AcceptableFeeRecipients: rpInfo.AcceptableFeeRecipients,
+			Node:                    node,
+		}
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func cacheImport(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	fs, cachePath := newCacheFlagSet("import", stderr)
+	format := fs.String("format", "json", "The input format, json or csv")
+	force := fs.Bool("force", false, "Replace an existing snapshot")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: rescue-proxy cache import [options] <dump file, or - for stdin>\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one dump file")
+	}
+
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+
+	if *cachePath == "" {
+		return fmt.Errorf("-cache-path is required")
+	}
+
+	r := stdin
+	if fs.Arg(0) != "-" {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var dump *executionlayer.CacheDump
+	if *format == "csv" {
+		var err error
+		dump, err = executionlayer.ReadCacheDumpCSV(r)
+		if err != nil {
+			return err
+		}
+	} else {
+		dump = new(executionlayer.CacheDump)
+		if err := json.NewDecoder(r).Decode(dump); err != nil {
+			return err
+		}
+	}
+
+	if _, err := metrics.Init("rescue_proxy"); err != nil {
+		return err
+	}
+
+	if err := executionlayer.ImportCacheSnapshot(*cachePath, dump, *force); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Imported %d nodes, %d minipools and %d odao nodes at block %d into %s\n",
+		len(dump.Nodes), len(dump.Minipools), len(dump.OdaoNodes), dump.HighestBlock, *cachePath)
+	return nil
+}
@@ -7,7 +7,9 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/pkg/errors"
 )
 
@@ -38,22 +40,65 @@ func (c *CredentialSecrets) Set(arg string) error {
 	return nil
 }
 
+// ExecutionURLs is an ordered list of execution clients, most preferred first.
+type ExecutionURLs []*url.URL
+
+func (e *ExecutionURLs) String() string {
+	if e == nil {
+		return ""
+	}
+
+	out := make([]string, 0, len(*e))
+	for _, u := range *e {
+		out = append(out, u.Redacted())
+	}
+
+	return strings.Join(out, ",")
+}
+
+func (e *ExecutionURLs) Set(arg string) error {
+	u, err := url.Parse(arg)
+	if err != nil {
+		return errors.Wrapf(err, "invalid -ec-url %s", arg)
+	}
+
+	// We must use websockets to subscribe to events
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return fmt.Errorf("invalid -ec-url %s: only ws Execution Clients are supported right now", arg)
+	}
+
+	*e = append(*e, u)
+	return nil
+}
+
 type Config struct {
-	BeaconURL            *url.URL
-	ExecutionURL         *url.URL
-	ListenAddr           string
-	APIListenAddr        string
-	AdminListenAddr      string
-	GRPCListenAddr       string
-	GRPCBeaconAddr       string
-	GRPCTLSCertFile      string
-	GRPCTLSKeyFile       string
-	RocketStorageAddr    string
-	CredentialSecrets    CredentialSecrets
-	CachePath            string
-	EnableSoloValidators bool
-	Debug                bool
-	ForceBNJSON          bool
+	BeaconURL             *url.URL
+	ExecutionURLs         ExecutionURLs
+	ListenAddr            string
+	APIListenAddr         string
+	APITLSCertFile        string
+	APITLSKeyFile         string
+	APITLSClientCAFile    string
+	APIToken              string
+	AdminListenAddr       string
+	GRPCListenAddr        string
+	GRPCBeaconAddr        string
+	GRPCTLSCertFile       string
+	GRPCTLSKeyFile        string
+	RocketStorageAddr     string
+	Network               *Network
+	CredentialSecrets     CredentialSecrets
+	CachePath             string
+	EnableSoloValidators  bool
+	Debug                 bool
+	ForceBNJSON           bool
+	ReconcileInterval     time.Duration
+	SPGraceEpochs         uint64
+	CacheSnapshotInterval time.Duration
+	CacheKVURL            *url.URL
+	CacheKVPrefix         string
+	EIP1271CacheTTL       time.Duration
+	EIP1271Concurrency    int
 }
 
 func InitFlags() *Config {
@@ -67,20 +112,39 @@ Value must be at least 32 bytes of entropy, base64-encoded.
 Use 'dd if=/dev/urandom bs=4 count=8 | base64' if you need to generate a new secret.`,
 	)
 
+	executionURLs := make(ExecutionURLs, 0)
+	flag.Var(&executionURLs, "ec-url",
+		`URL to the execution client to use, eg, ws://localhost:8546
+May be passed multiple times. Additional execution clients are failed over to, in order, if the current one becomes unavailable, and switched back from once a more preferred one recovers.`,
+	)
+
 	bnURLFlag := flag.String("bn-url", "", "URL to the beacon node to proxy, eg, http://localhost:5052")
-	ecURLFlag := flag.String("ec-url", "", "URL to the execution client to use, eg, http://localhost:8545")
 	addrURLFlag := flag.String("addr", "0.0.0.0:80", "Address on which to reply to HTTP requests")
 	adminAddrURLFlag := flag.String("admin-addr", "0.0.0.0:8000", "Address on which to reply to admin/metrics requests")
 	apiAddrURLFlag := flag.String("api-addr", "0.0.0.0:8080", "Address on which to reply to gRPC API requests")
+	apiTLSCertFileFlag := flag.String("api-tls-cert-file", "", "Optional TLS Certificate for the gRPC API")
+	apiTLSKeyFileFlag := flag.String("api-tls-key-file", "", "Optional TLS Key for the gRPC API")
+	apiTLSClientCAFileFlag := flag.String("api-tls-client-ca-file", "", "Optional CA certificates to verify gRPC API client certificates against. Clients without a certificate signed by one are refused. Requires -api-tls-cert-file.")
+	apiTokenFlag := flag.String("api-token", "", "Optional token gRPC API clients must send in their authorization metadata, as 'Bearer <token>'.\nOther users on the host can read it from the command line, so prefer -api-token-file.")
+	apiTokenFileFlag := flag.String("api-token-file", "", "Optional file to read -api-token from, ignoring surrounding whitespace. Can't be combined with -api-token.")
 	grpcAddrFlag := flag.String("grpc-addr", "", "Address on which to reply to gRPC requests")
 	grpcBeaconAddrFlag := flag.String("grpc-beacon-addr", "", "Address to the beacon node to proxy for gRPC, eg, localhost:4000")
 	grpcTLSCertFileFlag := flag.String("grpc-tls-cert-file", "", "Optional TLS Certificate for the gRPC host")
 	grpcTLSKeyFileFlag := flag.String("grpc-tls-key-file", "", "Optional TLS Key for the gRPC host")
-	rocketStorageAddrFlag := flag.String("rocketstorage-addr", "0x1d8f8f00cfa6758d7bE78336684788Fb0ee0Fa46", "Address of the Rocket Storage contract. Defaults to mainnet")
+	networkFlag := flag.String("network", "mainnet", fmt.Sprintf("The network to run on, one of %s.\nSets the Rocket Storage address, and the chain the execution clients and beacon node must be on.", strings.Join(NetworkNames(), ", ")))
+	rocketStorageAddrFlag := flag.String("rocketstorage-addr", "", "Address of the Rocket Storage contract. Required with -network custom, and overrides the preset's otherwise.")
+	chainIDFlag := flag.Uint64("chain-id", 0, "With -network custom, the chain ID the execution clients and beacon node must be on. 0 skips the check.")
 	debug := flag.Bool("debug", false, "Whether to enable verbose logging")
-	cachePathFlag := flag.String("cache-path", "", "A path to cache EL data in. Leave blank to disable caching.")
+	cachePathFlag := flag.String("cache-path", "", "A path to cache EL data, and snapshot the validator cache, in. Leave blank to disable caching.")
+	cacheKVURLFlag := flag.String("cache-kv-url", "", "A redis://[[user]:password@]host[:port][/db] url of a key-value store to keep the EL cache in, shared by every rescue-proxy pointed at it.\nOnly one of them at a time follows the chain and updates it. Can't be combined with -cache-path.")
+	cacheKVPrefixFlag := flag.String("cache-kv-prefix", "rescue-proxy", "Prefix for the keys of the EL cache in the -cache-kv-url store. Use a different one for each network sharing a store.")
+	cacheSnapshotIntervalFlag := flag.Duration("cache-snapshot-interval", 5*time.Minute, "How often to save the EL cache to -cache-path while running, so that it survives an unclean exit. 0 only saves it on shutdown.")
 	enableSoloValidatorsFlag := flag.Bool("enable-solo-validators", true, "Whether or not to allow solo validators access.")
 	forceBNJSONFlag := flag.Bool("force-bn-json", false, "Disables SSZ in the BN.")
+	reconcileIntervalFlag := flag.Duration("reconcile-interval", time.Hour, "How often to re-read all Rocket Pool state from the execution client and correct any drift in the cache. 0 disables reconciliation.")
+	eip1271CacheTTLFlag := flag.Duration("eip1271-cache-ttl", 12*time.Second, "How long to cache EIP-1271 signature validation results for the block they were validated at. 0 disables caching.")
+	eip1271ConcurrencyFlag := flag.Int("eip1271-concurrency", 8, "How many EIP-1271 signatures batch validation requests validate at once, between them.")
+	spGraceEpochsFlag := flag.Uint64("sp-grace-epochs", 4, "How many epochs after a node joins or leaves the smoothing pool to keep accepting its previous fee recipient.")
 
 	flag.Parse()
 
@@ -98,7 +162,7 @@ Use 'dd if=/dev/urandom bs=4 count=8 | base64' if you need to generate a new sec
 		return nil
 	}
 
-	if *ecURLFlag == "" {
+	if len(executionURLs) == 0 {
 		fmt.Fprintf(os.Stderr, "Invalid -ec-url:\n")
 		flag.PrintDefaults()
 		os.Exit(1)
@@ -113,13 +177,7 @@ Use 'dd if=/dev/urandom bs=4 count=8 | base64' if you need to generate a new sec
 	}
 	config.BeaconURL = base
 
-	base, err = url.Parse(*ecURLFlag)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Invalid -ec-url: %s\n %v\n", *ecURLFlag, err)
-		os.Exit(1)
-		return nil
-	}
-	config.ExecutionURL = base
+	config.ExecutionURLs = executionURLs
 
 	if config.BeaconURL.Scheme != "http" && config.BeaconURL.Scheme != "https" {
 		fmt.Fprintf(os.Stderr, "Invalid -bn-url: %s\nOnly http and https Beacon Nodes are supported right now.\n", *bnURLFlag)
@@ -127,13 +185,6 @@ Use 'dd if=/dev/urandom bs=4 count=8 | base64' if you need to generate a new sec
 		return nil
 	}
 
-	// We must use websockets to subscribe to events
-	if config.ExecutionURL.Scheme != "ws" {
-		fmt.Fprintf(os.Stderr, "Invalid -ec-url: %s\nOnly ws Execution Clients are supported right now.\n", *ecURLFlag)
-		os.Exit(1)
-		return nil
-	}
-
 	if *addrURLFlag == "" {
 		fmt.Fprintf(os.Stderr, "Invalid -addr:\n")
 		os.Exit(1)
@@ -162,6 +213,101 @@ Use 'dd if=/dev/urandom bs=4 count=8 | base64' if you need to generate a new sec
 		return nil
 	}
 
+	config.APITLSCertFile = *apiTLSCertFileFlag
+	config.APITLSKeyFile = *apiTLSKeyFileFlag
+	if (config.APITLSCertFile == "") != (config.APITLSKeyFile == "") {
+		fmt.Fprintf(os.Stderr, "If either -api-tls-key-file or -api-tls-cert-file is set, both must be set\n")
+		os.Exit(1)
+		return nil
+	}
+
+	config.APITLSClientCAFile = *apiTLSClientCAFileFlag
+	if config.APITLSClientCAFile != "" && config.APITLSCertFile == "" {
+		fmt.Fprintf(os.Stderr, "-api-tls-client-ca-file requires -api-tls-cert-file and -api-tls-key-file\n")
+		os.Exit(1)
+		return nil
+	}
+	config.APIToken = *apiTokenFlag
+	if *apiTokenFileFlag != "" {
+		if config.APIToken != "" {
+			fmt.Fprintf(os.Stderr, "Only one of -api-token and -api-token-file may be set\n")
+			os.Exit(1)
+			return nil
+		}
+
+		token, err := os.ReadFile(*apiTokenFileFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -api-token-file: %v\n", err)
+			os.Exit(1)
+			return nil
+		}
+
+		config.APIToken = strings.TrimSpace(string(token))
+		if config.APIToken == "" {
+			fmt.Fprintf(os.Stderr, "Invalid -api-token-file: %s is empty\n", *apiTokenFileFlag)
+			os.Exit(1)
+			return nil
+		}
+	}
+
+	if *cacheKVURLFlag != "" {
+		if *cachePathFlag != "" {
+			fmt.Fprintf(os.Stderr, "Only one of -cache-path and -cache-kv-url may be set\n")
+			os.Exit(1)
+			return nil
+		}
+
+		u, err := url.Parse(*cacheKVURLFlag)
+		if err != nil || u.Scheme != "redis" || u.Host == "" {
+			fmt.Fprintf(os.Stderr, "Invalid -cache-kv-url: %s\nOnly redis:// urls are supported.\n", *cacheKVURLFlag)
+			os.Exit(1)
+			return nil
+		}
+		config.CacheKVURL = u
+	}
+
+	if *eip1271ConcurrencyFlag < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid -eip1271-concurrency: %d\nMust be at least 1.\n", *eip1271ConcurrencyFlag)
+		os.Exit(1)
+		return nil
+	}
+
+	if *networkFlag == CustomNetwork {
+		if !common.IsHexAddress(*rocketStorageAddrFlag) {
+			fmt.Fprintf(os.Stderr, "Invalid -rocketstorage-addr: %s\nA Rocket Storage address is required with -network custom.\n", *rocketStorageAddrFlag)
+			os.Exit(1)
+			return nil
+		}
+
+		config.Network = &Network{
+			Name:              CustomNetwork,
+			RocketStorageAddr: *rocketStorageAddrFlag,
+			ChainID:           *chainIDFlag,
+		}
+	} else {
+		config.Network = LookupNetwork(*networkFlag)
+		if config.Network == nil {
+			fmt.Fprintf(os.Stderr, "Invalid -network: %s\nMust be one of %s.\n", *networkFlag, strings.Join(NetworkNames(), ", "))
+			os.Exit(1)
+			return nil
+		}
+
+		if *chainIDFlag != 0 {
+			fmt.Fprintf(os.Stderr, "-chain-id may only be set with -network custom\n")
+			os.Exit(1)
+			return nil
+		}
+
+		if *rocketStorageAddrFlag != "" {
+			if !common.IsHexAddress(*rocketStorageAddrFlag) {
+				fmt.Fprintf(os.Stderr, "Invalid -rocketstorage-addr: %s\n", *rocketStorageAddrFlag)
+				os.Exit(1)
+				return nil
+			}
+			config.Network.RocketStorageAddr = *rocketStorageAddrFlag
+		}
+	}
+
 	config.AdminListenAddr = *adminAddrURLFlag
 	config.APIListenAddr = *apiAddrURLFlag
 	config.CredentialSecrets = credentialSecrets
@@ -169,9 +315,15 @@ Use 'dd if=/dev/urandom bs=4 count=8 | base64' if you need to generate a new sec
 	config.GRPCListenAddr = *grpcAddrFlag
 	config.GRPCBeaconAddr = *grpcBeaconAddrFlag
 	config.ListenAddr = *addrURLFlag
-	config.RocketStorageAddr = *rocketStorageAddrFlag
+	config.RocketStorageAddr = config.Network.RocketStorageAddr
 	config.EnableSoloValidators = *enableSoloValidatorsFlag
 	config.Debug = *debug
 	config.ForceBNJSON = *forceBNJSONFlag
+	config.ReconcileInterval = *reconcileIntervalFlag
+	config.SPGraceEpochs = *spGraceEpochsFlag
+	config.EIP1271CacheTTL = *eip1271CacheTTLFlag
+	config.EIP1271Concurrency = *eip1271ConcurrencyFlag
+	config.CacheSnapshotInterval = *cacheSnapshotIntervalFlag
+	config.CacheKVPrefix = *cacheKVPrefixFlag
 	return config
 }
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Network holds what the proxy needs to know about the chain it runs on
+type Network struct {
+	Name string
+
+	// Address of the Rocket Storage contract
+	RocketStorageAddr string
+
+	// The chain the execution clients, and the beacon node's deposit contract, must be on.
+	// Zero skips the checks.
+	ChainID uint64
+
+	// Identify the beacon chain. Zero values skip the checks.
+	GenesisValidatorsRoot common.Hash
+	DepositContractAddr   common.Address
+}
+
+// CustomNetwork is the name of the network whose parameters all come from flags
+const CustomNetwork = "custom"
+
+var networks = map[string]*Network{
+	"mainnet": {
+		Name:                  "mainnet",
+		RocketStorageAddr:     "0x1d8f8f00cfa6758d7bE78336684788Fb0ee0Fa46",
+		ChainID:               1,
+		GenesisValidatorsRoot: common.HexToHash("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"),
+		DepositContractAddr:   common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa"),
+	},
+	"holesky": {
+		Name:                  "holesky",
+		RocketStorageAddr:     "0x594Fb75D3dc2DFa0150Ad03F99F97817747dd4E1",
+		ChainID:               17000,
+		GenesisValidatorsRoot: common.HexToHash("0x9143aa7c615a7f7115e2b6aac319c03529df8242ae705fba9df39b79c59fa8b1"),
+		DepositContractAddr:   common.HexToAddress("0x4242424242424242424242424242424242424242"),
+	},
+	"hoodi": {
+		Name:                  "hoodi",
+		RocketStorageAddr:     "0x594Fb75D3dc2DFa0150Ad03F99F97817747dd4E1",
+		ChainID:               560048,
+		GenesisValidatorsRoot: common.HexToHash("0x212f13fc4df078b6cb7db228f1c8307566dcecf900867401a92023d7ba99cb5f"),
+		DepositContractAddr:   common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa"),
+	},
+}
+
+// NetworkNames lists the valid values of -network
+func NetworkNames() []string {
+	out := make([]string, 0, len(networks)+1)
+	for name := range networks {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+
+	return append(out, CustomNetwork)
+}
+
+// LookupNetwork returns a copy of a preset network, or nil if there's no such preset
+func LookupNetwork(name string) *Network {
+	n, ok := networks[name]
+	if !ok {
+		return nil
+	}
+
+	out := *n
+	return &out
+}
@@ -1,14 +1,20 @@
 package consensuslayer
 
 import (
-	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	nethttp "net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
-	"github.com/allegro/bigcache/v3"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
 	"github.com/attestantio/go-eth2-client/api"
 	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
 	"github.com/attestantio/go-eth2-client/http"
@@ -19,11 +25,26 @@ import (
 	"go.uber.org/zap"
 )
 
+// How often the whole validator set is reloaded, which picks up withdrawal credential changes and exits.
+// Reloads are taxing on the bn, so only do it every 16 epochs (about once every hour and a half).
+const validatorReloadInterval = 16 * 32 * 12 * time.Second
+
+// The most any request to the bn may take. Requests can set their own via ctx.
+const bnTimeout = 5 * time.Minute
+
+// How many validator indices to ask for at a time when looking for new validators
+const validatorRefreshChunkSize = 1000
+
+const validatorSnapshotFileName = "rescue-proxy-validators.snapshot"
+
 // ConsensusLayer provides an abstraction for the rescue proxy over the consensus layer
 // It's specifically needed to map validator indices to pubkeys prior to EL validation
 type ConsensusLayer interface {
 	GetValidatorInfo([]string) (map[string]*ValidatorInfo, error)
-	GetValidators() ([]*apiv1.Validator, error)
+	GetValidatorInfoByPubkey([]rptypes.ValidatorPubkey) (map[rptypes.ValidatorPubkey]*ValidatorInfo, error)
+	GetWithdrawalAddresses() []common.Address
+	GetBNStatus() *BNStatus
+	IsFit() bool
 }
 
 // CachingConsensusLayer provides rescue-proxy with the consensus layer information needed
@@ -34,9 +55,13 @@ type CachingConsensusLayer struct {
 
 	// Client for the BN
 	client *http.Service
+	// For the requests client doesn't support, such as streamed states. It has the same timeout.
+	rawClient *nethttp.Client
+	// For event streams client doesn't support, which stay open, so it only times out waiting for the bn to respond
+	eventsClient *nethttp.Client
 
-	// Caches index->validatorInfo for prepare_beacon_proposer
-	validatorCache *validatorCache
+	// Every validator, for the guards and the api
+	validators *validatorRegistry
 
 	// Disconnects from the bn
 	disconnect func()
@@ -44,22 +69,82 @@ type CachingConsensusLayer struct {
 	// Force attestantio client to use json
 	forceJSON bool
 
+	// The chain the bn must be on, if set
+	network *ExpectedNetwork
+
+	// A directory to save the validator registry to, and warm it up from on startup. Blank disables snapshots.
+	CachePath string
+
+	// The context passed to Init, for background refreshes
+	ctx context.Context
+
+	// When the whole validator set was last loaded, directly or by the snapshot that was warmed up from.
+	// Only refresh and prewarm, which never run concurrently, touch it.
+	lastFullLoad time.Time
+
+	// The epoch of the latest head, and whether the validator registry is being refreshed for it
+	lastEpoch  atomic.Uint64
+	refreshing atomic.Bool
+
+	// Validators with BLS to execution changes the bn has seen, by when, until the changes reach
+	// the head state, and whether they're being checked for
+	credentialChangesLock sync.Mutex
+	credentialChanges     map[phase0.ValidatorIndex]time.Time
+	checkingCredentials   atomic.Bool
+
+	// Background work, which stops being started once Deinit is called
+	stopLock sync.Mutex
+	stopped  bool
+	wg       sync.WaitGroup
+
+	// Told of withdrawal addresses added to or removed from GetWithdrawalAddresses, if set
+	NodeSet *nodeset.Feed
+	// The withdrawal addresses as of the last refresh. Only Init and refreshes touch it.
+	soloAddresses map[common.Address]struct{}
+
+	// Validators whose deposits haven't been applied to the head state yet, reloaded every epoch
+	pending atomic.Pointer[pendingValidators]
+
+	// The bn's health, and whether it was fit to serve when last polled, which the router gates requests on
+	statusLock sync.Mutex
+	status     BNStatus
+	fit        atomic.Bool
+	pollerWG   sync.WaitGroup
+
 	m             *metrics.MetricsRegistry
 	slotsPerEpoch uint64
 }
 
+// ExpectedNetwork identifies the chain the beacon node must be following. Zero values aren't checked.
+type ExpectedNetwork struct {
+	GenesisValidatorsRoot common.Hash
+	DepositContractAddr   common.Address
+	DepositChainID        uint64
+}
+
 type ValidatorInfo struct {
+	// Only set for pending validators if the index they'll be given is known
+	Index             phase0.ValidatorIndex
 	Pubkey            rptypes.ValidatorPubkey
 	WithdrawalAddress common.Address
-	Is0x01            bool
+	// Set for validators with an execution layer withdrawal address, ie 0x01 or 0x02 credentials
+	HasExecutionCredentials bool
+	// Set for validators with 0x02, ie compounding, credentials
+	Compounding bool
+	// FAR_FUTURE_EPOCH unless the validator is exiting or has exited
+	ExitEpoch phase0.Epoch
+	// Set for validators that have been deposited, but aren't in the beacon state yet
+	Pending bool
 }
 
-// NewConsensusLayer creates a new consensus layer client using the provided url and logger
-func NewCachingConsensusLayer(bnURL *url.URL, logger *zap.Logger, forceJSON bool) *CachingConsensusLayer {
+// NewConsensusLayer creates a new consensus layer client using the provided url and logger.
+// If network is not nil, Init fails unless the beacon node is on that network.
+func NewCachingConsensusLayer(bnURL *url.URL, logger *zap.Logger, forceJSON bool, network *ExpectedNetwork) *CachingConsensusLayer {
 	out := &CachingConsensusLayer{}
 	out.bnURL = bnURL
 	out.logger = logger
 	out.forceJSON = forceJSON
+	out.network = network
 	out.m = metrics.NewMetricsRegistry("consensus_layer")
 
 	return out
@@ -79,6 +164,244 @@ func (c *CachingConsensusLayer) onHeadUpdate(e *apiv1.Event) {
 	epoch := uint64(headEvent.Slot) / c.slotsPerEpoch
 
 	metrics.OnHead(epoch)
+
+	// Validators whose credentials just changed would be misclassified until the next full reload
+	c.startCheckCredentialChanges()
+
+	if prev := c.lastEpoch.Load(); epoch > prev && c.lastEpoch.CompareAndSwap(prev, epoch) {
+		c.startRefresh()
+	}
+}
+
+// Refreshes the validator registry in the background, unless a refresh is already running
+func (c *CachingConsensusLayer) startRefresh() {
+	if !c.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+
+	started := c.goUnlessStopped(func() {
+		defer c.refreshing.Store(false)
+
+		if err := c.refresh(c.ctx); err != nil {
+			c.m.Counter("validator_cache_refresh_failed").Inc()
+			c.logger.Warn("Couldn't refresh the validator registry", zap.Error(err))
+		}
+		c.publishSoloChanges()
+	})
+	if !started {
+		c.refreshing.Store(false)
+	}
+}
+
+// Runs f in a goroutine tracked by wg, unless Deinit was called
+func (c *CachingConsensusLayer) goUnlessStopped(f func()) bool {
+	c.stopLock.Lock()
+	defer c.stopLock.Unlock()
+
+	if c.stopped {
+		return false
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		f()
+	}()
+	return true
+}
+
+// Publishes the withdrawal addresses added to or removed from GetWithdrawalAddresses since the last call
+func (c *CachingConsensusLayer) publishSoloChanges() {
+	if c.NodeSet == nil {
+		return
+	}
+
+	current := make(map[common.Address]struct{})
+	for _, addr := range c.GetWithdrawalAddresses() {
+		current[addr] = struct{}{}
+	}
+
+	// The first call, from Init, only records where to start from
+	if c.soloAddresses != nil {
+		for addr := range current {
+			if _, ok := c.soloAddresses[addr]; !ok {
+				c.NodeSet.Publish(0, nodeset.SoloAdded, addr)
+			}
+		}
+		for addr := range c.soloAddresses {
+			if _, ok := current[addr]; !ok {
+				c.NodeSet.Publish(0, nodeset.SoloRemoved, addr)
+			}
+		}
+	}
+
+	c.soloAddresses = current
+}
+
+// Reloads the whole validator set if it's due, or else adds validators that appeared since the last refresh.
+// Pending validators are reloaded either way.
+func (c *CachingConsensusLayer) refresh(ctx context.Context) error {
+	c.refreshPendingValidators(ctx)
+
+	if time.Since(c.lastFullLoad) >= validatorReloadInterval {
+		return c.loadAllValidators(ctx)
+	}
+
+	return c.loadNewValidators(ctx)
+}
+
+// Seeds the validator registry from the snapshot in CachePath, or the bn if there isn't one,
+// so that validator clients reconnecting after a restart don't all miss the cache at once.
+func (c *CachingConsensusLayer) prewarm(ctx context.Context) {
+	if c.CachePath != "" {
+		loadedAt, count, err := c.validators.load(c.snapshotPath())
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			c.logger.Info("No validator cache snapshot found")
+		case err != nil:
+			c.m.Counter("validator_snapshot_load_failed").Inc()
+			c.logger.Warn("Couldn't load the validator cache snapshot", zap.Error(err))
+		default:
+			c.lastFullLoad = loadedAt
+			c.logger.Info("Loaded the validator cache snapshot",
+				zap.Int("validators", count), zap.Time("loaded_at", loadedAt))
+
+			// Only catch up on new validators for now. If the snapshot is old enough to need
+			// reloading, the first refresh will do so, without holding up startup.
+			if err := c.loadNewValidators(ctx); err != nil {
+				c.logger.Warn("Couldn't load validators added since the snapshot", zap.Error(err))
+			}
+			return
+		}
+	}
+
+	if err := c.loadAllValidators(ctx); err != nil {
+		c.m.Counter("validator_cache_refresh_failed").Inc()
+		c.logger.Warn("Couldn't prewarm the validator registry, validators will be looked up as they're seen", zap.Error(err))
+	}
+}
+
+// Loads every validator in the head state into the registry
+func (c *CachingConsensusLayer) loadAllValidators(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	mark := c.validators.StartLoad()
+	defer c.validators.FinishLoad()
+
+	// Stream the validators into the registry one at a time, rather than decoding the whole
+	// state, which is hundreds of megabytes on mainnet
+	count, err := c.forEachValidator(ctx, "head", c.validators.Reserve, func(index phase0.ValidatorIndex, validator *phase0.Validator) error {
+		c.validators.SetLoaded(newValidatorInfo(index, validator), mark)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	c.lastFullLoad = start
+	c.m.Gauge("validator_cache_size").Set(float64(c.validators.Len()))
+	c.m.Counter("validator_cache_reloaded").Inc()
+	c.m.Gauge("validator_cache_reload_seconds").Set(time.Since(start).Seconds())
+	c.logger.Info("Loaded the validator set", zap.Int("validators", count), zap.Duration("took", time.Since(start)))
+
+	// Save it now rather than only on shutdown, so that an unclean exit doesn't lose it
+	c.saveSnapshot()
+	return nil
+}
+
+// Loads validators with indices above the highest one in the registry, a chunk at a time, up to the
+// number of validators in the head state
+func (c *CachingConsensusLayer) loadNewValidators(ctx context.Context) error {
+	countCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	count, err := c.validatorCount(countCtx, "head")
+	cancel()
+	if errors.Is(err, errNoSSZ) {
+		// Some bns reject indices past the end of the validator list, so without the count, new
+		// validators are looked up as they're seen, until the next full reload
+		c.logger.Debug("Couldn't count the validators in the head state, not looking for new ones", zap.Error(err))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for next := c.validators.nextIndex(); next < uint64(count); next += validatorRefreshChunkSize {
+		indices := make([]phase0.ValidatorIndex, min(validatorRefreshChunkSize, uint64(count)-next))
+		for i := range indices {
+			indices[i] = phase0.ValidatorIndex(next + uint64(i))
+		}
+
+		vCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		resp, err := c.client.Validators(vCtx, &api.ValidatorsOpts{
+			State:   "head",
+			Indices: indices,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		c.cacheValidators(resp.Data)
+		total += len(resp.Data)
+	}
+
+	if total > 0 {
+		c.m.Counter("validator_cache_new").Add(float64(total))
+		c.logger.Debug("Loaded new validators", zap.Int("validators", total))
+	}
+	return nil
+}
+
+func newValidatorInfo(index phase0.ValidatorIndex, validator *phase0.Validator) *ValidatorInfo {
+	out := &ValidatorInfo{
+		Index:     index,
+		Pubkey:    rptypes.ValidatorPubkey(validator.PublicKey),
+		ExitEpoch: validator.ExitEpoch,
+	}
+
+	out.setWithdrawalCredentials(validator.WithdrawalCredentials)
+	return out
+}
+
+func (v *ValidatorInfo) setWithdrawalCredentials(credentials []byte) {
+	if hasExecutionCredentials(credentials) {
+		// BytesToAddress will cut off all but the last 20 bytes
+		v.WithdrawalAddress = common.BytesToAddress(credentials)
+		v.HasExecutionCredentials = true
+		v.Compounding = credentials[0] == 0x02
+	}
+}
+
+func (c *CachingConsensusLayer) cacheValidators(validators map[phase0.ValidatorIndex]*apiv1.Validator) {
+	for index, validator := range validators {
+		c.validators.Set(newValidatorInfo(index, validator.Validator))
+	}
+
+	c.m.Gauge("validator_cache_size").Set(float64(c.validators.Len()))
+}
+
+func (c *CachingConsensusLayer) snapshotPath() string {
+	return filepath.Join(c.CachePath, validatorSnapshotFileName)
+}
+
+func (c *CachingConsensusLayer) saveSnapshot() {
+	if c.CachePath == "" || c.lastFullLoad.IsZero() {
+		return
+	}
+
+	start := time.Now()
+	count, err := c.validators.save(c.snapshotPath(), c.lastFullLoad)
+	if err != nil {
+		c.m.Counter("validator_snapshot_failed").Inc()
+		c.logger.Warn("Couldn't save the validator cache snapshot", zap.Error(err))
+		return
+	}
+
+	c.m.Counter("validator_snapshot_written").Inc()
+	c.logger.Info("Saved the validator cache snapshot", zap.Int("validators", count), zap.Duration("took", time.Since(start)))
 }
 
 // Init connects to the consensus layer and initializes the cache
@@ -90,13 +413,23 @@ func (c *CachingConsensusLayer) Init(ctx context.Context) error {
 		http.WithAddress(c.bnURL.String()),
 		// It's very chatty if we don't quiet it down
 		http.WithLogLevel(zerolog.WarnLevel),
-		// Set a sensible timeout. This is used as a maximum. Requests can set their own via ctx.
-		http.WithTimeout(5*time.Minute),
+		http.WithTimeout(bnTimeout),
 		http.WithEnforceJSON(c.forceJSON))
 	if err != nil {
 		return err
 	}
 	c.client = client.(*http.Service)
+	c.rawClient = &nethttp.Client{Timeout: bnTimeout}
+	eventsTransport := nethttp.DefaultTransport.(*nethttp.Transport).Clone()
+	eventsTransport.ResponseHeaderTimeout = 30 * time.Second
+	c.eventsClient = &nethttp.Client{Transport: eventsTransport}
+
+	if c.network != nil {
+		if err := c.checkNetwork(ctx); err != nil {
+			c.m.Counter("network_mismatch").Inc()
+			return err
+		}
+	}
 
 	speCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -110,29 +443,103 @@ func (c *CachingConsensusLayer) Init(ctx context.Context) error {
 
 	c.logger.Info("Connected to Beacon Node", zap.String("url", c.bnURL.String()))
 
-	// Listen for head updates
-	err = c.client.Events(ctx, []string{"head"}, c.onHeadUpdate)
+	// Refreshes follow the head epoch, and exits are judged against the finalized one, so find out
+	// what they are before events start arriving
+	c.seedEpoch(ctx)
+
+	c.validators = newValidatorRegistry()
+	c.credentialChanges = make(map[phase0.ValidatorIndex]time.Time)
+	c.ctx = ctx
+	c.prewarm(ctx)
+	c.refreshPendingValidators(ctx)
+	c.publishSoloChanges()
+	c.logger.Info("Initialized validator registry", zap.Int("validators", c.validators.Len()))
+
+	// Listen for head updates, which also drive validator registry refreshes, and for finality and reorgs
+	err = c.client.Events(ctx, []string{"head", "finalized_checkpoint", "chain_reorg"}, c.onEvent)
 	if err != nil {
 		c.logger.Warn("Clouldn't subscribe to CL events. Metrics will be inaccurate", zap.Error(err))
 	}
+	c.pollerWG.Add(1)
+	go c.followCredentialChanges(ctx)
+
+	// Find out whether the bn is fit to serve before the router starts, then keep checking
+	c.checkSyncStatus(ctx)
+	c.pollerWG.Add(1)
+	go c.pollSyncStatus(ctx)
 
-	validatorCacheConfig := bigcache.DefaultConfig(10 * time.Hour)
-	validatorCacheConfig.CleanWindow = 30 * time.Second
-	validatorCacheConfig.Shards = 32
-	validatorCacheConfig.HardMaxCacheSize = 512
+	return nil
+}
+
+func (c *CachingConsensusLayer) seedEpoch(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
-	c.validatorCache, err = newValidatorCache(ctx, validatorCacheConfig)
+	header, err := c.client.BeaconBlockHeader(ctx, &api.BeaconBlockHeaderOpts{Block: "head"})
 	if err != nil {
-		return err
+		c.logger.Warn("Couldn't get the head block, the epoch will be known from the next head event", zap.Error(err))
+	} else {
+		c.lastEpoch.Store(uint64(header.Data.Header.Message.Slot) / c.slotsPerEpoch)
 	}
 
-	c.logger.Info("Initialized pubkey cache")
+	finality, err := c.client.Finality(ctx, &api.FinalityOpts{State: "head"})
+	if err != nil {
+		c.logger.Warn("Couldn't get the finalized checkpoint, exits will be judged from the next one", zap.Error(err))
+		return
+	}
 
+	c.statusLock.Lock()
+	c.status.FinalizedEpoch = finality.Data.Finalized.Epoch
+	c.statusLock.Unlock()
+}
+
+// Checks that the bn follows the expected chain
+func (c *CachingConsensusLayer) checkNetwork(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if c.network.GenesisValidatorsRoot != (common.Hash{}) {
+		genesis, err := c.client.Genesis(ctx, &api.GenesisOpts{})
+		if err != nil {
+			return fmt.Errorf("couldn't get the beacon node's genesis: %w", err)
+		}
+
+		root := common.Hash(genesis.Data.GenesisValidatorsRoot)
+		if root != c.network.GenesisValidatorsRoot {
+			return fmt.Errorf("beacon node has genesis validators root %s, expected %s",
+				root.String(), c.network.GenesisValidatorsRoot.String())
+		}
+	}
+
+	if c.network.DepositContractAddr == (common.Address{}) && c.network.DepositChainID == 0 {
+		return nil
+	}
+
+	depositContract, err := c.client.DepositContract(ctx, &api.DepositContractOpts{})
+	if err != nil {
+		return fmt.Errorf("couldn't get the beacon node's deposit contract: %w", err)
+	}
+
+	if c.network.DepositChainID != 0 && depositContract.Data.ChainID != c.network.DepositChainID {
+		return fmt.Errorf("beacon node's deposit contract is on chain %d, expected %d",
+			depositContract.Data.ChainID, c.network.DepositChainID)
+	}
+
+	addr := common.BytesToAddress(depositContract.Data.Address)
+	if c.network.DepositContractAddr != (common.Address{}) && addr != c.network.DepositContractAddr {
+		return fmt.Errorf("beacon node's deposit contract is %s, expected %s",
+			addr.String(), c.network.DepositContractAddr.String())
+	}
+
+	c.logger.Info("Verified the beacon node's network",
+		zap.Uint64("deposit_chain_id", depositContract.Data.ChainID),
+		zap.String("deposit_contract", addr.String()))
 	return nil
 }
 
-// GetValidatorIfno maps a validator index to a pubkey and withdrawal credential.
-// It caches responses from the beacon client in memory for an arbitrary amount of time to save resources.
+// GetValidatorInfo maps validator indices to pubkeys and withdrawal credentials.
+// Validators missing from the registry, which is only refreshed once an epoch, are looked up on the bn and added to it.
+// Indices the bn doesn't have yet are looked up in the pending deposits, and returned with Pending set.
 func (c *CachingConsensusLayer) GetValidatorInfo(validatorIndices []string) (map[string]*ValidatorInfo, error) {
 
 	// Pre-allocate the retval based on the argument length
@@ -140,20 +547,20 @@ func (c *CachingConsensusLayer) GetValidatorInfo(validatorIndices []string) (map
 	missing := make([]phase0.ValidatorIndex, 0, len(validatorIndices))
 
 	for _, validatorIndex := range validatorIndices {
-		// Check the cache first
-		validatorInfo := c.validatorCache.Get(validatorIndex)
+		// Check the registry first. Invalid indices are logged, and left for the bn to reject as misses.
+		var validatorInfo *ValidatorInfo
+		index, err := strconv.ParseUint(validatorIndex, 10, 64)
+		if err != nil {
+			c.logger.Warn("Invalid validator index", zap.String("index", validatorIndex))
+		} else {
+			validatorInfo = c.validators.Get(phase0.ValidatorIndex(index))
+		}
 		if validatorInfo != nil {
-			// Add the pubkey to the output. We have to cast it to an array, but the length is correct (see above)
 			out[validatorIndex] = validatorInfo
 			c.logger.Debug("Cache hit", zap.String("validator", validatorIndex))
 			c.m.Counter("cache_hit").Inc()
 		} else {
-			// A nil value means the record wasn't in the cache or there was an error
 			// Add the index to the list to be queried against the BN
-			index, err := strconv.ParseUint(validatorIndex, 10, 64)
-			if err != nil {
-				c.logger.Warn("Invalid validator index", zap.String("index", validatorIndex))
-			}
 			missing = append(missing, phase0.ValidatorIndex(index))
 			c.m.Counter("cache_miss").Inc()
 			c.logger.Debug("Cache miss", zap.String("validator", validatorIndex))
@@ -169,7 +576,7 @@ func (c *CachingConsensusLayer) GetValidatorInfo(validatorIndices []string) (map
 	// Create a context to enforce a timeout
 	vCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-	// Grab the index->validator map from the client if missing from the cache
+	// Grab the index->validator map from the client if missing from the registry
 	resp, err := c.client.Validators(vCtx, &api.ValidatorsOpts{
 		State:   "head",
 		Indices: missing,
@@ -178,57 +585,112 @@ func (c *CachingConsensusLayer) GetValidatorInfo(validatorIndices []string) (map
 		return nil, err
 	}
 	for index, validator := range resp.Data {
-		strIndex := strconv.FormatUint(uint64(index), 10)
-		pubkey := rptypes.ValidatorPubkey(validator.Validator.PublicKey)
-		withdrawalCredentials := validator.Validator.WithdrawalCredentials
-
-		out[strIndex] = &ValidatorInfo{
-			Pubkey: pubkey,
-		}
+		validatorInfo := c.addValidator(index, validator.Validator)
+		out[strconv.FormatUint(uint64(index), 10)] = validatorInfo
+	}
 
-		if !bytes.HasPrefix(withdrawalCredentials, []byte{0x01}) {
-			c.logger.Warn("0x00 Validator seen", zap.Binary("pubkey", pubkey.Bytes()))
-		} else {
-			// BytesToAddress will cut off all but the last 20 bytes
-			out[strIndex].WithdrawalAddress = common.BytesToAddress(withdrawalCredentials)
-			out[strIndex].Is0x01 = true
+	// Validators the bn doesn't have may be about to be given the index by a pending deposit
+	for _, validatorIndex := range validatorIndices {
+		if _, ok := out[validatorIndex]; ok {
+			continue
 		}
 
-		// Add it to the cache. Ignore errors, we can always look the key up later
-		err = c.validatorCache.Set(strIndex, out[strIndex])
+		index, err := strconv.ParseUint(validatorIndex, 10, 64)
 		if err != nil {
-			c.logger.Warn("Error encountered while saving blob to cache", zap.Error(err))
-			c.m.Counter("cache_add_failed")
-		} else {
-			c.m.Counter("cache_add").Inc()
+			continue
+		}
+		if validatorInfo := c.getPendingValidatorByIndex(phase0.ValidatorIndex(index)); validatorInfo != nil {
+			out[validatorIndex] = validatorInfo
+			c.m.Counter("pending_validator_index_hit").Inc()
 		}
 	}
 
 	return out, nil
 }
 
-// GetValidators gets the list of all validators for the finalized state
-// It does no caching- the response is large, so caching should be done downstream, for the data the caller cares about.
-func (c *CachingConsensusLayer) GetValidators() ([]*apiv1.Validator, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+// GetValidatorInfoByPubkey looks validators up by pubkey.
+// Like GetValidatorInfo, validators missing from the registry are looked up on the bn, and then in
+// the pending deposits, which are returned with Pending set. Pubkeys that haven't been deposited
+// at all are left out of the result.
+func (c *CachingConsensusLayer) GetValidatorInfoByPubkey(pubkeys []rptypes.ValidatorPubkey) (map[rptypes.ValidatorPubkey]*ValidatorInfo, error) {
+	out := make(map[rptypes.ValidatorPubkey]*ValidatorInfo, len(pubkeys))
+	missing := make([]phase0.BLSPubKey, 0, len(pubkeys))
+
+	for _, pubkey := range pubkeys {
+		validatorInfo := c.validators.GetByPubkey(pubkey)
+		if validatorInfo != nil {
+			out[pubkey] = validatorInfo
+			c.m.Counter("pubkey_cache_hit").Inc()
+		} else {
+			missing = append(missing, phase0.BLSPubKey(pubkey))
+			c.m.Counter("pubkey_cache_miss").Inc()
+		}
+	}
 
-	vmap, err := c.client.Validators(ctx, &api.ValidatorsOpts{State: "finalized"})
+	if len(missing) == 0 {
+		return out, nil
+	}
+
+	vCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resp, err := c.client.Validators(vCtx, &api.ValidatorsOpts{
+		State:   "head",
+		PubKeys: missing,
+	})
 	if err != nil {
 		return nil, err
 	}
+	for index, validator := range resp.Data {
+		validatorInfo := c.addValidator(index, validator.Validator)
+		out[validatorInfo.Pubkey] = validatorInfo
+	}
 
-	out := make([]*apiv1.Validator, 0, len(vmap.Data))
-	for _, v := range vmap.Data {
-		out = append(out, v)
+	// Validators the bn doesn't have may just not have had their deposits applied yet
+	for _, pubkey := range missing {
+		if _, ok := out[rptypes.ValidatorPubkey(pubkey)]; ok {
+			continue
+		}
+
+		if validatorInfo := c.getPendingValidator(rptypes.ValidatorPubkey(pubkey)); validatorInfo != nil {
+			out[validatorInfo.Pubkey] = validatorInfo
+			c.m.Counter("pending_validator_hit").Inc()
+		}
 	}
 
 	return out, nil
 }
 
+// Adds a validator the registry didn't have to it
+func (c *CachingConsensusLayer) addValidator(index phase0.ValidatorIndex, validator *phase0.Validator) *ValidatorInfo {
+	validatorInfo := newValidatorInfo(index, validator)
+	if !validatorInfo.HasExecutionCredentials {
+		c.logger.Warn("0x00 Validator seen", zap.Binary("pubkey", validatorInfo.Pubkey.Bytes()))
+	}
+
+	c.validators.Refresh(validatorInfo)
+	c.m.Counter("cache_add").Inc()
+	return validatorInfo
+}
+
+// GetWithdrawalAddresses returns the withdrawal address of every 0x01 or 0x02 validator that
+// hadn't exited as of the finalized epoch
+func (c *CachingConsensusLayer) GetWithdrawalAddresses() []common.Address {
+	c.statusLock.Lock()
+	epoch := c.status.FinalizedEpoch
+	c.statusLock.Unlock()
+
+	return c.validators.WithdrawalAddresses(epoch)
+}
+
 // Deinit shuts down the consensus layer client
 func (c *CachingConsensusLayer) Deinit() {
-	c.validatorCache.Close()
+	c.stopLock.Lock()
+	c.stopped = true
+	c.stopLock.Unlock()
+
 	c.disconnect()
+	c.pollerWG.Wait()
+	c.wg.Wait()
+	c.saveSnapshot()
 	c.logger.Info("HTTP Client Disconnected from the BN")
 }
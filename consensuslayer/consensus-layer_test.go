@@ -7,12 +7,16 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
 	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -35,9 +39,8 @@ func (m *mockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.String()
 
 	m.t.Log("handling " + path)
-	// Let's return json
+	// Let's return json, unless the test-specific handler says otherwise
 	w.Header()["Content-Type"] = []string{"application/json"}
-	w.WriteHeader(200)
 	// Mainnet responses
 	switch path {
 	case "/eth/v1/beacon/genesis":
@@ -72,7 +75,7 @@ func setup(t *testing.T, url *url.URL) ccTest {
 	t.Cleanup(cancel)
 
 	return ccTest{
-		ccl: NewCachingConsensusLayer(url, zaptest.NewLogger(t), false),
+		ccl: NewCachingConsensusLayer(url, zaptest.NewLogger(t), false, nil),
 		ctx: ctx,
 	}
 }
@@ -98,6 +101,63 @@ func TestCLStartStop(t *testing.T) {
 	t.Cleanup(cct.ccl.Deinit)
 }
 
+func TestCLNetwork(t *testing.T) {
+	s := httptest.NewServer(&mockHandler{
+		t: t,
+		h: func(w http.ResponseWriter, r *http.Request) {
+		},
+	})
+	t.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cct := setup(t, u)
+	cct.ccl.network = &ExpectedNetwork{
+		GenesisValidatorsRoot: common.HexToHash("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"),
+		DepositContractAddr:   common.HexToAddress("0x00000000219ab540356cBB839Cbe05303d7705Fa"),
+		DepositChainID:        1,
+	}
+	err = cct.ccl.Init(cct.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(cct.ccl.Deinit)
+}
+
+func TestCLWrongNetwork(t *testing.T) {
+	s := httptest.NewServer(&mockHandler{
+		t: t,
+		h: func(w http.ResponseWriter, r *http.Request) {
+		},
+	})
+	t.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cct := setup(t, u)
+
+	for _, network := range []*ExpectedNetwork{
+		{GenesisValidatorsRoot: common.HexToHash("0x9143aa7c615a7f7115e2b6aac319c03529df8242ae705fba9df39b79c59fa8b1")},
+		{DepositContractAddr: common.HexToAddress("0x4242424242424242424242424242424242424242")},
+		{DepositChainID: 17000},
+	} {
+		ccl := NewCachingConsensusLayer(u, zaptest.NewLogger(t), false, network)
+
+		err := ccl.Init(cct.ctx)
+		if err == nil || !strings.Contains(err.Error(), "expected") {
+			t.Fatalf("expected Init to refuse a beacon node on the wrong network, got %v", err)
+		}
+		ccl.disconnect()
+		// Let the next one register the same metrics
+		ccl.m.UnregisterAll()
+	}
+}
+
 func TestGetValidatorInfo(t *testing.T) {
 	s := httptest.NewServer(&mockHandler{
 		t: t,
@@ -127,7 +187,7 @@ func TestGetValidatorInfo(t *testing.T) {
 	}
 
 	for _, v := range validatorInfo {
-		if !v.Is0x01 {
+		if !v.HasExecutionCredentials {
 			t.Fatal("Validator was not identified as 0x01")
 		}
 
@@ -191,7 +251,7 @@ func TestGetValidatorCached(t *testing.T) {
 		}
 
 		for _, v := range validatorInfo {
-			if !v.Is0x01 {
+			if !v.HasExecutionCredentials {
 				t.Fatal("Validator was not identified as 0x01")
 			}
 
@@ -211,15 +271,22 @@ func TestGetValidatorCached(t *testing.T) {
 	t.Cleanup(cct.ccl.Deinit)
 }
 
-const mockBeaconState = `{"version":"phase0","execution_optimistic":false,"finalized":false,"data":{"genesis_time":"1","genesis_validators_root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","slot":"1","fork":{"previous_version":"0x00000000","current_version":"0x00000000","epoch":"1"},"latest_block_header":{"slot":"1","proposer_index":"1","parent_root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","state_root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","body_root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"},"block_roots":["0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"],"state_roots":["0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"],"historical_roots":["0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"],"eth1_data":{"deposit_root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","deposit_count":"1","block_hash":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"},"eth1_data_votes":[{"deposit_root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","deposit_count":"1","block_hash":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"}],"eth1_deposit_index":"1","validators":[{"pubkey":"0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a","withdrawal_credentials":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","effective_balance":"1","slashed":false,"activation_eligibility_epoch":"1","activation_epoch":"1","exit_epoch":"1","withdrawable_epoch":"1"}],"balances":["1"],"randao_mixes":["0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"],"slashings":["1"],"previous_epoch_attestations":[{"aggregation_bits":"0x01","data":{"slot":"1","index":"1","beacon_block_root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","source":{"epoch":"1","root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"},"target":{"epoch":"1","root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"}},"inclusion_delay":"1","proposer_index":"1"}],"current_epoch_attestations":[{"aggregation_bits":"0x01","data":{"slot":"1","index":"1","beacon_block_root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","source":{"epoch":"1","root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"},"target":{"epoch":"1","root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"}},"inclusion_delay":"1","proposer_index":"1"}],"justification_bits":"0x01","previous_justified_checkpoint":{"epoch":"1","root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"},"current_justified_checkpoint":{"epoch":"1","root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"},"finalized_checkpoint":{"epoch":"1","root":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2"}}}`
+const mockValidators = `{"execution_optimistic":false,"finalized":false,"data":[{"index":"0","balance":"1","status":"exited_unslashed","validator":{"pubkey":"0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a","withdrawal_credentials":"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2","effective_balance":"1","slashed":false,"activation_eligibility_epoch":"1","activation_epoch":"1","exit_epoch":"1","withdrawable_epoch":"1"}}]}`
 
-func TestGetValidators(t *testing.T) {
+func TestGetValidatorInfoByPubkey(t *testing.T) {
+	once := false
 	s := httptest.NewServer(&mockHandler{
 		t: t,
 		h: func(w http.ResponseWriter, r *http.Request) {
 			switch r.URL.String() {
-			case "/eth/v2/debug/beacon/states/finalized":
-				fmt.Fprintln(w, mockBeaconState)
+			case "/eth/v1/beacon/states/head/validators?id=0xb5bc96b70df0dfcc252c9ff0d1b42cb6dc0d55f8defa474dc0a5c7e0402c241e2850fea9c582e276b638b3c2c3a5ec55,0xaa160542c2b1b9dbf5e11ca044067526c6dfff65efba88ea483d49bdbe478ab7489f8b1a903ea22b6d30cfa57626ca9e":
+				if once {
+					// Landmine to trigger an error if the registry is missed
+					fmt.Fprintf(w, "Not json!")
+					return
+				}
+				once = true
+				fmt.Fprintf(w, `{"execution_optimistic":false,"data":[{"index":"100","balance":"32005252956","status":"active_ongoing","validator":{"pubkey":"0xb5bc96b70df0dfcc252c9ff0d1b42cb6dc0d55f8defa474dc0a5c7e0402c241e2850fea9c582e276b638b3c2c3a5ec55","withdrawal_credentials":"0x010000000000000000000000801e880e2e9aa87b20c9cc9ebf7375adb11eac21","effective_balance":"32000000000","slashed":false,"activation_eligibility_epoch":"0","activation_epoch":"0","exit_epoch":"18446744073709551615","withdrawable_epoch":"18446744073709551615"}},{"index":"101","balance":"32005224938","status":"active_ongoing","validator":{"pubkey":"0xaa160542c2b1b9dbf5e11ca044067526c6dfff65efba88ea483d49bdbe478ab7489f8b1a903ea22b6d30cfa57626ca9e","withdrawal_credentials":"0x010000000000000000000000d944cf00517e2dd8d00bd5c4ea1ec45cf3ec52db","effective_balance":"32000000000","slashed":false,"activation_eligibility_epoch":"0","activation_epoch":"0","exit_epoch":"18446744073709551615","withdrawable_epoch":"18446744073709551615"}}]}`)
 				return
 			}
 		},
@@ -235,14 +302,51 @@ func TestGetValidators(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	t.Cleanup(cct.ccl.Deinit)
+
+	pubkeys := []rptypes.ValidatorPubkey{}
+	for _, key := range []string{
+		"b5bc96b70df0dfcc252c9ff0d1b42cb6dc0d55f8defa474dc0a5c7e0402c241e2850fea9c582e276b638b3c2c3a5ec55",
+		"aa160542c2b1b9dbf5e11ca044067526c6dfff65efba88ea483d49bdbe478ab7489f8b1a903ea22b6d30cfa57626ca9e",
+	} {
+		pubkey, err := rptypes.HexToValidatorPubkey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubkeys = append(pubkeys, pubkey)
+	}
+
+	// The second lookup is answered from the registry
+	for range []int{1, 2} {
+		validatorInfo, err := cct.ccl.GetValidatorInfoByPubkey(pubkeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(validatorInfo) != 2 || validatorInfo[pubkeys[0]].Index != 100 || validatorInfo[pubkeys[1]].Index != 101 {
+			t.Fatalf("unexpected validator info %+v", validatorInfo)
+		}
+	}
 
-	// Grab a random validator
-	_, err = cct.ccl.GetValidators()
+	// And so are lookups by index
+	validatorInfo, err := cct.ccl.GetValidatorInfo([]string{"100", "101"})
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(validatorInfo) != 2 || validatorInfo["100"].Pubkey != pubkeys[0] {
+		t.Fatalf("unexpected validator info %+v", validatorInfo)
+	}
 
-	t.Cleanup(cct.ccl.Deinit)
+	addresses := cct.ccl.GetWithdrawalAddresses()
+	if len(addresses) != 2 {
+		t.Fatalf("expected 2 withdrawal addresses, got %v", addresses)
+	}
+	for _, addr := range addresses {
+		if !strings.EqualFold(addr.String(), "0x801e880e2e9aa87b20c9cc9ebf7375adb11eac21") &&
+			!strings.EqualFold(addr.String(), "0xd944cf00517e2dd8d00bd5c4ea1ec45cf3ec52db") {
+			t.Fatal("Unexpected withdrawal address", addr.String())
+		}
+	}
 }
 
 func TestOnHeadUpdate(t *testing.T) {
@@ -335,3 +439,477 @@ func TestErrorPaths(t *testing.T) {
 		t.Fatal("Expected error")
 	}
 }
+
+const mockNewValidator = `{"execution_optimistic":false,"data":[{"index":"1","balance":"32005252956","status":"active_ongoing","validator":{"pubkey":"0xb5bc96b70df0dfcc252c9ff0d1b42cb6dc0d55f8defa474dc0a5c7e0402c241e2850fea9c582e276b638b3c2c3a5ec55","withdrawal_credentials":"0x010000000000000000000000801e880e2e9aa87b20c9cc9ebf7375adb11eac21","effective_balance":"32000000000","slashed":false,"activation_eligibility_epoch":"0","activation_epoch":"0","exit_epoch":"18446744073709551615","withdrawable_epoch":"18446744073709551615"}}]}`
+
+// The query the proxy makes for validators with indices from next up to count
+func newValidatorsPath(next int, count int) string {
+	ids := make([]string, count-next)
+	for i := range ids {
+		ids[i] = fmt.Sprint(next + i)
+	}
+	return "/eth/v1/beacon/states/head/validators?id=" + strings.Join(ids, ",")
+}
+
+// Serves the head state as SSZ, for the proxy to count its validators
+func writeHeadState(t *testing.T, w http.ResponseWriter, validators int) {
+	encoded, err := testState(validators).MarshalSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(encoded)
+}
+
+func TestValidatorCachePrewarm(t *testing.T) {
+	// How many validators the head state has, or 0 to serve it as json
+	headValidators := 0
+	s := httptest.NewServer(&mockHandler{
+		t: t,
+		h: func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.String() {
+			case "/eth/v2/debug/beacon/states/head":
+				if headValidators > 0 {
+					writeHeadState(t, w, headValidators)
+					return
+				}
+				// The mock serves json, so the proxy falls back to the validators endpoint
+				fmt.Fprintln(w, `{}`)
+				return
+			case "/eth/v1/beacon/states/head/validators":
+				fmt.Fprintln(w, mockValidators)
+				return
+			case newValidatorsPath(1, 2):
+				fmt.Fprintln(w, mockNewValidator)
+				return
+			}
+
+			// Landmine to trigger an error if the cache is missed
+			fmt.Fprintf(w, "Not json!")
+		},
+	})
+	t.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachePath := t.TempDir()
+	cct := setup(t, u)
+	cct.ccl.CachePath = cachePath
+	err = cct.ccl.Init(cct.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The validator set was loaded by Init
+	validatorInfo, err := cct.ccl.GetValidatorInfo([]string{"0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := validatorInfo["0"]; v == nil || v.HasExecutionCredentials || !strings.EqualFold(v.Pubkey.String(), "93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a") {
+		t.Fatalf("unexpected validator info %+v", v)
+	}
+
+	// Only indices in the head state are asked for, so nothing is while there are no new validators
+	headValidators = 1
+	if err := cct.ccl.loadNewValidators(cct.ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// New validators are picked up when the epoch advances
+	headValidators = 2
+	cct.ccl.onHeadUpdate(&apiv1.Event{
+		Data: &apiv1.HeadEvent{
+			Slot: phase0.Slot(32),
+		},
+	})
+	cct.ccl.wg.Wait()
+
+	validatorInfo, err = cct.ccl.GetValidatorInfo([]string{"0", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := validatorInfo["1"]; v == nil || !v.HasExecutionCredentials || !strings.EqualFold(v.WithdrawalAddress.String(), "0x801e880e2e9aa87b20c9cc9ebf7375adb11eac21") {
+		t.Fatalf("unexpected validator info %+v", v)
+	}
+	cct.ccl.Deinit()
+	// Let the restarted one register the same metrics
+	cct.ccl.m.UnregisterAll()
+
+	// A restart warms up from the snapshot, and only asks for validators added since
+	restarted := httptest.NewServer(&mockHandler{
+		t: t,
+		h: func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.String() {
+			case "/eth/v2/debug/beacon/states/head":
+				writeHeadState(t, w, 3)
+				return
+			case newValidatorsPath(2, 3):
+				fmt.Fprintln(w, `{"execution_optimistic":false,"data":[]}`)
+				return
+			case "/eth/v1/beacon/headers/head", "/eth/v1/beacon/states/head/finality_checkpoints", "/eth/v1/node/syncing", "/eth/v1/beacon/states/head/root", "/eth/v1/beacon/states/head/pending_deposits", "/eth/v1/events?topics=bls_to_execution_change":
+				// Not needed
+				return
+			}
+
+			t.Errorf("unexpected request for %s", r.URL.String())
+			fmt.Fprintf(w, "Not json!")
+		},
+	})
+	t.Cleanup(restarted.Close)
+
+	u, err = url.Parse(restarted.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ccl := NewCachingConsensusLayer(u, zaptest.NewLogger(t), false, nil)
+	ccl.CachePath = cachePath
+	err = ccl.Init(cct.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ccl.Deinit)
+
+	validatorInfo, err = ccl.GetValidatorInfo([]string{"0", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validatorInfo) != 2 || validatorInfo["0"].HasExecutionCredentials || !validatorInfo["1"].HasExecutionCredentials {
+		t.Fatalf("unexpected validator info %+v", validatorInfo)
+	}
+}
+
+const mockBLSToExecutionChange = `{"message":{"validator_index":"0","from_bls_pubkey":"0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a","to_execution_address":"0x801e880e2e9aa87b20c9cc9ebf7375adb11eac21"},"signature":"0x00"}`
+
+const mockChangedValidators = `{"execution_optimistic":false,"data":[{"index":"0","balance":"32000000000","status":"active_ongoing","validator":{"pubkey":"0x93247f2209abcacf57b75a51dafae777f9dd38bc7053d1af526f220a7489a6d3a2753e5f3e8b1cfe39b56f43611df74a","withdrawal_credentials":"0x010000000000000000000000801e880e2e9aa87b20c9cc9ebf7375adb11eac21","effective_balance":"32000000000","slashed":false,"activation_eligibility_epoch":"0","activation_epoch":"0","exit_epoch":"18446744073709551615","withdrawable_epoch":"18446744073709551615"}},{"index":"1","balance":"32005252956","status":"active_ongoing","validator":{"pubkey":"0xb5bc96b70df0dfcc252c9ff0d1b42cb6dc0d55f8defa474dc0a5c7e0402c241e2850fea9c582e276b638b3c2c3a5ec55","withdrawal_credentials":"0x020000000000000000000000801e880e2e9aa87b20c9cc9ebf7375adb11eac21","effective_balance":"32000000000","slashed":false,"activation_eligibility_epoch":"0","activation_epoch":"0","exit_epoch":"18446744073709551615","withdrawable_epoch":"18446744073709551615"}}]}`
+
+func TestCredentialChangesRefreshValidators(t *testing.T) {
+	s := httptest.NewServer(&mockHandler{
+		t: t,
+		h: func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.String() {
+			case "/eth/v1/beacon/states/head/validators":
+				// Validator 0 starts out 0x00, and validator 1 0x01
+				fmt.Fprintln(w, `{"execution_optimistic":false,"data":[`+
+					mockValidators[strings.Index(mockValidators, "[")+1:len(mockValidators)-2]+","+
+					mockNewValidator[strings.Index(mockNewValidator, "[")+1:len(mockNewValidator)-2]+`]}`)
+				return
+			case "/eth/v1/events?topics=bls_to_execution_change":
+				// The bn sees a BLS to execution change for validator 0, then the stream stays open
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprintf(w, "event: bls_to_execution_change\ndata: %s\n\n", mockBLSToExecutionChange)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			case "/eth/v1/beacon/states/head/validators?id=0":
+				fmt.Fprintln(w, mockChangedValidators)
+				return
+			}
+
+			fmt.Fprintf(w, "Not json!")
+		},
+	})
+	t.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cct := setup(t, u)
+	err = cct.ccl.Init(cct.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cct.ccl.Deinit)
+
+	validatorInfo, err := cct.ccl.GetValidatorInfo([]string{"0", "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validatorInfo) != 2 || validatorInfo["0"].HasExecutionCredentials || !validatorInfo["1"].HasExecutionCredentials {
+		t.Fatalf("unexpected validator info %+v", validatorInfo)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(cct.ccl.pendingCredentialChanges()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the BLS to execution change was never seen")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The change reaches the head state with the next block
+	cct.ccl.onHeadUpdate(&apiv1.Event{
+		Data: &apiv1.HeadEvent{
+			Slot:  phase0.Slot(1),
+			Block: phase0.Root{0x01},
+		},
+	})
+	cct.ccl.wg.Wait()
+
+	validatorInfo, err = cct.ccl.GetValidatorInfo([]string{"0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := validatorInfo["0"]
+	if v == nil || !v.HasExecutionCredentials || !strings.EqualFold(v.WithdrawalAddress.String(), "0x801e880e2e9aa87b20c9cc9ebf7375adb11eac21") {
+		t.Fatalf("unexpected info for validator 0: %+v", v)
+	}
+	if len(cct.ccl.pendingCredentialChanges()) != 0 {
+		t.Fatal("the change should no longer be waited for")
+	}
+
+	// Both withdraw to the same address now
+	addrs := cct.ccl.GetWithdrawalAddresses()
+	if len(addrs) != 1 || !strings.EqualFold(addrs[0].String(), "0x801e880e2e9aa87b20c9cc9ebf7375adb11eac21") {
+		t.Fatalf("unexpected withdrawal addresses %v", addrs)
+	}
+
+	// Nothing is started once it's stopped
+	cct.ccl.Deinit()
+	if cct.ccl.goUnlessStopped(func() {}) {
+		t.Fatal("work was started after Deinit")
+	}
+}
+
+func TestBNStatus(t *testing.T) {
+	syncing := `{"data":{"head_slot":"100","sync_distance":"20","is_syncing":true,"is_optimistic":false,"el_offline":false}}`
+	s := httptest.NewServer(&mockHandler{
+		t: t,
+		h: func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.String() {
+			case "/eth/v1/node/syncing":
+				fmt.Fprintln(w, syncing)
+				return
+			case "/eth/v1/beacon/states/head/validators":
+				fmt.Fprintln(w, mockValidators)
+				return
+			}
+
+			fmt.Fprintf(w, "Not json!")
+		},
+	})
+	t.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cct := setup(t, u)
+	err = cct.ccl.Init(cct.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cct.ccl.Deinit)
+
+	status := cct.ccl.GetBNStatus()
+	if status.HeadSlot != 100 || status.SyncDistance != 20 || !status.IsSyncing || status.Unfit == "" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// Caught up, but optimistic
+	syncing = `{"data":{"head_slot":"120","sync_distance":"0","is_syncing":false,"is_optimistic":true,"el_offline":false}}`
+	cct.ccl.checkSyncStatus(cct.ctx)
+	status = cct.ccl.GetBNStatus()
+	if status.HeadSlot != 120 || status.IsSyncing || !status.IsOptimistic || status.Unfit == "" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	syncing = `{"data":{"head_slot":"121","sync_distance":"0","is_syncing":false,"is_optimistic":false,"el_offline":false}}`
+	cct.ccl.checkSyncStatus(cct.ctx)
+	status = cct.ccl.GetBNStatus()
+	if status.Unfit != "" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// A failed poll keeps the last status, until it's too old to trust
+	syncing = "Not json!"
+	cct.ccl.checkSyncStatus(cct.ctx)
+	status = cct.ccl.GetBNStatus()
+	if status.Unfit != "" || status.Error == "" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if status.unfit(status.CheckedAt.Add(syncStatusTTL+time.Second)) == "" {
+		t.Fatal("expected a stale status to be unfit")
+	}
+
+	cct.ccl.onEvent(&apiv1.Event{
+		Topic: "finalized_checkpoint",
+		Data:  &apiv1.FinalizedCheckpointEvent{Epoch: 2},
+	})
+	cct.ccl.onEvent(&apiv1.Event{
+		Topic: "chain_reorg",
+		Data:  &apiv1.ChainReorgEvent{Slot: 121, Depth: 2},
+	})
+	status = cct.ccl.GetBNStatus()
+	if status.FinalizedEpoch != 2 || status.Reorgs != 1 || status.LastReorgSlot != 121 || status.LastReorgDepth != 2 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestPendingValidators(t *testing.T) {
+	// A pubkey in the state, one with two pending deposits, and one that was never deposited
+	inState := "b5bc96b70df0dfcc252c9ff0d1b42cb6dc0d55f8defa474dc0a5c7e0402c241e2850fea9c582e276b638b3c2c3a5ec55"
+	pending := "aa160542c2b1b9dbf5e11ca044067526c6dfff65efba88ea483d49bdbe478ab7489f8b1a903ea22b6d30cfa57626ca9e"
+	unknown := "a1d1ad0714035353258038e964ae9675dc0252ee22cea896825c01458e1807bfad2f9969338798548d9858a571f7425c"
+	// The head state, which has 101 validators, so the pending one will be given index 101
+	root := phase0.Root{0x01}
+	var badRequest atomic.Bool
+
+	s := httptest.NewServer(&mockHandler{
+		t: t,
+		h: func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.String() {
+			case "/eth/v1/beacon/states/head/root":
+				fmt.Fprintf(w, `{"execution_optimistic":false,"finalized":false,"data":{"root":"%s"}}`, root.String())
+				return
+			case "/eth/v2/debug/beacon/states/" + root.String():
+				writeHeadState(t, w, 101)
+				return
+			case "/eth/v1/beacon/states/head/validators?id=101":
+				fmt.Fprintln(w, `{"execution_optimistic":false,"data":[]}`)
+				return
+			case "/eth/v1/beacon/states/" + root.String() + "/pending_deposits":
+				if badRequest.Load() {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				fmt.Fprintf(w, `{"version":"electra","execution_optimistic":false,"finalized":false,"data":[`+
+					`{"pubkey":"0x%s","withdrawal_credentials":"0x020000000000000000000000d944cf00517e2dd8d00bd5c4ea1ec45cf3ec52db","amount":"32000000000","signature":"0x00","slot":"1"},`+
+					`{"pubkey":"0x%s","withdrawal_credentials":"0x010000000000000000000000801e880e2e9aa87b20c9cc9ebf7375adb11eac21","amount":"1000000000","signature":"0x00","slot":"2"},`+
+					`{"pubkey":"0x%s","withdrawal_credentials":"0x010000000000000000000000801e880e2e9aa87b20c9cc9ebf7375adb11eac21","amount":"1000000000","signature":"0x00","slot":"3"}]}`,
+					pending, pending, inState)
+				return
+			case "/eth/v1/beacon/states/head/validators?id=0x" + inState + ",0x" + pending + ",0x" + unknown:
+				fmt.Fprintf(w, `{"execution_optimistic":false,"data":[{"index":"100","balance":"32005252956","status":"active_ongoing","validator":{"pubkey":"0x%s","withdrawal_credentials":"0x010000000000000000000000801e880e2e9aa87b20c9cc9ebf7375adb11eac21","effective_balance":"32000000000","slashed":false,"activation_eligibility_epoch":"0","activation_epoch":"0","exit_epoch":"18446744073709551615","withdrawable_epoch":"18446744073709551615"}}]}`, inState)
+				return
+			}
+
+			fmt.Fprintf(w, "Not json!")
+		},
+	})
+	t.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cct := setup(t, u)
+	err = cct.ccl.Init(cct.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cct.ccl.Deinit)
+
+	pubkeys := []rptypes.ValidatorPubkey{}
+	for _, key := range []string{inState, pending, unknown} {
+		pubkey, err := rptypes.HexToValidatorPubkey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubkeys = append(pubkeys, pubkey)
+	}
+
+	validatorInfo, err := cct.ccl.GetValidatorInfoByPubkey(pubkeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validatorInfo) != 2 {
+		t.Fatalf("unexpected validator info %+v", validatorInfo)
+	}
+
+	if v := validatorInfo[pubkeys[0]]; v == nil || v.Pending || v.Index != 100 {
+		t.Fatalf("unexpected validator info %+v", v)
+	}
+
+	// The first deposit sets the withdrawal credentials
+	v := validatorInfo[pubkeys[1]]
+	if v == nil || !v.Pending || !v.HasExecutionCredentials || !strings.EqualFold(v.WithdrawalAddress.String(), "0xd944cf00517e2dd8d00bd5c4ea1ec45cf3ec52db") {
+		t.Fatalf("unexpected validator info %+v", v)
+	}
+
+	// It'll be given the next index, so validator clients can prepare it as a proposer before
+	// the bn has it
+	byIndex, err := cct.ccl.GetValidatorInfo([]string{"101"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := byIndex["101"]; v == nil || !v.Pending || v.Index != 101 || v.Pubkey != pubkeys[1] {
+		t.Fatalf("unexpected validator info %+v", v)
+	}
+
+	// Only a 404 means there are no pending deposits
+	badRequest.Store(true)
+	if _, err := cct.ccl.loadPendingValidators(cct.ctx); err == nil {
+		t.Fatal("expected an error for a 400")
+	}
+}
+
+func TestSoloChangesPublished(t *testing.T) {
+	s := httptest.NewServer(&mockHandler{
+		t: t,
+		h: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "Not json!")
+		},
+	})
+	t.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cct := setup(t, u)
+	cct.ccl.NodeSet = nodeset.NewFeed(nodeset.DefaultHistory)
+	err = cct.ccl.Init(cct.ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cct.ccl.Deinit)
+
+	sub, _, _, _ := cct.ccl.NodeSet.Subscribe(nil)
+	defer sub.Close()
+	expectChange := func(changeType nodeset.ChangeType, addr common.Address) {
+		t.Helper()
+
+		select {
+		case change := <-sub.Changes():
+			if change.Type != changeType || change.Address != addr {
+				t.Fatalf("expected %s %s, got %s %s", changeType, addr, change.Type, change.Address)
+			}
+		default:
+			t.Fatalf("expected %s %s to be published", changeType, addr)
+		}
+	}
+
+	addr := common.HexToAddress("0x801e880e2e9aa87b20c9cc9ebf7375adb11eac21")
+	cct.ccl.addValidator(100, &phase0.Validator{
+		WithdrawalCredentials: append([]byte{0x01}, append(make([]byte, 11), addr.Bytes()...)...),
+		ExitEpoch:             10,
+	})
+	// 0x00 validators have no withdrawal address
+	cct.ccl.addValidator(101, &phase0.Validator{
+		WithdrawalCredentials: make([]byte, 32),
+		ExitEpoch:             phase0.Epoch(^uint64(0)),
+	})
+	cct.ccl.onFinalizedCheckpoint(&apiv1.Event{Data: &apiv1.FinalizedCheckpointEvent{Epoch: 5}})
+	cct.ccl.publishSoloChanges()
+	expectChange(nodeset.SoloAdded, addr)
+
+	// Nothing changed
+	cct.ccl.publishSoloChanges()
+
+	// The validator exited, as of the finalized epoch
+	cct.ccl.lastEpoch.Store(12)
+	cct.ccl.publishSoloChanges()
+	cct.ccl.onFinalizedCheckpoint(&apiv1.Event{Data: &apiv1.FinalizedCheckpointEvent{Epoch: 10}})
+	cct.ccl.publishSoloChanges()
+	expectChange(nodeset.SoloRemoved, addr)
+
+	select {
+	case change := <-sub.Changes():
+		t.Fatalf("unexpected change %s %s", change.Type, change.Address)
+	default:
+	}
+}
@@ -0,0 +1,164 @@
+package consensuslayer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"go.uber.org/zap"
+)
+
+// How long a BLS to execution change is waited for before it's left to the next full reload.
+// A change the bn has seen may never be included, eg if another change for the validator was.
+const credentialChangeTimeout = validatorReloadInterval
+
+// How long to wait before resubscribing when the bn closes the event stream
+const credentialChangeReconnectDelay = time.Second
+
+// hasExecutionCredentials checks whether withdrawal credentials name an execution layer
+// withdrawal address, ie whether they're 0x01, or 0x02 (compounding) credentials
+func hasExecutionCredentials(credentials []byte) bool {
+	return bytes.HasPrefix(credentials, []byte{0x01}) || bytes.HasPrefix(credentials, []byte{0x02})
+}
+
+// The part of a bls_to_execution_change event that's needed
+type blsToExecutionChangeJSON struct {
+	Message struct {
+		ValidatorIndex uint64 `json:"validator_index,string"`
+	} `json:"message"`
+}
+
+// Follows the bn's bls_to_execution_change events until ctx is done. They aren't supported by
+// client, so the event stream is read directly.
+func (c *CachingConsensusLayer) followCredentialChanges(ctx context.Context) {
+	defer c.pollerWG.Done()
+
+	for {
+		err := c.readEvents(ctx, "bls_to_execution_change", func(_ string, data []byte) {
+			c.onBLSToExecutionChange(data)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			c.m.Counter("credential_change_stream_failed").Inc()
+			c.logger.Debug("Couldn't follow BLS to execution changes", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(credentialChangeReconnectDelay):
+		}
+	}
+}
+
+// Notes a validator whose credentials are changing, so it's refreshed once the change reaches
+// the head state, rather than left misclassified until the next full reload.
+// Consolidations only change a validator from 0x01 to 0x02 credentials, or exit it, and are
+// picked up by the reload.
+func (c *CachingConsensusLayer) onBLSToExecutionChange(data []byte) {
+	var change blsToExecutionChangeJSON
+	if err := json.Unmarshal(data, &change); err != nil {
+		c.logger.Warn("Couldn't decode bls_to_execution_change event", zap.Error(err))
+		return
+	}
+
+	index := phase0.ValidatorIndex(change.Message.ValidatorIndex)
+	if v := c.validators.Get(index); v != nil && v.HasExecutionCredentials {
+		return
+	}
+
+	c.credentialChangesLock.Lock()
+	defer c.credentialChangesLock.Unlock()
+
+	if _, ok := c.credentialChanges[index]; !ok {
+		c.credentialChanges[index] = time.Now()
+	}
+	c.m.Counter("credential_change_seen").Inc()
+}
+
+// Lists the validators with credential changes that are still being waited for
+func (c *CachingConsensusLayer) pendingCredentialChanges() []phase0.ValidatorIndex {
+	c.credentialChangesLock.Lock()
+	defer c.credentialChangesLock.Unlock()
+
+	out := make([]phase0.ValidatorIndex, 0, len(c.credentialChanges))
+	for index, seen := range c.credentialChanges {
+		if time.Since(seen) > credentialChangeTimeout {
+			delete(c.credentialChanges, index)
+			continue
+		}
+		out = append(out, index)
+	}
+
+	return out
+}
+
+// Refreshes the validators whose credential changes have reached the head state
+func (c *CachingConsensusLayer) checkCredentialChanges(ctx context.Context) error {
+	indices := c.pendingCredentialChanges()
+	if len(indices) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resp, err := c.client.Validators(ctx, &api.ValidatorsOpts{
+		State:   "head",
+		Indices: indices,
+	})
+	if err != nil {
+		return err
+	}
+
+	refreshed := 0
+	for index, validator := range resp.Data {
+		info := newValidatorInfo(index, validator.Validator)
+		if !info.HasExecutionCredentials {
+			continue
+		}
+
+		c.validators.Refresh(info)
+		c.credentialChangesLock.Lock()
+		delete(c.credentialChanges, index)
+		c.credentialChangesLock.Unlock()
+		refreshed++
+	}
+
+	if refreshed > 0 {
+		c.m.Counter("validator_cache_invalidated").Add(float64(refreshed))
+		c.logger.Debug("Refreshed validators with changed credentials", zap.Int("validators", refreshed))
+	}
+	return nil
+}
+
+// Checks for credential changes in the background, if any are waited for
+func (c *CachingConsensusLayer) startCheckCredentialChanges() {
+	c.credentialChangesLock.Lock()
+	waiting := len(c.credentialChanges)
+	c.credentialChangesLock.Unlock()
+	if waiting == 0 {
+		return
+	}
+
+	if !c.checkingCredentials.CompareAndSwap(false, true) {
+		return
+	}
+
+	started := c.goUnlessStopped(func() {
+		defer c.checkingCredentials.Store(false)
+
+		if err := c.checkCredentialChanges(c.ctx); err != nil {
+			c.m.Counter("validator_cache_invalidation_failed").Inc()
+			c.logger.Warn("Couldn't check for credential changes", zap.Error(err))
+		}
+	})
+	if !started {
+		c.checkingCredentials.Store(false)
+	}
+}
@@ -0,0 +1,216 @@
+package consensuslayer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"go.uber.org/zap"
+)
+
+// How often the bn's sync status is polled
+const syncStatusInterval = 12 * time.Second
+
+// How long a sync status is trusted for. If polling keeps failing for longer, the bn is treated as unfit.
+const syncStatusTTL = 3 * syncStatusInterval
+
+// BNStatus is what the proxy knows of the bn's health
+type BNStatus struct {
+	HeadSlot     phase0.Slot `json:"head_slot"`
+	SyncDistance phase0.Slot `json:"sync_distance"`
+	IsSyncing    bool        `json:"is_syncing"`
+	IsOptimistic bool        `json:"is_optimistic"`
+	ELOffline    bool        `json:"el_offline"`
+	// When the sync status was last polled successfully
+	CheckedAt time.Time `json:"checked_at"`
+	// Why the last poll failed, if it did
+	Error string `json:"error,omitempty"`
+
+	// From finalized_checkpoint and chain_reorg events
+	FinalizedEpoch phase0.Epoch `json:"finalized_epoch"`
+	Reorgs         uint64       `json:"reorgs"`
+	LastReorgSlot  phase0.Slot  `json:"last_reorg_slot"`
+	LastReorgDepth uint64       `json:"last_reorg_depth"`
+
+	// Why the bn isn't fit to serve validators, or empty if it is
+	Unfit string `json:"unfit,omitempty"`
+}
+
+// unfit explains why a bn with this status shouldn't serve validators, who would get stale
+// duties from it, or returns an empty string if it's fit to
+func (s *BNStatus) unfit(now time.Time) string {
+	switch {
+	case s.CheckedAt.IsZero():
+		return "the beacon node's sync status is unknown"
+	case now.Sub(s.CheckedAt) > syncStatusTTL:
+		return fmt.Sprintf("the beacon node hasn't reported its sync status since %s", s.CheckedAt.UTC().Format(time.RFC3339))
+	case s.IsSyncing:
+		return fmt.Sprintf("the beacon node is syncing, %d slots behind", s.SyncDistance)
+	case s.IsOptimistic:
+		return "the beacon node is optimistic, its execution client hasn't validated the head"
+	case s.ELOffline:
+		return "the beacon node's execution client is offline"
+	}
+
+	return ""
+}
+
+// The /eth/v1/node/syncing response. el_offline is read directly, as the client library doesn't have it.
+type syncStatusJSON struct {
+	Data struct {
+		HeadSlot     uint64 `json:"head_slot,string"`
+		SyncDistance uint64 `json:"sync_distance,string"`
+		IsSyncing    bool   `json:"is_syncing"`
+		IsOptimistic bool   `json:"is_optimistic"`
+		ELOffline    bool   `json:"el_offline"`
+	} `json:"data"`
+}
+
+func (c *CachingConsensusLayer) getSyncStatus(ctx context.Context) (*syncStatusJSON, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := c.get(ctx, "/eth/v1/node/syncing", "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("beacon node returned %s for its sync status", resp.Status)
+	}
+
+	out := &syncStatusJSON{}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("couldn't decode the beacon node's sync status: %w", err)
+	}
+
+	return out, nil
+}
+
+// Polls the bn's sync status and records it
+func (c *CachingConsensusLayer) checkSyncStatus(ctx context.Context) {
+	syncStatus, err := c.getSyncStatus(ctx)
+
+	c.statusLock.Lock()
+	if err != nil {
+		c.status.Error = err.Error()
+	} else {
+		c.status.HeadSlot = phase0.Slot(syncStatus.Data.HeadSlot)
+		c.status.SyncDistance = phase0.Slot(syncStatus.Data.SyncDistance)
+		c.status.IsSyncing = syncStatus.Data.IsSyncing
+		c.status.IsOptimistic = syncStatus.Data.IsOptimistic
+		c.status.ELOffline = syncStatus.Data.ELOffline
+		c.status.CheckedAt = time.Now()
+		c.status.Error = ""
+	}
+	unfit := c.status.unfit(time.Now())
+	c.statusLock.Unlock()
+
+	if err != nil {
+		c.m.Counter("sync_status_failed").Inc()
+		c.logger.Warn("Couldn't get the beacon node's sync status", zap.Error(err))
+	} else {
+		c.m.Gauge("bn_head_slot").Set(float64(syncStatus.Data.HeadSlot))
+		c.m.Gauge("bn_sync_distance").Set(float64(syncStatus.Data.SyncDistance))
+	}
+
+	fit := unfit == ""
+	if c.fit.Swap(fit) != fit {
+		if fit {
+			c.logger.Info("The beacon node is fit to serve")
+		} else {
+			c.logger.Warn("The beacon node is unfit to serve, turning requests away", zap.String("reason", unfit))
+		}
+	}
+	if fit {
+		c.m.Gauge("bn_fit").Set(1)
+	} else {
+		c.m.Gauge("bn_fit").Set(0)
+	}
+}
+
+// Polls the bn's sync status every syncStatusInterval until ctx is canceled
+func (c *CachingConsensusLayer) pollSyncStatus(ctx context.Context) {
+	defer c.pollerWG.Done()
+
+	ticker := time.NewTicker(syncStatusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkSyncStatus(ctx)
+		}
+	}
+}
+
+func (c *CachingConsensusLayer) onFinalizedCheckpoint(e *apiv1.Event) {
+	finalizedEvent, ok := e.Data.(*apiv1.FinalizedCheckpointEvent)
+	if !ok {
+		c.logger.Warn("Couldn't convert event to finalizedCheckpointEvent", zap.Any("event", e))
+		return
+	}
+
+	c.statusLock.Lock()
+	c.status.FinalizedEpoch = finalizedEvent.Epoch
+	c.statusLock.Unlock()
+
+	c.m.Gauge("finalized_epoch").Set(float64(finalizedEvent.Epoch))
+	c.logger.Debug("Observed finalized checkpoint", zap.Uint64("epoch", uint64(finalizedEvent.Epoch)))
+}
+
+func (c *CachingConsensusLayer) onChainReorg(e *apiv1.Event) {
+	reorgEvent, ok := e.Data.(*apiv1.ChainReorgEvent)
+	if !ok {
+		c.logger.Warn("Couldn't convert event to chainReorgEvent", zap.Any("event", e))
+		return
+	}
+
+	c.statusLock.Lock()
+	c.status.Reorgs++
+	c.status.LastReorgSlot = reorgEvent.Slot
+	c.status.LastReorgDepth = reorgEvent.Depth
+	c.statusLock.Unlock()
+
+	c.m.Counter("chain_reorg").Inc()
+	c.m.Gauge("chain_reorg_depth").Set(float64(reorgEvent.Depth))
+	c.logger.Info("Observed chain reorg",
+		zap.Uint64("slot", uint64(reorgEvent.Slot)),
+		zap.Uint64("depth", reorgEvent.Depth),
+		zap.String("old_head", reorgEvent.OldHeadBlock.String()),
+		zap.String("new_head", reorgEvent.NewHeadBlock.String()))
+}
+
+// Hands each event to the handler for its topic
+func (c *CachingConsensusLayer) onEvent(e *apiv1.Event) {
+	switch e.Topic {
+	case "head":
+		c.onHeadUpdate(e)
+	case "finalized_checkpoint":
+		c.onFinalizedCheckpoint(e)
+	case "chain_reorg":
+		c.onChainReorg(e)
+	}
+}
+
+// IsFit returns whether the bn was fit to serve validators when it was last polled
+func (c *CachingConsensusLayer) IsFit() bool {
+	return c.fit.Load()
+}
+
+// GetBNStatus returns what's known of the bn's health, and whether it's fit to serve validators
+func (c *CachingConsensusLayer) GetBNStatus() *BNStatus {
+	c.statusLock.Lock()
+	out := c.status
+	c.statusLock.Unlock()
+
+	out.Unfit = out.unfit(time.Now())
+	return &out
+}
@@ -0,0 +1,170 @@
+package consensuslayer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"go.uber.org/zap"
+)
+
+// The parts of a pending deposit needed to classify the validator it creates
+type pendingDepositJSON struct {
+	Pubkey                phase0.BLSPubKey `json:"pubkey"`
+	WithdrawalCredentials hexutil.Bytes    `json:"withdrawal_credentials"`
+}
+
+// pendingValidators are validators that have been deposited, but whose first deposit hasn't been
+// applied to the beacon state yet, so that they have no index
+type pendingValidators struct {
+	byPubkey map[rptypes.ValidatorPubkey]*ValidatorInfo
+	// The indices they'll be given, if they could be worked out. Pending deposits are applied
+	// in order, and each new validator is given the next index. A deposit with an invalid
+	// signature is dropped instead, which shifts the ones after it until the next refresh.
+	byIndex map[phase0.ValidatorIndex]*ValidatorInfo
+}
+
+func newPendingValidatorInfo(pubkey rptypes.ValidatorPubkey, withdrawalCredentials []byte) *ValidatorInfo {
+	out := &ValidatorInfo{
+		Pubkey:    pubkey,
+		ExitEpoch: phase0.Epoch(^uint64(0)),
+		Pending:   true,
+	}
+
+	out.setWithdrawalCredentials(withdrawalCredentials)
+	return out
+}
+
+// Reads the deposits in a state that haven't been applied yet. The validators they create are
+// returned in the order they'll be added to the state.
+func (c *CachingConsensusLayer) getPendingValidators(ctx context.Context, state string) ([]*ValidatorInfo, error) {
+	resp, err := c.get(ctx, "/eth/v1/beacon/states/"+state+"/pending_deposits", "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out := make([]*ValidatorInfo, 0)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// Before electra, there's no such endpoint, as deposits are applied as soon as they're
+		// included in a block, so there are no pending validators
+		return out, nil
+	default:
+		return nil, fmt.Errorf("beacon node returned %s for pending deposits", resp.Status)
+	}
+
+	seen := make(map[rptypes.ValidatorPubkey]struct{})
+	_, err = decodeDataList(resp.Body, "pending deposits", func(dec *json.Decoder, i int) error {
+		var deposit pendingDepositJSON
+		if err := dec.Decode(&deposit); err != nil {
+			return fmt.Errorf("couldn't decode pending deposit %d: %w", i, err)
+		}
+
+		// Only a validator's first deposit sets its withdrawal credentials. The rest are top ups,
+		// as are deposits to validators already in the state.
+		pubkey := rptypes.ValidatorPubkey(deposit.Pubkey)
+		if _, ok := seen[pubkey]; ok {
+			return nil
+		}
+		seen[pubkey] = struct{}{}
+		if c.validators.GetByPubkey(pubkey) != nil {
+			return nil
+		}
+
+		out = append(out, newPendingValidatorInfo(pubkey, deposit.WithdrawalCredentials))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Loads the pending validators from the head state, and the indices they'll get if it can
+func (c *CachingConsensusLayer) loadPendingValidators(ctx context.Context) (*pendingValidators, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	// The validator count and pending deposits have to come from the same state for the
+	// indices to line up, so the head is pinned by its root
+	state := "head"
+	root, err := c.client.BeaconStateRoot(ctx, &api.BeaconStateRootOpts{State: "head"})
+	if err != nil {
+		c.logger.Debug("Couldn't get the head state root, pending validators' indices won't be known", zap.Error(err))
+	} else {
+		state = root.Data.String()
+	}
+
+	list, err := c.getPendingValidators(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &pendingValidators{
+		byPubkey: make(map[rptypes.ValidatorPubkey]*ValidatorInfo, len(list)),
+		byIndex:  make(map[phase0.ValidatorIndex]*ValidatorInfo, len(list)),
+	}
+	for _, validatorInfo := range list {
+		out.byPubkey[validatorInfo.Pubkey] = validatorInfo
+	}
+
+	if state == "head" || len(list) == 0 {
+		return out, nil
+	}
+
+	count, err := c.validatorCount(ctx, state)
+	if err != nil {
+		c.logger.Debug("Couldn't count the validators in the head state, pending validators' indices won't be known", zap.Error(err))
+		return out, nil
+	}
+
+	for i, validatorInfo := range list {
+		indexed := *validatorInfo
+		indexed.Index = phase0.ValidatorIndex(count + i)
+		out.byIndex[indexed.Index] = &indexed
+	}
+
+	return out, nil
+}
+
+// Reloads the pending validators
+func (c *CachingConsensusLayer) refreshPendingValidators(ctx context.Context) {
+	pending, err := c.loadPendingValidators(ctx)
+	if err != nil {
+		c.m.Counter("pending_validators_failed").Inc()
+		c.logger.Warn("Couldn't load pending deposits", zap.Error(err))
+		return
+	}
+
+	c.pending.Store(pending)
+	c.m.Gauge("pending_validators").Set(float64(len(pending.byPubkey)))
+}
+
+// Looks a validator up in the pending deposits
+func (c *CachingConsensusLayer) getPendingValidator(pubkey rptypes.ValidatorPubkey) *ValidatorInfo {
+	pending := c.pending.Load()
+	if pending == nil {
+		return nil
+	}
+
+	return pending.byPubkey[pubkey]
+}
+
+// Looks a validator up in the pending deposits by the index it'll be given
+func (c *CachingConsensusLayer) getPendingValidatorByIndex(index phase0.ValidatorIndex) *ValidatorInfo {
+	pending := c.pending.Load()
+	if pending == nil {
+		return nil
+	}
+
+	return pending.byIndex[index]
+}
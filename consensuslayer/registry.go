@@ -0,0 +1,435 @@
+package consensuslayer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+)
+
+const pubkeyLength = 48
+const withdrawalLength = 20
+
+// Snapshots start with a magic string and a version byte, followed by the time the validator
+// set they hold was loaded, and then one record per validator of its index, pubkey, withdrawal
+// address, withdrawal credentials prefix, and exit epoch
+const snapshotMagic = "rpvcache"
+const snapshotVersion = 3
+
+// Version 2 snapshots only recorded whether a validator was 0x01 or 0x02, not which.
+// They're still loaded, and the next full reload tells the two apart.
+const snapshotVersionNoCompounding = 2
+const snapshotRecordLength = 8 + pubkeyLength + withdrawalLength + 1 + 8
+
+// Snapshots hold a whole validator set, plus any validators looked up past its end before it was
+// saved, so their indices stay close to their record count. Much larger indices mean the snapshot is
+// corrupt, and are rejected rather than growing the registry to fit them.
+func maxSnapshotIndex(records int64) uint64 {
+	return 2*uint64(records) + 1024
+}
+
+// Several times the current validator set, so a bad size hint can't reserve unbounded memory
+const maxReserve = 1 << 23
+
+type validatorRecord struct {
+	pubkey                  rptypes.ValidatorPubkey
+	withdrawalAddress       common.Address
+	exitEpoch               phase0.Epoch
+	hasExecutionCredentials bool
+	compounding             bool
+
+	// Whether the validator at this index has been loaded at all
+	present bool
+}
+
+// validatorRegistry holds the validators the bn knows of, indexed by validator index, pubkey,
+// and, for 0x01 validators, withdrawal address.
+//
+// None of its elements hold pointers other than the withdrawal address index, so the garbage
+// collector doesn't need to scan the rest, even with millions of validators.
+type validatorRegistry struct {
+	sync.RWMutex
+
+	// Indexed by validator index, which the beacon chain assigns sequentially
+	validators []validatorRecord
+
+	byPubkey            map[rptypes.ValidatorPubkey]phase0.ValidatorIndex
+	byWithdrawalAddress map[common.Address][]phase0.ValidatorIndex
+
+	// Whole-set loads stream a state that's minutes old by the time they finish, so validators
+	// refreshed on their own meanwhile are kept by the sequence number of their refresh, and
+	// the loads leave them be. They're forgotten once no load is running.
+	loading    int
+	refreshSeq uint64
+	refreshed  map[phase0.ValidatorIndex]uint64
+}
+
+func newValidatorRegistry() *validatorRegistry {
+	return &validatorRegistry{
+		byPubkey:            make(map[rptypes.ValidatorPubkey]phase0.ValidatorIndex),
+		byWithdrawalAddress: make(map[common.Address][]phase0.ValidatorIndex),
+		refreshed:           make(map[phase0.ValidatorIndex]uint64),
+	}
+}
+
+func (r *validatorRegistry) info(index phase0.ValidatorIndex) *ValidatorInfo {
+	if uint64(index) >= uint64(len(r.validators)) {
+		return nil
+	}
+
+	v := &r.validators[index]
+	if !v.present {
+		return nil
+	}
+
+	return &ValidatorInfo{
+		Index:                   index,
+		Pubkey:                  v.pubkey,
+		WithdrawalAddress:       v.withdrawalAddress,
+		HasExecutionCredentials: v.hasExecutionCredentials,
+		Compounding:             v.compounding,
+		ExitEpoch:               v.exitEpoch,
+	}
+}
+
+// Get looks up a validator by index, returning nil if it isn't in the registry
+func (r *validatorRegistry) Get(index phase0.ValidatorIndex) *ValidatorInfo {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.info(index)
+}
+
+// GetByPubkey looks up a validator by pubkey, returning nil if it isn't in the registry
+func (r *validatorRegistry) GetByPubkey(pubkey rptypes.ValidatorPubkey) *ValidatorInfo {
+	r.RLock()
+	defer r.RUnlock()
+
+	index, ok := r.byPubkey[pubkey]
+	if !ok {
+		return nil
+	}
+
+	return r.info(index)
+}
+
+// GetByWithdrawalAddress finds the 0x01 validators withdrawing to addr
+func (r *validatorRegistry) GetByWithdrawalAddress(addr common.Address) []*ValidatorInfo {
+	r.RLock()
+	defer r.RUnlock()
+
+	indices := r.byWithdrawalAddress[addr]
+	out := make([]*ValidatorInfo, 0, len(indices))
+	for _, index := range indices {
+		out = append(out, r.info(index))
+	}
+
+	return out
+}
+
+// WithdrawalAddresses returns the withdrawal address of every 0x01 validator that hasn't exited as of epoch
+func (r *validatorRegistry) WithdrawalAddresses(epoch phase0.Epoch) []common.Address {
+	r.RLock()
+	defer r.RUnlock()
+
+	out := make([]common.Address, 0, len(r.byWithdrawalAddress))
+	for addr, indices := range r.byWithdrawalAddress {
+		for _, index := range indices {
+			if r.validators[index].exitEpoch > epoch {
+				out = append(out, addr)
+				break
+			}
+		}
+	}
+
+	return out
+}
+
+// Set adds or replaces the validator at v.Index
+func (r *validatorRegistry) Set(v *ValidatorInfo) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.set(v)
+}
+
+// Refresh adds or replaces a validator fetched on its own, which is newer than what any
+// whole-set load in progress will find
+func (r *validatorRegistry) Refresh(v *ValidatorInfo) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.refreshSeq++
+	if r.loading > 0 {
+		r.refreshed[v.Index] = r.refreshSeq
+	}
+	r.set(v)
+}
+
+// StartLoad notes that a whole-set load is starting, returning the mark to pass to SetLoaded.
+// FinishLoad must be called once it's done.
+func (r *validatorRegistry) StartLoad() uint64 {
+	r.Lock()
+	defer r.Unlock()
+
+	r.loading++
+	return r.refreshSeq
+}
+
+// SetLoaded adds or replaces a validator found by a whole-set load that started at mark,
+// unless it's been refreshed since
+func (r *validatorRegistry) SetLoaded(v *ValidatorInfo, mark uint64) {
+	r.Lock()
+	defer r.Unlock()
+
+	if seq, ok := r.refreshed[v.Index]; ok && seq > mark {
+		return
+	}
+	r.set(v)
+}
+
+// FinishLoad notes that a whole-set load is done
+func (r *validatorRegistry) FinishLoad() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.loading--
+	if r.loading == 0 {
+		clear(r.refreshed)
+	}
+}
+
+func (r *validatorRegistry) set(v *ValidatorInfo) {
+	index := v.Index
+	if uint64(index) >= uint64(len(r.validators)) {
+		r.grow(uint64(index) + 1)
+	}
+
+	old := &r.validators[index]
+	if old.present {
+		if old.pubkey != v.Pubkey {
+			delete(r.byPubkey, old.pubkey)
+		}
+		if old.hasExecutionCredentials && (!v.HasExecutionCredentials || old.withdrawalAddress != v.WithdrawalAddress) {
+			r.removeWithdrawalAddress(old.withdrawalAddress, index)
+		}
+	}
+
+	if v.HasExecutionCredentials && (!old.present || !old.hasExecutionCredentials || old.withdrawalAddress != v.WithdrawalAddress) {
+		r.byWithdrawalAddress[v.WithdrawalAddress] = append(r.byWithdrawalAddress[v.WithdrawalAddress], index)
+	}
+	r.byPubkey[v.Pubkey] = index
+
+	*old = validatorRecord{
+		pubkey:                  v.Pubkey,
+		withdrawalAddress:       v.WithdrawalAddress,
+		exitEpoch:               v.ExitEpoch,
+		hasExecutionCredentials: v.HasExecutionCredentials,
+		compounding:             v.Compounding,
+		present:                 true,
+	}
+}
+
+// Reserve makes room for count validators, so that loading a validator set in index order
+// doesn't repeatedly grow the registry
+func (r *validatorRegistry) Reserve(count int) {
+	r.Lock()
+	defer r.Unlock()
+
+	count = min(count, maxReserve)
+	if count > cap(r.validators) {
+		grown := make([]validatorRecord, len(r.validators), count)
+		copy(grown, r.validators)
+		r.validators = grown
+	}
+
+	if len(r.byPubkey) == 0 {
+		r.byPubkey = make(map[rptypes.ValidatorPubkey]phase0.ValidatorIndex, count)
+	}
+}
+
+func (r *validatorRegistry) grow(length uint64) {
+	if length <= uint64(cap(r.validators)) {
+		r.validators = r.validators[:length]
+		return
+	}
+
+	// The validator set only ever grows, so leave some room for new validators
+	grown := make([]validatorRecord, length, length+length/8)
+	copy(grown, r.validators)
+	r.validators = grown
+}
+
+func (r *validatorRegistry) removeWithdrawalAddress(addr common.Address, index phase0.ValidatorIndex) {
+	indices := r.byWithdrawalAddress[addr]
+	for i, idx := range indices {
+		if idx != index {
+			continue
+		}
+
+		indices = append(indices[:i], indices[i+1:]...)
+		break
+	}
+
+	if len(indices) == 0 {
+		delete(r.byWithdrawalAddress, addr)
+		return
+	}
+	r.byWithdrawalAddress[addr] = indices
+}
+
+// Len returns how many validators are in the registry
+func (r *validatorRegistry) Len() int {
+	r.RLock()
+	defer r.RUnlock()
+
+	return len(r.byPubkey)
+}
+
+// nextIndex returns the lowest validator index above every one in the registry
+func (r *validatorRegistry) nextIndex() uint64 {
+	r.RLock()
+	defer r.RUnlock()
+
+	return uint64(len(r.validators))
+}
+
+// save writes every validator to path, along with the time the validator set was loaded.
+// The snapshot is written to a temporary file and renamed over the previous one, so that a crash
+// midway leaves the previous snapshot intact.
+func (r *validatorRegistry) save(path string, loadedAt time.Time) (int, error) {
+	tmpPath := path + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmpPath)
+
+	count, err := r.write(f, loadedAt)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return count, os.Rename(tmpPath, path)
+}
+
+func (r *validatorRegistry) write(w io.Writer, loadedAt time.Time) (int, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	bw := bufio.NewWriter(w)
+
+	var header [len(snapshotMagic) + 1 + 8]byte
+	copy(header[:], snapshotMagic)
+	header[len(snapshotMagic)] = snapshotVersion
+	binary.BigEndian.PutUint64(header[len(snapshotMagic)+1:], uint64(loadedAt.Unix()))
+	if _, err := bw.Write(header[:]); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var record [snapshotRecordLength]byte
+	for index, v := range r.validators {
+		if !v.present {
+			continue
+		}
+
+		binary.BigEndian.PutUint64(record[:], uint64(index))
+		copy(record[8:], v.pubkey[:])
+		copy(record[8+pubkeyLength:], v.withdrawalAddress[:])
+		record[8+pubkeyLength+withdrawalLength] = 0
+		if v.compounding {
+			record[8+pubkeyLength+withdrawalLength] = 0x02
+		} else if v.hasExecutionCredentials {
+			record[8+pubkeyLength+withdrawalLength] = 0x01
+		}
+		binary.BigEndian.PutUint64(record[8+pubkeyLength+withdrawalLength+1:], uint64(v.exitEpoch))
+		if _, err := bw.Write(record[:]); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	return count, bw.Flush()
+}
+
+// load adds the validators in the snapshot at path to the registry, and returns the time the
+// validator set they came from was loaded
+func (r *validatorRegistry) load(path string) (time.Time, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	records := info.Size() / snapshotRecordLength
+	r.Reserve(int(records))
+
+	return r.read(f, maxSnapshotIndex(records))
+}
+
+// read adds the validators in a snapshot to the registry, failing if any index is maxIndex or more
+func (r *validatorRegistry) read(rd io.Reader, maxIndex uint64) (time.Time, int, error) {
+	br := bufio.NewReader(rd)
+
+	var header [len(snapshotMagic) + 1 + 8]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return time.Time{}, 0, fmt.Errorf("couldn't read the snapshot header: %w", err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return time.Time{}, 0, errors.New("not a validator cache snapshot")
+	}
+	if header[len(snapshotMagic)] != snapshotVersion && header[len(snapshotMagic)] != snapshotVersionNoCompounding {
+		return time.Time{}, 0, fmt.Errorf("unsupported validator cache snapshot version %d", header[len(snapshotMagic)])
+	}
+	loadedAt := time.Unix(int64(binary.BigEndian.Uint64(header[len(snapshotMagic)+1:])), 0)
+
+	count := 0
+	var record [snapshotRecordLength]byte
+	for {
+		_, err := io.ReadFull(br, record[:])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return time.Time{}, 0, fmt.Errorf("couldn't read validator %d from the snapshot: %w", count, err)
+		}
+
+		index := binary.BigEndian.Uint64(record[:])
+		if index >= maxIndex {
+			return time.Time{}, 0, fmt.Errorf("validator %d in the snapshot has an invalid index %d", count, index)
+		}
+
+		prefix := record[8+pubkeyLength+withdrawalLength]
+		r.Set(&ValidatorInfo{
+			Index:                   phase0.ValidatorIndex(index),
+			Pubkey:                  rptypes.BytesToValidatorPubkey(record[8 : 8+pubkeyLength]),
+			WithdrawalAddress:       common.BytesToAddress(record[8+pubkeyLength : 8+pubkeyLength+withdrawalLength]),
+			HasExecutionCredentials: prefix == 0x01 || prefix == 0x02,
+			Compounding:             prefix == 0x02,
+			ExitEpoch:               phase0.Epoch(binary.BigEndian.Uint64(record[8+pubkeyLength+withdrawalLength+1:])),
+		})
+		count++
+	}
+
+	return loadedAt, count, nil
+}
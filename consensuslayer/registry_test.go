@@ -0,0 +1,333 @@
+package consensuslayer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+)
+
+const farFutureEpoch = phase0.Epoch(0xffffffffffffffff)
+
+func testPubkey(i byte) rptypes.ValidatorPubkey {
+	var out rptypes.ValidatorPubkey
+	out[0] = 0xb2
+	out[len(out)-1] = i
+	return out
+}
+
+func TestRegistryRoundTrip(t *testing.T) {
+	registry := newValidatorRegistry()
+
+	expectedKey, _ := hex.DecodeString("b20fb4a9340f8b23197b8449db7a5d3d8d068570a2b61a8d78817537aac4fd5645434d3e89a918a3ba9d0b7707cbeae0")
+	expectedAddr, _ := hex.DecodeString("6a6d731664115Ff3C823807442a4dC94999b0923")
+
+	registry.Set(&ValidatorInfo{
+		Index:                   7,
+		Pubkey:                  rptypes.BytesToValidatorPubkey(expectedKey),
+		WithdrawalAddress:       common.BytesToAddress(expectedAddr),
+		HasExecutionCredentials: true,
+		ExitEpoch:               farFutureEpoch,
+	})
+
+	for _, vInfo := range []*ValidatorInfo{registry.Get(7), registry.GetByPubkey(rptypes.BytesToValidatorPubkey(expectedKey))} {
+		if vInfo == nil {
+			t.Fatal("registry lookup failed")
+			return
+		}
+
+		if vInfo.Index != 7 {
+			t.Fatal("unexpected index", vInfo.Index)
+		}
+
+		if !bytes.Equal(vInfo.Pubkey[:], expectedKey) {
+			t.Fatal("unexpected public key", vInfo.Pubkey.String())
+		}
+
+		if !bytes.Equal(vInfo.WithdrawalAddress[:], expectedAddr) {
+			t.Fatal("unexpected Withdrawal Address", vInfo.WithdrawalAddress.String())
+		}
+	}
+
+	if registry.Get(6) != nil || registry.Get(8) != nil || registry.GetByPubkey(testPubkey(1)) != nil {
+		t.Fatal("expected validators that were never added to be missing")
+	}
+
+	if registry.nextIndex() != 8 || registry.Len() != 1 {
+		t.Fatalf("unexpected registry size, next index %d, %d validators", registry.nextIndex(), registry.Len())
+	}
+}
+
+func TestRegistryReserve(t *testing.T) {
+	registry := newValidatorRegistry()
+	registry.Set(&ValidatorInfo{Index: 1, Pubkey: testPubkey(1)})
+
+	registry.Reserve(100)
+	if cap(registry.validators) != 100 || registry.Len() != 1 || registry.nextIndex() != 2 {
+		t.Fatalf("unexpected registry after reserving, cap %d len %d", cap(registry.validators), registry.Len())
+	}
+	if v := registry.Get(1); v == nil || v.Pubkey != testPubkey(1) {
+		t.Fatalf("lost validator 1 reserving, got %+v", v)
+	}
+
+	// Filling the reserved space doesn't move it
+	for i := 0; i < 100; i++ {
+		registry.Set(&ValidatorInfo{Index: phase0.ValidatorIndex(i), Pubkey: testPubkey(byte(i))})
+	}
+	if cap(registry.validators) != 100 || registry.Len() != 100 {
+		t.Fatalf("unexpected registry after filling it, cap %d len %d", cap(registry.validators), registry.Len())
+	}
+
+	// Reserving less than is there already does nothing, and huge hints are capped
+	registry.Reserve(10)
+	if cap(registry.validators) != 100 {
+		t.Fatalf("reserve shrank the registry to %d", cap(registry.validators))
+	}
+	registry.Reserve(maxReserve * 4)
+	if cap(registry.validators) != maxReserve || registry.Len() != 100 {
+		t.Fatalf("unexpected registry after reserving too much, cap %d len %d", cap(registry.validators), registry.Len())
+	}
+}
+
+func TestRegistryWithdrawalAddresses(t *testing.T) {
+	registry := newValidatorRegistry()
+
+	addr1 := common.HexToAddress("0x01")
+	addr2 := common.HexToAddress("0x02")
+	addr3 := common.HexToAddress("0x03")
+
+	registry.Set(&ValidatorInfo{Index: 0, Pubkey: testPubkey(0), WithdrawalAddress: addr1, HasExecutionCredentials: true, ExitEpoch: farFutureEpoch})
+	registry.Set(&ValidatorInfo{Index: 1, Pubkey: testPubkey(1), WithdrawalAddress: addr1, HasExecutionCredentials: true, ExitEpoch: 10})
+	registry.Set(&ValidatorInfo{Index: 2, Pubkey: testPubkey(2), WithdrawalAddress: addr2, HasExecutionCredentials: true, ExitEpoch: 10})
+	// BLS validators have no withdrawal address
+	registry.Set(&ValidatorInfo{Index: 3, Pubkey: testPubkey(3), ExitEpoch: farFutureEpoch})
+
+	addresses := func(epoch phase0.Epoch) []common.Address {
+		out := registry.WithdrawalAddresses(epoch)
+		sort.Slice(out, func(i, j int) bool {
+			return bytes.Compare(out[i].Bytes(), out[j].Bytes()) < 0
+		})
+		return out
+	}
+
+	if out := addresses(5); len(out) != 2 || out[0] != addr1 || out[1] != addr2 {
+		t.Fatalf("unexpected withdrawal addresses %v", out)
+	}
+
+	// Once validator 2 exits, nothing withdraws to addr2
+	if out := addresses(10); len(out) != 1 || out[0] != addr1 {
+		t.Fatalf("unexpected withdrawal addresses %v", out)
+	}
+
+	if out := registry.GetByWithdrawalAddress(addr1); len(out) != 2 {
+		t.Fatalf("expected 2 validators withdrawing to %s, got %d", addr1, len(out))
+	}
+
+	// Validator 0 changes its withdrawal address, and the BLS validator moves to 0x01
+	registry.Set(&ValidatorInfo{Index: 0, Pubkey: testPubkey(0), WithdrawalAddress: addr3, HasExecutionCredentials: true, ExitEpoch: farFutureEpoch})
+	registry.Set(&ValidatorInfo{Index: 3, Pubkey: testPubkey(3), WithdrawalAddress: addr2, HasExecutionCredentials: true, ExitEpoch: farFutureEpoch})
+
+	if out := addresses(10); len(out) != 2 || out[0] != addr2 || out[1] != addr3 {
+		t.Fatalf("unexpected withdrawal addresses %v", out)
+	}
+
+	if out := registry.GetByWithdrawalAddress(addr1); len(out) != 1 || out[0].Index != 1 {
+		t.Fatalf("unexpected validators withdrawing to %s: %+v", addr1, out)
+	}
+
+	// Setting a validator again changes nothing
+	registry.Set(&ValidatorInfo{Index: 3, Pubkey: testPubkey(3), WithdrawalAddress: addr2, HasExecutionCredentials: true, ExitEpoch: farFutureEpoch})
+	if out := registry.GetByWithdrawalAddress(addr2); len(out) != 2 {
+		t.Fatalf("expected 2 validators withdrawing to %s, got %d", addr2, len(out))
+	}
+}
+
+func TestRegistryRefreshDuringLoad(t *testing.T) {
+	registry := newValidatorRegistry()
+
+	addr := common.HexToAddress("0x01")
+	registry.Set(&ValidatorInfo{Index: 0, Pubkey: testPubkey(0), ExitEpoch: farFutureEpoch})
+	registry.Set(&ValidatorInfo{Index: 1, Pubkey: testPubkey(1), ExitEpoch: farFutureEpoch})
+
+	// A reload starts from an older state, then validator 0's credential change is refreshed
+	mark := registry.StartLoad()
+	registry.Refresh(&ValidatorInfo{Index: 0, Pubkey: testPubkey(0), WithdrawalAddress: addr, HasExecutionCredentials: true, ExitEpoch: farFutureEpoch})
+
+	// The reload reaches both validators, and only overwrites the one that wasn't refreshed
+	registry.SetLoaded(&ValidatorInfo{Index: 0, Pubkey: testPubkey(0), ExitEpoch: farFutureEpoch}, mark)
+	registry.SetLoaded(&ValidatorInfo{Index: 1, Pubkey: testPubkey(1), WithdrawalAddress: addr, HasExecutionCredentials: true, ExitEpoch: 10}, mark)
+	registry.FinishLoad()
+
+	if v := registry.Get(0); v == nil || !v.HasExecutionCredentials || v.WithdrawalAddress != addr {
+		t.Fatalf("the refresh of validator 0 was overwritten: %+v", v)
+	}
+	if v := registry.Get(1); v == nil || v.ExitEpoch != 10 {
+		t.Fatalf("validator 1 wasn't reloaded: %+v", v)
+	}
+
+	// The next reload overwrites it again
+	mark = registry.StartLoad()
+	registry.SetLoaded(&ValidatorInfo{Index: 0, Pubkey: testPubkey(0), WithdrawalAddress: addr, HasExecutionCredentials: true, ExitEpoch: 20}, mark)
+	registry.FinishLoad()
+
+	if v := registry.Get(0); v == nil || v.ExitEpoch != 20 {
+		t.Fatalf("validator 0 wasn't reloaded: %+v", v)
+	}
+}
+
+func TestRegistrySnapshotRoundTrip(t *testing.T) {
+	registry := newValidatorRegistry()
+
+	expectedKey, _ := hex.DecodeString("b20fb4a9340f8b23197b8449db7a5d3d8d068570a2b61a8d78817537aac4fd5645434d3e89a918a3ba9d0b7707cbeae0")
+	expectedAddr, _ := hex.DecodeString("6a6d731664115Ff3C823807442a4dC94999b0923")
+
+	for _, index := range []phase0.ValidatorIndex{7, 42} {
+		registry.Set(&ValidatorInfo{
+			Index:                   index,
+			Pubkey:                  testPubkey(byte(index)),
+			WithdrawalAddress:       common.BytesToAddress(expectedAddr),
+			HasExecutionCredentials: index == 42,
+			ExitEpoch:               phase0.Epoch(index),
+		})
+	}
+	registry.Set(&ValidatorInfo{
+		Index:  42,
+		Pubkey: rptypes.BytesToValidatorPubkey(expectedKey),
+		// Replaces the previous validator 42
+		WithdrawalAddress:       common.BytesToAddress(expectedAddr),
+		HasExecutionCredentials: true,
+		ExitEpoch:               farFutureEpoch,
+	})
+	registry.Set(&ValidatorInfo{
+		Index:                   50,
+		Pubkey:                  testPubkey(50),
+		WithdrawalAddress:       common.BytesToAddress(expectedAddr),
+		HasExecutionCredentials: true,
+		Compounding:             true,
+		ExitEpoch:               farFutureEpoch,
+	})
+
+	path := filepath.Join(t.TempDir(), "validators.snapshot")
+	loadedAt := time.Unix(1700000000, 0)
+	count, err := registry.save(path, loadedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 validators to be saved, got %d", count)
+	}
+
+	restored := newValidatorRegistry()
+	restoredAt, count, err := restored.load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 || !restoredAt.Equal(loadedAt) {
+		t.Fatalf("unexpected snapshot contents, %d validators loaded at %v", count, restoredAt)
+	}
+	if restored.nextIndex() != 51 {
+		t.Fatalf("expected the next index to be 51, got %d", restored.nextIndex())
+	}
+
+	vInfo := restored.GetByPubkey(rptypes.BytesToValidatorPubkey(expectedKey))
+	if vInfo == nil || vInfo.Index != 42 || !vInfo.HasExecutionCredentials || vInfo.Compounding || vInfo.ExitEpoch != farFutureEpoch || !bytes.Equal(vInfo.WithdrawalAddress[:], expectedAddr) {
+		t.Fatalf("unexpected validator info %+v", vInfo)
+	}
+	vInfo = restored.Get(50)
+	if vInfo == nil || !vInfo.HasExecutionCredentials || !vInfo.Compounding {
+		t.Fatalf("unexpected validator info %+v", vInfo)
+	}
+	vInfo = restored.Get(7)
+	if vInfo == nil || vInfo.HasExecutionCredentials || vInfo.ExitEpoch != 7 {
+		t.Fatalf("unexpected validator info %+v", vInfo)
+	}
+	if restored.GetByPubkey(testPubkey(42)) != nil {
+		t.Fatal("expected the replaced pubkey to be gone")
+	}
+	if out := restored.WithdrawalAddresses(0); len(out) != 1 {
+		t.Fatalf("unexpected withdrawal addresses %v", out)
+	}
+}
+
+func TestRegistrySnapshotErrors(t *testing.T) {
+	registry := newValidatorRegistry()
+
+	if _, _, err := registry.load(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing snapshot to be reported as such, got %v", err)
+	}
+
+	var header bytes.Buffer
+	header.WriteString(snapshotMagic)
+	header.WriteByte(snapshotVersion)
+	header.Write(make([]byte, 8))
+
+	badIndex := make([]byte, snapshotRecordLength)
+	badIndex[0] = 0xff
+
+	for name, snapshot := range map[string][]byte{
+		"empty":     {},
+		"magic":     []byte("not a snapshot at all"),
+		"version":   append([]byte(snapshotMagic), 99, 0, 0, 0, 0, 0, 0, 0, 0),
+		"truncated": append(bytes.Clone(header.Bytes()), 1, 2, 3),
+		"index":     append(bytes.Clone(header.Bytes()), badIndex...),
+	} {
+		if _, _, err := registry.read(bytes.NewReader(snapshot), maxSnapshotIndex(1)); err == nil {
+			t.Fatalf("expected an error reading a %s snapshot", name)
+		}
+	}
+}
+
+func TestRegistrySnapshotIndexBound(t *testing.T) {
+	// A single record whose index would have the registry allocate millions of records
+	var snapshot bytes.Buffer
+	snapshot.WriteString(snapshotMagic)
+	snapshot.WriteByte(snapshotVersion)
+	snapshot.Write(make([]byte, 8))
+	record := make([]byte, snapshotRecordLength)
+	binary.BigEndian.PutUint64(record, 1<<24)
+	snapshot.Write(record)
+
+	path := filepath.Join(t.TempDir(), "validators.snapshot")
+	if err := os.WriteFile(path, snapshot.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := newValidatorRegistry()
+	if _, _, err := registry.load(path); err == nil || !strings.Contains(err.Error(), "invalid index") {
+		t.Fatalf("expected an invalid index error, got %v", err)
+	}
+	if registry.nextIndex() != 0 {
+		t.Fatalf("expected nothing to be loaded, got up to index %d", registry.nextIndex())
+	}
+}
+
+func TestRegistrySnapshotVersion2(t *testing.T) {
+	var snapshot bytes.Buffer
+	snapshot.WriteString(snapshotMagic)
+	snapshot.WriteByte(snapshotVersionNoCompounding)
+	snapshot.Write(make([]byte, 8))
+
+	// Version 2 marked both 0x01 and 0x02 validators with 0x01
+	record := make([]byte, snapshotRecordLength)
+	record[7] = 5
+	record[8+pubkeyLength+withdrawalLength] = 0x01
+	snapshot.Write(record)
+
+	registry := newValidatorRegistry()
+	if _, count, err := registry.read(&snapshot, maxSnapshotIndex(1)); err != nil || count != 1 {
+		t.Fatalf("couldn't read a version 2 snapshot, %d validators: %v", count, err)
+	}
+	if vInfo := registry.Get(5); vInfo == nil || !vInfo.HasExecutionCredentials || vInfo.Compounding {
+		t.Fatalf("unexpected validator info %+v", vInfo)
+	}
+}
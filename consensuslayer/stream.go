@@ -0,0 +1,329 @@
+package consensuslayer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/attestantio/go-eth2-client/api"
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"go.uber.org/zap"
+)
+
+// ForEachValidatorClosure is called with each validator in a state as it's decoded.
+// Returning an error stops decoding.
+type ForEachValidatorClosure func(phase0.ValidatorIndex, *phase0.Validator) error
+
+// The SSZ encoding of a validator: pubkey, withdrawal credentials, effective balance, slashed,
+// and the activation eligibility, activation, exit and withdrawable epochs
+const validatorSSZLength = 48 + 32 + 8 + 1 + 8 + 8 + 8 + 8
+
+// Every fork's beacon state starts with the same fields, up to and including the offsets of
+// the validator and balance lists, so they can be found without knowing which fork it's from.
+// The block and state roots are vectors of SLOTS_PER_HISTORICAL_ROOT roots, which varies by preset.
+func validatorsOffsetPosition(slotsPerHistoricalRoot uint64) uint64 {
+	// genesis_time, genesis_validators_root, slot, fork, latest_block_header
+	pos := uint64(8 + 32 + 8 + 16 + 112)
+	// block_roots, state_roots
+	pos += 2 * 32 * slotsPerHistoricalRoot
+	// historical_roots offset, eth1_data, eth1_data_votes offset, eth1_deposit_index
+	return pos + 4 + 72 + 4 + 8
+}
+
+// errNoSSZ means the bn wouldn't send the state as SSZ
+var errNoSSZ = errors.New("the beacon node doesn't serve states as SSZ")
+
+// forEachValidator streams the validators in state from the bn, handing each to closure as
+// it's decoded, so that the whole validator set is never held in memory at once.
+//
+// It asks for the state as SSZ, and only decodes the validator list out of it. If the bn can't
+// send SSZ, or forceJSON is set, it streams the JSON validator list instead.
+//
+// When the number of validators is known before they're decoded, it's passed to reserve.
+func (c *CachingConsensusLayer) forEachValidator(ctx context.Context, state string, reserve func(int), closure ForEachValidatorClosure) (int, error) {
+	if !c.forceJSON {
+		count, err := c.forEachValidatorSSZ(ctx, state, reserve, closure)
+		if !errors.Is(err, errNoSSZ) {
+			return count, err
+		}
+
+		c.logger.Debug("Falling back to JSON to load the validator set", zap.Error(err))
+	}
+
+	return c.forEachValidatorJSON(ctx, state, closure)
+}
+
+func (c *CachingConsensusLayer) get(ctx context.Context, path string, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.bnURL.JoinPath(path).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	return c.rawClient.Do(req)
+}
+
+// Reads a topic from the bn's event stream, calling onEvent with each event's name and data,
+// until the stream ends or ctx is done
+func (c *CachingConsensusLayer) readEvents(ctx context.Context, topic string, onEvent func(string, []byte)) error {
+	u := c.bnURL.JoinPath("/eth/v1/events")
+	u.RawQuery = "topics=" + topic
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.eventsClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("beacon node returned %s for %s events", resp.Status, topic)
+	}
+
+	var event string
+	var data bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				onEvent(event, data.Bytes())
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (c *CachingConsensusLayer) forEachValidatorSSZ(ctx context.Context, state string, reserve func(int), closure ForEachValidatorClosure) (int, error) {
+	body, slotsPerHistoricalRoot, err := c.getStateSSZ(ctx, state)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	return decodeValidatorsSSZ(body, slotsPerHistoricalRoot, reserve, closure)
+}
+
+// validatorCount reads how many validators are in state from the start of its SSZ encoding, and
+// hangs up before the validators themselves are sent. It returns errNoSSZ if the bn can't send SSZ,
+// or forceJSON is set.
+func (c *CachingConsensusLayer) validatorCount(ctx context.Context, state string) (int, error) {
+	if c.forceJSON {
+		return 0, errNoSSZ
+	}
+
+	body, slotsPerHistoricalRoot, err := c.getStateSSZ(ctx, state)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	_, _, count, err := readValidatorListBounds(body, slotsPerHistoricalRoot)
+	return count, err
+}
+
+// getStateSSZ requests state from the bn as SSZ, returning the response body and the
+// SLOTS_PER_HISTORICAL_ROOT needed to find the validator list in it
+func (c *CachingConsensusLayer) getStateSSZ(ctx context.Context, state string) (io.ReadCloser, uint64, error) {
+	spec, err := c.client.Spec(ctx, &api.SpecOpts{})
+	if err != nil {
+		return nil, 0, err
+	}
+	slotsPerHistoricalRoot, ok := spec.Data["SLOTS_PER_HISTORICAL_ROOT"].(uint64)
+	if !ok {
+		return nil, 0, errors.New("the beacon node's spec has no SLOTS_PER_HISTORICAL_ROOT")
+	}
+
+	resp, err := c.get(ctx, "/eth/v2/debug/beacon/states/"+state, "application/octet-stream")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotAcceptable:
+		err = errNoSSZ
+	case resp.StatusCode != http.StatusOK:
+		err = fmt.Errorf("beacon node returned %s for state %s", resp.Status, state)
+	case !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/octet-stream"):
+		err = errNoSSZ
+	}
+	if err != nil {
+		resp.Body.Close()
+		return nil, 0, err
+	}
+
+	return resp.Body, slotsPerHistoricalRoot, nil
+}
+
+// readValidatorListBounds reads an SSZ encoded beacon state up to the end of the validator and
+// balance list offsets, returning how far it read, where the validator list starts, and its length
+func readValidatorListBounds(r io.Reader, slotsPerHistoricalRoot uint64) (uint64, uint64, int, error) {
+	pos := validatorsOffsetPosition(slotsPerHistoricalRoot)
+
+	// Read up to the end of the balances offset, which ends the validator list
+	if _, err := io.CopyN(io.Discard, r, int64(pos)); err != nil {
+		return 0, 0, 0, fmt.Errorf("couldn't read the beacon state: %w", err)
+	}
+	var offsets [8]byte
+	if _, err := io.ReadFull(r, offsets[:]); err != nil {
+		return 0, 0, 0, fmt.Errorf("couldn't read the beacon state: %w", err)
+	}
+	pos += uint64(len(offsets))
+
+	validatorsOffset := uint64(binary.LittleEndian.Uint32(offsets[:4]))
+	balancesOffset := uint64(binary.LittleEndian.Uint32(offsets[4:]))
+	if validatorsOffset < pos || balancesOffset < validatorsOffset ||
+		(balancesOffset-validatorsOffset)%validatorSSZLength != 0 {
+
+		return 0, 0, 0, fmt.Errorf("invalid validator list offsets %d and %d in the beacon state", validatorsOffset, balancesOffset)
+	}
+
+	return pos, validatorsOffset, int((balancesOffset - validatorsOffset) / validatorSSZLength), nil
+}
+
+// decodeValidatorsSSZ decodes the validator list out of an SSZ encoded beacon state
+func decodeValidatorsSSZ(r io.Reader, slotsPerHistoricalRoot uint64, reserve func(int), closure ForEachValidatorClosure) (int, error) {
+	pos, validatorsOffset, count, err := readValidatorListBounds(r, slotsPerHistoricalRoot)
+	if err != nil {
+		return 0, err
+	}
+
+	// Skip the historical roots and eth1 data votes, which come before the validators
+	if _, err := io.CopyN(io.Discard, r, int64(validatorsOffset-pos)); err != nil {
+		return 0, fmt.Errorf("couldn't read the beacon state: %w", err)
+	}
+
+	if reserve != nil {
+		reserve(count)
+	}
+
+	var buf [validatorSSZLength]byte
+	for i := 0; i < count; i++ {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return i, fmt.Errorf("couldn't read validator %d: %w", i, err)
+		}
+
+		validator := &phase0.Validator{}
+		if err := validator.UnmarshalSSZ(buf[:]); err != nil {
+			return i, fmt.Errorf("couldn't decode validator %d: %w", i, err)
+		}
+
+		if err := closure(phase0.ValidatorIndex(i), validator); err != nil {
+			return i, err
+		}
+	}
+
+	return count, nil
+}
+
+func (c *CachingConsensusLayer) forEachValidatorJSON(ctx context.Context, state string, closure ForEachValidatorClosure) (int, error) {
+	resp, err := c.get(ctx, "/eth/v1/beacon/states/"+state+"/validators", "application/json")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("beacon node returned %s for the validators in state %s", resp.Status, state)
+	}
+
+	return decodeValidatorsJSON(resp.Body, closure)
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %s, got %v", delim, token)
+	}
+	return nil
+}
+
+// decodeValidatorsJSON decodes a validators api response one validator at a time
+func decodeValidatorsJSON(r io.Reader, closure ForEachValidatorClosure) (int, error) {
+	return decodeDataList(r, "validators", func(dec *json.Decoder, i int) error {
+		validator := &apiv1.Validator{}
+		if err := dec.Decode(validator); err != nil {
+			return fmt.Errorf("couldn't decode validator %d: %w", i, err)
+		}
+
+		return closure(validator.Index, validator.Validator)
+	})
+}
+
+// decodeDataList decodes the data list of an api response one item at a time, calling decode to
+// decode each, so that the whole list is never held in memory at once
+func decodeDataList(r io.Reader, name string, decode func(dec *json.Decoder, i int) error) (int, error) {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return 0, fmt.Errorf("invalid %s response: %w", name, err)
+	}
+
+	count := 0
+	found := false
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return count, fmt.Errorf("invalid %s response: %w", name, err)
+		}
+
+		if key, _ := token.(string); key != "data" {
+			// execution_optimistic, finalized and the like
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return count, fmt.Errorf("invalid %s response: %w", name, err)
+			}
+			continue
+		}
+
+		found = true
+		if err := expectDelim(dec, '['); err != nil {
+			return count, fmt.Errorf("invalid %s response: %w", name, err)
+		}
+
+		for dec.More() {
+			if err := decode(dec, count); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if err := expectDelim(dec, ']'); err != nil {
+			return count, fmt.Errorf("invalid %s response: %w", name, err)
+		}
+	}
+
+	if !found {
+		return 0, fmt.Errorf("invalid %s response: no data", name)
+	}
+
+	return count, nil
+}
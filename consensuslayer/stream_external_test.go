@@ -0,0 +1,201 @@
+package consensuslayer_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/rescue-proxy/consensuslayer"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/test"
+	"github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/http"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"github.com/rs/zerolog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+)
+
+// These live outside the consensuslayer package, as the mocks in test import it
+
+func startMockBeacon(tb testing.TB, numValidators int) (*test.MockConsensusLayer, *url.URL) {
+	_, err := metrics.Init("cc_test_" + tb.Name())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(metrics.Deinit)
+
+	m := test.NewMockConsensusLayer(numValidators, tb.Name())
+	handler, err := m.BeaconHandler(tb.TempDir())
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	s := httptest.NewServer(handler)
+	tb.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return m, u
+}
+
+// Checks that every mock validator makes it into the registry when Init loads the validator set
+func testLoadValidatorSet(t *testing.T, forceJSON bool) {
+	m, u := startMockBeacon(t, 400)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	t.Cleanup(cancel)
+
+	cl := consensuslayer.NewCachingConsensusLayer(u, zaptest.NewLogger(t), forceJSON, nil)
+	if err := cl.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cl.Deinit)
+
+	pubkeys := make([]rptypes.ValidatorPubkey, 0, len(m.Indices))
+	for pubkey := range m.Indices {
+		pubkeys = append(pubkeys, pubkey)
+	}
+
+	// The mock bn can't look up validators by pubkey, so these must all come from the registry
+	got, err := cl.GetValidatorInfoByPubkey(pubkeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := m.GetValidatorInfoByPubkey(pubkeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("got %d validators, expected %d", len(got), len(expected))
+	}
+	for pubkey, e := range expected {
+		if g := got[pubkey]; g == nil || *g != *e {
+			t.Fatalf("got %+v for %s, expected %+v", g, pubkey.String(), e)
+		}
+	}
+}
+
+func TestLoadValidatorSetSSZ(t *testing.T) {
+	testLoadValidatorSet(t, false)
+}
+
+func TestLoadValidatorSetJSON(t *testing.T) {
+	testLoadValidatorSet(t, true)
+}
+
+// Samples the heap while f runs, and reports the most it grew by. Garbage is collected
+// aggressively meanwhile, so that the peak is close to what f needed live at once.
+// Sampling stops the world, so ns/op is only good for comparing benchmarks that use this.
+func reportPeakHeap(b *testing.B, f func()) {
+	defer debug.SetGCPercent(debug.SetGCPercent(5))
+
+	// Twice, so that pooled buffers from encoding the mock's responses are freed too
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	baseline := stats.HeapAlloc
+	peak := baseline
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > peak {
+				peak = stats.HeapAlloc
+			}
+
+			select {
+			case <-done:
+				return
+			default:
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	f()
+	close(done)
+	wg.Wait()
+
+	b.ReportMetric(float64(peak-baseline)/(1<<20), "peak-heap-MiB")
+}
+
+const benchmarkValidators = 50000
+
+// Loads the whole validator set as the api used to, decoding the full state and then picking
+// the fields it needs out of it
+func BenchmarkLoadValidatorSetFromState(b *testing.B) {
+	_, u := startMockBeacon(b, benchmarkValidators)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(cancel)
+
+	client, err := http.New(ctx,
+		http.WithAddress(u.String()),
+		http.WithLogLevel(zerolog.Disabled),
+		http.WithTimeout(time.Minute))
+	if err != nil {
+		b.Fatal(err)
+	}
+	service := client.(*http.Service)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	reportPeakHeap(b, func() {
+		for i := 0; i < b.N; i++ {
+			resp, err := service.Validators(ctx, &api.ValidatorsOpts{State: "head"})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			registry := make(map[rptypes.ValidatorPubkey]struct{}, len(resp.Data))
+			for _, v := range resp.Data {
+				registry[rptypes.ValidatorPubkey(v.Validator.PublicKey)] = struct{}{}
+			}
+		}
+	})
+}
+
+// Loads the whole validator set into the registry, streaming it from the bn
+func benchmarkLoadValidatorSet(b *testing.B, forceJSON bool) {
+	_, u := startMockBeacon(b, benchmarkValidators)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.Cleanup(cancel)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	reportPeakHeap(b, func() {
+		for i := 0; i < b.N; i++ {
+			cl := consensuslayer.NewCachingConsensusLayer(u, zap.NewNop(), forceJSON, nil)
+			if err := cl.Init(ctx); err != nil {
+				b.Fatal(err)
+			}
+			cl.Deinit()
+		}
+	})
+}
+
+func BenchmarkLoadValidatorSetSSZ(b *testing.B) {
+	benchmarkLoadValidatorSet(b, false)
+}
+
+func BenchmarkLoadValidatorSetJSON(b *testing.B) {
+	benchmarkLoadValidatorSet(b, true)
+}
@@ -0,0 +1,169 @@
+package consensuslayer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+func testState(validators int) *phase0.BeaconState {
+	out := &phase0.BeaconState{
+		Fork:              &phase0.Fork{},
+		LatestBlockHeader: &phase0.BeaconBlockHeader{},
+		BlockRoots:        make([]phase0.Root, 8192),
+		StateRoots:        make([]phase0.Root, 8192),
+		HistoricalRoots:   make([]phase0.Root, 2),
+		ETH1Data: &phase0.ETH1Data{
+			BlockHash: make([]byte, 32),
+		},
+		ETH1DataVotes: []*phase0.ETH1Data{{
+			BlockHash: make([]byte, 32),
+		}},
+		Validators:                  make([]*phase0.Validator, validators),
+		Balances:                    make([]phase0.Gwei, validators),
+		RANDAOMixes:                 make([]phase0.Root, 65536),
+		Slashings:                   make([]phase0.Gwei, 8192),
+		JustificationBits:           []byte{0},
+		PreviousJustifiedCheckpoint: &phase0.Checkpoint{},
+		CurrentJustifiedCheckpoint:  &phase0.Checkpoint{},
+		FinalizedCheckpoint:         &phase0.Checkpoint{},
+	}
+
+	for i := range out.Validators {
+		credentials := make([]byte, 32)
+		credentials[0] = 0x01
+		credentials[31] = byte(i)
+		out.Validators[i] = &phase0.Validator{
+			PublicKey:             phase0.BLSPubKey(testPubkey(byte(i))),
+			WithdrawalCredentials: credentials,
+			ExitEpoch:             phase0.Epoch(i),
+		}
+	}
+
+	return out
+}
+
+func TestDecodeValidatorsSSZ(t *testing.T) {
+	state := testState(5)
+	encoded, err := state.MarshalSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded []*phase0.Validator
+	count, err := decodeValidatorsSSZ(bytes.NewReader(encoded), 8192, nil, func(index phase0.ValidatorIndex, v *phase0.Validator) error {
+		if int(index) != len(decoded) {
+			t.Fatalf("got validator %d out of order", index)
+		}
+		decoded = append(decoded, v)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != len(state.Validators) || len(decoded) != len(state.Validators) {
+		t.Fatalf("decoded %d validators, expected %d", count, len(state.Validators))
+	}
+	for i, v := range decoded {
+		if v.String() != state.Validators[i].String() {
+			t.Fatalf("validator %d decoded as %s, expected %s", i, v.String(), state.Validators[i].String())
+		}
+	}
+
+	// Returning an error stops decoding
+	stop := errors.New("stop")
+	count, err = decodeValidatorsSSZ(bytes.NewReader(encoded), 8192, nil, func(index phase0.ValidatorIndex, v *phase0.Validator) error {
+		if index == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || count != 2 {
+		t.Fatalf("expected to stop at validator 2, got %d and %v", count, err)
+	}
+}
+
+func TestDecodeValidatorsSSZErrors(t *testing.T) {
+	encoded, err := testState(2).MarshalSSZ()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noop := func(phase0.ValidatorIndex, *phase0.Validator) error {
+		return nil
+	}
+
+	// Truncated inside the validator list
+	pos := validatorsOffsetPosition(8192)
+	validatorsOffset := binary.LittleEndian.Uint32(encoded[pos:])
+	_, err = decodeValidatorsSSZ(bytes.NewReader(encoded[:validatorsOffset+validatorSSZLength+10]), 8192, nil, noop)
+	if err == nil || !strings.Contains(err.Error(), "validator 1") {
+		t.Fatalf("expected an error reading validator 1, got %v", err)
+	}
+
+	// Truncated before the offsets
+	_, err = decodeValidatorsSSZ(bytes.NewReader(encoded[:pos]), 8192, nil, noop)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// A balance list offset that doesn't leave room for a whole number of validators
+	corrupt := bytes.Clone(encoded)
+	binary.LittleEndian.PutUint32(corrupt[pos+4:], validatorsOffset+validatorSSZLength+1)
+	_, err = decodeValidatorsSSZ(bytes.NewReader(corrupt), 8192, nil, noop)
+	if err == nil || !strings.Contains(err.Error(), "invalid validator list offsets") {
+		t.Fatalf("expected an offset error, got %v", err)
+	}
+
+	// The wrong preset puts the offsets somewhere else
+	_, err = decodeValidatorsSSZ(bytes.NewReader(encoded), 64, nil, noop)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestDecodeValidatorsJSON(t *testing.T) {
+	var decoded []phase0.ValidatorIndex
+	count, err := decodeValidatorsJSON(strings.NewReader(mockNewValidator), func(index phase0.ValidatorIndex, v *phase0.Validator) error {
+		decoded = append(decoded, index)
+		if !strings.EqualFold(v.PublicKey.String(), "0xb5bc96b70df0dfcc252c9ff0d1b42cb6dc0d55f8defa474dc0a5c7e0402c241e2850fea9c582e276b638b3c2c3a5ec55") {
+			t.Fatalf("unexpected pubkey %s", v.PublicKey.String())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || len(decoded) != 1 || decoded[0] != 1 {
+		t.Fatalf("unexpected validators %v", decoded)
+	}
+
+	noop := func(phase0.ValidatorIndex, *phase0.Validator) error {
+		return nil
+	}
+
+	// Keys other than data are skipped, wherever they are
+	count, err = decodeValidatorsJSON(strings.NewReader(`{"data":[],"finalized":{"nested":[1,2]}}`), noop)
+	if err != nil || count != 0 {
+		t.Fatalf("unexpected result %d, %v", count, err)
+	}
+
+	for _, body := range []string{
+		``,
+		`[]`,
+		`{"execution_optimistic":false}`,
+		`{"data":{}}`,
+		`{"data":[{"index":"x"}]}`,
+		`{"data":[`,
+		`Not json!`,
+	} {
+		if _, err := decodeValidatorsJSON(strings.NewReader(body), noop); err == nil {
+			t.Fatalf("expected an error decoding %q", body)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+package executionlayer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+)
+
+// CacheDump is a portable copy of everything in the cache
+type CacheDump struct {
+	HighestBlock uint64               `json:"highest_block"`
+	Nodes        []*CacheDumpNode     `json:"nodes"`
+	Minipools    []*CacheDumpMinipool `json:"minipools"`
+	OdaoNodes    []common.Address     `json:"odao_nodes"`
+}
+
+type CacheDumpNode struct {
+	Address         common.Address `json:"address"`
+	InSmoothingPool bool           `json:"in_smoothing_pool"`
+	FeeDistributor  common.Address `json:"fee_distributor"`
+	// Unix time at which the node last joined or left the smoothing pool, or 0 if unknown
+	SmoothingPoolChanged int64          `json:"smoothing_pool_changed"`
+	WithdrawalAddress    common.Address `json:"withdrawal_address"`
+	// The zero address unless the node set an RPL withdrawal address
+	RPLWithdrawalAddress common.Address `json:"rpl_withdrawal_address"`
+	// Unix times at which the node registered, and its minipools and odao membership last changed, or 0 if unknown
+	FeeDistributorChanged int64 `json:"fee_distributor_changed"`
+	MinipoolsChanged      int64 `json:"minipools_changed"`
+	OdaoChanged           int64 `json:"odao_changed"`
+}
+
+type CacheDumpMinipool struct {
+	Pubkey      rptypes.ValidatorPubkey `json:"pubkey"`
+	NodeAddress common.Address          `json:"node_address"`
+}
+
+func newCacheDumpNode(addr common.Address, n *nodeInfo) *CacheDumpNode {
+	return &CacheDumpNode{
+		Address:               addr,
+		InSmoothingPool:       n.inSmoothingPool,
+		FeeDistributor:        n.feeDistributor,
+		SmoothingPoolChanged:  unixTime(n.smoothingPoolChanged),
+		WithdrawalAddress:     n.withdrawalAddress,
+		RPLWithdrawalAddress:  n.rplWithdrawalAddress,
+		FeeDistributorChanged: unixTime(n.feeDistributorChanged),
+		MinipoolsChanged:      unixTime(n.minipoolsChanged),
+		OdaoChanged:           unixTime(n.odaoChanged),
+	}
+}
+
+func (n *CacheDumpNode) nodeInfo() *nodeInfo {
+	return &nodeInfo{
+		inSmoothingPool:       n.InSmoothingPool,
+		feeDistributor:        n.FeeDistributor,
+		smoothingPoolChanged:  fromUnixTime(n.SmoothingPoolChanged),
+		withdrawalAddress:     n.WithdrawalAddress,
+		rplWithdrawalAddress:  n.RPLWithdrawalAddress,
+		feeDistributorChanged: fromUnixTime(n.FeeDistributorChanged),
+		minipoolsChanged:      fromUnixTime(n.MinipoolsChanged),
+		odaoChanged:           fromUnixTime(n.OdaoChanged),
+	}
+}
+
+var cacheDumpCSVHeader = []string{"kind", "key", "node_address", "in_smoothing_pool", "fee_distributor", "smoothing_pool_changed",
+	"withdrawal_address", "rpl_withdrawal_address", "fee_distributor_changed", "minipools_changed", "odao_changed"}
+
+// Dumps written before the other change times were kept lack the last three columns
+var unchangedCacheDumpCSVHeader = cacheDumpCSVHeader[:8]
+
+// Dumps written before withdrawal addresses were kept lack the two before those, too
+var legacyCacheDumpCSVHeader = cacheDumpCSVHeader[:6]
+
+// WriteCSV writes the dump as one row per entry. The kind column says what each row holds:
+// the highest block, a node, a minipool or an odao node, which is identified by the key column.
+func (d *CacheDump) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	records := [][]string{
+		cacheDumpCSVHeader,
+		{"highest_block", strconv.FormatUint(d.HighestBlock, 10), "", "", "", "", "", "", "", "", ""},
+	}
+	for _, n := range d.Nodes {
+		rplWithdrawalAddress := ""
+		if n.RPLWithdrawalAddress != (common.Address{}) {
+			rplWithdrawalAddress = n.RPLWithdrawalAddress.String()
+		}
+
+		records = append(records, []string{
+			"node",
+			n.Address.String(),
+			"",
+			strconv.FormatBool(n.InSmoothingPool),
+			n.FeeDistributor.String(),
+			strconv.FormatInt(n.SmoothingPoolChanged, 10),
+			n.WithdrawalAddress.String(),
+			rplWithdrawalAddress,
+			strconv.FormatInt(n.FeeDistributorChanged, 10),
+			strconv.FormatInt(n.MinipoolsChanged, 10),
+			strconv.FormatInt(n.OdaoChanged, 10),
+		})
+	}
+	for _, m := range d.Minipools {
+		records = append(records, []string{"minipool", m.Pubkey.String(), m.NodeAddress.String(), "", "", "", "", "", "", "", ""})
+	}
+	for _, addr := range d.OdaoNodes {
+		records = append(records, []string{"odao_node", addr.String(), "", "", "", "", "", "", "", "", ""})
+	}
+
+	return cw.WriteAll(records)
+}
+
+func parseCSVAddress(value string) (common.Address, error) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("invalid address %q", value)
+	}
+
+	return common.HexToAddress(value), nil
+}
+
+// ReadCacheDumpCSV parses a dump written by WriteCSV
+func ReadCacheDumpCSV(r io.Reader) (*CacheDump, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 || (strings.Join(records[0], ",") != strings.Join(cacheDumpCSVHeader, ",") &&
+		strings.Join(records[0], ",") != strings.Join(unchangedCacheDumpCSVHeader, ",") &&
+		strings.Join(records[0], ",") != strings.Join(legacyCacheDumpCSVHeader, ",")) {
+		return nil, fmt.Errorf("missing or unexpected csv header, expected %s", strings.Join(cacheDumpCSVHeader, ","))
+	}
+	// The reader makes sure every record has as many columns as the header
+	legacy := len(records[0]) == len(legacyCacheDumpCSVHeader)
+	unchanged := len(records[0]) < len(cacheDumpCSVHeader)
+
+	out := &CacheDump{
+		Nodes:     make([]*CacheDumpNode, 0),
+		Minipools: make([]*CacheDumpMinipool, 0),
+		OdaoNodes: make([]common.Address, 0),
+	}
+	for i, record := range records[1:] {
+		line := i + 2
+
+		switch record[0] {
+		case "highest_block":
+			out.HighestBlock, err = strconv.ParseUint(record[1], 10, 64)
+		case "node":
+			n := &CacheDumpNode{}
+			n.Address, err = parseCSVAddress(record[1])
+			if err == nil {
+				n.InSmoothingPool, err = strconv.ParseBool(record[3])
+			}
+			if err == nil {
+				n.FeeDistributor, err = parseCSVAddress(record[4])
+			}
+			if err == nil {
+				n.SmoothingPoolChanged, err = strconv.ParseInt(record[5], 10, 64)
+			}
+			if err == nil && !legacy {
+				n.WithdrawalAddress, err = parseCSVAddress(record[6])
+			}
+			if err == nil && !legacy && record[7] != "" {
+				n.RPLWithdrawalAddress, err = parseCSVAddress(record[7])
+			}
+			for i, changed := range []*int64{&n.FeeDistributorChanged, &n.MinipoolsChanged, &n.OdaoChanged} {
+				if err == nil && !unchanged {
+					*changed, err = strconv.ParseInt(record[8+i], 10, 64)
+				}
+			}
+			out.Nodes = append(out.Nodes, n)
+		case "minipool":
+			m := &CacheDumpMinipool{}
+			m.Pubkey, err = rptypes.HexToValidatorPubkey(strings.TrimPrefix(record[1], "0x"))
+			if err == nil {
+				m.NodeAddress, err = parseCSVAddress(record[2])
+			}
+			out.Minipools = append(out.Minipools, m)
+		case "odao_node":
+			var addr common.Address
+			addr, err = parseCSVAddress(record[1])
+			out.OdaoNodes = append(out.OdaoNodes, addr)
+		default:
+			err = fmt.Errorf("unknown kind %q", record[0])
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	return out, nil
+}
+
+// CacheSnapshot gives offline, read-only access to the sqlite cache snapshot in a -cache-path directory
+type CacheSnapshot struct {
+	cache *SqliteCache
+}
+
+// OpenCacheSnapshot loads the snapshot in the given directory. It must not be in use by a running proxy.
+func OpenCacheSnapshot(path string) (*CacheSnapshot, error) {
+	if _, err := os.Stat(path + "/" + snapshotFileName); err != nil {
+		return nil, err
+	}
+
+	cache := &SqliteCache{Path: path}
+	if err := cache.init(); err != nil {
+		return nil, err
+	}
+
+	return &CacheSnapshot{cache}, nil
+}
+
+// Close releases the snapshot, leaving it unchanged on disk
+func (c *CacheSnapshot) Close() {
+	c.cache.close()
+}
+
+// Dump copies every entry in the snapshot
+func (c *CacheSnapshot) Dump() (*CacheDump, error) {
+	out := &CacheDump{
+		HighestBlock: c.cache.getHighestBlock().Uint64(),
+		Nodes:        make([]*CacheDumpNode, 0),
+		Minipools:    make([]*CacheDumpMinipool, 0),
+		OdaoNodes:    make([]common.Address, 0),
+	}
+
+	addrs := make([]common.Address, 0)
+	err := c.cache.forEachNode(func(addr common.Address) bool {
+		addrs = append(addrs, addr)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+		n, err := c.cache.getNodeInfo(addr)
+		if err != nil {
+			return nil, err
+		}
+		out.Nodes = append(out.Nodes, newCacheDumpNode(addr, n))
+	}
+
+	err = c.cache.forEachMinipool(func(pubkey rptypes.ValidatorPubkey, addr common.Address) bool {
+		out.Minipools = append(out.Minipools, &CacheDumpMinipool{Pubkey: pubkey, NodeAddress: addr})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.cache.forEachOdaoNode(func(addr common.Address) bool {
+		out.OdaoNodes = append(out.OdaoNodes, addr)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetRPInfo looks up a validator as CachingExecutionLayer.GetRPInfo would, given the smoothing pool
+// address and grace period it would use. Returns nil if the validator isn't a minipool.
+func (c *CacheSnapshot) GetRPInfo(pubkey rptypes.ValidatorPubkey, smoothingPool common.Address, graceEpochs uint64) (*RPInfo, error) {
+	nodeAddr, err := c.cache.getMinipoolNode(pubkey)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	n, err := c.cache.getNodeInfo(nodeAddr)
+	if err != nil {
+		return nil, fmt.Errorf("node %s of minipool %s: %w", nodeAddr.String(), pubkey.String(), err)
+	}
+
+	return newRPInfo(nodeAddr, n, smoothingPool, graceEpochs, time.Now()), nil
+}
+
+// GetNode looks up a node, returning nil if it isn't in the snapshot
+func (c *CacheSnapshot) GetNode(addr common.Address) (*CacheDumpNode, error) {
+	n, err := c.cache.getNodeInfo(addr)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return newCacheDumpNode(addr, n), nil
+}
+
+// GetNodeMinipools lists the pubkeys of a node's minipools
+func (c *CacheSnapshot) GetNodeMinipools(addr common.Address) ([]rptypes.ValidatorPubkey, error) {
+	return c.cache.getNodeMinipools(addr)
+}
+
+// IsOdaoNode checks whether a node is an odao member
+func (c *CacheSnapshot) IsOdaoNode(addr common.Address) (bool, error) {
+	return c.cache.isOdaoNode(addr)
+}
+
+// ImportCacheSnapshot writes a snapshot of the dump into the given directory, which a proxy started
+// with it as -cache-path will load. An existing snapshot is only replaced if overwrite is set.
+func ImportCacheSnapshot(path string, dump *CacheDump, overwrite bool) error {
+	snapshotPath := path + "/" + snapshotFileName
+	if _, err := os.Stat(snapshotPath); err == nil {
+		if !overwrite {
+			return fmt.Errorf("a snapshot already exists at %s", snapshotPath)
+		}
+
+		if err := os.Remove(snapshotPath); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	cache := &SqliteCache{Path: path}
+	if err := cache.init(); err != nil {
+		return err
+	}
+
+	err := importCacheDump(cache, dump)
+	if err != nil {
+		cache.close()
+		return err
+	}
+
+	return cache.deinit()
+}
+
+func importCacheDump(cache Cache, dump *CacheDump) error {
+	for _, n := range dump.Nodes {
+		if err := cache.addNodeInfo(n.Address, n.nodeInfo()); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range dump.Minipools {
+		if err := cache.addMinipoolNode(m.Pubkey, m.NodeAddress); err != nil {
+			return err
+		}
+	}
+
+	for _, addr := range dump.OdaoNodes {
+		if err := cache.addOdaoNode(addr); err != nil {
+			return err
+		}
+	}
+
+	// History begins with the dumped state
+	if hc, ok := cache.(historyCache); ok {
+		entries := make([]*historyEntry, 0, len(dump.Nodes)+len(dump.Minipools))
+		for _, n := range dump.Nodes {
+			entries = append(entries, nodeHistoryEntry(dump.HighestBlock, n.Address, n.nodeInfo()))
+		}
+		for _, m := range dump.Minipools {
+			entries = append(entries, minipoolHistoryEntry(dump.HighestBlock, m.Pubkey, m.NodeAddress))
+		}
+
+		if err := hc.addHistory(entries); err != nil {
+			return err
+		}
+	}
+
+	cache.setHighestBlock(big.NewInt(0).SetUint64(dump.HighestBlock))
+	return nil
+}
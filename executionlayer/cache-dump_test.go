@@ -0,0 +1,237 @@
+package executionlayer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+)
+
+// Each cache registers its own metrics, so they must be reset before opening another
+func resetMetrics(t *testing.T) {
+	metrics.Deinit()
+	if _, err := metrics.Init("cc_test_" + t.Name()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLELCacheDumpImport(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:                 common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:                 true,
+				minipools:            2,
+				withdrawalAddress:    common.HexToAddress("0x0a010a"),
+				rplWithdrawalAddress: common.HexToAddress("0x0a020a"),
+			},
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543211"),
+				inSP:      false,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{
+			&mockNode{
+				addr: common.HexToAddress("0x0000000000000000000001234567899876543212"),
+			},
+		},
+	}
+
+	// Warm up a cache and write its snapshot
+	et := setup(t, hec)
+	et.ec.CachePath = t.TempDir()
+	errs := startEL(t, et)
+
+	pubkeyBytes, err := hex.DecodeString(pubkeyFromMinipool(common.HexToAddress(addrToMinipool(1, hec.nodes[0].addr))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey := rptypes.BytesToValidatorPubkey(pubkeyBytes)
+	expected, err := et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected == nil {
+		t.Fatal("expected minipool to be cached")
+	}
+
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	// Dump it
+	resetMetrics(t)
+	snapshot, err := OpenCacheSnapshot(et.ec.CachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump, err := snapshot.Dump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.HighestBlock != 0x11af2c8 {
+		t.Fatalf("expected the dump to be at block %d, got %d", 0x11af2c8, dump.HighestBlock)
+	}
+	if len(dump.Nodes) != 2 || len(dump.Minipools) != 3 || len(dump.OdaoNodes) != 1 {
+		t.Fatalf("expected 2 nodes, 3 minipools and 1 odao node, got %d, %d and %d",
+			len(dump.Nodes), len(dump.Minipools), len(dump.OdaoNodes))
+	}
+
+	// Lookups agree with the execution layer
+	rpInfo, err := snapshot.GetRPInfo(pubkey, common.HexToAddress(rocketSmoothingPool), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rpInfo, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, rpInfo)
+	}
+
+	node, err := snapshot.GetNode(hec.nodes[0].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node == nil || !node.InSmoothingPool {
+		t.Fatalf("expected node in the smoothing pool, got %+v", node)
+	}
+	if node.WithdrawalAddress != hec.nodes[0].withdrawalAddress || node.RPLWithdrawalAddress != hec.nodes[0].rplWithdrawalAddress {
+		t.Fatalf("expected the node's withdrawal addresses, got %+v", node)
+	}
+	minipools, err := snapshot.GetNodeMinipools(hec.nodes[0].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(minipools) != 2 {
+		t.Fatalf("expected 2 minipools, got %v", minipools)
+	}
+	isOdao, err := snapshot.IsOdaoNode(hec.daoNodes[0].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isOdao {
+		t.Fatal("expected odao node")
+	}
+
+	missing, err := snapshot.GetRPInfo(rptypes.BytesToValidatorPubkey(bytes.Repeat([]byte{0xaa}, 48)), common.Address{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != nil {
+		t.Fatalf("expected no rp info for an unknown validator, got %+v", missing)
+	}
+	snapshot.Close()
+
+	// Both formats round trip
+	var csvOut bytes.Buffer
+	if err := dump.WriteCSV(&csvOut); err != nil {
+		t.Fatal(err)
+	}
+	fromCSV, err := ReadCacheDumpCSV(&csvOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromCSV, dump) {
+		t.Fatalf("csv round trip changed the dump, got %+v", fromCSV)
+	}
+
+	jsonOut, err := json.Marshal(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON := new(CacheDump)
+	if err := json.Unmarshal(jsonOut, fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON, dump) {
+		t.Fatalf("json round trip changed the dump, got %+v", fromJSON)
+	}
+
+	// Import it somewhere else, which refuses to replace a snapshot unless asked to
+	importPath := t.TempDir()
+	resetMetrics(t)
+	if err := ImportCacheSnapshot(importPath, fromCSV, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportCacheSnapshot(importPath, fromCSV, false); err == nil {
+		t.Fatal("expected an error importing over an existing snapshot")
+	}
+	resetMetrics(t)
+	if err := ImportCacheSnapshot(importPath, fromJSON, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// A proxy started from the imported snapshot is warm
+	metrics.Deinit()
+	et = setup(t, hec)
+	et.ec.CachePath = importPath
+	errs = startEL(t, et)
+
+	if et.ec.cache.getHighestBlock().Uint64() != dump.HighestBlock {
+		t.Fatalf("expected the imported snapshot to be at block %d, got %d", dump.HighestBlock, et.ec.cache.getHighestBlock().Uint64())
+	}
+	rpInfo, err = et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rpInfo, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, rpInfo)
+	}
+
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadCacheDumpCSVLegacy(t *testing.T) {
+	// Dumps from before withdrawal addresses were kept
+	dump, err := ReadCacheDumpCSV(strings.NewReader("kind,key,node_address,in_smoothing_pool,fee_distributor,smoothing_pool_changed\n" +
+		"node,0x0000000000000000000000000000000000000001,,true,0x0000000000000000000000000000000000000002,0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Nodes) != 1 || !dump.Nodes[0].InSmoothingPool || dump.Nodes[0].WithdrawalAddress != (common.Address{}) {
+		t.Fatalf("unexpected nodes %+v", dump.Nodes)
+	}
+
+	// and from before the other change times were kept
+	dump, err = ReadCacheDumpCSV(strings.NewReader("kind,key,node_address,in_smoothing_pool,fee_distributor,smoothing_pool_changed," +
+		"withdrawal_address,rpl_withdrawal_address\n" +
+		"node,0x0000000000000000000000000000000000000001,,true,0x0000000000000000000000000000000000000002,0," +
+		"0x0000000000000000000000000000000000000003,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Nodes) != 1 || dump.Nodes[0].WithdrawalAddress != common.HexToAddress("0x03") || dump.Nodes[0].MinipoolsChanged != 0 {
+		t.Fatalf("unexpected nodes %+v", dump.Nodes)
+	}
+}
+
+func TestReadCacheDumpCSVErrors(t *testing.T) {
+	header := "kind,key,node_address,in_smoothing_pool,fee_distributor,smoothing_pool_changed\n"
+	withdrawalHeader := "kind,key,node_address,in_smoothing_pool,fee_distributor,smoothing_pool_changed,withdrawal_address,rpl_withdrawal_address\n"
+	changedHeader := strings.Join(cacheDumpCSVHeader, ",") + "\n"
+	for _, input := range []string{
+		"",
+		"kind,key\nnode,0x01\n",
+		header + "node,0x1234,,true,0x0000000000000000000000000000000000000000,0\n",
+		header + "node,0x0000000000000000000000000000000000000001,,maybe,0x0000000000000000000000000000000000000000,0\n",
+		header + "minipool,f0f0,0x0000000000000000000000000000000000000001,,,\n",
+		header + "highest_block,-1,,,,\n",
+		header + "validator,0x0000000000000000000000000000000000000001,,,,\n",
+		withdrawalHeader + "node,0x0000000000000000000000000000000000000001,,true,0x0000000000000000000000000000000000000000,0,0x1234,\n",
+		withdrawalHeader + "node,0x0000000000000000000000000000000000000001,,true,0x0000000000000000000000000000000000000000,0,0x0000000000000000000000000000000000000001,maybe\n",
+		changedHeader + "node,0x0000000000000000000000000000000000000001,,true,0x0000000000000000000000000000000000000000,0,0x0000000000000000000000000000000000000001,,0,soon,0\n",
+	} {
+		if _, err := ReadCacheDumpCSV(strings.NewReader(input)); err == nil {
+			t.Fatalf("expected an error parsing %q", input)
+		}
+	}
+}
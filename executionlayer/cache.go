@@ -2,6 +2,7 @@ package executionlayer
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	rptypes "github.com/rocket-pool/rocketpool-go/types"
@@ -13,18 +14,78 @@ func (e *NotFoundError) Error() string {
 	return "Key not found in cache"
 }
 
+// Caches store times as unix seconds, with 0 for a time that isn't known
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+func fromUnixTime(seconds int64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(seconds, 0)
+}
+
+type ForEachMinipoolClosure func(rptypes.ValidatorPubkey, common.Address) bool
+
+// processedEvent records which cache entry an event touched, and the block it was in,
+// so that the entry can be recomputed if that block is reorganized out of the chain.
+type processedEvent struct {
+	blockNumber uint64
+	blockHash   common.Hash
+	// The log's position in its block, or unknownLogIndex for events journaled before it was recorded
+	logIndex uint
+	topic    common.Hash
+
+	// The node or odao member the event pertains to
+	address common.Address
+	// The pubkey of the minipool, for minipool launches
+	pubkey rptypes.ValidatorPubkey
+}
+
+// The log index of processed events journaled before log indices were recorded
+const unknownLogIndex = ^uint(0)
+
 type Cache interface {
 	init() error
 	getMinipoolNode(rptypes.ValidatorPubkey) (common.Address, error)
 	addMinipoolNode(rptypes.ValidatorPubkey, common.Address) error
+	removeMinipoolNode(rptypes.ValidatorPubkey) error
+	forEachMinipool(ForEachMinipoolClosure) error
+	// The pubkeys of the minipools belonging to the given node
+	getNodeMinipools(common.Address) ([]rptypes.ValidatorPubkey, error)
 	getNodeInfo(common.Address) (*nodeInfo, error)
 	addNodeInfo(common.Address, *nodeInfo) error
+	removeNodeInfo(common.Address) error
 	forEachNode(ForEachNodeClosure) error
+	// The nodes whose withdrawal address or RPL withdrawal address is the given address
+	getWithdrawalAddressNodes(common.Address) ([]common.Address, error)
 	addOdaoNode(common.Address) error
 	removeOdaoNode(common.Address) error
 	forEachOdaoNode(ForEachNodeClosure) error
+	isOdaoNode(common.Address) (bool, error)
 	setHighestBlock(*big.Int)
 	getHighestBlock() *big.Int
+
+	// The journal of recently processed events, in the order they were added
+	addProcessedEvent(*processedEvent) error
+	getProcessedEvents(fromBlock uint64) ([]*processedEvent, error)
+	removeProcessedEvents(fromBlock uint64) error
+	pruneProcessedEvents(beforeBlock uint64) error
+
 	deinit() error
 	reset() error
 }
+
+// snapshotCache is a cache that saves itself to disk in the background. It saves the block it's told
+// every log up to has been applied, rather than the highest block, so a crash doesn't skip any.
+type snapshotCache interface {
+	Cache
+
+	setAppliedBlock(*big.Int)
+}
@@ -0,0 +1,145 @@
+package executionlayer
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"golang.org/x/sync/errgroup"
+)
+
+// EIP1271Request is a signature for ValidateEIP1271Batch to validate
+type EIP1271Request struct {
+	DataHash  common.Hash
+	Signature []byte
+	Address   common.Address
+}
+
+// EIP1271Result is the outcome of validating an EIP1271Request
+type EIP1271Result struct {
+	Valid bool
+	Err   error
+}
+
+type eip1271CacheKey struct {
+	dataHash  common.Hash
+	signature string
+	address   common.Address
+	block     uint64
+}
+
+type eip1271CacheEntry struct {
+	valid   bool
+	expires time.Time
+}
+
+// eip1271Cache remembers whether signatures were valid as of a block, for a while
+type eip1271Cache struct {
+	sync.Mutex
+	ttl       time.Duration
+	entries   map[eip1271CacheKey]eip1271CacheEntry
+	lastSweep time.Time
+}
+
+func newEIP1271Cache(ttl time.Duration) *eip1271Cache {
+	return &eip1271Cache{
+		ttl:     ttl,
+		entries: make(map[eip1271CacheKey]eip1271CacheEntry),
+	}
+}
+
+func (c *eip1271Cache) get(key eip1271CacheKey, now time.Time) (bool, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		return false, false
+	}
+
+	return entry.valid, true
+}
+
+func (c *eip1271Cache) add(key eip1271CacheKey, valid bool, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	// Entries for past blocks are never looked up again, so drop expired ones once per ttl
+	if now.Sub(c.lastSweep) > c.ttl {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	c.entries[key] = eip1271CacheEntry{valid: valid, expires: now.Add(c.ttl)}
+}
+
+// Validates an EIP-1271 signature as of the highest processed block, consulting the cache first
+func (e *CachingExecutionLayer) validateEIP1271(ctx context.Context, request EIP1271Request) (bool, error) {
+	// Pin the call to the block the cache is current as of, so cached results match what a fresh call would return
+	var block *big.Int
+	var key eip1271CacheKey
+	if highest := e.cache.getHighestBlock(); highest != nil && highest.Sign() > 0 {
+		block = new(big.Int).Set(highest)
+		key = eip1271CacheKey{
+			dataHash:  request.DataHash,
+			signature: string(request.Signature),
+			address:   request.Address,
+			block:     highest.Uint64(),
+		}
+	}
+
+	if e.eip1271Cache != nil && block != nil {
+		if valid, ok := e.eip1271Cache.get(key, time.Now()); ok {
+			e.m.Counter("eip1271_cache_hit").Inc()
+			return valid, nil
+		}
+		e.m.Counter("eip1271_cache_miss").Inc()
+	}
+
+	valid, err := e.isValidSignature(ctx, request.DataHash, request.Signature, request.Address, block)
+	if err != nil {
+		return false, err
+	}
+
+	if e.eip1271Cache != nil && block != nil {
+		e.eip1271Cache.add(key, valid, time.Now())
+	}
+
+	return valid, nil
+}
+
+// ValidateEIP1271Batch validates many EIP-1271 signatures concurrently, returning a result for each request, in order.
+// Every batch shares EIP1271Concurrency slots, so concurrent batches can't multiply the load on the execution client.
+func (e *CachingExecutionLayer) ValidateEIP1271Batch(ctx context.Context, requests []EIP1271Request) []EIP1271Result {
+	results := make([]EIP1271Result, len(requests))
+
+	var wg errgroup.Group
+	for i, request := range requests {
+		i, request := i, request
+		wg.Go(func() error {
+			if e.eip1271Slots != nil {
+				select {
+				case e.eip1271Slots <- struct{}{}:
+					defer func() { <-e.eip1271Slots }()
+				case <-ctx.Done():
+					results[i] = EIP1271Result{Err: ctx.Err()}
+					return nil
+				}
+			}
+
+			valid, err := e.validateEIP1271(ctx, request)
+			results[i] = EIP1271Result{Valid: valid, Err: err}
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	e.m.Counter("eip1271_batch_signatures").Add(float64(len(requests)))
+	return results
+}
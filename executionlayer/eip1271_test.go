@@ -0,0 +1,167 @@
+package executionlayer
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// eip1271CountingEC serves the same chain as happyEC, counting the isValidSignature calls it serves
+type eip1271CountingEC struct {
+	*happyEC
+	calls atomic.Int32
+}
+
+func (e *eip1271CountingEC) Serve(mt int, data []byte) (int, []byte) {
+	m := jsonrpcMessage{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		e.t.Fatal(err)
+	}
+
+	if m.Method == "eth_call" {
+		var params []json.RawMessage
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			e.t.Fatal(err)
+		}
+
+		var callMsg call
+		if err := json.Unmarshal(params[0], &callMsg); err != nil {
+			e.t.Fatal(err)
+		}
+
+		switch callMsg.To {
+		case common.HexToAddress(eip1271SmartContractValidSignerAddress), common.HexToAddress(eip1271SmartContractInvalidSignerAddress):
+			e.calls.Add(1)
+		}
+	}
+
+	return e.happyEC.Serve(mt, data)
+}
+
+func TestValidateEIP1271Batch(t *testing.T) {
+	cec := &eip1271CountingEC{happyEC: &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}}
+	et := setup(t, cec)
+	et.ec.EIP1271CacheTTL = time.Minute
+	et.ec.EIP1271Concurrency = 2
+	errs := startEL(t, et)
+
+	valid := EIP1271Request{
+		Signature: common.FromHex(eip1271ValidSignature),
+		Address:   common.HexToAddress(eip1271SmartContractValidSignerAddress),
+	}
+	invalid := EIP1271Request{
+		Signature: common.FromHex(eip1271InvalidSignature),
+		Address:   common.HexToAddress(eip1271SmartContractValidSignerAddress),
+	}
+	broken := EIP1271Request{
+		Signature: common.FromHex(eip1271ValidSignature),
+		Address:   common.HexToAddress(eip1271SmartContractInvalidSignerAddress),
+	}
+	requests := []EIP1271Request{valid, invalid, broken, valid, invalid}
+
+	check := func(requests []EIP1271Request) {
+		t.Helper()
+
+		results := et.ec.ValidateEIP1271Batch(context.Background(), requests)
+		if len(results) != len(requests) {
+			t.Fatalf("expected %d results, got %d", len(requests), len(results))
+		}
+		for i, expected := range []bool{true, false, false, true, false}[:len(requests)] {
+			if results[i].Valid != expected {
+				t.Fatalf("expected result %d to be %v, got %v", i, expected, results[i].Valid)
+			}
+		}
+		if results[2].Err == nil {
+			t.Fatal("expected an error from a contract returning bad data")
+		}
+	}
+
+	check(requests)
+	// Duplicates in the same batch may race each other to the execution client
+	first := cec.calls.Load()
+	if first < 3 || first > 5 {
+		t.Fatalf("expected 3 to 5 calls, got %d", first)
+	}
+
+	// Results are cached, except for errors
+	check(requests)
+	if calls := cec.calls.Load() - first; calls != 1 {
+		t.Fatalf("expected only the failed call to be repeated, got %d calls", calls)
+	}
+
+	// ValidateEIP1271 checks at the latest block, so it doesn't use the cache
+	ok, err := et.ec.ValidateEIP1271(context.Background(), valid.DataHash, valid.Signature, valid.Address)
+	if err != nil || !ok {
+		t.Fatalf("expected a valid signature, got %v %v", ok, err)
+	}
+	if calls := cec.calls.Load() - first; calls != 2 {
+		t.Fatalf("expected ValidateEIP1271 to call the execution client, got %d calls", calls)
+	}
+
+	// Results are cached per block
+	et.ec.cache.setHighestBlock(big.NewInt(historyHead + 1))
+	check(requests[:3])
+	if calls := cec.calls.Load() - first; calls != 5 {
+		t.Fatalf("expected a new block to miss the cache, got %d calls", calls)
+	}
+
+	// Batches share the concurrency limit, so they wait while other batches hold every slot
+	for i := 0; i < et.ec.EIP1271Concurrency; i++ {
+		et.ec.eip1271Slots <- struct{}{}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	results := et.ec.ValidateEIP1271Batch(ctx, requests[:1])
+	if results[0].Err != context.DeadlineExceeded {
+		t.Fatalf("expected the batch to wait for a slot, got %+v", results[0])
+	}
+	for i := 0; i < et.ec.EIP1271Concurrency; i++ {
+		<-et.ec.eip1271Slots
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEIP1271CacheExpiry(t *testing.T) {
+	c := newEIP1271Cache(time.Minute)
+	now := time.Now()
+
+	key := eip1271CacheKey{address: common.HexToAddress("0x0f010f"), block: historyHead}
+	stale := eip1271CacheKey{address: common.HexToAddress("0x1f101f"), block: historyHead - 10}
+	c.add(stale, true, now)
+	c.add(key, true, now.Add(30*time.Second))
+
+	if valid, ok := c.get(key, now.Add(time.Minute)); !ok || !valid {
+		t.Fatal("expected an entry to be cached within its ttl")
+	}
+	if _, ok := c.get(key, now.Add(2*time.Minute)); ok {
+		t.Fatal("expected an entry to expire after its ttl")
+	}
+
+	// Adding after a ttl has passed drops expired entries
+	c.add(key, false, now.Add(90*time.Second))
+	if _, ok := c.entries[stale]; ok {
+		t.Fatal("expected the expired entry to be swept")
+	}
+	if valid, ok := c.get(key, now.Add(90*time.Second)); !ok || valid {
+		t.Fatal("expected the entry to be replaced")
+	}
+}
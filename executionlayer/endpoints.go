@@ -0,0 +1,128 @@
+package executionlayer
+
+import (
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Each consecutive failure of an endpoint adds this much time before it is retried
+const endpointBackoff = 5 * time.Second
+
+// Upper bound on how long a failed endpoint is skipped for
+const maxEndpointBackoff = 1 * time.Minute
+
+// ecEndpoint is a single execution client and its health
+type ecEndpoint struct {
+	url *url.URL
+
+	// Consecutive failures since the endpoint was last known to be good
+	failures int
+	// The endpoint should not be retried before this time
+	retryAt time.Time
+}
+
+func (e *ecEndpoint) String() string {
+	// Execution client urls often carry api keys, so keep them out of the logs
+	return e.url.Redacted()
+}
+
+// ecEndpoints tracks the health of a list of execution clients, in order of preference,
+// so that subscription failures can rotate to a different one.
+type ecEndpoints struct {
+	sync.Mutex
+
+	endpoints []*ecEndpoint
+}
+
+func newECEndpoints(urls []*url.URL) *ecEndpoints {
+	out := &ecEndpoints{
+		endpoints: make([]*ecEndpoint, 0, len(urls)),
+	}
+
+	for _, u := range urls {
+		out.endpoints = append(out.endpoints, &ecEndpoint{url: u})
+	}
+
+	return out
+}
+
+// next returns the most preferred endpoint that hasn't failed.
+// If every endpoint has failed, the one that may be retried soonest is returned.
+func (e *ecEndpoints) next() *ecEndpoint {
+	e.Lock()
+	defer e.Unlock()
+
+	var soonest *ecEndpoint
+	for _, endpoint := range e.endpoints {
+		if endpoint.failures == 0 {
+			return endpoint
+		}
+
+		if soonest == nil || endpoint.retryAt.Before(soonest.retryAt) {
+			soonest = endpoint
+		}
+	}
+
+	return soonest
+}
+
+// markFailed records a failure and backs the endpoint off
+func (e *ecEndpoints) markFailed(endpoint *ecEndpoint) {
+	e.Lock()
+	defer e.Unlock()
+
+	// The first failure may be retried immediately, and each subsequent one waits a little longer
+	backoff := time.Duration(endpoint.failures) * endpointBackoff
+	if backoff > maxEndpointBackoff {
+		backoff = maxEndpointBackoff
+	}
+
+	endpoint.failures++
+	endpoint.retryAt = time.Now().Add(backoff)
+}
+
+// markHealthy clears any failures recorded against the endpoint
+func (e *ecEndpoints) markHealthy(endpoint *ecEndpoint) {
+	e.Lock()
+	defer e.Unlock()
+
+	endpoint.failures = 0
+	endpoint.retryAt = time.Time{}
+}
+
+// preferredTo returns the endpoints more preferred than the given one which may be retried now,
+// in order of preference
+func (e *ecEndpoints) preferredTo(endpoint *ecEndpoint) []*ecEndpoint {
+	e.Lock()
+	defer e.Unlock()
+
+	var out []*ecEndpoint
+	now := time.Now()
+	for _, candidate := range e.endpoints {
+		if candidate == endpoint {
+			break
+		}
+
+		if !candidate.retryAt.After(now) {
+			out = append(out, candidate)
+		}
+	}
+
+	return out
+}
+
+// healthy returns the number of endpoints that haven't failed
+func (e *ecEndpoints) healthy() int {
+	e.Lock()
+	defer e.Unlock()
+
+	out := 0
+	for _, endpoint := range e.endpoints {
+		if endpoint.failures == 0 {
+			out++
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,76 @@
+package executionlayer
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestECEndpoints(t *testing.T) {
+	primary, _ := url.Parse("ws://primary:8546")
+	backup, _ := url.Parse("ws://user:password@backup:8546")
+
+	e := newECEndpoints([]*url.URL{primary, backup})
+
+	if e.next() != e.endpoints[0] {
+		t.Fatal("expected the primary endpoint to be preferred")
+	}
+
+	if e.healthy() != 2 {
+		t.Fatalf("expected 2 healthy endpoints, got %d", e.healthy())
+	}
+
+	// Fail the primary, and the backup should be picked
+	e.markFailed(e.endpoints[0])
+	if e.next() != e.endpoints[1] {
+		t.Fatal("expected the backup endpoint after the primary failed")
+	}
+
+	if e.healthy() != 1 {
+		t.Fatalf("expected 1 healthy endpoint, got %d", e.healthy())
+	}
+
+	// Fail the backup twice. The primary may be retried sooner, so it should be picked
+	e.markFailed(e.endpoints[1])
+	e.markFailed(e.endpoints[1])
+	if e.next() != e.endpoints[0] {
+		t.Fatal("expected the endpoint with the earliest retry to be picked")
+	}
+
+	if !e.endpoints[1].retryAt.After(time.Now()) {
+		t.Fatal("expected repeated failures to back off")
+	}
+
+	// Repeated failures shouldn't back off forever
+	for i := 0; i < 100; i++ {
+		e.markFailed(e.endpoints[1])
+	}
+	if e.endpoints[1].retryAt.After(time.Now().Add(maxEndpointBackoff)) {
+		t.Fatal("expected backoff to be capped")
+	}
+
+	// Once healthy again, the primary is preferred
+	e.markHealthy(e.endpoints[1])
+	e.markHealthy(e.endpoints[0])
+	if e.next() != e.endpoints[0] {
+		t.Fatal("expected the primary endpoint to be preferred once healthy")
+	}
+
+	// Only endpoints ahead of the current one, which may be retried, are candidates to switch back to
+	if len(e.preferredTo(e.endpoints[0])) != 0 {
+		t.Fatal("expected no endpoints to be preferred to the primary")
+	}
+	if p := e.preferredTo(e.endpoints[1]); len(p) != 1 || p[0] != e.endpoints[0] {
+		t.Fatal("expected the primary to be preferred to the backup")
+	}
+	e.markFailed(e.endpoints[0])
+	e.markFailed(e.endpoints[0])
+	if len(e.preferredTo(e.endpoints[1])) != 0 {
+		t.Fatal("expected a backed off primary not to be a candidate")
+	}
+
+	// Credentials shouldn't be logged
+	if e.endpoints[1].String() != "ws://user:xxxxx@backup:8546" {
+		t.Fatalf("unexpected endpoint string %s", e.endpoints[1].String())
+	}
+}
@@ -8,9 +8,12 @@ import (
 	"math/big"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -18,28 +21,69 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/rocket-pool/rocketpool-go/dao/trustednode"
 	"github.com/rocket-pool/rocketpool-go/minipool"
 	"github.com/rocket-pool/rocketpool-go/node"
 	"github.com/rocket-pool/rocketpool-go/rocketpool"
 	rptypes "github.com/rocket-pool/rocketpool-go/types"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
 )
 
 type ForEachNodeClosure func(common.Address) bool
 
-const reconnectRetries = 10
-const maxCacheAgeBlocks = 64
+// Backfill requests logs in chunks of blocks, sized adaptively to what the execution client will serve
+const initialBackfillChunk = 1000
+const maxBackfillChunk = 10000
+
+// After a chunk fails, this many chunks must succeed before the chunk size is grown again
+const backfillRegrowChunks = 10
+
+// How often to check whether a more preferred execution client has recovered, after failing over
+var failbackInterval = 1 * time.Minute
+
+// Used to convert the smoothing pool grace period from epochs to a duration
+const secondsPerSlot = 12
+const slotsPerEpoch = 32
 
 type nodeInfo struct {
 	inSmoothingPool bool
 	feeDistributor  common.Address
+
+	// When the node last joined or left the smoothing pool, if we saw it happen
+	smoothingPoolChanged time.Time
+	// When the node registered, which set its fee distributor, and when its minipools and odao
+	// membership last changed, if we saw it happen
+	feeDistributorChanged time.Time
+	minipoolsChanged      time.Time
+	odaoChanged           time.Time
+
+	// Where the node's ETH is withdrawn to, which is the node address unless it set another
+	withdrawalAddress common.Address
+	// Where the node's RPL is withdrawn to, or empty if it hasn't set one apart from withdrawalAddress
+	rplWithdrawalAddress common.Address
+	// Set if the node was cached before withdrawal addresses were kept, so both are empty
+	withdrawalAddressesUnknown bool
 }
 
 type RPInfo struct {
 	ExpectedFeeRecipient *common.Address
 	NodeAddress          common.Address
+	InSmoothingPool      bool
+
+	// Every fee recipient the node's validators may use, including ExpectedFeeRecipient.
+	// For a grace period after the node joins or leaves the smoothing pool, its previous
+	// fee recipient is also acceptable, as validator clients take a while to update.
+	AcceptableFeeRecipients []common.Address
+}
+
+// AcceptsFeeRecipient checks a hex-encoded fee recipient against AcceptableFeeRecipients
+func (r *RPInfo) AcceptsFeeRecipient(feeRecipient string) bool {
+	for _, acceptable := range r.AcceptableFeeRecipients {
+		if strings.EqualFold(acceptable.String(), feeRecipient) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // ExecutionLayer is the abstract interface which provides the rescue proxy
@@ -48,8 +92,15 @@ type ExecutionLayer interface {
 	ForEachNode(ForEachNodeClosure) error
 	ForEachOdaoNode(ForEachNodeClosure) error
 	GetRPInfo(rptypes.ValidatorPubkey) (*RPInfo, error)
+	GetNodeRPInfo(nodeAddr common.Address) (*RPInfo, error)
+	GetRPInfoAt(pubkey rptypes.ValidatorPubkey, block uint64) (*RPInfo, error)
+	GetNodeWithdrawalAddresses(nodeAddr common.Address) (*WithdrawalAddresses, error)
+	GetNodeDetails(nodeAddr common.Address) (*NodeDetails, error)
+	GetWithdrawalAddressNodes(withdrawalAddr common.Address) ([]*WithdrawalAddresses, error)
 	REthAddress() *common.Address
 	ValidateEIP1271(ctx context.Context, dataHash common.Hash, signature []byte, address common.Address) (bool, error)
+	ValidateEIP1271Batch(ctx context.Context, requests []EIP1271Request) []EIP1271Result
+	ValidateSignature(ctx context.Context, dataHash common.Hash, signature []byte, address common.Address, block *big.Int) (SignatureMethod, error)
 }
 
 // CachingExecutionLayer is a bespoke execution layer client for the rescue proxy.
@@ -58,13 +109,43 @@ type ExecutionLayer interface {
 type CachingExecutionLayer struct {
 	// Fields passed in by the constructor which are later referenced
 	Logger            *zap.Logger
-	ECURL             *url.URL
+	ECURLs            []*url.URL
 	RocketStorageAddr string
 
-	// The rocketpool-go client and its ethclient instance
+	// The chain ID execution clients must report. Those on any other chain are refused.
+	// Zero accepts any chain.
+	ChainID uint64
+
+	// How often to re-read the full chain state and correct any drift in the cache.
+	// Zero disables reconciliation.
+	ReconcileInterval time.Duration
+
+	// How many epochs after a node joins or leaves the smoothing pool its previous fee recipient is accepted
+	SmoothingPoolGraceEpochs uint64
+
+	// How long EIP-1271 validation results are cached for the block they were validated at.
+	// Zero disables caching.
+	EIP1271CacheTTL time.Duration
+	// How many EIP-1271 signatures ValidateEIP1271Batch validates at once, across every batch.
+	// Zero doesn't limit them.
+	EIP1271Concurrency int
+	eip1271Slots       chan struct{}
+	eip1271Cache       *eip1271Cache
 
-	rp     *rocketpool.RocketPool
-	client *ethclient.Client
+	// Told of nodes registering and odao membership changes, if set
+	NodeSet *nodeset.Feed
+
+	// Health of each of the execution clients in ECURLs, and the one currently in use
+	endpoints *ecEndpoints
+	endpoint  *ecEndpoint
+
+	// The rocketpool-go client and its ethclient instance.
+	// These are only replaced from the goroutine that processes events, when failing
+	// over to a different execution client, so other goroutines must use getClient().
+
+	clientLock sync.RWMutex
+	rp         *rocketpool.RocketPool
+	client     *ethclient.Client
 
 	// Smart contracts we either read from or need the address of
 
@@ -82,6 +163,9 @@ type CachingExecutionLayer struct {
 	odaoJoinedTopic                 common.Hash
 	odaoLeftTopic                   common.Hash
 	odaoKickedTopic                 common.Hash
+	withdrawalAddressSetTopic       common.Hash
+	rplWithdrawalAddressSetTopic    common.Hash
+	rplWithdrawalAddressUnsetTopic  common.Hash
 
 	// The "topics" and contract filter for the events we subscribe to
 	query ethereum.FilterQuery
@@ -90,24 +174,186 @@ type CachingExecutionLayer struct {
 	events     chan types.Log
 	newHeaders chan *types.Header
 
+	// Hashes of the most recent blocks, by number, so reorgs can be detected
+	recentBlocks map[uint64]common.Hash
+
+	// Timestamps of recent blocks, so that a block's events don't each fetch its header.
+	// Guarded by eventsLock.
+	blockTimes map[uint64]time.Time
+
+	// How many blocks of logs to request at once while backfilling
+	backfillChunk uint64
+
+	// Held while processing an event, so the reconciler doesn't interleave with it
+	eventsLock sync.Mutex
+
+	// The highest block whose logs have all been applied. Guarded by eventsLock.
+	appliedBlock uint64
+
+	// While the reconciler runs, the first block whose processed events it compares against,
+	// which mustn't be pruned. Zero otherwise.
+	journalPinned atomic.Uint64
+
+	// Background goroutines which must exit before the cache is closed
+	wg sync.WaitGroup
+
 	// Somewhere to store chain data we care about
 	CachePath string
 	cache     Cache
 
-	// ethclient subscription needs to be manually closed on shutdown
-	ethclientShutdownCb func()
+	// How often to save the cache to CachePath while running, so that it survives an unclean exit
+	CacheSnapshotInterval time.Duration
+
+	// A key-value store to keep the cache in instead, shared with other instances of the proxy.
+	// Keys are prefixed with CacheKVPrefix.
+	CacheKVURL    *url.URL
+	CacheKVPrefix string
+
+	// How long the ingestion lease for a shared cache lasts without being renewed
+	IngestionLeaseTTL time.Duration
+
+	// Identifies this instance as the holder of the ingestion lease
+	instanceID string
+	// Whether this instance follows the chain and writes to the cache. Always true unless the cache is shared.
+	ingestionOwner atomic.Bool
+	// Closed if the ingestion lease is lost. Replaced each time this instance starts ingesting.
+	leaseLost chan struct{}
+
+	// ethclient subscription needs to be manually closed on shutdown.
+	// It's replaced whenever we resubscribe, which can race Stop.
+	ethclientShutdownCb   func()
+	ethclientShutdownLock sync.Mutex
 
 	// A context that can be canceled in order to gracefully stop the EL abstraction
 	ctx context.Context
 	// A function that cancels that context
 	shutdown func()
+	// Held while cancelling ctx, so goroutines aren't added to wg once Stop is waiting for it
+	shutdownLock sync.Mutex
 
 	m *metrics.MetricsRegistry
 
 	connected chan bool
 }
 
+func (e *CachingExecutionLayer) getClient() *ethclient.Client {
+	e.clientLock.RLock()
+	defer e.clientLock.RUnlock()
+
+	return e.client
+}
+
+func (e *CachingExecutionLayer) getRocketPool() *rocketpool.RocketPool {
+	e.clientLock.RLock()
+	defer e.clientLock.RUnlock()
+
+	return e.rp
+}
+
+// Opens a client for the given execution client, checking that it's on the expected chain
+func (e *CachingExecutionLayer) connect(ctx context.Context, endpoint *ecEndpoint) (*ethclient.Client, error) {
+	client, err := ethclient.DialContext(ctx, endpoint.url.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if e.ChainID != 0 {
+		chainID, err := client.ChainID(ctx)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+
+		if !chainID.IsUint64() || chainID.Uint64() != e.ChainID {
+			client.Close()
+			e.m.Counter("ec_chain_id_mismatch").Inc()
+			return nil, fmt.Errorf("execution client is on chain %s, expected %d", chainID.String(), e.ChainID)
+		}
+	}
+
+	return client, nil
+}
+
+// Checks that the given execution client can return the current block, without switching to it
+func (e *CachingExecutionLayer) probe(endpoint *ecEndpoint) error {
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+	client, err := e.connect(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err = client.HeaderByNumber(ctx, nil)
+	return err
+}
+
+// Connects to the given execution client, replacing the current one if they differ
+func (e *CachingExecutionLayer) dial(endpoint *ecEndpoint) error {
+	if endpoint == e.endpoint {
+		// The ethclient re-establishes its own connection, so there's nothing to do
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+	client, err := e.connect(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+
+	rp, err := rocketpool.NewRocketPool(client, common.HexToAddress(e.RocketStorageAddr))
+	if err != nil {
+		client.Close()
+		return err
+	}
+
+	e.clientLock.Lock()
+	old := e.client
+	e.client = client
+	e.rp = rp
+	e.endpoint = endpoint
+	e.clientLock.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	return nil
+}
+
+// Connects to the first execution client, in order of preference, which can return the current block
+func (e *CachingExecutionLayer) dialFirst() (*types.Header, error) {
+	var errs error
+
+	for _, endpoint := range e.endpoints.endpoints {
+		err := e.dial(endpoint)
+		if err == nil {
+			var header *types.Header
+
+			ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+			header, err = e.client.HeaderByNumber(ctx, nil)
+			cancel()
+			if err == nil {
+				e.endpoints.markHealthy(endpoint)
+				e.m.Gauge("healthy_ec_endpoints").Set(float64(e.endpoints.healthy()))
+				e.Logger.Info("Connected to execution client", zap.Stringer("ec", endpoint))
+				return header, nil
+			}
+		}
+
+		e.Logger.Warn("Couldn't connect to execution client", zap.Stringer("ec", endpoint), zap.Error(err))
+		e.endpoints.markFailed(endpoint)
+		errs = errors.Join(errs, err)
+	}
+
+	return nil, errs
+}
+
 func (e *CachingExecutionLayer) setECShutdownCb(cb func()) {
+	e.ethclientShutdownLock.Lock()
+	defer e.ethclientShutdownLock.Unlock()
+
 	if cb == nil {
 		e.ethclientShutdownCb = nil
 		return
@@ -133,10 +379,22 @@ func (e *CachingExecutionLayer) handleNodeEvent(event types.Log) {
 		if err != nil {
 			e.Logger.Warn("Couldn't get fee distributor address for newly registered node", zap.String("node", addr.String()))
 		}
+		// And their withdrawal addresses, which may have been set before they registered
+		nodeInfo.withdrawalAddress, nodeInfo.rplWithdrawalAddress, err = loadWithdrawalAddresses(e.rp, addr, nil)
+		if err != nil {
+			e.Logger.Warn("Couldn't get withdrawal addresses for newly registered node", zap.String("node", addr.String()), zap.Error(err))
+			nodeInfo.withdrawalAddress = addr
+		}
+		nodeInfo.feeDistributorChanged, err = e.blockTime(event.BlockNumber)
+		if err != nil {
+			e.Logger.Warn("Couldn't get the time of a node's registration", zap.String("node", addr.String()), zap.Error(err))
+		}
 		err = e.cache.addNodeInfo(addr, nodeInfo)
 		if err != nil {
 			e.Logger.Error("Failed to add nodeInfo to cache", zap.Error(err))
 		}
+		e.recordEvent(event, addr, rptypes.ValidatorPubkey{})
+		e.NodeSet.Publish(event.BlockNumber, nodeset.NodeRegistered, addr)
 
 		e.m.Counter("node_registration_added").Inc()
 		e.Logger.Info("New node registered", zap.String("addr", addr.String()))
@@ -168,23 +426,87 @@ func (e *CachingExecutionLayer) handleNodeEvent(event types.Log) {
 			if err != nil {
 				e.Logger.Warn("Couldn't compute fee distributor address for unknown node", zap.String("node", nodeAddr.String()))
 			}
+			n.withdrawalAddress, n.rplWithdrawalAddress, err = loadWithdrawalAddresses(e.rp, nodeAddr, nil)
+			if err != nil {
+				e.Logger.Warn("Couldn't get withdrawal addresses for unknown node", zap.String("node", nodeAddr.String()), zap.Error(err))
+				n.withdrawalAddress = nodeAddr
+			}
 
 		}
 
 		e.Logger.Info("Node SP status changed", zap.String("addr", nodeAddr.String()), zap.Bool("in_sp", status.Cmp(big.NewInt(1)) == 0))
-		n.inSmoothingPool = status.Cmp(big.NewInt(1)) == 0
-		err = e.cache.addNodeInfo(nodeAddr, n)
+		// The cache may share n with concurrent readers, so update a copy
+		updated := *n
+		inSmoothingPool := status.Cmp(big.NewInt(1)) == 0
+		if updated.inSmoothingPool != inSmoothingPool {
+			// Without the time of the change, there's no grace period rather than one that starts now
+			updated.smoothingPoolChanged, err = e.blockTime(event.BlockNumber)
+			if err != nil {
+				e.Logger.Warn("Couldn't get the time of a smoothing pool status change", zap.String("node", nodeAddr.String()), zap.Error(err))
+			}
+		}
+		updated.inSmoothingPool = inSmoothingPool
+		err = e.cache.addNodeInfo(nodeAddr, &updated)
 		if err != nil {
 			e.Logger.Error("Failed to add nodeInfo to cache", zap.Error(err))
 		}
+		e.recordEvent(event, nodeAddr, rptypes.ValidatorPubkey{})
 
 		e.m.Counter("smoothing_pool_status_changed").Inc()
 		return
 	}
 
+	// Or a change of RPL withdrawal address
+	if event.Topics[0] == e.rplWithdrawalAddressSetTopic || event.Topics[0] == e.rplWithdrawalAddressUnsetTopic {
+		e.handleWithdrawalAddressEvent(event)
+		return
+	}
+
 	e.Logger.Warn("Event with unknown topic received", zap.String("string", event.Topics[0].String()))
 }
 
+// Returns the timestamp of a block, fetching its header the first time one of its events is seen.
+// The caller must hold eventsLock.
+func (e *CachingExecutionLayer) blockTime(number uint64) (time.Time, error) {
+	if t, ok := e.blockTimes[number]; ok {
+		return t, nil
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	header, err := e.client.HeaderByNumber(ctx, big.NewInt(0).SetUint64(number))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("couldn't get the header of block %d: %w", number, err)
+	}
+
+	t := time.Unix(int64(header.Time), 0)
+	e.rememberBlockTime(number, t)
+	return t, nil
+}
+
+// Caches a block's timestamp, forgetting those of blocks too far behind it to see more events.
+// The caller must hold eventsLock.
+func (e *CachingExecutionLayer) rememberBlockTime(number uint64, t time.Time) {
+	for n := range e.blockTimes {
+		if n+maxReorgDepth < number {
+			delete(e.blockTimes, n)
+		}
+	}
+	e.blockTimes[number] = t
+}
+
+// Records a change to a node at the time of the event's block. If the time can't be determined,
+// the change isn't recorded, rather than recorded at the wrong time.
+func (e *CachingExecutionLayer) recordNodeChangeAt(event types.Log, nodeAddr common.Address, update func(*nodeInfo, time.Time)) error {
+	t, err := e.blockTime(event.BlockNumber)
+	if err != nil {
+		return err
+	}
+
+	return e.recordNodeChange(nodeAddr, func(n *nodeInfo) { update(n, t) })
+}
+
 func (e *CachingExecutionLayer) handleMinipoolEvent(event types.Log) {
 
 	// Make sure it's an event for the only topic we subscribed to, minipool launches
@@ -209,6 +531,11 @@ func (e *CachingExecutionLayer) handleMinipoolEvent(event types.Log) {
 	if err != nil {
 		e.Logger.Warn("Error updating minipool cache", zap.Error(err))
 	}
+	err = e.recordNodeChangeAt(event, nodeAddr, func(n *nodeInfo, t time.Time) { n.minipoolsChanged = t })
+	if err != nil {
+		e.Logger.Warn("Error recording node change", zap.String("node", nodeAddr.String()), zap.Error(err))
+	}
+	e.recordEvent(event, nodeAddr, pubkey)
 	e.m.Counter("minipool_launch_received").Inc()
 	e.Logger.Info("Added new minipool", zap.String("pubkey", pubkey.String()), zap.String("node", nodeAddr.String()))
 }
@@ -222,6 +549,12 @@ func (e *CachingExecutionLayer) handleOdaoEvent(event types.Log) {
 		if err != nil {
 			e.Logger.Warn("Error updating odao cache", zap.Error(err))
 		}
+		err = e.recordNodeChangeAt(event, addr, func(n *nodeInfo, t time.Time) { n.odaoChanged = t })
+		if err != nil {
+			e.Logger.Warn("Error recording node change", zap.String("node", addr.String()), zap.Error(err))
+		}
+		e.recordEvent(event, addr, rptypes.ValidatorPubkey{})
+		e.NodeSet.Publish(event.BlockNumber, nodeset.OdaoJoined, addr)
 		return
 	}
 
@@ -234,6 +567,16 @@ func (e *CachingExecutionLayer) handleOdaoEvent(event types.Log) {
 		if err != nil {
 			e.Logger.Warn("Error updating odao cache", zap.Error(err))
 		}
+		err = e.recordNodeChangeAt(event, addr, func(n *nodeInfo, t time.Time) { n.odaoChanged = t })
+		if err != nil {
+			e.Logger.Warn("Error recording node change", zap.String("node", addr.String()), zap.Error(err))
+		}
+		e.recordEvent(event, addr, rptypes.ValidatorPubkey{})
+		if event.Topics[0] == e.odaoKickedTopic {
+			e.NodeSet.Publish(event.BlockNumber, nodeset.OdaoKicked, addr)
+		} else {
+			e.NodeSet.Publish(event.BlockNumber, nodeset.OdaoLeft, addr)
+		}
 		return
 	}
 
@@ -241,8 +584,29 @@ func (e *CachingExecutionLayer) handleOdaoEvent(event types.Log) {
 }
 
 func (e *CachingExecutionLayer) handleEvent(event types.Log) {
-	// events from the rocketNodeManager contract
+	e.eventsLock.Lock()
+	defer e.eventsLock.Unlock()
+
+	e.processEvent(event)
+}
+
+// Applies an event to the cache. The caller must hold eventsLock.
+func (e *CachingExecutionLayer) processEvent(event types.Log) {
 	e.m.Counter("subscription_event_received").Inc()
+
+	// The execution client retracts logs from blocks that were reorganized out
+	if event.Removed {
+		e.handleRemovedEvent(event)
+		return
+	}
+
+	// After a reorg or reconnection, the subscription may deliver logs that a backfill already applied
+	if e.alreadyProcessed(event) {
+		e.m.Counter("duplicate_event_skipped").Inc()
+		return
+	}
+
+	// events from the rocketNodeManager contract
 	if bytes.Equal(e.rocketNodeManager.Address[:], event.Address[:]) {
 		e.handleNodeEvent(event)
 		goto out
@@ -260,11 +624,39 @@ func (e *CachingExecutionLayer) handleEvent(event types.Log) {
 		goto out
 	}
 
+	// withdrawal address changes from the RocketStorage contract
+	if event.Address == common.HexToAddress(e.RocketStorageAddr) {
+		e.handleWithdrawalAddressEvent(event)
+		goto out
+	}
+
 	// Shouldn't ever happen, barring a bug in ethclient
 	e.Logger.Warn("Received event for unknown contract", zap.String("address", event.Address.String()))
 out:
 	// We should always update highestBlock when we receive any event
 	e.cache.setHighestBlock(big.NewInt(int64(event.BlockNumber)))
+
+	// Logs arrive in block order, so the block's other logs may still be to come, but not the previous block's
+	if event.BlockNumber > 0 {
+		e.advanceAppliedBlock(event.BlockNumber - 1)
+	}
+}
+
+// Records that every log up to and including the block has been applied, if it's further than before.
+// The caller must hold eventsLock.
+func (e *CachingExecutionLayer) advanceAppliedBlock(block uint64) {
+	if block > e.appliedBlock {
+		e.setAppliedBlock(block)
+	}
+}
+
+// Records the highest block whose logs have all been applied, so that snapshots save no further.
+// The caller must hold eventsLock.
+func (e *CachingExecutionLayer) setAppliedBlock(block uint64) {
+	e.appliedBlock = block
+	if sc, ok := e.cache.(snapshotCache); ok {
+		sc.setAppliedBlock(big.NewInt(0).SetUint64(block))
+	}
 }
 
 // Gets the current block and loads any events we missed between highestBlock and the current one
@@ -275,10 +667,30 @@ out:
 // All of this is only necessary because SubscribeFilterLogs doesn't seem to send old events, no matter
 // what FromBlock is set to.
 func (e *CachingExecutionLayer) backfillEvents() error {
+	e.eventsLock.Lock()
+	defer e.eventsLock.Unlock()
+
 	// Since highestBlock was the highest processed block, start one block after
 	start := big.NewInt(0).Add(e.cache.getHighestBlock(), big.NewInt(1))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// If any events we processed were reorganized out while we weren't watching,
+	// they've been rolled back, and we need to replay the new chain from the fork
+	fork, err := e.checkProcessedEvents()
+	if err != nil {
+		return err
+	}
+	if fork != nil && fork.Cmp(start) < 0 {
+		start = fork
+	}
+
+	return e.backfillEventsFrom(start)
+}
+
+// Loads any events between start and the current block, in chunks.
+// Progress is checkpointed into the cache after each chunk, so a failure part way through
+// resumes from the last completed chunk rather than starting over. The caller must hold eventsLock.
+func (e *CachingExecutionLayer) backfillEventsFrom(start *big.Int) error {
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
 	defer cancel()
 
 	// Get current block
@@ -299,48 +711,144 @@ func (e *CachingExecutionLayer) backfillEvents() error {
 		return nil
 	}
 
-	missedEvents, err := e.client.FilterLogs(ctx, ethereum.FilterQuery{
-		// We only want events for 2 contracts
-		Addresses: []common.Address{*e.rocketMinipoolManager.Address, *e.rocketNodeManager.Address},
-		FromBlock: start,
+	if e.backfillChunk == 0 {
+		e.backfillChunk = initialBackfillChunk
+	}
+
+	// Once a chunk has failed, don't grow it back towards the size that failed until
+	// enough smaller chunks have succeeded, as the failure may have been transient
+	shrunk := false
+	sinceShrunk := 0
+
+	events := 0
+	from := start.Uint64()
+	for from <= stop.Uint64() {
 		// The current block is actually the last block processed by the EC, so play any events from it as well
 		// The range is inclusive
-		ToBlock: stop,
-		Topics: [][]common.Hash{[]common.Hash{
-			e.nodeRegisteredTopic,
-			e.smoothingPoolStatusChangedTopic,
-			e.minipoolLaunchedTopic,
-			e.odaoJoinedTopic,
-			e.odaoLeftTopic,
-			e.odaoKickedTopic,
-		}},
-	})
+		to := min(from+e.backfillChunk-1, stop.Uint64())
+
+		ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+		missedEvents, err := e.client.FilterLogs(ctx, ethereum.FilterQuery{
+			// Filter for the same contracts and events we subscribe to
+			Addresses: e.query.Addresses,
+			FromBlock: big.NewInt(0).SetUint64(from),
+			ToBlock:   big.NewInt(0).SetUint64(to),
+			Topics:    e.query.Topics,
+		})
+		cancel()
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			// Execution clients cap the range or result count of log queries, or time out
+			// on large ones, so retry with a smaller chunk before giving up
+			if e.backfillChunk == 1 || e.ctx.Err() != nil {
+				return err
+			}
 
-	for _, event := range missedEvents {
-		e.handleEvent(event)
-		e.m.Counter("backfill_events").Inc()
-	}
+			shrunk = true
+			sinceShrunk = 0
+			e.backfillChunk /= 2
+			e.m.Counter("backfill_chunk_shrunk").Inc()
+			e.Logger.Warn("Error backfilling events, retrying with a smaller range",
+				zap.Uint64("from", from), zap.Uint64("to", to),
+				zap.Uint64("chunk", e.backfillChunk), zap.Error(err))
+			continue
+		}
 
-	// Force the highest block to update, as we may not have received any events in it, which would have updated it
-	e.cache.setHighestBlock(stop)
+		for _, event := range missedEvents {
+			e.processEvent(event)
+			e.m.Counter("backfill_events").Inc()
+		}
+		events += len(missedEvents)
+
+		// Checkpoint the chunk, as we may not have received any events in it, which would have updated it
+		e.cache.setHighestBlock(big.NewInt(0).SetUint64(to))
+		e.advanceAppliedBlock(to)
+		e.m.Counter("backfill_blocks").Add(float64(to - from + 1))
+		e.m.Gauge("backfill_remaining_blocks").Set(float64(stop.Uint64() - to))
+		e.Logger.Debug("Backfilled chunk", zap.Int("events", len(missedEvents)),
+			zap.Uint64("from", from), zap.Uint64("to", to))
+
+		from = to + 1
+		if shrunk {
+			sinceShrunk++
+			shrunk = sinceShrunk < backfillRegrowChunks
+		}
+		if !shrunk {
+			e.backfillChunk = min(e.backfillChunk*2, maxBackfillChunk)
+		}
+	}
 
 	delta := big.NewInt(0).Sub(stop, start)
 
 	// If start == stop we actually fill that one block, so add one to delta
 	delta = delta.Add(delta, big.NewInt(1))
-	e.m.Counter("backfill_blocks").Add(float64(delta.Uint64()))
 
-	e.Logger.Info("Backfilled events", zap.Int("events", len(missedEvents)),
+	e.Logger.Info("Backfilled events", zap.Int("events", events),
 		zap.Uint64("blocks", delta.Uint64()),
 		zap.Int64("start", start.Int64()), zap.Int64("stop", stop.Int64()))
 	return nil
 }
 
-// Will likely attempt to reconnect, and will overwrite the pointers passed with the new subscription objects
+// Connects to the given execution client, subscribes to events and new blocks, and backfills any
+// events that were missed while disconnected.
+func (e *CachingExecutionLayer) resubscribe(endpoint *ecEndpoint) (ethereum.Subscription, ethereum.Subscription, error) {
+	if err := e.dial(endpoint); err != nil {
+		return nil, nil, err
+	}
+
+	s, err := e.client.SubscribeFilterLogs(context.Background(), e.query, e.events)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Resubscribe to new headers
+	h, err := e.client.SubscribeNewHead(context.Background(), e.newHeaders)
+	if err != nil {
+		s.Unsubscribe()
+		return nil, nil, err
+	}
+
+	e.setECShutdownCb(func() {
+		s.Unsubscribe()
+		h.Unsubscribe()
+	})
+
+	// Now that we've reconnected, we need to backfill
+	err = e.backfillEvents()
+	if err != nil {
+		e.setECShutdownCb(nil)
+		s.Unsubscribe()
+		h.Unsubscribe()
+		return nil, nil, err
+	}
+
+	return s, h, nil
+}
+
+// Discards events and headers the subscriptions delivered but which weren't handled, after unsubscribing.
+// Whoever subscribes next backfills from the cache's highest block, so nothing is lost.
+func (e *CachingExecutionLayer) drainSubscriptions() {
+	for {
+		select {
+		case _, ok := <-e.events:
+			if !ok {
+				return
+			}
+		case _, ok := <-e.newHeaders:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Will attempt to reconnect, failing over between execution clients, and will overwrite the pointers
+// passed with the new subscription objects.
+//
+// It keeps trying until it succeeds or the execution layer is stopped. While disconnected, the cache
+// continues to serve the last state we observed.
 func (e *CachingExecutionLayer) handleSubscriptionError(err error, logEventSub **ethereum.Subscription, headerSub **ethereum.Subscription) {
 	if e.ctx.Err() != nil {
 		// We're shutting down, so return quietly
@@ -348,53 +856,92 @@ func (e *CachingExecutionLayer) handleSubscriptionError(err error, logEventSub *
 	}
 
 	e.m.Counter("subscription_disconnected").Inc()
-	e.Logger.Warn("Error received from eth client subscription", zap.Error(err))
-	// Attempt to reconnect `reconnectRetries` times with steadily increasing waits
-	for i := 0; i < reconnectRetries; i++ {
+	e.Logger.Warn("Error received from eth client subscription", zap.Stringer("ec", e.endpoint), zap.Error(err))
+	e.endpoints.markFailed(e.endpoint)
+	e.m.Gauge("healthy_ec_endpoints").Set(float64(e.endpoints.healthy()))
+
+	for i := 0; ; i++ {
+		// Pick the healthiest execution client, and wait until it may be retried
+		endpoint := e.endpoints.next()
+		select {
+		case <-e.ctx.Done():
+			// We're shutting down, so exit now
+			e.Logger.Info("Terminating while re-establishing the connection to the EL")
+			return
+		case <-time.After(time.Until(endpoint.retryAt)):
+		}
 
-		e.Logger.Warn("Attempting to reconnect", zap.Int("attempt", i+1))
+		e.Logger.Warn("Attempting to reconnect", zap.Int("attempt", i+1), zap.Stringer("ec", endpoint))
 		e.m.Counter("reconnection_attempt").Inc()
-		s, err := e.client.SubscribeFilterLogs(context.Background(), e.query, e.events)
-		if err == nil {
-			e.Logger.Warn("Reconnected", zap.Int("attempt", i+1))
+		previous := e.endpoint
+		s, h, err := e.resubscribe(endpoint)
+		if previous != e.endpoint {
+			e.m.Counter("ec_failover").Inc()
+			e.Logger.Warn("Failed over to a different execution client",
+				zap.Stringer("from", previous), zap.Stringer("to", e.endpoint))
+		}
+		if err != nil {
+			e.Logger.Warn("Error trying to reconnect to execution client", zap.Stringer("ec", endpoint), zap.Error(err))
+			e.endpoints.markFailed(endpoint)
+			e.m.Gauge("healthy_ec_endpoints").Set(float64(e.endpoints.healthy()))
+			continue
+		}
 
-			// Resubscribe to new headers - no retries
-			h, err := e.client.SubscribeNewHead(context.Background(), e.newHeaders)
-			if err != nil {
-				e.Logger.Warn("Couldn't resubscribe to block headers after reconnecting")
-				break
-			}
+		e.Logger.Warn("Reconnected", zap.Int("attempt", i+1), zap.Stringer("ec", endpoint))
+		e.endpoints.markHealthy(endpoint)
+		e.m.Gauge("healthy_ec_endpoints").Set(float64(e.endpoints.healthy()))
+
+		*logEventSub = &s
+		*headerSub = &h
+		return
+	}
+}
+
+// Switches back to the most preferred execution client that has recovered, if there's one more
+// preferred than the current one, and overwrites the pointers passed with the new subscription objects.
+func (e *CachingExecutionLayer) failback(logEventSub **ethereum.Subscription, headerSub **ethereum.Subscription) {
+	if e.ctx.Err() != nil {
+		return
+	}
 
-			e.setECShutdownCb(func() {
-				s.Unsubscribe()
-				h.Unsubscribe()
-			})
+	for _, endpoint := range e.endpoints.preferredTo(e.endpoint) {
+		if err := e.probe(endpoint); err != nil {
+			e.Logger.Debug("Preferred execution client is still unavailable", zap.Stringer("ec", endpoint), zap.Error(err))
+			e.endpoints.markFailed(endpoint)
+			continue
+		}
+
+		e.endpoints.markHealthy(endpoint)
+		e.m.Gauge("healthy_ec_endpoints").Set(float64(e.endpoints.healthy()))
+
+		previous := e.endpoint
+		(**logEventSub).Unsubscribe()
+		(**headerSub).Unsubscribe()
+		e.setECShutdownCb(nil)
+		s, h, err := e.resubscribe(endpoint)
+		if err != nil {
+			e.Logger.Warn("Couldn't switch back to a preferred execution client", zap.Stringer("ec", endpoint), zap.Error(err))
+			e.endpoints.markFailed(endpoint)
 
-			// Now that we've reconnected, we need to backfill
-			err = e.backfillEvents()
+			// Return to the execution client we were using
+			s, h, err = e.resubscribe(previous)
 			if err != nil {
-				// Failed to backfill
-				e.Logger.Panic("Couldn't backfill blocks after reconnecting to execution client")
+				e.handleSubscriptionError(err, logEventSub, headerSub)
+				return
 			}
-
 			*logEventSub = &s
 			*headerSub = &h
 			return
 		}
 
-		e.Logger.Warn("Error trying to reconnect to execution client", zap.Error(err))
-		select {
-		case <-e.ctx.Done():
-			// We're shutting down, so exit now
-			e.Logger.Info("Terminating while re-establishing the connection to the EL")
-			return
-		case <-time.After(time.Duration(i) * (5 * time.Second)):
-			// Loop again
-		}
-	}
+		e.m.Counter("ec_failback").Inc()
+		e.Logger.Info("Switched back to a preferred execution client",
+			zap.Stringer("from", previous), zap.Stringer("to", endpoint))
 
-	// Failed to reconnect after 10 tries
-	e.Logger.Panic("Couldn't re-establish eth client connection")
+		*logEventSub = &s
+		*headerSub = &h
+		return
+	}
 }
 
 // Registers to receive the events we care about
@@ -407,10 +954,19 @@ func (e *CachingExecutionLayer) ecEventsConnect(opts *bind.CallOpts) error {
 	e.odaoJoinedTopic = crypto.Keccak256Hash([]byte("ActionJoined(address,uint256,uint256)"))
 	e.odaoLeftTopic = crypto.Keccak256Hash([]byte("ActionLeave(address,uint256,uint256)"))
 	e.odaoKickedTopic = crypto.Keccak256Hash([]byte("ActionKick(address,uint256,uint256)"))
+	e.withdrawalAddressSetTopic = crypto.Keccak256Hash([]byte("NodeWithdrawalAddressSet(address,address,uint256)"))
+	e.rplWithdrawalAddressSetTopic = crypto.Keccak256Hash([]byte("NodeRPLWithdrawalAddressSet(address,address,uint256)"))
+	e.rplWithdrawalAddressUnsetTopic = crypto.Keccak256Hash([]byte("NodeRPLWithdrawalAddressUnset(address,uint256)"))
 
-	// Subscribe to events from rocketNodeManager and rocketMinipoolManager
+	// Subscribe to events from rocketNodeManager, rocketMinipoolManager, rocketDAONodeTrustedActions,
+	// and RocketStorage, which keeps nodes' withdrawal addresses
 	e.query = ethereum.FilterQuery{
-		Addresses: []common.Address{*e.rocketMinipoolManager.Address, *e.rocketNodeManager.Address},
+		Addresses: []common.Address{
+			*e.rocketMinipoolManager.Address,
+			*e.rocketNodeManager.Address,
+			*e.rocketDaoNodeTrustedActions.Address,
+			common.HexToAddress(e.RocketStorageAddr),
+		},
 		Topics: [][]common.Hash{[]common.Hash{
 			e.nodeRegisteredTopic,
 			e.smoothingPoolStatusChangedTopic,
@@ -418,16 +974,17 @@ func (e *CachingExecutionLayer) ecEventsConnect(opts *bind.CallOpts) error {
 			e.odaoJoinedTopic,
 			e.odaoLeftTopic,
 			e.odaoKickedTopic,
+			e.withdrawalAddressSetTopic,
+			e.rplWithdrawalAddressSetTopic,
+			e.rplWithdrawalAddressUnsetTopic,
 		}},
 	}
 
-	e.events = make(chan types.Log, 32)
 	sub, err := e.client.SubscribeFilterLogs(context.Background(), e.query, e.events)
 	if err != nil {
 		return err
 	}
 
-	e.newHeaders = make(chan *types.Header, 32)
 	newHeadSub, err := e.client.SubscribeNewHead(context.Background(), e.newHeaders)
 	if err != nil {
 		return err
@@ -448,22 +1005,40 @@ func (e *CachingExecutionLayer) ecEventsConnect(opts *bind.CallOpts) error {
 		newHeadSub.Unsubscribe()
 	})
 
-	e.connected <- true
+	// Nobody may have read that we connected the last time we held the ingestion lease
+	select {
+	case e.connected <- true:
+	default:
+	}
 	{
 		var noMoreEvents bool
 		var noMoreHeaders bool
 
 		logSubscription := &sub
 		newHeadSubscription := &newHeadSub
+
+		// Periodically check whether a more preferred execution client has recovered
+		failbackTicker := time.NewTicker(failbackInterval)
+		defer failbackTicker.Stop()
 		for {
 
 			select {
+			case <-e.leaseLost:
+				// Another instance may be ingesting events now, so stop following the chain and follow it instead
+				e.setECShutdownCb(nil)
+				(*logSubscription).Unsubscribe()
+				(*newHeadSubscription).Unsubscribe()
+				e.drainSubscriptions()
+				return errIngestionLeaseLost
 			case err := <-(*logSubscription).Err():
 				(*newHeadSubscription).Unsubscribe()
 				e.handleSubscriptionError(err, &logSubscription, &newHeadSubscription)
 			case err := <-(*newHeadSubscription).Err():
 				(*logSubscription).Unsubscribe()
 				e.handleSubscriptionError(err, &logSubscription, &newHeadSubscription)
+			case <-failbackTicker.C:
+				e.failback(&logSubscription, &newHeadSubscription)
+				continue
 			case event, ok := <-e.events:
 				noMoreEvents = !ok
 				if !noMoreEvents {
@@ -472,11 +1047,12 @@ func (e *CachingExecutionLayer) ecEventsConnect(opts *bind.CallOpts) error {
 			case newHeader, ok := <-e.newHeaders:
 				noMoreHeaders = !ok
 				if !noMoreHeaders {
-					// Just advance highest block
+					// Check for reorgs, and advance highest block
 					e.m.Counter("block_header_received").Inc()
 					e.Logger.Debug("New block received",
 						zap.Int64("new height", newHeader.Number.Int64()),
 						zap.Int64("old height", e.cache.getHighestBlock().Int64()))
+					e.handleNewHeader(newHeader)
 					e.cache.setHighestBlock(newHeader.Number)
 
 					// Continue here to check for new events
@@ -498,18 +1074,39 @@ func (e *CachingExecutionLayer) ecEventsConnect(opts *bind.CallOpts) error {
 }
 
 // Init creates and warms up the ExecutionLayer cache.
-func (e *CachingExecutionLayer) Init() error {
-	var err error
-
+func (e *CachingExecutionLayer) Init() (err error) {
 	e.m = metrics.NewMetricsRegistry("execution_layer")
 	e.connected = make(chan bool, 1)
+	e.recentBlocks = make(map[uint64]common.Hash)
+	e.blockTimes = make(map[uint64]time.Time)
+	e.events = make(chan types.Log, 32)
+	e.newHeaders = make(chan *types.Header, 32)
+	if e.EIP1271CacheTTL > 0 {
+		e.eip1271Cache = newEIP1271Cache(e.EIP1271CacheTTL)
+	}
+	if e.EIP1271Concurrency > 0 {
+		e.eip1271Slots = make(chan struct{}, e.EIP1271Concurrency)
+	}
+
+	if len(e.ECURLs) == 0 {
+		return fmt.Errorf("at least one execution client url is required")
+	}
+	e.endpoints = newECEndpoints(e.ECURLs)
 
 	// Pick a cache
-	if e.CachePath == "" {
+	if e.CacheKVURL != nil {
+		e.cache = &KVCache{
+			URL:    e.CacheKVURL,
+			Prefix: e.CacheKVPrefix,
+			Logger: e.Logger,
+		}
+	} else if e.CachePath == "" {
 		e.cache = &MapsCache{}
 	} else {
 		e.cache = &SqliteCache{
-			Path: e.CachePath,
+			Path:             e.CachePath,
+			SnapshotInterval: e.CacheSnapshotInterval,
+			Logger:           e.Logger,
 		}
 	}
 
@@ -518,21 +1115,27 @@ func (e *CachingExecutionLayer) Init() error {
 	if err := e.cache.init(); err != nil {
 		return err
 	}
-	cacheBlock := e.cache.getHighestBlock()
 
-	e.client, err = ethclient.Dial(e.ECURL.String())
-	if err != nil {
-		return err
-	}
-	e.rp, err = rocketpool.NewRocketPool(e.client, common.HexToAddress(e.RocketStorageAddr))
-	if err != nil {
-		return err
+	if sc, ok := e.cache.(sharedCache); ok {
+		e.instanceID = newInstanceID()
+		if err := e.joinSharedCache(sc); err != nil {
+			return err
+		}
+
+		// The lease is renewed from when it's acquired, so stop renewing it if we fail to warm up
+		defer func() {
+			if err != nil {
+				e.abandonLease(sc)
+			}
+		}()
+	} else {
+		e.ingestionOwner.Store(true)
 	}
+	cacheBlock := e.cache.getHighestBlock()
+	e.appliedBlock = cacheBlock.Uint64()
 
-	// First, get the current block
-	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
-	defer cancel()
-	header, err := e.client.HeaderByNumber(ctx, nil)
+	// Connect, and get the current block
+	header, err := e.dialFirst()
 	if err != nil {
 		return err
 	}
@@ -540,9 +1143,15 @@ func (e *CachingExecutionLayer) Init() error {
 	// Subtract the cache's highest block from the current block
 	delta := big.NewInt(0)
 	delta.Sub(header.Number, cacheBlock)
-	if delta.Int64() < 0 || delta.Int64() > maxCacheAgeBlocks {
-		// Reset caches from the future and the distance past
-		e.Logger.Info("Cache is stale or from the future, resetting...",
+	if !e.ingestionOwner.Load() {
+		// The shared cache is kept up to date by another instance, whose execution client may be
+		// a block or two ahead of ours, so it's neither reset nor warmed up here
+		e.Logger.Info("Using the shared cache",
+			zap.Int64("cache block", cacheBlock.Int64()),
+			zap.Int64("current block", header.Number.Int64()))
+	} else if delta.Sign() < 0 {
+		// Reset caches from the future, as they were built from a different chain
+		e.Logger.Info("Cache is from the future, resetting...",
 			zap.Int64("cache block", cacheBlock.Int64()),
 			zap.Int64("current block", header.Number.Int64()),
 			zap.Int64("delta", delta.Int64()))
@@ -552,6 +1161,13 @@ func (e *CachingExecutionLayer) Init() error {
 		}
 
 		cacheBlock = big.NewInt(0)
+		e.setAppliedBlock(0)
+	} else if cacheBlock.Sign() != 0 {
+		// Stale caches are caught up by backfilling the events they missed once we Start
+		e.Logger.Info("Loaded cache snapshot",
+			zap.Int64("cache block", cacheBlock.Int64()),
+			zap.Int64("current block", header.Number.Int64()),
+			zap.Int64("delta", delta.Int64()))
 	}
 
 	// Create opts to query state at the latest block
@@ -583,118 +1199,136 @@ func (e *CachingExecutionLayer) Init() error {
 		return err
 	}
 
+	// Index what older versions cached without indexing, before adding to it
+	if mc, ok := e.cache.(minipoolIndexCache); ok && e.ingestionOwner.Load() {
+		if err := mc.indexNodeMinipools(); err != nil {
+			return err
+		}
+	}
+	if wc, ok := e.cache.(withdrawalIndexCache); ok && e.ingestionOwner.Load() {
+		if err := wc.indexWithdrawalAddresses(); err != nil {
+			return err
+		}
+	}
+
 	// If the cache is warm, skip the slow path
 	if cacheBlock.Cmp(big.NewInt(0)) != 0 {
-		return nil
+		if !e.ingestionOwner.Load() {
+			return nil
+		}
+
+		// Fill in what older versions didn't cache
+		return e.backfillWithdrawalAddresses(opts)
 	}
 	e.Logger.Info("Warming up the cache")
 
-	// Get all nodes at the given block
-	nodes, err := node.GetNodeAddresses(e.rp, opts)
+	state, err := e.loadChainState(e.rp, opts)
 	if err != nil {
 		return err
 	}
-	e.Logger.Info("Found nodes to preload", zap.Int("count", len(nodes)), zap.Int64("block", opts.BlockNumber.Int64()))
 
-	minipoolCount := 0
-	for _, addr := range nodes {
-		// Allocate a pointer for this node
-		nodeInfo := &nodeInfo{}
-		// Determine their smoothing pool status
-		nodeInfo.inSmoothingPool, err = node.GetSmoothingPoolRegistrationState(e.rp, addr, opts)
-		if err != nil {
-			return err
-		}
-
-		// Get their fee distributor address
-		nodeInfo.feeDistributor, err = node.GetDistributorAddress(e.rp, addr, opts)
-		if err != nil {
-			return err
-		}
-
-		// Store the smoothing pool state / fee distributor in the node index
+	// Store the smoothing pool state / fee distributor in the node index
+	for addr, nodeInfo := range state.nodes {
 		err = e.cache.addNodeInfo(addr, nodeInfo)
 		if err != nil {
 			return err
 		}
+	}
 
-		// Also grab their minipools
-		minipoolAddresses, err := minipool.GetNodeMinipoolAddresses(e.rp, addr, opts)
+	for pubkey, addr := range state.minipools {
+		err = e.cache.addMinipoolNode(pubkey, addr)
 		if err != nil {
 			return err
 		}
+	}
 
-		minipoolCount += len(minipoolAddresses)
-		var wg errgroup.Group
-		wg.SetLimit(64)
-		for _, m := range minipoolAddresses {
-			m := m
-			wg.Go(func() error {
-				pubkey, err := minipool.GetMinipoolPubkey(e.rp, m, opts)
-				if err != nil {
-					return err
-				}
-				err = e.cache.addMinipoolNode(pubkey, addr)
-				if err != nil {
-					return err
-				}
-				return nil
-			})
-		}
-		err = wg.Wait()
+	for member := range state.odaoNodes {
+		err = e.cache.addOdaoNode(member)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Get all odao nodes at the given block
-	odaoNodes, err := trustednode.GetMemberAddresses(e.rp, opts)
-	if err != nil {
+	if err := e.recordStateHistory(opts.BlockNumber.Uint64(), state); err != nil {
 		return err
 	}
 
-	for _, member := range odaoNodes {
-
-		err = e.cache.addOdaoNode(member)
-		if err != nil {
-			return err
-		}
-	}
-	e.Logger.Info("Found odao nodes to preload", zap.Int("count", len(odaoNodes)), zap.Int64("block", opts.BlockNumber.Int64()))
-
 	// Set highestBlock to the cache's highestBlock, since it was just warmed up
 	e.cache.setHighestBlock(opts.BlockNumber)
+	e.setAppliedBlock(opts.BlockNumber.Uint64())
 
 	e.Logger.Info("Pre-loaded nodes and minipools",
-		zap.Int("nodes", len(nodes)),
-		zap.Int("minipools", minipoolCount),
-		zap.Int("odao nodes", len(odaoNodes)))
+		zap.Int("nodes", len(state.nodes)),
+		zap.Int("minipools", len(state.minipools)),
+		zap.Int("odao nodes", len(state.odaoNodes)))
 
 	return nil
 }
 
 func (e *CachingExecutionLayer) Start() error {
-	// First, get the current block
-	header, err := e.client.HeaderByNumber(e.ctx, nil)
-	if err != nil {
-		return err
+	e.NodeSet.Advance(e.cache.getHighestBlock().Uint64())
+
+	if e.ReconcileInterval > 0 && !e.goUnlessStopped(e.reconcileLoop) {
+		return nil
 	}
 
-	// Create opts to query state at the latest block
-	opts := &bind.CallOpts{BlockNumber: header.Number}
+	sc, _ := e.cache.(sharedCache)
+	for {
+		if !e.ingestionOwner.Load() {
+			// Another instance is ingesting events, so serve what it writes until we can take over
+			if !e.waitForLease(sc) {
+				return nil
+			}
+		}
+		// First, get the current block
+		header, err := e.getClient().HeaderByNumber(e.ctx, nil)
+		if err != nil {
+			return err
+		}
 
-	return e.ecEventsConnect(opts)
+		// Create opts to query state at the latest block
+		opts := &bind.CallOpts{BlockNumber: header.Number}
+
+		err = e.ecEventsConnect(opts)
+		if err != errIngestionLeaseLost {
+			return err
+		}
+
+		e.Logger.Warn("Following the instance that took over the ingestion lease for the shared cache")
+	}
+}
+
+// Runs f in a goroutine tracked by wg, which must call wg.Done, unless Stop was called
+func (e *CachingExecutionLayer) goUnlessStopped(f func()) bool {
+	e.shutdownLock.Lock()
+	defer e.shutdownLock.Unlock()
+
+	if e.ctx.Err() != nil {
+		return false
+	}
+
+	e.wg.Add(1)
+	go f()
+	return true
 }
 
 // Stop shuts down this ExecutionLayer
 func (e *CachingExecutionLayer) Stop() {
 	e.Logger.Info("Stopping ethclient")
+	e.shutdownLock.Lock()
 	e.shutdown()
+	e.shutdownLock.Unlock()
+	e.ethclientShutdownLock.Lock()
 	if e.ethclientShutdownCb != nil {
 		e.ethclientShutdownCb()
 	}
+	e.ethclientShutdownLock.Unlock()
 	close(e.events)
 	close(e.newHeaders)
+	e.wg.Wait()
+	if sc, ok := e.cache.(sharedCache); ok {
+		e.releaseLease(sc)
+	}
 	e.Logger.Info("Stopping EL cache")
 	err := e.cache.deinit()
 	if err != nil {
@@ -748,17 +1382,101 @@ func (e *CachingExecutionLayer) GetRPInfo(pubkey rptypes.ValidatorPubkey) (*RPIn
 		return nil, fmt.Errorf("node %s not found in cache despite pubkey %s being present", nodeAddr.String(), pubkey.String())
 	}
 
+	return newRPInfo(nodeAddr, nodeInfo, *e.smoothingPool.Address, e.SmoothingPoolGraceEpochs, time.Now()), nil
+}
+
+// GetNodeRPInfo returns the fee recipients a node's validators may use, or nil if it isn't a registered node.
+// It's for validators that can't be looked up by pubkey, eg because the bn doesn't know their index yet.
+func (e *CachingExecutionLayer) GetNodeRPInfo(nodeAddr common.Address) (*RPInfo, error) {
+	nodeInfo, err := e.cache.getNodeInfo(nodeAddr)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); !ok {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	return newRPInfo(nodeAddr, nodeInfo, *e.smoothingPool.Address, e.SmoothingPoolGraceEpochs, time.Now()), nil
+}
+
+// GetRPInfoAt returns what GetRPInfo would have as of the given block, or nil if the validator was not
+// a minipool then. It returns ErrHistoryUnavailable unless the cache has history reaching back to the block.
+func (e *CachingExecutionLayer) GetRPInfoAt(pubkey rptypes.ValidatorPubkey, block uint64) (*RPInfo, error) {
+	hc, ok := e.cache.(historyCache)
+	if !ok {
+		return nil, fmt.Errorf("%w: the cache doesn't keep history", ErrHistoryUnavailable)
+	}
+
+	start, ok, err := hc.getHistoryStart()
+	if err != nil {
+		return nil, err
+	}
+	if !ok || block < start {
+		return nil, fmt.Errorf("%w: history begins at block %d", ErrHistoryUnavailable, start)
+	}
+	if highest := e.cache.getHighestBlock(); highest.Cmp(big.NewInt(0).SetUint64(block)) < 0 {
+		return nil, fmt.Errorf("%w: block %d is after the last processed block %d", ErrHistoryUnavailable, block, highest.Uint64())
+	}
+
+	nodeAddr, err := hc.getMinipoolNodeAt(pubkey, block)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); !ok {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	nodeInfo, err := hc.getNodeInfoAt(nodeAddr, block)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); !ok {
+			return nil, err
+		}
+
+		e.m.Counter("history_inconsistent").Inc()
+		return nil, fmt.Errorf("node %s not found in history at block %d despite pubkey %s being present",
+			nodeAddr.String(), block, pubkey.String())
+	}
+
+	// The grace period is measured from the block's time rather than the current time
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+	header, err := e.getClient().HeaderByNumber(ctx, big.NewInt(0).SetUint64(block))
+	if err != nil {
+		return nil, err
+	}
+
+	e.m.Counter("history_lookup").Inc()
+	return newRPInfo(nodeAddr, nodeInfo, *e.smoothingPool.Address, e.SmoothingPoolGraceEpochs, time.Unix(int64(header.Time), 0)), nil
+}
+
+// Determines the fee recipients a node's validators may use at the given time
+func newRPInfo(nodeAddr common.Address, nodeInfo *nodeInfo, smoothingPool common.Address, graceEpochs uint64, now time.Time) *RPInfo {
+	expected := nodeInfo.feeDistributor
+	previous := smoothingPool
 	if nodeInfo.inSmoothingPool {
-		return &RPInfo{
-			ExpectedFeeRecipient: e.smoothingPool.Address,
-			NodeAddress:          nodeAddr,
-		}, nil
+		expected, previous = previous, expected
+	}
+
+	out := &RPInfo{
+		ExpectedFeeRecipient:    &expected,
+		NodeAddress:             nodeAddr,
+		InSmoothingPool:         nodeInfo.inSmoothingPool,
+		AcceptableFeeRecipients: []common.Address{expected},
+	}
+
+	if graceEpochs == 0 || nodeInfo.smoothingPoolChanged.IsZero() {
+		return out
 	}
 
-	return &RPInfo{
-		ExpectedFeeRecipient: &nodeInfo.feeDistributor,
-		NodeAddress:          nodeAddr,
-	}, nil
+	// A change stamped slightly ahead of now, eg by a block from a fast clock, has only just happened
+	gracePeriod := time.Duration(graceEpochs*slotsPerEpoch*secondsPerSlot) * time.Second
+	if max(now.Sub(nodeInfo.smoothingPoolChanged), 0) < gracePeriod {
+		out.AcceptableFeeRecipients = append(out.AcceptableFeeRecipients, previous)
+	}
+
+	return out
 }
 
 // REthAddress is a convenience function to get the rEth contract address
@@ -768,20 +1486,21 @@ func (e *CachingExecutionLayer) REthAddress() *common.Address {
 
 // EIP1271ABI is the ABI for the EIP-1271 isValidSignature function
 var eip1271ABI *abi.ABI
+var eip1271ABIOnce sync.Once
 
 // getEIP1271ABI returns the EIP1271ABI
 func getEIP1271ABI() *abi.ABI {
-	if eip1271ABI != nil {
-		return eip1271ABI
-	}
+	eip1271ABIOnce.Do(parseEIP1271ABI)
+	return eip1271ABI
+}
 
+func parseEIP1271ABI() {
 	const abiJSON = `[{"inputs":[{"name":"_hash","type":"bytes32"},{"name":"_signature","type":"bytes"}],"name":"isValidSignature","outputs":[{"type":"bytes4"}],"stateMutability":"view","type":"function"}]`
 	parsedABI, err := abi.JSON(strings.NewReader(abiJSON))
 	if err != nil {
 		panic(fmt.Sprintf("failed to parse EIP1271 ABI: %v", err))
 	}
 	eip1271ABI = &parsedABI
-	return eip1271ABI
 }
 
 var NoDataError = errors.New("no data were returned from the EVM, did you pass the correct smart contract wallet address?")
@@ -790,40 +1509,5 @@ var InternalError = errors.New("an internal error occurred, please contact the m
 
 // ValidateEIP1271 validates an EIP-1271 signature
 func (e *CachingExecutionLayer) ValidateEIP1271(ctx context.Context, dataHash common.Hash, signature []byte, address common.Address) (bool, error) {
-	parsedABI := getEIP1271ABI()
-
-	// Encode the function call
-	encodedData, err := parsedABI.Pack("isValidSignature", dataHash, signature)
-	if err != nil {
-		e.Logger.Warn("error packing isValidSignature call", zap.Error(err))
-		return false, InternalError
-	}
-
-	// Make the contract call
-	data, err := e.client.CallContract(ctx, ethereum.CallMsg{
-		To:   &address,
-		Data: encodedData,
-	}, nil)
-	if err != nil {
-		e.Logger.Warn("error querying the execution client to validate an EIP1271 signature", zap.Error(err))
-		return false, InternalError
-	}
-
-	if len(data) == 0 {
-		return false, NoDataError
-	}
-
-	// Trim the trailing 0-bytes from the evm
-	data = bytes.TrimRight(data, "\x00")
-
-	// Check the return value, it should be exactly 4 bytes long
-	if len(data) != 4 {
-		return false, BadDataError
-	}
-
-	// The expected return value for a valid signature is 0x1626ba7e
-	// bytes4(keccak256("isValidSignature(bytes32,bytes)")
-	// invalid signatures return 4 bytes that do not match the magic
-	expectedReturnValue := [4]byte{0x16, 0x26, 0xba, 0x7e}
-	return bytes.Equal(data, expectedReturnValue[:]), nil
+	return e.isValidSignature(ctx, dataHash, signature, address, nil)
 }
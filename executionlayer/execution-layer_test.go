@@ -3,10 +3,12 @@ package executionlayer
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	_ "embed"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"math/big"
 	"net/http"
@@ -15,16 +17,23 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
+	"github.com/alicebob/miniredis/v2"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/gorilla/websocket"
 	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 	"golang.org/x/exp/slices"
 )
@@ -82,9 +91,15 @@ type mockEC interface {
 }
 
 type elTest struct {
-	t  *testing.T
+	t  testing.TB
 	m  mockEC
 	ec *CachingExecutionLayer
+
+	// Skips logging every message, for benchmarks
+	quiet bool
+
+	// Open websocket connections, so tests can sever them
+	conns sync.Map
 }
 
 func (e *elTest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -93,6 +108,8 @@ func (e *elTest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		e.t.Fatal(err)
 	}
 	defer c.Close()
+	e.conns.Store(c, struct{}{})
+	defer e.conns.Delete(c)
 	for {
 		mt, data, err := c.ReadMessage()
 		if err != nil {
@@ -100,9 +117,17 @@ func (e *elTest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		e.t.Logf("mockEC recv: %d - %s\n", mt, string(data))
-		mt, data = e.m.Serve(mt, data)
-		e.t.Logf("mockEC resp: %d - %s\n", mt, string(data))
+		if !e.quiet {
+			e.t.Logf("mockEC recv: %d - %s\n", mt, string(data))
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+			mt, data = e.serveBatch(mt, data)
+		} else {
+			mt, data = e.m.Serve(mt, data)
+		}
+		if !e.quiet {
+			e.t.Logf("mockEC resp: %d - %s\n", mt, string(data))
+		}
 
 		err = c.WriteMessage(mt, data)
 		if err != nil {
@@ -112,7 +137,45 @@ func (e *elTest) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func setup(t *testing.T, m mockEC) *elTest {
+// Serves each message of a JSON-RPC batch, and returns their responses as a batch
+func (e *elTest) serveBatch(mt int, data []byte) (int, []byte) {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		e.t.Fatal(err)
+	}
+
+	resps := make([]json.RawMessage, 0, len(batch))
+	for _, msg := range batch {
+		_, resp := e.m.Serve(mt, msg)
+		resps = append(resps, resp)
+	}
+
+	out, err := json.Marshal(resps)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return mt, out
+}
+
+// Simulates the execution client going away
+func (e *elTest) disconnect() {
+	e.conns.Range(func(k, v any) bool {
+		_ = k.(*websocket.Conn).Close()
+		return true
+	})
+}
+
+func mockECURL(t testing.TB, s *httptest.Server) *url.URL {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replace scheme
+	u.Scheme = "ws"
+	return u
+}
+
+func setup(t testing.TB, m mockEC) *elTest {
 	_, err := metrics.Init("cc_test_" + t.Name())
 	if err != nil {
 		t.Fatal(err)
@@ -125,15 +188,9 @@ func setup(t *testing.T, m mockEC) *elTest {
 	}
 
 	s := httptest.NewServer(out)
-	url, err := url.Parse(s.URL)
-	// replace scheme
-	url.Scheme = "ws"
-	if err != nil {
-		t.Fatal(err)
-	}
 	out.ec = &CachingExecutionLayer{
 		Logger:            zaptest.NewLogger(t),
-		ECURL:             url,
+		ECURLs:            []*url.URL{mockECURL(t, s)},
 		RocketStorageAddr: rocketStorage,
 	}
 	t.Cleanup(s.Close)
@@ -158,10 +215,32 @@ type mockNode struct {
 	inSP        bool
 	minipools   int
 	minipoolMap map[rptypes.ValidatorPubkey]interface{}
+
+	// The node address is used unless a withdrawal address is set
+	withdrawalAddress    common.Address
+	rplWithdrawalAddress common.Address
+}
+
+func (e *happyEC) findNode(addr common.Address) *mockNode {
+	for _, n := range e.nodes {
+		if n.addr == addr {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func (n *mockNode) getWithdrawalAddress() common.Address {
+	if n.withdrawalAddress == (common.Address{}) {
+		return n.addr
+	}
+
+	return n.withdrawalAddress
 }
 
 type happyEC struct {
-	t        *testing.T
+	t        testing.TB
 	nodes    []*mockNode
 	daoNodes []*mockNode
 }
@@ -201,6 +280,24 @@ func (e *happyEC) Serve(mt int, data []byte) (int, []byte) {
 		resp = fmt.Sprintf(blockByNumberFmt, m.ID, "0x11af2c8")
 	case "eth_subscribe", "eth_unsubscribe":
 		resp = fmt.Sprintf(callResultFmt, m.ID, "0x")
+	case "eth_chainId":
+		resp = fmt.Sprintf(callResultFmt, m.ID, "0x1")
+	case "eth_getCode":
+		var paramsArray []json.RawMessage
+		if err := json.Unmarshal(m.Params, &paramsArray); err != nil {
+			e.t.Fatal(err)
+		}
+
+		var addr common.Address
+		if err := json.Unmarshal(paramsArray[0], &addr); err != nil {
+			e.t.Fatal(err)
+		}
+
+		// Only multicall3 needs to look deployed
+		resp = fmt.Sprintf(callResultFmt, m.ID, "0x")
+		if addr == multicall3Address {
+			resp = fmt.Sprintf(callResultFmt, m.ID, "0x6080")
+		}
 	case "eth_getLogs":
 		resp = fmt.Sprintf(logFilterFmt, m.ID, backfillNode)
 	case "eth_call":
@@ -225,6 +322,8 @@ func (e *happyEC) Serve(mt int, data []byte) (int, []byte) {
 		}
 
 		switch callMsg.To.String() {
+		case multicall3Address.String():
+			resp = fmt.Sprintf(callResultFmt, m.ID, e.serveMulticall(m.ID, callMsg.Data))
 		case rocketStorage:
 			selector := callMsg.Data[:10]
 			input := callMsg.Data[10:]
@@ -284,6 +383,13 @@ func (e *happyEC) Serve(mt int, data []byte) (int, []byte) {
 				default:
 					e.t.Log("Unhandled GetString", input)
 				}
+			// GetNodeWithdrawalAddress(address)
+			case "0x5b49ff62":
+				if n := e.findNode(common.HexToAddress(input)); n != nil {
+					resp = fmt.Sprintf(callResultFmt, m.ID, common.BytesToHash(n.getWithdrawalAddress().Bytes()).String())
+				} else {
+					resp = fmt.Sprintf(callResultFmt, m.ID, common.BytesToHash(common.HexToAddress(input).Bytes()).String())
+				}
 			default:
 				e.t.Log("Unhandled rocketStorage selector", selector)
 			}
@@ -325,6 +431,32 @@ func (e *happyEC) Serve(mt int, data []byte) (int, []byte) {
 					}
 					break
 				}
+			// GetNodeExists(address)
+			case "0x65d4176f":
+				addr := common.HexToAddress(input)
+				resp = fmt.Sprintf(callResultFmt, m.ID, intToHex(0))
+				for _, n := range e.nodes {
+					if n.addr == addr {
+						resp = fmt.Sprintf(callResultFmt, m.ID, intToHex(1))
+						break
+					}
+				}
+
+			// GetNodeRPLWithdrawalAddressIsSet(address)
+			case "0xe667d828":
+				resp = fmt.Sprintf(callResultFmt, m.ID, intToHex(0))
+				if n := e.findNode(common.HexToAddress(input)); n != nil && n.rplWithdrawalAddress != (common.Address{}) {
+					resp = fmt.Sprintf(callResultFmt, m.ID, intToHex(1))
+				}
+			// GetNodeRPLWithdrawalAddress(address), which falls back to the withdrawal address
+			case "0xb71f0c7c":
+				if n := e.findNode(common.HexToAddress(input)); n != nil {
+					addr := n.getWithdrawalAddress()
+					if n.rplWithdrawalAddress != (common.Address{}) {
+						addr = n.rplWithdrawalAddress
+					}
+					resp = fmt.Sprintf(callResultFmt, m.ID, common.BytesToHash(addr.Bytes()).String())
+				}
 
 			default:
 				e.t.Log("Unhandled rocketNodeManager selector", selector)
@@ -413,6 +545,23 @@ func (e *happyEC) Serve(mt int, data []byte) (int, []byte) {
 				}
 
 				resp = fmt.Sprintf(callResultFmt, m.ID, fmt.Sprintf("0x%s", hex.EncodeToString(solBytes)))
+			// GetMinipoolByPubkey(bytes)
+			case "0xcf6a4763":
+				// The input is the offset and length of the bytes, followed by the pubkey
+				h, err := hex.DecodeString(input[128 : 128+96])
+				if err != nil {
+					e.t.Fatal(err)
+				}
+				pk := rptypes.BytesToValidatorPubkey(h)
+
+				// Return the zero address for unknown minipools, and the node address otherwise
+				resp = fmt.Sprintf(callResultFmt, m.ID, intToHex(0))
+				for _, n := range e.nodes {
+					if _, ok := n.minipoolMap[pk]; ok {
+						resp = fmt.Sprintf(callResultFmt, m.ID, "0x000000000000000000000000"+n.addr.String()[2:])
+						break
+					}
+				}
 
 			default:
 				e.t.Log("Unhandled rocketMinipoolManager selector", selector)
@@ -439,6 +588,16 @@ func (e *happyEC) Serve(mt int, data []byte) (int, []byte) {
 
 				n := e.daoNodes[int(i)]
 				resp = fmt.Sprintf(callResultFmt, m.ID, "0x000000000000000000000000"+n.addr.String()[2:])
+			// GetMemberIsValid(address)
+			case "0x5dc33bdd":
+				addr := common.HexToAddress(input)
+				resp = fmt.Sprintf(callResultFmt, m.ID, intToHex(0))
+				for _, n := range e.daoNodes {
+					if n.addr == addr {
+						resp = fmt.Sprintf(callResultFmt, m.ID, intToHex(1))
+						break
+					}
+				}
 			default:
 				e.t.Log("Unhandled rocketDAONodeTrusted selector", selector)
 			}
@@ -499,6 +658,66 @@ func (e *happyEC) Serve(mt int, data []byte) (int, []byte) {
 	return mt, []byte(resp)
 }
 
+// Serves each call in an aggregate3 call, and returns their packed results
+func (e *happyEC) serveMulticall(id json.RawMessage, data string) string {
+	parsed, err := abi.JSON(strings.NewReader(multicall3Abi))
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	input, err := hexutil.Decode(data)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	args, err := parsed.Methods["aggregate3"].Inputs.Unpack(input[4:])
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	calls := *abi.ConvertType(args[0], new([]multicall3Call)).(*[]multicall3Call)
+
+	results := make([]multicall3Result, len(calls))
+	for i, c := range calls {
+		params, err := json.Marshal([]interface{}{
+			call{To: c.Target, Data: hexutil.Encode(c.CallData)},
+			"latest",
+		})
+		if err != nil {
+			e.t.Fatal(err)
+		}
+
+		req, err := json.Marshal(jsonrpcMessage{ID: id, Method: "eth_call", Params: params})
+		if err != nil {
+			e.t.Fatal(err)
+		}
+
+		// Unhandled calls have empty responses, and fail
+		_, resp := e.Serve(websocket.TextMessage, req)
+		var respMsg jsonrpcMessage
+		if err := json.Unmarshal(resp, &respMsg); err != nil {
+			continue
+		}
+
+		var result string
+		if err := json.Unmarshal(respMsg.Result, &result); err != nil {
+			e.t.Fatal(err)
+		}
+
+		results[i].Success = true
+		results[i].ReturnData, err = hexutil.Decode(result)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+	}
+
+	out, err := parsed.Methods["aggregate3"].Outputs.Pack(results)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	return hexutil.Encode(out)
+}
+
 func TestELStartStop(t *testing.T) {
 	et := setup(t, &happyEC{t,
 		[]*mockNode{
@@ -597,7 +816,7 @@ func TestSQLELCache(t *testing.T) {
 
 	addr := common.HexToAddress("0x1234567891232222222212345678912345678900")
 	addr2 := common.HexToAddress("0x1234567891255555555555545678912345678900")
-	ni := nodeInfo{true, addr2}
+	ni := nodeInfo{inSmoothingPool: true, feeDistributor: addr2}
 
 	// Add a node to the cache
 	err = et.ec.cache.addNodeInfo(addr, &ni)
@@ -801,6 +1020,69 @@ func TestELGetRPInfoMissing(t *testing.T) {
 	}
 }
 
+func TestELGetNodeRPInfo(t *testing.T) {
+	et := setup(t, &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000002234567899876543210"),
+				inSP:      false,
+				minipools: 3,
+			},
+		},
+		[]*mockNode{},
+	})
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+
+	rpinfo, err := et.ec.GetNodeRPInfo(common.HexToAddress("0x0000000000000000000001234567899876543210"))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if rpinfo == nil || !rpinfo.InSmoothingPool || rpinfo.ExpectedFeeRecipient.String() != common.HexToAddress(rocketSmoothingPool).String() {
+		t.Fatal("unexpected rp info", rpinfo)
+	}
+
+	rpinfo, err = et.ec.GetNodeRPInfo(common.HexToAddress("0x0000000000000000000002234567899876543210"))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if rpinfo == nil || rpinfo.InSmoothingPool || rpinfo.ExpectedFeeRecipient.String() == common.HexToAddress(rocketSmoothingPool).String() {
+		t.Fatal("unexpected rp info", rpinfo)
+	}
+
+	rpinfo, err = et.ec.GetNodeRPInfo(common.HexToAddress("0x0000000000000000000003234567899876543210"))
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if rpinfo != nil {
+		t.Fatal("unexpected rp info", rpinfo)
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestELGetRETHAddress(t *testing.T) {
 	et := setup(t, &happyEC{t,
 		[]*mockNode{
@@ -1003,6 +1285,7 @@ func TestELODAOJoinLeave(t *testing.T) {
 			},
 		},
 	})
+	et.ec.NodeSet = nodeset.NewFeed(nodeset.DefaultHistory)
 
 	if err := et.ec.Init(); err != nil {
 		t.Fatal(err)
@@ -1019,6 +1302,21 @@ func TestELODAOJoinLeave(t *testing.T) {
 	// Wait for connection
 	<-et.ec.connected
 
+	sub, _, _, _ := et.ec.NodeSet.Subscribe(nil)
+	defer sub.Close()
+	expectChange := func(changeType nodeset.ChangeType, addr common.Address) {
+		t.Helper()
+
+		select {
+		case change := <-sub.Changes():
+			if change.Type != changeType || change.Address != addr {
+				t.Fatalf("expected %s %s, got %s %s", changeType, addr, change.Type, change.Address)
+			}
+		default:
+			t.Fatalf("expected %s %s to be published", changeType, addr)
+		}
+	}
+
 	// New odao node
 	a := common.HexToAddress("0x0f010f")
 
@@ -1051,6 +1349,7 @@ func TestELODAOJoinLeave(t *testing.T) {
 	if !found {
 		t.Fatalf("didn't find %s in active odao set", a.String())
 	}
+	expectChange(nodeset.OdaoJoined, a)
 
 	// Simulate odao left event
 	et.ec.handleEvent(types.Log{
@@ -1080,6 +1379,7 @@ func TestELODAOJoinLeave(t *testing.T) {
 	if found {
 		t.Fatalf("founnd %s in active odao set", a.String())
 	}
+	expectChange(nodeset.OdaoLeft, a)
 
 	// Finally, make sure we don't crash on unknown topics
 	et.ec.handleEvent(types.Log{
@@ -1121,6 +1421,7 @@ func TestELNodeEvents(t *testing.T) {
 			},
 		},
 	})
+	et.ec.NodeSet = nodeset.NewFeed(nodeset.DefaultHistory)
 
 	if err := et.ec.Init(); err != nil {
 		t.Fatal(err)
@@ -1137,6 +1438,9 @@ func TestELNodeEvents(t *testing.T) {
 	// Wait for connection
 	<-et.ec.connected
 
+	sub, _, _, _ := et.ec.NodeSet.Subscribe(nil)
+	defer sub.Close()
+
 	// New node
 	a := common.HexToAddress("0x0f010f")
 
@@ -1170,6 +1474,15 @@ func TestELNodeEvents(t *testing.T) {
 		t.Fatalf("didn't find %s in active node set", a.String())
 	}
 
+	select {
+	case change := <-sub.Changes():
+		if change.Type != nodeset.NodeRegistered || change.Address != a {
+			t.Fatalf("unexpected change %s %s", change.Type, change.Address)
+		}
+	default:
+		t.Fatal("expected the registration to be published")
+	}
+
 	// Add a minipool
 	minipoolAddr := common.HexToAddress("0x1f101f")
 	et.ec.handleEvent(types.Log{
@@ -1487,8 +1800,8 @@ func TestELHandleSubscriptionError(t *testing.T) {
 	}
 }
 
-func TestValidateEIP1271(t *testing.T) {
-	et := setup(t, &happyEC{t,
+func TestELFailover(t *testing.T) {
+	hec := &happyEC{t,
 		[]*mockNode{
 			&mockNode{
 				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
@@ -1503,12 +1816,34 @@ func TestValidateEIP1271(t *testing.T) {
 				minipools: 0,
 			},
 		},
-	})
+	}
+	et := setup(t, hec)
+
+	// An execution client that is down
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := mockECURL(t, down)
+	down.Close()
+
+	// And a backup to fail over to
+	backup := &elTest{t: t, m: hec}
+	backupServer := httptest.NewServer(backup)
+	t.Cleanup(backupServer.Close)
+
+	et.ec.ECURLs = []*url.URL{downURL, et.ec.ECURLs[0], mockECURL(t, backupServer)}
 
 	if err := et.ec.Init(); err != nil {
 		t.Fatal(err)
 	}
 
+	// The first healthy execution client should have been picked
+	if et.ec.endpoint != et.ec.endpoints.endpoints[1] {
+		t.Fatalf("expected to connect to %s, connected to %s", et.ec.endpoints.endpoints[1], et.ec.endpoint)
+	}
+
+	if et.ec.endpoints.healthy() != 2 {
+		t.Fatalf("expected 2 healthy execution clients, got %d", et.ec.endpoints.healthy())
+	}
+
 	errs := make(chan error)
 	go func() {
 		if err := et.ec.Start(); err != nil {
@@ -1520,62 +1855,1998 @@ func TestValidateEIP1271(t *testing.T) {
 	// Wait for connection
 	<-et.ec.connected
 
-	// Test cases
-	testCases := []struct {
-		name           string
-		dataHash       [32]byte
-		signature      []byte
-		address        common.Address
-		expectedResult bool
-		expectedError  bool
-	}{
-		{
-			name:           "Valid signature",
-			dataHash:       [32]byte{0x00},
-			signature:      common.FromHex(eip1271ValidSignature),
-			address:        common.HexToAddress(eip1271SmartContractValidSignerAddress),
-			expectedResult: true,
-			expectedError:  false,
-		},
-		{
-			name:           "Invalid signature",
-			dataHash:       [32]byte{0x00},
-			signature:      common.FromHex(eip1271InvalidSignature),
-			address:        common.HexToAddress(eip1271SmartContractValidSignerAddress),
-			expectedResult: false,
-			expectedError:  false,
-		},
-		{
-			name:           "Invalid contract",
-			dataHash:       [32]byte{0x00},
-			signature:      common.FromHex(eip1271InvalidSignature),
-			address:        common.HexToAddress(eip1271SmartContractInvalidSignerAddress),
-			expectedResult: false,
-			expectedError:  true,
-		},
-	}
+	// Take the primary down, and make sure we fail over to the backup
+	et.disconnect()
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		et.ec.clientLock.RLock()
+		endpoint := et.ec.endpoint
+		et.ec.clientLock.RUnlock()
 
-			result, err := et.ec.ValidateEIP1271(ctx, tc.dataHash, tc.signature, tc.address)
+		if endpoint == et.ec.endpoints.endpoints[2] {
+			break
+		}
 
-			if tc.expectedError {
-				if err == nil {
-					t.Errorf("Expected an error, but got nil")
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Unexpected error: %v", err)
-				}
+		if time.Now().After(deadline) {
+			t.Fatalf("didn't fail over to the backup execution client, still on %s", endpoint)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
 
-				if result != tc.expectedResult {
-					t.Errorf("Expected result %v, but got %v", tc.expectedResult, result)
-				}
+	// The cache should still be usable
+	found := 0
+	for _, n := range hec.nodes {
+		for pk := range n.minipoolMap {
+			ri, err := et.ec.GetRPInfo(pk)
+			if err != nil {
+				t.Fatal(err)
 			}
-		})
+			found++
+
+			if ri.NodeAddress.String() != n.addr.String() {
+				t.Fatal("Mismatched node addresses")
+			}
+		}
+	}
+
+	if found == 0 {
+		t.Fatal("Didn't find any cached data")
+	}
+
+	et.ec.Stop()
+	err := <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestELFailback(t *testing.T) {
+	interval := failbackInterval
+	failbackInterval = 50 * time.Millisecond
+	t.Cleanup(func() { failbackInterval = interval })
+
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+
+	// A primary execution client that can be taken down and brought back
+	var down atomic.Bool
+	primary := &elTest{t: t, m: hec}
+	primaryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.NotFound(w, r)
+			return
+		}
+		primary.ServeHTTP(w, r)
+	}))
+	t.Cleanup(primaryServer.Close)
+
+	// The execution layer's own mock is the backup
+	et.ec.ECURLs = []*url.URL{mockECURL(t, primaryServer), et.ec.ECURLs[0]}
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+
+	waitFor := func(want *ecEndpoint) {
+		t.Helper()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			et.ec.clientLock.RLock()
+			endpoint := et.ec.endpoint
+			et.ec.clientLock.RUnlock()
+
+			if endpoint == want {
+				return
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("expected to be connected to %s, still on %s", want, endpoint)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Take the primary down, and make sure we fail over to the backup
+	down.Store(true)
+	primary.disconnect()
+	waitFor(et.ec.endpoints.endpoints[1])
+
+	// Once the primary recovers, we should switch back to it
+	down.Store(false)
+	waitFor(et.ec.endpoints.endpoints[0])
+
+	if et.ec.endpoints.healthy() != 2 {
+		t.Fatalf("expected 2 healthy execution clients, got %d", et.ec.endpoints.healthy())
+	}
+
+	// The cache should still be usable
+	for pk := range hec.nodes[0].minipoolMap {
+		ri, err := et.ec.GetRPInfo(pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ri.NodeAddress.String() != hec.nodes[0].addr.String() {
+			t.Fatal("Mismatched node addresses")
+		}
+	}
+
+	et.ec.Stop()
+	err := <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// holeskyEC serves the same chain as happyEC, but reports the holesky chain ID
+type holeskyEC struct {
+	*happyEC
+}
+
+func (e *holeskyEC) Serve(mt int, data []byte) (int, []byte) {
+	m := jsonrpcMessage{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		e.t.Fatal(err)
+	}
+
+	if m.Method == "eth_chainId" {
+		return mt, []byte(fmt.Sprintf(callResultFmt, m.ID, "0x4268"))
+	}
+
+	return e.happyEC.Serve(mt, data)
+}
+
+func TestELChainID(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+	et.ec.ChainID = 1
+
+	// An execution client on the wrong chain is skipped, like one that is down
+	wrongChain := httptest.NewServer(&elTest{t: t, m: &holeskyEC{hec}})
+	t.Cleanup(wrongChain.Close)
+	et.ec.ECURLs = []*url.URL{mockECURL(t, wrongChain), et.ec.ECURLs[0]}
+
+	errs := startEL(t, et)
+
+	if et.ec.endpoint != et.ec.endpoints.endpoints[1] {
+		t.Fatalf("expected to connect to %s, connected to %s", et.ec.endpoints.endpoints[1], et.ec.endpoint)
+	}
+	if et.ec.endpoints.healthy() != 1 {
+		t.Fatalf("expected 1 healthy execution client, got %d", et.ec.endpoints.healthy())
+	}
+
+	et.ec.Stop()
+	err := <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestELWrongChainID(t *testing.T) {
+	et := setup(t, &holeskyEC{&happyEC{t, []*mockNode{}, []*mockNode{}}})
+	et.ec.ChainID = 1
+
+	err := et.ec.Init()
+	if err == nil || !strings.Contains(err.Error(), "expected 1") {
+		t.Fatalf("expected Init to refuse an execution client on the wrong chain, got %v", err)
+	}
+}
+
+func TestValidateEIP1271(t *testing.T) {
+	et := setup(t, &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000222222222222222222222222222222"),
+				inSP:      false,
+				minipools: 0,
+			},
+		},
+	})
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		dataHash       [32]byte
+		signature      []byte
+		address        common.Address
+		expectedResult bool
+		expectedError  bool
+	}{
+		{
+			name:           "Valid signature",
+			dataHash:       [32]byte{0x00},
+			signature:      common.FromHex(eip1271ValidSignature),
+			address:        common.HexToAddress(eip1271SmartContractValidSignerAddress),
+			expectedResult: true,
+			expectedError:  false,
+		},
+		{
+			name:           "Invalid signature",
+			dataHash:       [32]byte{0x00},
+			signature:      common.FromHex(eip1271InvalidSignature),
+			address:        common.HexToAddress(eip1271SmartContractValidSignerAddress),
+			expectedResult: false,
+			expectedError:  false,
+		},
+		{
+			name:           "Invalid contract",
+			dataHash:       [32]byte{0x00},
+			signature:      common.FromHex(eip1271InvalidSignature),
+			address:        common.HexToAddress(eip1271SmartContractInvalidSignerAddress),
+			expectedResult: false,
+			expectedError:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			result, err := et.ec.ValidateEIP1271(ctx, tc.dataHash, tc.signature, tc.address)
+
+			if tc.expectedError {
+				if err == nil {
+					t.Errorf("Expected an error, but got nil")
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+
+				if result != tc.expectedResult {
+					t.Errorf("Expected result %v, but got %v", tc.expectedResult, result)
+				}
+			}
+		})
+	}
+
+	et.ec.Stop()
+	err := <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reorgEC serves a different chain from happyEC once reorged is set
+type reorgEC struct {
+	*happyEC
+	reorged atomic.Bool
+}
+
+func (e *reorgEC) Serve(mt int, data []byte) (int, []byte) {
+	mt, resp := e.happyEC.Serve(mt, data)
+	if e.reorged.Load() {
+		// Give every block a different parent, and therefore a different hash
+		resp = bytes.ReplaceAll(resp,
+			[]byte("0xaebf5ad31c37d7014e8432038faeb837e5f0415798a83dc1efa7109cdfbd71a4"),
+			[]byte("0x0000000000000000000000000000000000000000000000000000000000000001"))
+	}
+	return mt, resp
+}
+
+// Logs in a block each have their own index, so the journal doesn't mistake one for another
+var testLogIndex atomic.Uint32
+
+func nextLogIndex() uint {
+	return uint(testLogIndex.Add(1))
+}
+
+func nodeRegisteredLog(et *elTest, addr common.Address, header *types.Header) types.Log {
+	return types.Log{
+		Address: common.HexToAddress(rocketNodeManager),
+		Topics: []common.Hash{
+			common.BytesToHash(
+				et.ec.nodeRegisteredTopic.Bytes(),
+			),
+			common.BytesToHash(
+				addr.Bytes(),
+			),
+		},
+		BlockNumber: header.Number.Uint64(),
+		BlockHash:   header.Hash(),
+		Index:       nextLogIndex(),
+	}
+}
+
+func hasNode(t *testing.T, et *elTest, addr common.Address) bool {
+	found := false
+	err := et.ec.ForEachNode(func(n common.Address) bool {
+		if n == addr {
+			found = true
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return found
+}
+
+// Configure the execution layer to use each kind of cache, for tests that should pass with all of them
+
+func useMapsCache(t *testing.T) func(*CachingExecutionLayer) {
+	return func(*CachingExecutionLayer) {}
+}
+
+func useSqliteCache(t *testing.T) func(*CachingExecutionLayer) {
+	cachePath := t.TempDir()
+	return func(ec *CachingExecutionLayer) {
+		ec.CachePath = cachePath
+	}
+}
+
+func useKVCache(t *testing.T) func(*CachingExecutionLayer) {
+	store := miniredis.RunT(t)
+	return func(ec *CachingExecutionLayer) {
+		ec.CacheKVURL = kvURL(store)
+		ec.CacheKVPrefix = t.Name()
+	}
+}
+
+func testELReorg(t *testing.T, useCache func(*CachingExecutionLayer)) {
+	rec := &reorgEC{
+		happyEC: &happyEC{t,
+			[]*mockNode{
+				&mockNode{
+					addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+					inSP:      true,
+					minipools: 1,
+				},
+			},
+			[]*mockNode{},
+		},
+	}
+	et := setup(t, rec)
+	useCache(et.ec)
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+
+	head, err := et.ec.getClient().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new node registers and launches a minipool in the head block
+	a := common.HexToAddress("0x0f010f")
+	registered := nodeRegisteredLog(et, a, head)
+	et.ec.handleEvent(registered)
+
+	minipoolAddr := common.HexToAddress("0x1f101f")
+	et.ec.handleEvent(types.Log{
+		Address: common.HexToAddress(rocketMinipoolManager),
+		Topics: []common.Hash{
+			common.BytesToHash(
+				et.ec.minipoolLaunchedTopic.Bytes(),
+			),
+			common.BytesToHash(
+				minipoolAddr.Bytes(),
+			),
+			common.BytesToHash(
+				a.Bytes(),
+			),
+		},
+		BlockNumber: head.Number.Uint64(),
+		BlockHash:   head.Hash(),
+		Index:       nextLogIndex(),
+	})
+	h, err := hex.DecodeString(pubkeyFromMinipool(minipoolAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey := rptypes.BytesToValidatorPubkey(h)
+
+	rpinfo, err := et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpinfo == nil || rpinfo.NodeAddress != a {
+		t.Fatalf("expected minipool %s to belong to %s, got %v", pubkey.String(), a.String(), rpinfo)
+	}
+
+	// A log that was already applied, for instance by a backfill, isn't applied again
+	et.ec.handleEvent(registered)
+	events, err := et.ec.cache.getProcessedEvents(head.Number.Uint64())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 processed events, got %d", len(events))
+	}
+
+	// The head block is reorganized out, and the execution client retracts its logs
+	rec.reorged.Store(true)
+	registered.Removed = true
+	et.ec.handleEvent(registered)
+
+	rpinfo, err = et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpinfo != nil {
+		t.Fatalf("expected minipool %s to be rolled back, got %v", pubkey.String(), rpinfo)
+	}
+	if hasNode(t, et, a) {
+		t.Fatalf("expected node %s to be rolled back", a.String())
+	}
+
+	// Nodes registered on the new chain are unaffected
+	if !hasNode(t, et, common.HexToAddress(backfillNode)) {
+		t.Fatal("expected backfilled node to remain")
+	}
+
+	// Next, a node registers in a block that the following header doesn't build on
+	head, err = et.ec.getClient().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	et.ec.handleNewHeader(head)
+
+	b := common.HexToAddress("0x0f020f")
+	et.ec.handleEvent(nodeRegisteredLog(et, b, head))
+	if !hasNode(t, et, b) {
+		t.Fatalf("didn't find %s in active node set", b.String())
+	}
+
+	rec.reorged.Store(false)
+	et.ec.handleNewHeader(&types.Header{
+		Number:     big.NewInt(0).Add(head.Number, big.NewInt(1)),
+		ParentHash: common.HexToHash("0x02"),
+	})
+	if hasNode(t, et, b) {
+		t.Fatalf("expected node %s to be rolled back", b.String())
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestELReorg(t *testing.T) {
+	testELReorg(t, useMapsCache(t))
+}
+
+func TestSQLELReorg(t *testing.T) {
+	testELReorg(t, useSqliteCache(t))
+}
+
+func TestKVELReorg(t *testing.T) {
+	testELReorg(t, useKVCache(t))
+}
+
+// rangeLimitedEC refuses log queries spanning more than maxRange blocks, like many hosted execution clients.
+// If maxRefusals is set, it stops refusing after that many, like a client that was briefly overloaded.
+type rangeLimitedEC struct {
+	*happyEC
+	maxRange    uint64
+	maxRefusals int32
+	queries     atomic.Int32
+	refusals    atomic.Int32
+}
+
+func (e *rangeLimitedEC) Serve(mt int, data []byte) (int, []byte) {
+	m := jsonrpcMessage{}
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	if m.Method == "eth_getLogs" {
+		var params []struct {
+			FromBlock string `json:"fromBlock"`
+			ToBlock   string `json:"toBlock"`
+		}
+		err := json.Unmarshal(m.Params, &params)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+
+		from, err := hexutil.DecodeUint64(params[0].FromBlock)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		to, err := hexutil.DecodeUint64(params[0].ToBlock)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+
+		refusing := e.maxRefusals == 0 || e.refusals.Load() < e.maxRefusals
+		if to-from+1 > e.maxRange && refusing {
+			e.refusals.Add(1)
+			return mt, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32005,"message":"block range too large"}}`, m.ID))
+		}
+		e.queries.Add(1)
+	}
+
+	return e.happyEC.Serve(mt, data)
+}
+
+func TestSQLELCatchUp(t *testing.T) {
+	rec := &rangeLimitedEC{
+		happyEC: &happyEC{t,
+			[]*mockNode{
+				&mockNode{
+					addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+					inSP:      true,
+					minipools: 1,
+				},
+			},
+			[]*mockNode{},
+		},
+		maxRange: 500,
+	}
+	et := setup(t, rec)
+	cachePath := t.TempDir()
+
+	// Leave behind a snapshot from long before the current block
+	const head = 0x11af2c8
+	const behind = 5000
+	seedNode := common.HexToAddress("0x0f030f")
+	snapshot := &SqliteCache{Path: cachePath}
+	if err := snapshot.init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshot.addNodeInfo(seedNode, &nodeInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	snapshot.setHighestBlock(big.NewInt(head - behind))
+	if err := snapshot.deinit(); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics.Deinit()
+
+	et = setup(t, rec)
+	et.ec.CachePath = cachePath
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot should have been kept, rather than reset and warmed up again
+	if !hasNode(t, et, seedNode) {
+		t.Fatal("expected the snapshot to be loaded")
+	}
+	if et.ec.cache.getHighestBlock().Int64() != head-behind {
+		t.Fatalf("expected the snapshot to be at block %d, got %d", head-behind, et.ec.cache.getHighestBlock().Int64())
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection, which happens after backfilling
+	<-et.ec.connected
+
+	if et.ec.cache.getHighestBlock().Int64() < head {
+		t.Fatalf("expected the cache to catch up to block %d, got %d", head, et.ec.cache.getHighestBlock().Int64())
+	}
+
+	if rec.queries.Load() < behind/500 {
+		t.Fatalf("expected the backfill to be chunked, but only %d queries were made", rec.queries.Load())
+	}
+
+	if et.ec.backfillChunk > 1000 {
+		t.Fatalf("expected the backfill chunk size to adapt, got %d", et.ec.backfillChunk)
+	}
+
+	if !hasNode(t, et, common.HexToAddress(backfillNode)) {
+		t.Fatal("expected backfilled node to be present")
+	}
+
+	et.ec.Stop()
+	err := <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestELBackfillRegrow(t *testing.T) {
+	rec := &rangeLimitedEC{
+		happyEC: &happyEC{t,
+			[]*mockNode{
+				&mockNode{
+					addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+					inSP:      true,
+					minipools: 1,
+				},
+			},
+			[]*mockNode{},
+		},
+		maxRange:    500,
+		maxRefusals: 1,
+	}
+	et := setup(t, rec)
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+
+	// Backfill a long range, which is briefly refused at the initial chunk size
+	const head = 0x11af2c8
+	et.ec.eventsLock.Lock()
+	et.ec.backfillChunk = initialBackfillChunk
+	err := et.ec.backfillEventsFrom(big.NewInt(head - 20000))
+	et.ec.eventsLock.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.refusals.Load() != 1 {
+		t.Fatalf("expected 1 refused query, got %d", rec.refusals.Load())
+	}
+
+	// Once the smaller chunks succeeded, the chunk size should have grown back
+	if et.ec.backfillChunk <= initialBackfillChunk {
+		t.Fatalf("expected the backfill chunk size to grow back, got %d", et.ec.backfillChunk)
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// callBlockEC remembers the block the last contract call read the state at
+type callBlockEC struct {
+	*happyEC
+	lastCallBlock atomic.Value
+}
+
+func (e *callBlockEC) Serve(mt int, data []byte) (int, []byte) {
+	m := jsonrpcMessage{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		e.t.Fatal(err)
+	}
+
+	if m.Method == "eth_call" {
+		var params []json.RawMessage
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			e.t.Fatal(err)
+		}
+
+		var block string
+		if len(params) > 1 && json.Unmarshal(params[1], &block) == nil {
+			e.lastCallBlock.Store(block)
+		}
+	}
+
+	return e.happyEC.Serve(mt, data)
+}
+
+func TestELReconcile(t *testing.T) {
+	hec := &callBlockEC{happyEC: &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000002234567899876543210"),
+				inSP:      false,
+				minipools: 3,
+			},
+		},
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000222222222222222222222222222222"),
+				inSP:      false,
+				minipools: 0,
+			},
+		},
+	}}
+	et := setup(t, hec)
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+
+	// ODAO events must be subscribed to and backfilled along with the others
+	if !slices.Contains(et.ec.query.Addresses, common.HexToAddress(rocketDAONodeTrustedActions)) {
+		t.Fatal("expected rocketDAONodeTrustedActions events to be filtered for")
+	}
+
+	// Let the cache drift from the chain state
+	spNode := hec.nodes[0].addr
+	err := et.ec.cache.addNodeInfo(spNode, &nodeInfo{inSmoothingPool: false})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bogusNode := common.HexToAddress("0x0f040f")
+	err = et.ec.cache.addNodeInfo(bogusNode, &nodeInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var missingMinipool rptypes.ValidatorPubkey
+	for pk := range hec.nodes[1].minipoolMap {
+		missingMinipool = pk
+		break
+	}
+	err = et.ec.cache.removeMinipoolNode(missingMinipool)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bogusMinipool := rptypes.BytesToValidatorPubkey(bytes.Repeat([]byte{0xab}, 48))
+	err = et.ec.cache.addMinipoolNode(bogusMinipool, spNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = et.ec.cache.removeOdaoNode(hec.daoNodes[0].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bogusOdaoNode := common.HexToAddress("0x0f050f")
+	err = et.ec.cache.addOdaoNode(bogusOdaoNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A node registered after the block the reconciler reads should be left alone
+	head, err := et.ec.getClient().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newNode := common.HexToAddress("0x0f060f")
+	et.ec.handleEvent(nodeRegisteredLog(et, newNode, &types.Header{
+		Number: big.NewInt(0).Add(head.Number, big.NewInt(1)),
+	}))
+
+	// Ingestion may fall behind the chain, eg while backfilling, and the reconciler mustn't read
+	// ahead of it, or it would correct entries whose events are still to be applied
+	et.ec.eventsLock.Lock()
+	et.ec.setAppliedBlock(head.Number.Uint64() - 1)
+	et.ec.eventsLock.Unlock()
+
+	if err := et.ec.reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	if block := hec.lastCallBlock.Load(); block != hexutil.EncodeUint64(head.Number.Uint64()-1) {
+		t.Fatalf("expected the chain state to be read at the last applied block, got %v", block)
+	}
+
+	ni, err := et.ec.cache.getNodeInfo(spNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ni.inSmoothingPool {
+		t.Fatal("expected smoothing pool status to be corrected")
+	}
+
+	if hasNode(t, et, bogusNode) {
+		t.Fatal("expected unregistered node to be removed")
+	}
+
+	if !hasNode(t, et, newNode) {
+		t.Fatal("expected newly registered node to be kept")
+	}
+
+	nodeAddr, err := et.ec.cache.getMinipoolNode(missingMinipool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodeAddr != hec.nodes[1].addr {
+		t.Fatalf("expected missing minipool to be restored to %s, got %s", hec.nodes[1].addr.String(), nodeAddr.String())
+	}
+
+	_, err = et.ec.cache.getMinipoolNode(bogusMinipool)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Fatalf("expected nonexistent minipool to be removed, got %v", err)
+	}
+
+	odaoNodes := make([]common.Address, 0)
+	err = et.ec.ForEachOdaoNode(func(addr common.Address) bool {
+		odaoNodes = append(odaoNodes, addr)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(odaoNodes) != 1 || odaoNodes[0] != hec.daoNodes[0].addr {
+		t.Fatalf("expected odao membership to be corrected, got %v", odaoNodes)
+	}
+
+	// The chain may advance further than the reorg depth while the reconciler reads, and the events
+	// it compares against must be kept meanwhile
+	et.ec.journalPinned.Store(head.Number.Uint64() + 1)
+	et.ec.handleNewHeader(&types.Header{
+		Number: big.NewInt(0).Add(head.Number, big.NewInt(2*maxReorgDepth)),
+	})
+	et.ec.journalPinned.Store(0)
+	events, err := et.ec.cache.getProcessedEvents(head.Number.Uint64() + 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the processed event after the reconciled block to be kept, got %d", len(events))
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testELSPGracePeriod(t *testing.T, useCache func(*CachingExecutionLayer)) {
+	et := setup(t, &happyEC{t, []*mockNode{}, []*mockNode{}})
+	useCache(et.ec)
+	et.ec.SmoothingPoolGraceEpochs = 4
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+
+	head, err := et.ec.getClient().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new node registers and launches a minipool
+	a := common.HexToAddress("0x0f010f")
+	et.ec.handleEvent(nodeRegisteredLog(et, a, head))
+
+	minipoolAddr := common.HexToAddress("0x1f101f")
+	et.ec.handleEvent(types.Log{
+		Address: common.HexToAddress(rocketMinipoolManager),
+		Topics: []common.Hash{
+			common.BytesToHash(
+				et.ec.minipoolLaunchedTopic.Bytes(),
+			),
+			common.BytesToHash(
+				minipoolAddr.Bytes(),
+			),
+			common.BytesToHash(
+				a.Bytes(),
+			),
+		},
+	})
+	h, err := hex.DecodeString(pubkeyFromMinipool(minipoolAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey := rptypes.BytesToValidatorPubkey(h)
+
+	rpinfo, err := et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rpinfo.AcceptableFeeRecipients) != 1 || rpinfo.AcceptableFeeRecipients[0] != *rpinfo.ExpectedFeeRecipient {
+		t.Fatalf("expected only the fee distributor to be acceptable, got %v", rpinfo.AcceptableFeeRecipients)
+	}
+	feeDistributor := *rpinfo.ExpectedFeeRecipient
+
+	// Opt into the SP in a block mined long before the grace period
+	spChanged := types.Log{
+		Address: common.HexToAddress(rocketNodeManager),
+		Topics: []common.Hash{
+			common.BytesToHash(
+				et.ec.smoothingPoolStatusChangedTopic.Bytes(),
+			),
+			common.BytesToHash(
+				a.Bytes(),
+			),
+		},
+		Data:        big.NewInt(1).Bytes(),
+		BlockNumber: head.Number.Uint64(),
+		BlockHash:   head.Hash(),
+		Index:       nextLogIndex(),
+	}
+	et.ec.handleEvent(spChanged)
+
+	n, err := et.ec.cache.getNodeInfo(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.smoothingPoolChanged.Equal(time.Unix(int64(head.Time), 0)) {
+		t.Fatalf("expected the status change to be recorded at the block time, got %v", n.smoothingPoolChanged)
+	}
+
+	rpinfo, err = et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpinfo.ExpectedFeeRecipient.String() != common.HexToAddress(rocketSmoothingPool).String() {
+		t.Fatal("Expected node to be in SP")
+	}
+	if rpinfo.AcceptsFeeRecipient(feeDistributor.String()) {
+		t.Fatal("expected the fee distributor to be rejected after the grace period")
+	}
+
+	// Pretend the change was recent
+	n.smoothingPoolChanged = time.Now()
+	if err := et.ec.cache.addNodeInfo(a, n); err != nil {
+		t.Fatal(err)
+	}
+
+	rpinfo, err = et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rpinfo.AcceptsFeeRecipient(strings.ToLower(feeDistributor.String())) {
+		t.Fatalf("expected the fee distributor to be accepted during the grace period, got %v", rpinfo.AcceptableFeeRecipients)
+	}
+	if !rpinfo.AcceptsFeeRecipient(common.HexToAddress(rocketSmoothingPool).String()) {
+		t.Fatalf("expected the smoothing pool to be accepted, got %v", rpinfo.AcceptableFeeRecipients)
+	}
+
+	// Replaying the same status doesn't restart the grace period from the old block
+	et.ec.handleEvent(spChanged)
+	rpinfo, err = et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rpinfo.AcceptsFeeRecipient(feeDistributor.String()) {
+		t.Fatal("expected the grace period to survive a repeated status event")
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewRPInfoGracePeriod(t *testing.T) {
+	nodeAddr := common.HexToAddress("0x0f010f")
+	feeDistributor := common.HexToAddress("0x0f020f")
+	smoothingPool := common.HexToAddress(rocketSmoothingPool)
+	now := time.Unix(1700000000, 0)
+	epoch := time.Duration(slotsPerEpoch*secondsPerSlot) * time.Second
+
+	for _, tc := range []struct {
+		name        string
+		graceEpochs uint64
+		changed     time.Time
+		grace       bool
+	}{
+		{"recent change", 4, now.Add(-epoch), true},
+		{"old change", 4, now.Add(-5 * epoch), false},
+		{"unknown change", 4, time.Time{}, false},
+		{"change ahead of now", 4, now.Add(time.Minute), true},
+		{"no grace period", 0, now.Add(-epoch), false},
+		{"no grace period for a change ahead of now", 0, now.Add(time.Minute), false},
+	} {
+		n := &nodeInfo{
+			inSmoothingPool:      true,
+			feeDistributor:       feeDistributor,
+			smoothingPoolChanged: tc.changed,
+		}
+		rpinfo := newRPInfo(nodeAddr, n, smoothingPool, tc.graceEpochs, now)
+		if *rpinfo.ExpectedFeeRecipient != smoothingPool {
+			t.Fatalf("%s: expected the smoothing pool, got %s", tc.name, rpinfo.ExpectedFeeRecipient.String())
+		}
+		if grace := rpinfo.AcceptsFeeRecipient(feeDistributor.String()); grace != tc.grace {
+			t.Fatalf("%s: expected the fee distributor to be accepted: %v, got %v", tc.name, tc.grace, rpinfo.AcceptableFeeRecipients)
+		}
+	}
+}
+
+func TestELRefreshUncachedNode(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(et.ec.Stop)
+
+	// A node the cache doesn't know of is restored by a rollback
+	addr := hec.nodes[0].addr
+	if err := et.ec.cache.removeNodeInfo(addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := et.ec.refreshNode(addr, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := et.ec.cache.getNodeInfo(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.inSmoothingPool {
+		t.Fatal("expected the node to be in the smoothing pool")
+	}
+
+	// It had no previous fee recipient, so it's owed no grace period
+	if !n.smoothingPoolChanged.IsZero() {
+		t.Fatalf("expected no smoothing pool change, got %s", n.smoothingPoolChanged)
+	}
+}
+
+func TestELSPGracePeriod(t *testing.T) {
+	testELSPGracePeriod(t, useMapsCache(t))
+}
+
+func TestSQLELSPGracePeriod(t *testing.T) {
+	testELSPGracePeriod(t, useSqliteCache(t))
+}
+
+func TestKVELSPGracePeriod(t *testing.T) {
+	testELSPGracePeriod(t, useKVCache(t))
+}
+
+// headerEC counts requests for the headers of past blocks, and fails them for one block
+type headerEC struct {
+	*happyEC
+	failBlock uint64
+	requests  sync.Map
+}
+
+func (e *headerEC) Serve(mt int, data []byte) (int, []byte) {
+	m := jsonrpcMessage{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		e.t.Fatal(err)
+	}
+
+	if m.Method == "eth_getBlockByNumber" {
+		var params []json.RawMessage
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			e.t.Fatal(err)
+		}
+
+		var tag string
+		if err := json.Unmarshal(params[0], &tag); err != nil {
+			e.t.Fatal(err)
+		}
+		if number, err := hexutil.DecodeUint64(tag); err == nil {
+			count, _ := e.requests.LoadOrStore(number, new(atomic.Int32))
+			count.(*atomic.Int32).Add(1)
+			if number == e.failBlock {
+				return mt, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":-32000,"message":"header not found"}}`, m.ID))
+			}
+		}
+	}
+
+	return e.happyEC.Serve(mt, data)
+}
+
+func (e *headerEC) headerRequests(number uint64) int32 {
+	count, ok := e.requests.Load(number)
+	if !ok {
+		return 0
+	}
+	return count.(*atomic.Int32).Load()
+}
+
+func TestELBlockTimes(t *testing.T) {
+	hec := &headerEC{
+		happyEC: &happyEC{t,
+			[]*mockNode{
+				&mockNode{
+					addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+					inSP:      false,
+					minipools: 1,
+				},
+			},
+			[]*mockNode{},
+		},
+		failBlock: 200,
+	}
+	et := setup(t, hec)
+	et.ec.SmoothingPoolGraceEpochs = 4
+	errs := startEL(t, et)
+
+	addr := hec.nodes[0].addr
+	spChanged := func(block uint64, inSP int64) types.Log {
+		return types.Log{
+			Address: common.HexToAddress(rocketNodeManager),
+			Topics: []common.Hash{
+				common.BytesToHash(et.ec.smoothingPoolStatusChangedTopic.Bytes()),
+				common.BytesToHash(addr.Bytes()),
+			},
+			Data:        big.NewInt(inSP).Bytes(),
+			BlockNumber: block,
+			Index:       nextLogIndex(),
+		}
+	}
+
+	// Several events in a block fetch its header once
+	et.ec.handleEvent(spChanged(100, 1))
+	et.ec.handleEvent(spChanged(100, 0))
+	et.ec.handleEvent(spChanged(100, 1))
+	if requests := hec.headerRequests(100); requests != 1 {
+		t.Fatalf("expected the header to be fetched once, got %d requests", requests)
+	}
+	n, err := et.ec.cache.getNodeInfo(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.smoothingPoolChanged.IsZero() {
+		t.Fatal("expected the status change to be recorded at the block time")
+	}
+
+	// When the block's time can't be read, there's no grace period rather than one starting now
+	et.ec.handleEvent(spChanged(200, 0))
+	n, err = et.ec.cache.getNodeInfo(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.inSmoothingPool {
+		t.Fatal("expected the node to leave the smoothing pool")
+	}
+	if !n.smoothingPoolChanged.IsZero() {
+		t.Fatalf("expected no smoothing pool change time, got %v", n.smoothingPoolChanged)
+	}
+	rpinfo := newRPInfo(addr, n, common.HexToAddress(rocketSmoothingPool), et.ec.SmoothingPoolGraceEpochs, time.Now())
+	if len(rpinfo.AcceptableFeeRecipients) != 1 {
+		t.Fatalf("expected no grace period, got %v", rpinfo.AcceptableFeeRecipients)
+	}
+
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testELGetNodeDetails(t *testing.T, useCache func(*CachingExecutionLayer)) {
+	odaoNode := &mockNode{
+		addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+		inSP:      true,
+		minipools: 2,
+	}
+	other := &mockNode{
+		addr:      common.HexToAddress("0x0000000000000000000002234567899876543210"),
+		inSP:      false,
+		minipools: 3,
+	}
+	et := setup(t, &happyEC{t, []*mockNode{odaoNode, other}, []*mockNode{odaoNode}})
+	useCache(et.ec)
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+
+	details, err := et.ec.GetNodeDetails(odaoNode.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details == nil || !details.InSmoothingPool || !details.OdaoMember || len(details.Minipools) != 2 {
+		t.Fatalf("unexpected node details %+v", details)
+	}
+	if details.FeeDistributor == (common.Address{}) {
+		t.Fatal("expected a fee distributor")
+	}
+	// Warming up the cache doesn't tell us when anything changed
+	if !details.MinipoolsChanged.IsZero() || !details.OdaoChanged.IsZero() || !details.FeeDistributorChanged.IsZero() {
+		t.Fatalf("unexpected change times %+v", details)
+	}
+
+	details, err = et.ec.GetNodeDetails(other.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details == nil || details.InSmoothingPool || details.OdaoMember || len(details.Minipools) != 3 {
+		t.Fatalf("unexpected node details %+v", details)
+	}
+
+	head, err := et.ec.getClient().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The node launches another minipool
+	minipoolAddr := common.HexToAddress("0x1f101f")
+	et.ec.handleEvent(types.Log{
+		Address: common.HexToAddress(rocketMinipoolManager),
+		Topics: []common.Hash{
+			common.BytesToHash(
+				et.ec.minipoolLaunchedTopic.Bytes(),
+			),
+			common.BytesToHash(
+				minipoolAddr.Bytes(),
+			),
+			common.BytesToHash(
+				other.addr.Bytes(),
+			),
+		},
+		BlockNumber: head.Number.Uint64(),
+		BlockHash:   head.Hash(),
+		Index:       nextLogIndex(),
+	})
+	h, err := hex.DecodeString(pubkeyFromMinipool(minipoolAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubkey := rptypes.BytesToValidatorPubkey(h)
+
+	details, err = et.ec.GetNodeDetails(other.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(details.Minipools) != 4 {
+		t.Fatalf("expected 4 minipools, got %v", details.Minipools)
+	}
+	if !details.MinipoolsChanged.Equal(time.Unix(int64(head.Time), 0)) {
+		t.Fatalf("expected the minipools to change at the block time, got %v", details.MinipoolsChanged)
+	}
+
+	// The odao node leaves
+	et.ec.handleEvent(types.Log{
+		Address: common.HexToAddress(rocketDAONodeTrustedActions),
+		Topics: []common.Hash{
+			common.BytesToHash(
+				et.ec.odaoLeftTopic.Bytes(),
+			),
+			common.BytesToHash(
+				odaoNode.addr.Bytes(),
+			),
+		},
+		BlockNumber: head.Number.Uint64(),
+		BlockHash:   head.Hash(),
+		Index:       nextLogIndex(),
+	})
+
+	details, err = et.ec.GetNodeDetails(odaoNode.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.OdaoMember || !details.OdaoChanged.Equal(time.Unix(int64(head.Time), 0)) {
+		t.Fatalf("expected the node to have left the odao at the block time, got %+v", details)
+	}
+
+	// The change times are kept on the cached node
+	n, err := et.ec.cache.getNodeInfo(odaoNode.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.odaoChanged.Equal(details.OdaoChanged) {
+		t.Fatalf("expected the odao change to be cached, got %v", n.odaoChanged)
+	}
+
+	// Moving the minipool to another node updates both nodes' minipools
+	if err := et.ec.cache.addMinipoolNode(pubkey, odaoNode.addr); err != nil {
+		t.Fatal(err)
+	}
+	minipools, err := et.ec.cache.getNodeMinipools(other.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(minipools) != 3 {
+		t.Fatalf("expected 3 minipools, got %v", minipools)
+	}
+	minipools, err = et.ec.cache.getNodeMinipools(odaoNode.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(minipools) != 3 {
+		t.Fatalf("expected 3 minipools, got %v", minipools)
+	}
+
+	if err := et.ec.cache.removeMinipoolNode(pubkey); err != nil {
+		t.Fatal(err)
+	}
+	minipools, err = et.ec.cache.getNodeMinipools(odaoNode.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(minipools) != 2 {
+		t.Fatalf("expected 2 minipools, got %v", minipools)
+	}
+
+	details, err = et.ec.GetNodeDetails(common.HexToAddress("0x0000000000000000000003234567899876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details != nil {
+		t.Fatalf("unexpected details for an unregistered node %+v", details)
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestELGetNodeDetails(t *testing.T) {
+	testELGetNodeDetails(t, useMapsCache(t))
+}
+
+func TestSQLELGetNodeDetails(t *testing.T) {
+	testELGetNodeDetails(t, useSqliteCache(t))
+}
+
+func TestKVELGetNodeDetails(t *testing.T) {
+	testELGetNodeDetails(t, useKVCache(t))
+}
+
+// noMulticallEC serves a chain without Multicall3 deployed
+type noMulticallEC struct {
+	*happyEC
+}
+
+func (e *noMulticallEC) Serve(mt int, data []byte) (int, []byte) {
+	m := jsonrpcMessage{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		e.t.Fatal(err)
+	}
+
+	if m.Method == "eth_getCode" {
+		return mt, []byte(fmt.Sprintf(callResultFmt, m.ID, "0x"))
+	}
+
+	return e.happyEC.Serve(mt, data)
+}
+
+// latencyEC delays every message, as a remote execution client would
+type latencyEC struct {
+	mockEC
+	latency time.Duration
+}
+
+func (e *latencyEC) Serve(mt int, data []byte) (int, []byte) {
+	time.Sleep(e.latency)
+	return e.mockEC.Serve(mt, data)
+}
+
+func startEL(t testing.TB, et *elTest) chan error {
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// Wait for connection
+	<-et.ec.connected
+	return errs
+}
+
+func TestELWarmupWithoutMulticall(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000002234567899876543210"),
+				inSP:      false,
+				minipools: 3,
+			},
+		},
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000222222222222222222222222222222"),
+				inSP:      false,
+				minipools: 0,
+			},
+		},
+	}
+	et := setup(t, &noMulticallEC{hec})
+	errs := startEL(t, et)
+
+	opts := &bind.CallOpts{BlockNumber: et.ec.cache.getHighestBlock()}
+	serial, err := et.ec.loadChainState(et.ec.rp, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The batched loader must agree with the one used for warmup
+	mc, err := newMulticaller(et.ec.client.Client(), opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batched, err := et.ec.loadChainStateBatched(et.ec.rp, mc, func(int) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(serial.nodes) != 2 || len(batched.nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got %d and %d", len(serial.nodes), len(batched.nodes))
+	}
+	for addr, n := range serial.nodes {
+		if *batched.nodes[addr] != *n {
+			t.Fatalf("node %s loaded as %v and %v", addr.String(), n, batched.nodes[addr])
+		}
+	}
+
+	if len(serial.minipools) != 4 || len(batched.minipools) != 4 {
+		t.Fatalf("expected 4 minipools, got %d and %d", len(serial.minipools), len(batched.minipools))
+	}
+	for pubkey, addr := range serial.minipools {
+		if batched.minipools[pubkey] != addr {
+			t.Fatalf("minipool %s loaded as %s and %s", pubkey.String(), addr.String(), batched.minipools[pubkey].String())
+		}
+	}
+
+	// And the cache was warmed up
+	for _, n := range hec.nodes {
+		for pk := range n.minipoolMap {
+			ri, err := et.ec.GetRPInfo(pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ri.NodeAddress != n.addr {
+				t.Fatal("Mismatched node addresses")
+			}
+		}
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A synthetic node set of 250 nodes with 1989 minipools. It wasn't recorded from a chain: the
+// addresses, smoothing pool membership and minipool counts are generated. Warmup is benchmarked
+// against it until a chain state recorded by TestRecordChainState is checked in.
+//
+//go:embed test-data/synthetic-chain-state.json
+var syntheticChainState []byte
+
+// Where TestRecordChainState records the chain state to, and the benchmarks read it from
+const recordedChainStatePath = "test-data/chain-state.json"
+
+var recordChainStateFrom = flag.String("record-chain-state", "",
+	"the url of a mainnet execution client to record "+recordedChainStatePath+" from")
+
+// The node set the benchmarks warm up against, and what TestRecordChainState records
+type chainStateFixture struct {
+	Block     uint64           `json:"block,omitempty"`
+	OdaoNodes []common.Address `json:"odao_nodes"`
+	Nodes     []chainStateNode `json:"nodes"`
+}
+
+type chainStateNode struct {
+	Address   common.Address `json:"address"`
+	InSP      bool           `json:"in_sp"`
+	Minipools int            `json:"minipools"`
+}
+
+// Records the node set from a mainnet execution client, for the benchmarks to warm up against, eg
+//
+//	go test ./executionlayer -run TestRecordChainState -record-chain-state ws://localhost:8546
+func TestRecordChainState(t *testing.T) {
+	if *recordChainStateFrom == "" {
+		t.Skip("-record-chain-state isn't set")
+	}
+	u, err := url.Parse(*recordChainStateFrom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = metrics.Init("cc_test_" + t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(metrics.Deinit)
+
+	ec := &CachingExecutionLayer{
+		Logger:            zaptest.NewLogger(t),
+		ECURLs:            []*url.URL{u},
+		RocketStorageAddr: rocketStorage,
+	}
+	if err := ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ec.Stop)
+
+	fixture := chainStateFixture{Block: ec.cache.getHighestBlock().Uint64()}
+	minipools := make(map[common.Address]int)
+	err = ec.cache.forEachMinipool(func(_ rptypes.ValidatorPubkey, addr common.Address) bool {
+		minipools[addr]++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.cache.forEachNode(func(addr common.Address) bool {
+		n, err := ec.cache.getNodeInfo(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fixture.Nodes = append(fixture.Nodes, chainStateNode{addr, n.inSmoothingPool, minipools[addr]})
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ec.cache.forEachOdaoNode(func(addr common.Address) bool {
+		fixture.OdaoNodes = append(fixture.OdaoNodes, addr)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.MarshalIndent(fixture, "", " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(recordedChainStatePath, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Recorded %d nodes and %d odao nodes at block %d", len(fixture.Nodes), len(fixture.OdaoNodes), fixture.Block)
+}
+
+// Builds a mock execution client serving the recorded chain state if there is one, and otherwise
+// the synthetic node set
+func loadChainStateFixture(b *testing.B) *happyEC {
+	data, err := os.ReadFile(recordedChainStatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		b.Log("No chain state has been recorded, so warming up against the synthetic node set")
+		data = syntheticChainState
+	} else if err != nil {
+		b.Fatal(err)
+	}
+
+	var fixture chainStateFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		b.Fatal(err)
+	}
+
+	out := &happyEC{t: b}
+	for _, n := range fixture.Nodes {
+		out.nodes = append(out.nodes, &mockNode{addr: n.Address, inSP: n.InSP, minipools: n.Minipools})
+	}
+	for _, addr := range fixture.OdaoNodes {
+		out.daoNodes = append(out.daoNodes, &mockNode{addr: addr})
+	}
+
+	return out
+}
+
+// Measures how long it takes to read the chain state fixture from an execution client
+// with a round trip time of 200µs. The mock serves one message at a time.
+func benchmarkELLoadChainState(b *testing.B, m mockEC, hec *happyEC) {
+	minipools := 0
+	for _, n := range hec.nodes {
+		minipools += n.minipools
+	}
+
+	et := setup(b, &latencyEC{m, 200 * time.Microsecond})
+	et.quiet = true
+	et.ec.Logger = zap.NewNop()
+	errs := startEL(b, et)
+
+	opts := &bind.CallOpts{BlockNumber: et.ec.cache.getHighestBlock()}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		state, err := et.ec.loadChainState(et.ec.rp, opts)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if len(state.nodes) != len(hec.nodes) || len(state.minipools) != minipools {
+			b.Fatalf("loaded %d nodes and %d minipools", len(state.nodes), len(state.minipools))
+		}
+	}
+	b.StopTimer()
+
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkELLoadChainState(b *testing.B) {
+	hec := loadChainStateFixture(b)
+	benchmarkELLoadChainState(b, hec, hec)
+}
+
+func BenchmarkELLoadChainStateWithoutMulticall(b *testing.B) {
+	hec := loadChainStateFixture(b)
+	benchmarkELLoadChainState(b, &noMulticallEC{hec}, hec)
+}
+
+// Checks whether a snapshot on disk has the node
+func snapshotHasNode(t *testing.T, path string, addr common.Address) bool {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM nodes WHERE address = ?;", addr.Bytes()).Scan(&count)
+	if err != nil {
+		// The first snapshot may not have been written yet
+		return false
+	}
+
+	return count > 0
+}
+
+func TestSQLELSnapshot(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+	et.ec.CachePath = t.TempDir()
+	et.ec.CacheSnapshotInterval = 10 * time.Millisecond
+	errs := startEL(t, et)
+
+	head, err := et.ec.getClient().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A node registers while we're running
+	a := common.HexToAddress("0x0f010f")
+	et.ec.handleEvent(nodeRegisteredLog(et, a, head))
+
+	// Wait for it to be snapshotted
+	snapshotPath := et.ec.CachePath + "/" + snapshotFileName
+	deadline := time.Now().Add(5 * time.Second)
+	for !snapshotHasNode(t, snapshotPath, a) {
+		if time.Now().After(deadline) {
+			t.Fatal("node wasn't snapshotted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A node registers in the next block, whose other logs may not have arrived yet
+	b := common.HexToAddress("0x0f020f")
+	et.ec.handleEvent(nodeRegisteredLog(et, b, &types.Header{
+		Number: big.NewInt(0).Add(head.Number, big.NewInt(1)),
+	}))
+	deadline = time.Now().Add(5 * time.Second)
+	for !snapshotHasNode(t, snapshotPath, b) {
+		if time.Now().After(deadline) {
+			t.Fatal("node wasn't snapshotted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// So the snapshot is only at the block before it, and that block is replayed after a crash
+	db, err := sql.Open("sqlite3", "file:"+snapshotPath+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshotBlock uint64
+	err = db.QueryRow("SELECT value FROM highest_block WHERE id = 0;").Scan(&snapshotBlock)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshotBlock != head.Number.Uint64() {
+		t.Fatalf("expected the snapshot to be at block %d, got %d", head.Number.Uint64(), snapshotBlock)
+	}
+
+	// Simulate a crash by keeping the snapshot as it is now, before the clean shutdown replaces it
+	crashPath := t.TempDir()
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(crashPath+"/"+snapshotFileName, snapshot, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(snapshotPath + ".tmp"); !os.IsNotExist(err) {
+		t.Fatal("expected the temporary snapshot to be renamed", err)
+	}
+
+	// Restarting from the crash snapshot is warm, and has the node
+	metrics.Deinit()
+	et = setup(t, hec)
+	et.ec.CachePath = crashPath
+	errs = startEL(t, et)
+
+	if et.ec.cache.getHighestBlock().Uint64() != head.Number.Uint64() {
+		t.Fatalf("expected the snapshot to be at block %d, got %d", head.Number.Uint64(), et.ec.cache.getHighestBlock().Uint64())
+	}
+	if !hasNode(t, et, a) {
+		t.Fatal("expected node from the crash snapshot")
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Leaves a snapshot built by the given statements in dir
+func writeSnapshot(t *testing.T, dir string, statements string, args ...any) {
+	db, err := sql.Open("sqlite3", "file:"+dir+"/"+snapshotFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(statements, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLELSchemaMigration(t *testing.T) {
+	seedNode := common.HexToAddress("0x0f030f")
+	withdrawalAddr := common.HexToAddress("0x0a010a")
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:              seedNode,
+				inSP:              true,
+				minipools:         1,
+				withdrawalAddress: withdrawalAddr,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+	et.ec.CachePath = t.TempDir()
+
+	// A snapshot from before the schema was versioned
+	seedPubkey := rptypes.BytesToValidatorPubkey([]byte{0x01})
+	writeSnapshot(t, et.ec.CachePath, `
+		CREATE TABLE nodes (address BLOB PRIMARY KEY, smoothing_pool_status TINYINT, fee_distributor BLOB);
+		CREATE TABLE minipools (pubkey BLOB PRIMARY KEY, node_address BLOB);
+		CREATE TABLE highest_block (id INTEGER PRIMARY KEY CHECK (id = 0), value INTEGER(8));
+		CREATE TABLE odao_nodes (address BLOB PRIMARY KEY);
+		INSERT INTO nodes VALUES (?, 1, ?);
+		INSERT INTO minipools VALUES (?, ?);
+		INSERT INTO highest_block VALUES (0, ?);`,
+		seedNode.Bytes(), seedNode.Bytes(), seedPubkey.Bytes(), seedNode.Bytes(), 0x11af2c8)
+
+	errs := startEL(t, et)
+
+	// The snapshot was migrated rather than reset
+	rpinfo, err := et.ec.GetRPInfo(seedPubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpinfo == nil || rpinfo.NodeAddress != seedNode {
+		t.Fatalf("expected the snapshot's minipool to belong to %s, got %v", seedNode.String(), rpinfo)
+	}
+	if rpinfo.ExpectedFeeRecipient.String() != common.HexToAddress(rocketSmoothingPool).String() {
+		t.Fatal("expected the snapshot's node to be in the smoothing pool")
+	}
+
+	// Its history begins with its state as of its highest block
+	rpinfo, err = et.ec.GetRPInfoAt(seedPubkey, 0x11af2c8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpinfo == nil || rpinfo.NodeAddress != seedNode {
+		t.Fatalf("expected the snapshot's minipool to be in its history, got %v", rpinfo)
+	}
+
+	// The snapshot predates withdrawal addresses, so its nodes' were read from the chain
+	n, err := et.ec.cache.getNodeInfo(seedNode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.withdrawalAddressesUnknown || n.withdrawalAddress != withdrawalAddr {
+		t.Fatalf("expected the node's withdrawal addresses to be backfilled, got %+v", n)
+	}
+	nodes, err := et.ec.GetWithdrawalAddressNodes(withdrawalAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].NodeAddress != seedNode {
+		t.Fatalf("expected the node to be found by its withdrawal address, got %v", nodes)
+	}
+
+	n.smoothingPoolChanged = time.Unix(1700000000, 0)
+	if err := et.ec.cache.addNodeInfo(seedNode, n); err != nil {
+		t.Fatal(err)
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The saved snapshot is at the latest version
+	db, err := sql.Open("sqlite3", "file:"+et.ec.CachePath+"/"+snapshotFileName+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var version int
+	if err := db.QueryRow("SELECT version FROM schema_version;").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(migrations) {
+		t.Fatalf("expected schema version %d, got %d", len(migrations), version)
+	}
+
+	var spChanged int64
+	if err := db.QueryRow("SELECT smoothing_pool_changed FROM nodes WHERE address = ?;", seedNode.Bytes()).Scan(&spChanged); err != nil {
+		t.Fatal(err)
+	}
+	if spChanged != 1700000000 {
+		t.Fatalf("unexpected smoothing pool change time %d", spChanged)
+	}
+}
+
+func TestSQLELSchemaFromFuture(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+	et.ec.CachePath = t.TempDir()
+
+	// A snapshot from a version of the proxy that changed the nodes table in some unknown way
+	seedNode := common.HexToAddress("0x0f030f")
+	writeSnapshot(t, et.ec.CachePath, `
+		CREATE TABLE schema_version (id INTEGER PRIMARY KEY CHECK (id = 0), version INTEGER);
+		CREATE TABLE nodes (address BLOB PRIMARY KEY, status TEXT);
+		CREATE TABLE highest_block (id INTEGER PRIMARY KEY CHECK (id = 0), value INTEGER(8));
+		INSERT INTO schema_version VALUES (0, ?);
+		INSERT INTO nodes VALUES (?, 'future');
+		INSERT INTO highest_block VALUES (0, ?);`,
+		len(migrations)+1, seedNode.Bytes(), 0x11af2c8-10)
+
+	errs := startEL(t, et)
+
+	// The snapshot was discarded and the cache warmed up from the chain instead
+	if hasNode(t, et, seedNode) {
+		t.Fatal("expected the snapshot to be discarded")
+	}
+	if !hasNode(t, et, hec.nodes[0].addr) {
+		t.Fatal("expected the cache to be warmed up")
+	}
+	if et.ec.cache.getHighestBlock().Int64() != 0x11af2c8 {
+		t.Fatalf("expected the cache to be warmed up at the head, got block %d", et.ec.cache.getHighestBlock().Int64())
 	}
 
 	et.ec.Stop()
@@ -0,0 +1,98 @@
+package executionlayer
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"go.uber.org/zap"
+)
+
+// ErrHistoryUnavailable is returned by GetRPInfoAt for blocks the cache has no history of
+var ErrHistoryUnavailable = errors.New("no history is available for that block")
+
+// historyEntry records the state a node or minipool was left in as of a block
+type historyEntry struct {
+	blockNumber uint64
+	// The topic of the event that changed the state, or empty if it was read from the chain directly
+	topic common.Hash
+
+	nodeAddress common.Address
+	// Set for minipool entries, and empty for node entries
+	pubkey rptypes.ValidatorPubkey
+	// The node's state, for node entries
+	node *nodeInfo
+	// Whether the node or minipool stopped existing
+	removed bool
+}
+
+func nodeHistoryEntry(block uint64, addr common.Address, n *nodeInfo) *historyEntry {
+	return &historyEntry{blockNumber: block, nodeAddress: addr, node: n}
+}
+
+func minipoolHistoryEntry(block uint64, pubkey rptypes.ValidatorPubkey, nodeAddr common.Address) *historyEntry {
+	return &historyEntry{blockNumber: block, nodeAddress: nodeAddr, pubkey: pubkey}
+}
+
+// historyCache is a Cache that also keeps the history of every node and minipool, so that
+// their state as of past blocks can be looked up. Entries are only ever appended, except
+// for those from blocks that are reorganized out of the chain.
+type historyCache interface {
+	Cache
+
+	addHistory([]*historyEntry) error
+	// The latest state at or before the block, or a NotFoundError if there was none or it had been removed
+	getNodeInfoAt(common.Address, uint64) (*nodeInfo, error)
+	getMinipoolNodeAt(rptypes.ValidatorPubkey, uint64) (common.Address, error)
+	// The first block with any history, and whether there is any
+	getHistoryStart() (uint64, bool, error)
+	removeHistory(fromBlock uint64) error
+}
+
+// Appends the state an event left its node or minipool in to the cache's history, if it keeps one
+func (e *CachingExecutionLayer) recordHistory(event types.Log, address common.Address, pubkey rptypes.ValidatorPubkey) {
+	hc, ok := e.cache.(historyCache)
+	if !ok {
+		return
+	}
+
+	var entry *historyEntry
+	switch event.Topics[0] {
+	case e.minipoolLaunchedTopic:
+		entry = minipoolHistoryEntry(event.BlockNumber, pubkey, address)
+	case e.nodeRegisteredTopic, e.smoothingPoolStatusChangedTopic:
+		n, err := e.cache.getNodeInfo(address)
+		if err != nil {
+			e.Logger.Warn("Error reading node to record its history", zap.String("node", address.String()), zap.Error(err))
+			return
+		}
+		entry = nodeHistoryEntry(event.BlockNumber, address, n)
+	default:
+		// Neither odao membership nor withdrawal addresses affect fee recipients
+		return
+	}
+	entry.topic = event.Topics[0]
+
+	if err := hc.addHistory([]*historyEntry{entry}); err != nil {
+		e.Logger.Warn("Error recording history", zap.Error(err))
+	}
+}
+
+// Records a baseline for every node and minipool in the chain state read at `block`
+func (e *CachingExecutionLayer) recordStateHistory(block uint64, state *chainState) error {
+	hc, ok := e.cache.(historyCache)
+	if !ok {
+		return nil
+	}
+
+	entries := make([]*historyEntry, 0, len(state.nodes)+len(state.minipools))
+	for addr, n := range state.nodes {
+		entries = append(entries, nodeHistoryEntry(block, addr, n))
+	}
+	for pubkey, addr := range state.minipools {
+		entries = append(entries, minipoolHistoryEntry(block, pubkey, addr))
+	}
+
+	return hc.addHistory(entries)
+}
@@ -0,0 +1,155 @@
+package executionlayer
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+)
+
+const historyHead = 0x11af2c8
+
+func getRPInfoAt(t *testing.T, et *elTest, pubkey rptypes.ValidatorPubkey, block uint64) *RPInfo {
+	rpinfo, err := et.ec.GetRPInfoAt(pubkey, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rpinfo
+}
+
+func TestSQLELHistory(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      false,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+	et.ec.CachePath = t.TempDir()
+	et.ec.SmoothingPoolGraceEpochs = 4
+	errs := startEL(t, et)
+
+	var warmPubkey rptypes.ValidatorPubkey
+	var warmNode common.Address
+	err := et.ec.cache.forEachMinipool(func(pubkey rptypes.ValidatorPubkey, addr common.Address) bool {
+		warmPubkey, warmNode = pubkey, addr
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// History begins when the cache was warmed up, and ends at the last processed block
+	for _, block := range []uint64{historyHead - 1, historyHead + 100} {
+		_, err := et.ec.GetRPInfoAt(warmPubkey, block)
+		if !errors.Is(err, ErrHistoryUnavailable) {
+			t.Fatalf("expected no history at block %d, got %v", block, err)
+		}
+	}
+
+	rpinfo := getRPInfoAt(t, et, warmPubkey, historyHead)
+	if rpinfo == nil || rpinfo.NodeAddress != warmNode {
+		t.Fatalf("expected minipool %s to belong to %s, got %v", warmPubkey.String(), warmNode.String(), rpinfo)
+	}
+	feeDistributor := *rpinfo.ExpectedFeeRecipient
+	if feeDistributor == common.HexToAddress(rocketSmoothingPool) {
+		t.Fatal("expected the node to be out of the smoothing pool")
+	}
+
+	// A new node registers and launches a minipool, and later the warm node joins the smoothing pool
+	a := common.HexToAddress("0x0f010f")
+	et.ec.handleEvent(types.Log{
+		Address: common.HexToAddress(rocketNodeManager),
+		Topics: []common.Hash{
+			common.BytesToHash(et.ec.nodeRegisteredTopic.Bytes()),
+			common.BytesToHash(a.Bytes()),
+		},
+		BlockNumber: historyHead + 5,
+	})
+	launched, pubkey := minipoolLaunchedLog(et, common.HexToAddress("0x1f101f"), a)
+	launched.BlockNumber = historyHead + 5
+	et.ec.handleEvent(launched)
+
+	et.ec.handleEvent(types.Log{
+		Address: common.HexToAddress(rocketNodeManager),
+		Topics: []common.Hash{
+			common.BytesToHash(et.ec.smoothingPoolStatusChangedTopic.Bytes()),
+			common.BytesToHash(warmNode.Bytes()),
+		},
+		Data:        big.NewInt(1).Bytes(),
+		BlockNumber: historyHead + 10,
+	})
+
+	if rpinfo := getRPInfoAt(t, et, pubkey, historyHead+4); rpinfo != nil {
+		t.Fatalf("expected minipool %s not to exist yet, got %v", pubkey.String(), rpinfo)
+	}
+	if rpinfo := getRPInfoAt(t, et, pubkey, historyHead+5); rpinfo == nil || rpinfo.NodeAddress != a {
+		t.Fatalf("expected minipool %s to belong to %s, got %v", pubkey.String(), a.String(), rpinfo)
+	}
+
+	rpinfo = getRPInfoAt(t, et, warmPubkey, historyHead+9)
+	if *rpinfo.ExpectedFeeRecipient != feeDistributor {
+		t.Fatalf("expected the fee distributor before the node joined the smoothing pool, got %s", rpinfo.ExpectedFeeRecipient.String())
+	}
+
+	// The grace period is measured from the block, not from now
+	rpinfo = getRPInfoAt(t, et, warmPubkey, historyHead+10)
+	if *rpinfo.ExpectedFeeRecipient != common.HexToAddress(rocketSmoothingPool) {
+		t.Fatalf("expected the smoothing pool once the node joined it, got %s", rpinfo.ExpectedFeeRecipient.String())
+	}
+	if !rpinfo.AcceptsFeeRecipient(feeDistributor.String()) {
+		t.Fatalf("expected the fee distributor to be accepted during the grace period, got %v", rpinfo.AcceptableFeeRecipients)
+	}
+
+	// History from reorganized blocks is forgotten
+	if err := et.ec.rollbackFrom(historyHead + 10); err != nil {
+		t.Fatal(err)
+	}
+	rpinfo = getRPInfoAt(t, et, warmPubkey, historyHead+10)
+	if *rpinfo.ExpectedFeeRecipient != feeDistributor {
+		t.Fatalf("expected the smoothing pool change to be rolled back, got %s", rpinfo.ExpectedFeeRecipient.String())
+	}
+	if rpinfo := getRPInfoAt(t, et, pubkey, historyHead+10); rpinfo == nil || rpinfo.NodeAddress != a {
+		t.Fatalf("expected minipool %s to survive the rollback, got %v", pubkey.String(), rpinfo)
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestELGetRPInfoAtWithoutHistory(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+	errs := startEL(t, et)
+
+	_, err := et.ec.GetRPInfoAt(rptypes.ValidatorPubkey{}, historyHead)
+	if !errors.Is(err, ErrHistoryUnavailable) {
+		t.Fatalf("expected the maps cache to have no history, got %v", err)
+	}
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,257 @@
+package executionlayer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+)
+
+// How long the ingestion lease lasts if its holder stops renewing it
+const defaultIngestionLeaseTTL = 15 * time.Second
+
+var errIngestionLeaseLost = errors.New("lost the ingestion lease for the shared cache")
+
+// sharedCache is a Cache shared by several instances of the proxy. Only the holder of the ingestion
+// lease follows the chain and writes to it, while the others read what it wrote.
+type sharedCache interface {
+	Cache
+
+	acquireLease(owner string, ttl time.Duration) (bool, error)
+	renewLease(owner string, ttl time.Duration) (bool, error)
+	releaseLease(owner string) error
+}
+
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "rescue-proxy"
+	}
+
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(b))
+}
+
+func (e *CachingExecutionLayer) leaseTTL() time.Duration {
+	if e.IngestionLeaseTTL == 0 {
+		return defaultIngestionLeaseTTL
+	}
+
+	return e.IngestionLeaseTTL
+}
+
+func (e *CachingExecutionLayer) setIngestionOwner() {
+	e.ingestionOwner.Store(true)
+	e.m.Gauge("ingestion_lease_held").Set(1)
+	e.m.Counter("ingestion_lease_acquired").Inc()
+	e.Logger.Info("Acquired the ingestion lease for the shared cache", zap.String("instance", e.instanceID))
+}
+
+// Starts renewing the lease as soon as it's acquired, since warming up the cache can take longer than
+// the lease lasts. Returns false if the execution layer is stopped.
+func (e *CachingExecutionLayer) holdLease(sc sharedCache) bool {
+	e.setIngestionOwner()
+	leaseLost := make(chan struct{})
+	e.leaseLost = leaseLost
+	return e.goUnlessStopped(func() { e.renewLeaseLoop(sc, leaseLost) })
+}
+
+// Called from Init to decide whether this instance will ingest events into the shared cache, or
+// follow the instance that does. Blocks until the lease is acquired or the cache has been warmed up.
+func (e *CachingExecutionLayer) joinSharedCache(sc sharedCache) error {
+	e.m.Gauge("ingestion_lease_held").Set(0)
+
+	for {
+		acquired, err := sc.acquireLease(e.instanceID, e.leaseTTL())
+		if err != nil {
+			return err
+		}
+
+		if acquired {
+			if !e.holdLease(sc) {
+				return e.ctx.Err()
+			}
+			return nil
+		}
+
+		if sc.getHighestBlock().Sign() != 0 {
+			e.Logger.Info("Following the instance that holds the ingestion lease for the shared cache")
+			return nil
+		}
+
+		e.Logger.Info("Waiting for the instance that holds the ingestion lease to warm up the shared cache")
+		select {
+		case <-e.ctx.Done():
+			return e.ctx.Err()
+		case <-time.After(e.leaseTTL() / 3):
+		}
+	}
+}
+
+// Called from Start when following another instance. Blocks until the lease is acquired,
+// returning true, or the execution layer is stopped.
+func (e *CachingExecutionLayer) waitForLease(sc sharedCache) bool {
+	ticker := time.NewTicker(e.leaseTTL() / 3)
+	defer ticker.Stop()
+
+	var followed *followedNodeSet
+	for {
+		select {
+		case <-e.ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		if e.NodeSet != nil {
+			var err error
+			if followed, err = e.followNodeSet(sc, followed); err != nil {
+				e.Logger.Warn("Error reading the node set from the shared cache", zap.Error(err))
+			}
+		}
+
+		acquired, err := sc.acquireLease(e.instanceID, e.leaseTTL())
+		if err != nil {
+			e.Logger.Warn("Error trying to acquire the ingestion lease", zap.Error(err))
+			continue
+		}
+
+		if acquired {
+			return e.holdLease(sc)
+		}
+	}
+}
+
+// The nodes and odao members in the shared cache when a follower last looked
+type followedNodeSet struct {
+	nodes map[common.Address]bool
+	odao  map[common.Address]bool
+}
+
+// Followers don't process events, so they publish changes to the node set by comparing the shared
+// cache against what it held the last time they looked. Returns what it holds now.
+func (e *CachingExecutionLayer) followNodeSet(sc sharedCache, prev *followedNodeSet) (*followedNodeSet, error) {
+	block := sc.getHighestBlock().Uint64()
+
+	out := &followedNodeSet{
+		nodes: make(map[common.Address]bool),
+		odao:  make(map[common.Address]bool),
+	}
+	err := sc.forEachNode(func(addr common.Address) bool {
+		out.nodes[addr] = true
+		return true
+	})
+	if err != nil {
+		return prev, err
+	}
+	err = sc.forEachOdaoNode(func(addr common.Address) bool {
+		out.odao[addr] = true
+		return true
+	})
+	if err != nil {
+		return prev, err
+	}
+
+	e.NodeSet.Advance(block)
+	if prev == nil {
+		return out, nil
+	}
+
+	for addr := range prev.nodes {
+		if !out.nodes[addr] {
+			// Nodes are only removed by reorgs and the reconciler, and there's no change for that
+			e.NodeSet.Reset()
+			return out, nil
+		}
+	}
+	for addr := range out.nodes {
+		if !prev.nodes[addr] {
+			e.NodeSet.Publish(block, nodeset.NodeRegistered, addr)
+		}
+	}
+	for addr := range out.odao {
+		if !prev.odao[addr] {
+			e.NodeSet.Publish(block, nodeset.OdaoJoined, addr)
+		}
+	}
+	for addr := range prev.odao {
+		if !out.odao[addr] {
+			e.NodeSet.Publish(block, nodeset.OdaoLeft, addr)
+		}
+	}
+
+	return out, nil
+}
+
+// Renews the ingestion lease until the execution layer is stopped. If the lease is lost to another
+// instance, or can't be renewed before it expires, leaseLost is closed so that this instance stops
+// ingesting and follows whoever takes over.
+func (e *CachingExecutionLayer) renewLeaseLoop(sc sharedCache, leaseLost chan struct{}) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.leaseTTL() / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		held, err := sc.renewLease(e.instanceID, e.leaseTTL())
+		if err != nil {
+			e.m.Counter("ingestion_lease_renewal_failed").Inc()
+			e.Logger.Warn("Error renewing the ingestion lease", zap.Error(err))
+
+			// We may still hold it, so keep trying until it would have expired
+			if time.Since(renewed) < e.leaseTTL() {
+				continue
+			}
+		}
+
+		if err == nil && held {
+			renewed = time.Now()
+			continue
+		}
+
+		e.ingestionOwner.Store(false)
+		e.m.Gauge("ingestion_lease_held").Set(0)
+		e.m.Counter("ingestion_lease_lost").Inc()
+		e.Logger.Error("Lost the ingestion lease for the shared cache", zap.String("instance", e.instanceID))
+		close(leaseLost)
+		return
+	}
+}
+
+// Stops renewing the lease and releases it when Init fails, since Stop won't be called
+func (e *CachingExecutionLayer) abandonLease(sc sharedCache) {
+	e.shutdownLock.Lock()
+	e.shutdown()
+	e.shutdownLock.Unlock()
+	e.wg.Wait()
+	e.releaseLease(sc)
+}
+
+// Lets another instance take over ingestion without waiting for the lease to expire
+func (e *CachingExecutionLayer) releaseLease(sc sharedCache) {
+	if !e.ingestionOwner.Load() {
+		return
+	}
+
+	if err := sc.releaseLease(e.instanceID); err != nil {
+		e.Logger.Warn("Error releasing the ingestion lease", zap.Error(err))
+		return
+	}
+
+	e.ingestionOwner.Store(false)
+	e.m.Gauge("ingestion_lease_held").Set(0)
+	e.Logger.Info("Released the ingestion lease for the shared cache")
+}
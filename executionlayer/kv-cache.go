@@ -0,0 +1,932 @@
+package executionlayer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/redis/go-redis/v9"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"go.uber.org/zap"
+)
+
+// How many entries to request at a time while iterating
+const kvScanCount = 1000
+
+// How long the highest block read from the store is used before reading it again
+const kvHighestBlockTTL = time.Second
+
+var errNotLeaseHolder = errors.New("this instance doesn't hold the ingestion lease for the shared cache")
+
+// Prefixes the error scripts return when the ingestion lease is held by someone else
+const kvNotLeaseHolder = "NOTLEASEHOLDER"
+
+// Runs a write only if the ingestion lease is held by the writer, atomically, so that an instance
+// which lost the lease can't overwrite what the new holder writes.
+// KEYS[1] is the lease and ARGV[1] the writer. ARGV[2] is the command, run with the rest of KEYS, then of ARGV.
+var kvFencedWrite = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return redis.error_reply('` + kvNotLeaseHolder + `')
+end
+
+local args = {}
+for i = 2, #KEYS do
+	args[#args + 1] = KEYS[i]
+end
+for i = 3, #ARGV do
+	args[#args + 1] = ARGV[i]
+end
+
+return redis.call(ARGV[2], unpack(args))
+`)
+
+// Adds a processed event to KEYS[3], scored by the block number in ARGV[2], if the lease in KEYS[1] is
+// held by ARGV[1]. The member ARGV[3] is prefixed with the next big-endian sequence number from KEYS[2].
+var kvAddProcessedEvent = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return redis.error_reply('` + kvNotLeaseHolder + `')
+end
+
+local seq = redis.call('INCR', KEYS[2])
+local prefix = ''
+for i = 1, 8 do
+	prefix = string.char(seq % 256) .. prefix
+	seq = math.floor(seq / 256)
+end
+
+return redis.call('ZADD', KEYS[3], ARGV[2], prefix .. ARGV[3])
+`)
+
+// Lua has no hex encoding, which the per-node minipool set keys use
+const kvHex = `
+local function hex(s)
+	return (s:gsub('.', function(c) return string.format('%02x', string.byte(c)) end))
+end
+`
+
+// Sets the node of the minipool ARGV[2] to ARGV[3] in the hash KEYS[2], and adds the minipool to the node's
+// set KEYS[3], if the lease in KEYS[1] is held by ARGV[1]. The minipool is removed from the set of the node
+// it belonged to before, whose key is ARGV[4] followed by its hex address.
+var kvAddMinipool = redis.NewScript(kvHex + `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return redis.error_reply('` + kvNotLeaseHolder + `')
+end
+
+local previous = redis.call('HGET', KEYS[2], ARGV[2])
+if previous and previous ~= ARGV[3] then
+	redis.call('SREM', ARGV[4] .. hex(previous), ARGV[2])
+end
+
+redis.call('HSET', KEYS[2], ARGV[2], ARGV[3])
+return redis.call('SADD', KEYS[3], ARGV[2])
+`)
+
+// Removes the minipool ARGV[2] from the hash KEYS[2], and from its node's set, whose key is ARGV[3]
+// followed by the node's hex address, if the lease in KEYS[1] is held by ARGV[1]
+var kvRemoveMinipool = redis.NewScript(kvHex + `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return redis.error_reply('` + kvNotLeaseHolder + `')
+end
+
+local previous = redis.call('HGET', KEYS[2], ARGV[2])
+if not previous then
+	return 0
+end
+
+redis.call('SREM', ARGV[3] .. hex(previous), ARGV[2])
+return redis.call('HDEL', KEYS[2], ARGV[2])
+`)
+
+// Lua functions for the withdrawal address sets, which the node writes below keep up to date.
+// withdrawalAddresses returns the nonzero withdrawal addresses of an encoded node, which has none if it
+// predates withdrawal addresses, and reindex moves the node ARGV[2] from the sets of the addresses in the
+// node info it had to those in the node info it has, whose keys are ARGV[3] followed by the hex address.
+var kvWithdrawalAddresses = kvHex + `
+local function withdrawalAddresses(info)
+	local out = {}
+	if not info or #info < ` + strconv.Itoa(unchangedNodeInfoLength) + ` then
+		return out
+	end
+
+	for i = 0, 1 do
+		local first = ` + strconv.Itoa(legacyNodeInfoLength) + ` + i * ` + strconv.Itoa(common.AddressLength) + ` + 1
+		local addr = info:sub(first, first + ` + strconv.Itoa(common.AddressLength-1) + `)
+		if addr ~= string.rep('\0', ` + strconv.Itoa(common.AddressLength) + `) then
+			out[addr] = true
+		end
+	end
+	return out
+end
+
+local function reindex(previous, info)
+	local before = withdrawalAddresses(previous)
+	local after = withdrawalAddresses(info)
+	for addr in pairs(before) do
+		if not after[addr] then
+			redis.call('SREM', ARGV[3] .. hex(addr), ARGV[2])
+		end
+	end
+	for addr in pairs(after) do
+		redis.call('SADD', ARGV[3] .. hex(addr), ARGV[2])
+	end
+end
+`
+
+// Sets the node ARGV[2] to ARGV[4] in the hash KEYS[2], and moves it between the withdrawal address
+// sets, if the lease in KEYS[1] is held by ARGV[1]
+var kvSetNode = redis.NewScript(kvWithdrawalAddresses + `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return redis.error_reply('` + kvNotLeaseHolder + `')
+end
+
+reindex(redis.call('HGET', KEYS[2], ARGV[2]), ARGV[4])
+return redis.call('HSET', KEYS[2], ARGV[2], ARGV[4])
+`)
+
+// Removes the node ARGV[2] from the hash KEYS[2], and from its withdrawal address sets, if the lease in
+// KEYS[1] is held by ARGV[1]
+var kvRemoveNode = redis.NewScript(kvWithdrawalAddresses + `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return redis.error_reply('` + kvNotLeaseHolder + `')
+end
+
+local previous = redis.call('HGET', KEYS[2], ARGV[2])
+if not previous then
+	return 0
+end
+
+reindex(previous, nil)
+return redis.call('HDEL', KEYS[2], ARGV[2])
+`)
+
+// Extends the lease in KEYS[1] by ARGV[2] milliseconds if it's held by ARGV[1], returning 1 if it was
+var kvRenewLease = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+
+return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+`)
+
+// Deletes the lease in KEYS[1] if it's held by ARGV[1]
+var kvReleaseLease = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+
+return redis.call('DEL', KEYS[1])
+`)
+
+// KVCache keeps the cache in a key-value store which speaks the Redis protocol, so that it can be
+// shared by several instances of the proxy. Only the instance holding the ingestion lease writes to it.
+type KVCache struct {
+	// A redis://[[username]:password@]host[:port][/db] url
+	URL *url.URL
+	// Prepended to every key, so that caches for several networks can share a store
+	Prefix string
+	Logger *zap.Logger
+
+	client *redis.Client
+
+	// The instance this cache last acquired the ingestion lease for. Writes fail unless it still holds it.
+	ownerLock sync.Mutex
+	owner     string
+
+	// The last highest block read, which is served until it's kvHighestBlockTTL old,
+	// or if the store can't be reached
+	highestBlockLock sync.Mutex
+	highestBlock     *big.Int
+	highestBlockRead time.Time
+
+	// Set once the per-node minipool sets are known to be complete
+	minipoolsIndexed atomic.Bool
+
+	// Set once the withdrawal address sets are known to be complete
+	withdrawalNodesIndexed atomic.Bool
+
+	m *metrics.MetricsRegistry
+}
+
+func (k *KVCache) key(name string) string {
+	return k.Prefix + ":" + name
+}
+
+func (k *KVCache) init() error {
+	k.m = metrics.NewMetricsRegistry("kv_cache")
+	if k.Logger == nil {
+		k.Logger = zap.NewNop()
+	}
+	k.highestBlock = big.NewInt(0)
+
+	if k.URL == nil {
+		return fmt.Errorf("a key-value store url is required")
+	}
+	opts, err := redis.ParseURL(k.URL.String())
+	if err != nil {
+		return err
+	}
+	k.client = redis.NewClient(opts)
+
+	// Make sure the store is reachable before we rely on it
+	if err := k.client.Ping(context.Background()).Err(); err != nil {
+		k.client.Close()
+		return fmt.Errorf("unable to reach key-value store %s: %w", k.URL.Redacted(), err)
+	}
+
+	return nil
+}
+
+func (k *KVCache) leaseOwner() string {
+	k.ownerLock.Lock()
+	defer k.ownerLock.Unlock()
+
+	return k.owner
+}
+
+func (k *KVCache) fenceError(err error) error {
+	if redis.HasErrorPrefix(err, kvNotLeaseHolder) {
+		k.m.Counter("write_fenced").Inc()
+		return errNotLeaseHolder
+	}
+
+	return err
+}
+
+// Runs command with keys, then args, if this instance holds the ingestion lease
+func (k *KVCache) write(command string, keys []string, args ...interface{}) error {
+	err := kvFencedWrite.Run(context.Background(), k.client,
+		append([]string{k.key("ingestion_lease")}, keys...),
+		append([]interface{}{k.leaseOwner(), command}, args...)...).Err()
+
+	return k.fenceError(err)
+}
+
+func (k *KVCache) getMinipoolNode(pubkey rptypes.ValidatorPubkey) (common.Address, error) {
+	nodeAddr, err := k.client.HGet(context.Background(), k.key("minipools"), string(pubkey[:])).Bytes()
+	if err == redis.Nil {
+		return common.Address{}, &NotFoundError{}
+	}
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return common.BytesToAddress(nodeAddr), nil
+}
+
+// Each node's minipools are also kept in a set, so they can be listed without scanning every minipool
+func (k *KVCache) nodeMinipoolsKey(nodeAddr common.Address) string {
+	return k.key("node_minipools:") + hex.EncodeToString(nodeAddr.Bytes())
+}
+
+func (k *KVCache) addMinipoolNode(pubkey rptypes.ValidatorPubkey, nodeAddr common.Address) error {
+	err := kvAddMinipool.Run(context.Background(), k.client,
+		[]string{k.key("ingestion_lease"), k.key("minipools"), k.nodeMinipoolsKey(nodeAddr)},
+		k.leaseOwner(), pubkey[:], nodeAddr.Bytes(), k.key("node_minipools:")).Err()
+
+	return k.fenceError(err)
+}
+
+func (k *KVCache) removeMinipoolNode(pubkey rptypes.ValidatorPubkey) error {
+	err := kvRemoveMinipool.Run(context.Background(), k.client,
+		[]string{k.key("ingestion_lease"), k.key("minipools")},
+		k.leaseOwner(), pubkey[:], k.key("node_minipools:")).Err()
+
+	return k.fenceError(err)
+}
+
+func (k *KVCache) forEachMinipool(closure ForEachMinipoolClosure) error {
+	ctx := context.Background()
+
+	// HSCAN returns fields and values alternately
+	iter := k.client.HScan(ctx, k.key("minipools"), 0, "", kvScanCount).Iterator()
+	for iter.Next(ctx) {
+		pubkey := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+
+		if !closure(rptypes.BytesToValidatorPubkey([]byte(pubkey)), common.BytesToAddress([]byte(iter.Val()))) {
+			return nil
+		}
+	}
+
+	return iter.Err()
+}
+
+// Whether the per-node minipool sets are complete. Stores written by versions which didn't keep
+// them are missing some, until the ingestion lease holder builds them with indexNodeMinipools.
+func (k *KVCache) nodeMinipoolsIndexed() (bool, error) {
+	if k.minipoolsIndexed.Load() {
+		return true, nil
+	}
+
+	n, err := k.client.Exists(context.Background(), k.key("node_minipools_indexed")).Result()
+	if err != nil {
+		return false, err
+	}
+
+	// Once built, the sets are kept complete, so there's no need to check again
+	indexed := n > 0
+	k.minipoolsIndexed.Store(indexed)
+	return indexed, nil
+}
+
+func (k *KVCache) getNodeMinipools(nodeAddr common.Address) ([]rptypes.ValidatorPubkey, error) {
+	indexed, err := k.nodeMinipoolsIndexed()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]rptypes.ValidatorPubkey, 0)
+	if !indexed {
+		k.m.Counter("node_minipools_scanned").Inc()
+		err := k.forEachMinipool(func(pubkey rptypes.ValidatorPubkey, addr common.Address) bool {
+			if addr == nodeAddr {
+				out = append(out, pubkey)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	}
+
+	pubkeys, err := k.client.SMembers(context.Background(), k.nodeMinipoolsKey(nodeAddr)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pubkey := range pubkeys {
+		out = append(out, rptypes.BytesToValidatorPubkey([]byte(pubkey)))
+	}
+
+	return out, nil
+}
+
+// Deletes every key starting with the prefix, such as the per-node minipool sets
+func (k *KVCache) removeKeys(prefix string) error {
+	ctx := context.Background()
+
+	// Glob characters in the prefix must match themselves
+	pattern := strings.NewReplacer("\\", "\\\\", "*", "\\*", "?", "\\?", "[", "\\[", "]", "\\]").
+		Replace(prefix) + "*"
+
+	keys := make([]string, 0, kvScanCount)
+	iter := k.client.Scan(ctx, 0, pattern, kvScanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) < kvScanCount {
+			continue
+		}
+
+		if err := k.write("DEL", keys); err != nil {
+			return err
+		}
+		keys = keys[:0]
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+	return k.write("DEL", keys)
+}
+
+// Builds the per-node minipool sets from the minipools, if they weren't kept as the minipools were added
+func (k *KVCache) indexNodeMinipools() error {
+	indexed, err := k.nodeMinipoolsIndexed()
+	if err != nil || indexed {
+		return err
+	}
+
+	// Start over, in case an earlier attempt was interrupted
+	if err := k.removeKeys(k.key("node_minipools:")); err != nil {
+		return err
+	}
+
+	nodeMinipools := make(map[common.Address][]interface{})
+	err = k.forEachMinipool(func(pubkey rptypes.ValidatorPubkey, nodeAddr common.Address) bool {
+		nodeMinipools[nodeAddr] = append(nodeMinipools[nodeAddr], pubkey[:])
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	k.Logger.Info("Indexing the shared cache's minipools by node", zap.Int("nodes", len(nodeMinipools)))
+	for nodeAddr, pubkeys := range nodeMinipools {
+		if err := k.write("SADD", []string{k.nodeMinipoolsKey(nodeAddr)}, pubkeys...); err != nil {
+			return err
+		}
+	}
+
+	return k.write("SET", []string{k.key("node_minipools_indexed")}, 1)
+}
+
+// Node info is stored as the smoothing pool status byte, then the fee distributor,
+// then the big-endian unix time of the last smoothing pool status change, or 0 if unknown,
+// then the withdrawal address and the RPL withdrawal address, which is zero unless set,
+// then the unix times the node registered and its minipools and odao membership last changed
+const nodeInfoLength = unchangedNodeInfoLength + 3*8
+
+// Entries written before the other change times were kept end after the withdrawal addresses
+const unchangedNodeInfoLength = legacyNodeInfoLength + 2*common.AddressLength
+
+// Entries written before withdrawal addresses were kept end after the smoothing pool change time.
+// They decode with withdrawalAddressesUnknown set, and are re-encoded the same way until the
+// ingestion lease holder reads their withdrawal addresses from the chain.
+const legacyNodeInfoLength = 1 + common.AddressLength + 8
+
+func encodeNodeInfo(node *nodeInfo) []byte {
+	length := nodeInfoLength
+	if node.withdrawalAddressesUnknown {
+		length = legacyNodeInfoLength
+	}
+	out := make([]byte, length)
+	if node.inSmoothingPool {
+		out[0] = 1
+	}
+	copy(out[1:], node.feeDistributor.Bytes())
+	binary.BigEndian.PutUint64(out[1+common.AddressLength:], uint64(unixTime(node.smoothingPoolChanged)))
+	if node.withdrawalAddressesUnknown {
+		return out
+	}
+
+	copy(out[legacyNodeInfoLength:], node.withdrawalAddress.Bytes())
+	copy(out[legacyNodeInfoLength+common.AddressLength:], node.rplWithdrawalAddress.Bytes())
+	for i, changed := range []time.Time{node.feeDistributorChanged, node.minipoolsChanged, node.odaoChanged} {
+		binary.BigEndian.PutUint64(out[unchangedNodeInfoLength+i*8:], uint64(unixTime(changed)))
+	}
+
+	return out
+}
+
+func decodeNodeInfo(b []byte) (*nodeInfo, error) {
+	if len(b) != nodeInfoLength && len(b) != unchangedNodeInfoLength && len(b) != legacyNodeInfoLength {
+		return nil, fmt.Errorf("invalid node info length %d", len(b))
+	}
+
+	out := &nodeInfo{
+		inSmoothingPool:      b[0] == 1,
+		feeDistributor:       common.BytesToAddress(b[1 : 1+common.AddressLength]),
+		smoothingPoolChanged: fromUnixTime(int64(binary.BigEndian.Uint64(b[1+common.AddressLength:]))),
+	}
+	if len(b) == legacyNodeInfoLength {
+		out.withdrawalAddressesUnknown = true
+		return out, nil
+	}
+
+	out.withdrawalAddress = common.BytesToAddress(b[legacyNodeInfoLength : legacyNodeInfoLength+common.AddressLength])
+	out.rplWithdrawalAddress = common.BytesToAddress(b[legacyNodeInfoLength+common.AddressLength : unchangedNodeInfoLength])
+	if len(b) == nodeInfoLength {
+		for i, changed := range []*time.Time{&out.feeDistributorChanged, &out.minipoolsChanged, &out.odaoChanged} {
+			*changed = fromUnixTime(int64(binary.BigEndian.Uint64(b[unchangedNodeInfoLength+i*8:])))
+		}
+	}
+
+	return out, nil
+}
+
+func (k *KVCache) getNodeInfo(nodeAddr common.Address) (*nodeInfo, error) {
+	b, err := k.client.HGet(context.Background(), k.key("nodes"), string(nodeAddr.Bytes())).Bytes()
+	if err == redis.Nil {
+		return nil, &NotFoundError{}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeNodeInfo(b)
+}
+
+// Nodes are also kept in a set for each of their withdrawal addresses, so they can be found without
+// scanning every node
+func (k *KVCache) withdrawalNodesKey(withdrawalAddr common.Address) string {
+	return k.key("withdrawal_nodes:") + hex.EncodeToString(withdrawalAddr.Bytes())
+}
+
+func (k *KVCache) addNodeInfo(nodeAddr common.Address, node *nodeInfo) error {
+	err := kvSetNode.Run(context.Background(), k.client,
+		[]string{k.key("ingestion_lease"), k.key("nodes")},
+		k.leaseOwner(), nodeAddr.Bytes(), k.key("withdrawal_nodes:"), encodeNodeInfo(node)).Err()
+
+	return k.fenceError(err)
+}
+
+func (k *KVCache) removeNodeInfo(nodeAddr common.Address) error {
+	err := kvRemoveNode.Run(context.Background(), k.client,
+		[]string{k.key("ingestion_lease"), k.key("nodes")},
+		k.leaseOwner(), nodeAddr.Bytes(), k.key("withdrawal_nodes:")).Err()
+
+	return k.fenceError(err)
+}
+
+func (k *KVCache) forEachNode(closure ForEachNodeClosure) error {
+	ctx := context.Background()
+
+	// HSCAN returns fields and values alternately, so skip the values
+	iter := k.client.HScan(ctx, k.key("nodes"), 0, "", kvScanCount).Iterator()
+	for iter.Next(ctx) {
+		addr := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+
+		if !closure(common.BytesToAddress([]byte(addr))) {
+			return nil
+		}
+	}
+
+	return iter.Err()
+}
+
+func (k *KVCache) getLegacyNodes() ([]common.Address, error) {
+	ctx := context.Background()
+	out := make([]common.Address, 0)
+
+	iter := k.client.HScan(ctx, k.key("nodes"), 0, "", kvScanCount).Iterator()
+	for iter.Next(ctx) {
+		addr := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+
+		if len(iter.Val()) == legacyNodeInfoLength {
+			out = append(out, common.BytesToAddress([]byte(addr)))
+		}
+	}
+
+	return out, iter.Err()
+}
+
+// Whether the withdrawal address sets are complete. Stores written by versions which didn't keep
+// them are missing some, until the ingestion lease holder builds them with indexWithdrawalAddresses.
+func (k *KVCache) withdrawalAddressesIndexed() (bool, error) {
+	if k.withdrawalNodesIndexed.Load() {
+		return true, nil
+	}
+
+	n, err := k.client.Exists(context.Background(), k.key("withdrawal_nodes_indexed")).Result()
+	if err != nil {
+		return false, err
+	}
+
+	// Once built, the sets are kept complete, so there's no need to check again
+	indexed := n > 0
+	k.withdrawalNodesIndexed.Store(indexed)
+	return indexed, nil
+}
+
+func (k *KVCache) getWithdrawalAddressNodes(withdrawalAddr common.Address) ([]common.Address, error) {
+	indexed, err := k.withdrawalAddressesIndexed()
+	if err != nil {
+		return nil, err
+	}
+
+	if indexed {
+		nodes, err := k.client.SMembers(context.Background(), k.withdrawalNodesKey(withdrawalAddr)).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		out := make([]common.Address, 0, len(nodes))
+		for _, nodeAddr := range nodes {
+			out = append(out, common.BytesToAddress([]byte(nodeAddr)))
+		}
+		return out, nil
+	}
+
+	k.m.Counter("withdrawal_nodes_scanned").Inc()
+	return k.scanWithdrawalAddressNodes(withdrawalAddr)
+}
+
+// Scans every node, for stores whose withdrawal address sets haven't been built yet
+func (k *KVCache) scanWithdrawalAddressNodes(withdrawalAddr common.Address) ([]common.Address, error) {
+	ctx := context.Background()
+	out := make([]common.Address, 0)
+
+	iter := k.client.HScan(ctx, k.key("nodes"), 0, "", kvScanCount).Iterator()
+	for iter.Next(ctx) {
+		addr := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+
+		n, err := decodeNodeInfo([]byte(iter.Val()))
+		if err != nil {
+			return nil, err
+		}
+
+		if n.withdrawalAddress == withdrawalAddr || n.rplWithdrawalAddress == withdrawalAddr {
+			out = append(out, common.BytesToAddress([]byte(addr)))
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Builds the withdrawal address sets from the nodes, if they weren't kept as the nodes were added
+func (k *KVCache) indexWithdrawalAddresses() error {
+	indexed, err := k.withdrawalAddressesIndexed()
+	if err != nil || indexed {
+		return err
+	}
+
+	// Start over, in case an earlier attempt was interrupted
+	if err := k.removeKeys(k.key("withdrawal_nodes:")); err != nil {
+		return err
+	}
+
+	withdrawalNodes := make(map[common.Address][]interface{})
+	ctx := context.Background()
+	iter := k.client.HScan(ctx, k.key("nodes"), 0, "", kvScanCount).Iterator()
+	for iter.Next(ctx) {
+		addr := iter.Val()
+		if !iter.Next(ctx) {
+			break
+		}
+
+		n, err := decodeNodeInfo([]byte(iter.Val()))
+		if err != nil {
+			return err
+		}
+		if n.withdrawalAddressesUnknown {
+			continue
+		}
+
+		for _, withdrawalAddr := range []common.Address{n.withdrawalAddress, n.rplWithdrawalAddress} {
+			if withdrawalAddr != (common.Address{}) {
+				withdrawalNodes[withdrawalAddr] = append(withdrawalNodes[withdrawalAddr], addr)
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	k.Logger.Info("Indexing the shared cache's nodes by withdrawal address", zap.Int("addresses", len(withdrawalNodes)))
+	for withdrawalAddr, nodes := range withdrawalNodes {
+		if err := k.write("SADD", []string{k.withdrawalNodesKey(withdrawalAddr)}, nodes...); err != nil {
+			return err
+		}
+	}
+
+	return k.write("SET", []string{k.key("withdrawal_nodes_indexed")}, 1)
+}
+
+func (k *KVCache) addOdaoNode(nodeAddr common.Address) error {
+	return k.write("SADD", []string{k.key("odao_nodes")}, nodeAddr.Bytes())
+}
+
+func (k *KVCache) removeOdaoNode(nodeAddr common.Address) error {
+	return k.write("SREM", []string{k.key("odao_nodes")}, nodeAddr.Bytes())
+}
+
+func (k *KVCache) forEachOdaoNode(closure ForEachNodeClosure) error {
+	ctx := context.Background()
+
+	iter := k.client.SScan(ctx, k.key("odao_nodes"), 0, "", kvScanCount).Iterator()
+	for iter.Next(ctx) {
+		if !closure(common.BytesToAddress([]byte(iter.Val()))) {
+			return nil
+		}
+	}
+
+	return iter.Err()
+}
+
+func (k *KVCache) isOdaoNode(nodeAddr common.Address) (bool, error) {
+	return k.client.SIsMember(context.Background(), k.key("odao_nodes"), nodeAddr.Bytes()).Result()
+}
+
+// Processed events are kept in a sorted set scored by block number. Each member starts with a sequence
+// number, so that events in the same block sort in the order they were added, and ends with the log index.
+// The sequence number is prepended by kvAddProcessedEvent.
+// Members written before the log index was recorded lack it.
+const processedEventLength = 8 + 8 + common.HashLength*2 + common.AddressLength + len(rptypes.ValidatorPubkey{})
+
+func encodeProcessedEvent(event *processedEvent) []byte {
+	out := make([]byte, 0, processedEventLength)
+	out = binary.BigEndian.AppendUint64(out, event.blockNumber)
+	out = append(out, event.blockHash.Bytes()...)
+	out = append(out, event.topic.Bytes()...)
+	out = append(out, event.address.Bytes()...)
+	out = append(out, event.pubkey[:]...)
+	out = binary.BigEndian.AppendUint64(out, uint64(event.logIndex))
+	return out
+}
+
+func decodeProcessedEvent(b []byte) (*processedEvent, error) {
+	if len(b) != processedEventLength && len(b) != processedEventLength+8 {
+		return nil, fmt.Errorf("invalid processed event length %d", len(b))
+	}
+
+	r := bytes.NewReader(b[8:])
+	var blockNumber uint64
+	var blockHash, topic common.Hash
+	var address common.Address
+	var pubkey rptypes.ValidatorPubkey
+	for _, field := range []interface{}{&blockNumber, &blockHash, &topic, &address, &pubkey} {
+		if err := binary.Read(r, binary.BigEndian, field); err != nil {
+			return nil, err
+		}
+	}
+
+	logIndex := unknownLogIndex
+	if len(b) == processedEventLength+8 {
+		logIndex = uint(binary.BigEndian.Uint64(b[processedEventLength:]))
+	}
+
+	return &processedEvent{
+		blockNumber: blockNumber,
+		blockHash:   blockHash,
+		logIndex:    logIndex,
+		topic:       topic,
+		address:     address,
+		pubkey:      pubkey,
+	}, nil
+}
+
+func (k *KVCache) addProcessedEvent(event *processedEvent) error {
+	// The sequence number is taken and the event added in one script, so neither happens without the other
+	err := kvAddProcessedEvent.Run(context.Background(), k.client,
+		[]string{k.key("ingestion_lease"), k.key("events_seq"), k.key("events")},
+		k.leaseOwner(), event.blockNumber, encodeProcessedEvent(event)).Err()
+
+	return k.fenceError(err)
+}
+
+func (k *KVCache) getProcessedEvents(fromBlock uint64) ([]*processedEvent, error) {
+	members, err := k.client.ZRangeByScore(context.Background(), k.key("events"), &redis.ZRangeBy{
+		Min: strconv.FormatUint(fromBlock, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*processedEvent, 0, len(members))
+	for _, member := range members {
+		event, err := decodeProcessedEvent([]byte(member))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, event)
+	}
+
+	return out, nil
+}
+
+func (k *KVCache) removeProcessedEvents(fromBlock uint64) error {
+	return k.write("ZREMRANGEBYSCORE", []string{k.key("events")}, fromBlock, "+inf")
+}
+
+func (k *KVCache) pruneProcessedEvents(beforeBlock uint64) error {
+	return k.write("ZREMRANGEBYSCORE", []string{k.key("events")}, "-inf", "("+strconv.FormatUint(beforeBlock, 10))
+}
+
+func (k *KVCache) readHighestBlock() (*big.Int, error) {
+	b, err := k.client.Get(context.Background(), k.key("highest_block")).Result()
+	if err == redis.Nil {
+		return big.NewInt(0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := big.NewInt(0).SetString(b, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid highest block %q", b)
+	}
+
+	return out, nil
+}
+
+func (k *KVCache) setHighestBlock(block *big.Int) {
+	k.highestBlockLock.Lock()
+	defer k.highestBlockLock.Unlock()
+
+	// Only the ingestion lease holder writes, and it keeps the last block it wrote,
+	// so there's no need to read it back first
+	if k.highestBlock.Cmp(block) >= 0 {
+		return
+	}
+
+	err := k.write("SET", []string{k.key("highest_block")}, block.String())
+	if err != nil {
+		k.m.Counter("highest_block_write_failed").Inc()
+		k.Logger.Warn("Couldn't save the highest block", zap.Stringer("block", block), zap.Error(err))
+		return
+	}
+
+	k.m.Gauge("highest_block").Set(float64(block.Uint64()))
+	k.highestBlock = big.NewInt(0).Set(block)
+	k.highestBlockRead = time.Now()
+}
+
+func (k *KVCache) getHighestBlock() *big.Int {
+	k.highestBlockLock.Lock()
+	defer k.highestBlockLock.Unlock()
+
+	// Signatures are checked against it, so don't make a round trip every time
+	if time.Since(k.highestBlockRead) < kvHighestBlockTTL {
+		return k.highestBlock
+	}
+
+	block, err := k.readHighestBlock()
+	if err != nil {
+		k.m.Counter("highest_block_read_failed").Inc()
+		k.Logger.Warn("Couldn't read the highest block, using the last one read", zap.Error(err))
+		return k.highestBlock
+	}
+
+	k.highestBlock = block
+	k.highestBlockRead = time.Now()
+	return block
+}
+
+// Tries to take the ingestion lease for owner, returning whether it succeeded
+func (k *KVCache) acquireLease(owner string, ttl time.Duration) (bool, error) {
+	acquired, err := k.client.SetNX(context.Background(), k.key("ingestion_lease"), owner, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if acquired {
+		k.ownerLock.Lock()
+		k.owner = owner
+		k.ownerLock.Unlock()
+	}
+
+	return acquired, nil
+}
+
+// Extends owner's ingestion lease, returning false if it's no longer held by owner.
+// If the lease expired and nobody else took it, it is taken again.
+func (k *KVCache) renewLease(owner string, ttl time.Duration) (bool, error) {
+	renewed, err := kvRenewLease.Run(context.Background(), k.client,
+		[]string{k.key("ingestion_lease")}, owner, ttl.Milliseconds()).Int()
+	if err != nil || renewed == 1 {
+		return renewed == 1, err
+	}
+
+	return k.acquireLease(owner, ttl)
+}
+
+// Gives up owner's ingestion lease, so another instance can take it without waiting for it to expire
+func (k *KVCache) releaseLease(owner string) error {
+	return kvReleaseLease.Run(context.Background(), k.client, []string{k.key("ingestion_lease")}, owner).Err()
+}
+
+func (k *KVCache) deinit() error {
+	return k.client.Close()
+}
+
+func (k *KVCache) reset() error {
+	err := k.write("DEL", []string{
+		k.key("minipools"),
+		k.key("nodes"),
+		k.key("odao_nodes"),
+		k.key("events"),
+		k.key("events_seq"),
+		k.key("highest_block"),
+	})
+	if err != nil {
+		return err
+	}
+
+	// An empty cache's per-node minipool and withdrawal address sets are complete
+	for _, prefix := range []string{"node_minipools", "withdrawal_nodes"} {
+		if err := k.removeKeys(k.key(prefix + ":")); err != nil {
+			return err
+		}
+		if err := k.write("SET", []string{k.key(prefix + "_indexed")}, 1); err != nil {
+			return err
+		}
+	}
+
+	k.highestBlockLock.Lock()
+	defer k.highestBlockLock.Unlock()
+	k.highestBlock = big.NewInt(0)
+	k.highestBlockRead = time.Now()
+	return nil
+}
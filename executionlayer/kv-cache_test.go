@@ -0,0 +1,955 @@
+package executionlayer
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"net/url"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+)
+
+func kvURL(store *miniredis.Miniredis) *url.URL {
+	return &url.URL{Scheme: "redis", Host: store.Addr()}
+}
+
+// Metrics must be initialized first
+func newTestKVCache(t *testing.T, store *miniredis.Miniredis) *KVCache {
+	k := &KVCache{
+		URL:    kvURL(store),
+		Prefix: t.Name(),
+	}
+	if err := k.init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = k.deinit()
+	})
+
+	return k
+}
+
+func initMetrics(t *testing.T) {
+	if _, err := metrics.Init("cc_test_" + t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(metrics.Deinit)
+}
+
+func TestKVCache(t *testing.T) {
+	initMetrics(t)
+	store := miniredis.RunT(t)
+	k := newTestKVCache(t, store)
+	if _, err := k.acquireLease(t.Name(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// Enough minipools to take several pages to iterate over
+	minipools := make(map[rptypes.ValidatorPubkey]common.Address)
+	for i := 0; i < kvScanCount*2+500; i++ {
+		var pubkey rptypes.ValidatorPubkey
+		big.NewInt(int64(i + 1)).FillBytes(pubkey[:])
+		minipools[pubkey] = common.BigToAddress(big.NewInt(int64(i % 7)))
+		if err := k.addMinipoolNode(pubkey, minipools[pubkey]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := make(map[rptypes.ValidatorPubkey]common.Address)
+	err := k.forEachMinipool(func(pubkey rptypes.ValidatorPubkey, addr common.Address) bool {
+		seen[pubkey] = addr
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(seen, minipools) {
+		t.Fatalf("expected %d minipools, iterated over %d", len(minipools), len(seen))
+	}
+
+	visited := 0
+	err = k.forEachMinipool(func(rptypes.ValidatorPubkey, common.Address) bool {
+		visited++
+		return visited < 3
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visited != 3 {
+		t.Fatalf("expected iteration to stop after 3 minipools, got %d", visited)
+	}
+
+	var pubkey rptypes.ValidatorPubkey
+	big.NewInt(1).FillBytes(pubkey[:])
+	if err := k.removeMinipoolNode(pubkey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.getMinipoolNode(pubkey); err == nil {
+		t.Fatal("expected removed minipool to be missing")
+	} else if _, ok := err.(*NotFoundError); !ok {
+		t.Fatal(err)
+	}
+
+	// A node's minipools are listed from its set
+	nodeMinipools, err := k.getNodeMinipools(common.BigToAddress(big.NewInt(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeMinipools) != len(minipools)/7 {
+		t.Fatalf("expected %d minipools, got %d", len(minipools)/7, len(nodeMinipools))
+	}
+	for _, pubkey := range nodeMinipools {
+		if minipools[pubkey] != common.BigToAddress(big.NewInt(2)) {
+			t.Fatalf("minipool %s belongs to %s", pubkey.String(), minipools[pubkey].String())
+		}
+	}
+
+	// Nodes round trip, including when they last changed and their withdrawal addresses
+	addr := common.HexToAddress("0x0f010f")
+	node := &nodeInfo{
+		inSmoothingPool:       true,
+		feeDistributor:        common.HexToAddress("0x0f020f"),
+		smoothingPoolChanged:  time.Unix(1700000000, 0),
+		feeDistributorChanged: time.Unix(1600000000, 0),
+		minipoolsChanged:      time.Unix(1700000001, 0),
+		odaoChanged:           time.Unix(1700000002, 0),
+		withdrawalAddress:     common.HexToAddress("0x0f040f"),
+		rplWithdrawalAddress:  common.HexToAddress("0x0f050f"),
+	}
+	if err := k.addNodeInfo(addr, node); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.addNodeInfo(common.HexToAddress("0x0f030f"), &nodeInfo{}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := k.getNodeInfo(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, node) {
+		t.Fatalf("expected %+v, got %+v", node, got)
+	}
+	got, err = k.getNodeInfo(common.HexToAddress("0x0f030f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.smoothingPoolChanged.IsZero() {
+		t.Fatalf("expected an unknown smoothing pool change time, got %v", got.smoothingPoolChanged)
+	}
+
+	// Entries written before the other change times were kept decode with them unknown
+	unchangedAddr := common.HexToAddress("0x0f070f")
+	store.HSet(k.key("nodes"), string(unchangedAddr.Bytes()), string(encodeNodeInfo(node)[:unchangedNodeInfoLength]))
+	got, err = k.getNodeInfo(unchangedAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.rplWithdrawalAddress != node.rplWithdrawalAddress || !got.smoothingPoolChanged.Equal(node.smoothingPoolChanged) ||
+		!got.minipoolsChanged.IsZero() || !got.odaoChanged.IsZero() || !got.feeDistributorChanged.IsZero() {
+		t.Fatalf("unexpected node %+v", got)
+	}
+	if err := k.removeNodeInfo(unchangedAddr); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries written before withdrawal addresses were kept decode with them unknown
+	legacyAddr := common.HexToAddress("0x0f060f")
+	legacy := encodeNodeInfo(node)[:legacyNodeInfoLength]
+	store.HSet(k.key("nodes"), string(legacyAddr.Bytes()), string(legacy))
+	got, err = k.getNodeInfo(legacyAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.feeDistributor != node.feeDistributor || !got.withdrawalAddressesUnknown {
+		t.Fatalf("unexpected legacy node %+v", got)
+	}
+
+	// and stay unknown when rewritten, until they're backfilled
+	got.inSmoothingPool = false
+	if err := k.addNodeInfo(legacyAddr, got); err != nil {
+		t.Fatal(err)
+	}
+	legacyNodes, err := k.getLegacyNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legacyNodes) != 1 || legacyNodes[0] != legacyAddr {
+		t.Fatalf("expected %s to be a legacy node, got %v", legacyAddr.String(), legacyNodes)
+	}
+
+	withdrawalNodes, err := k.getWithdrawalAddressNodes(node.rplWithdrawalAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(withdrawalNodes) != 1 || withdrawalNodes[0] != addr {
+		t.Fatalf("expected %s to use the withdrawal address, got %v", addr.String(), withdrawalNodes)
+	}
+
+	nodes := 0
+	if err := k.forEachNode(func(common.Address) bool { nodes++; return true }); err != nil {
+		t.Fatal(err)
+	}
+	if nodes != 3 {
+		t.Fatalf("expected 3 nodes, got %d", nodes)
+	}
+
+	if err := k.removeNodeInfo(addr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.getNodeInfo(addr); err == nil {
+		t.Fatal("expected removed node to be missing")
+	}
+
+	// Odao nodes
+	if err := k.addOdaoNode(addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.addOdaoNode(common.HexToAddress("0x0f030f")); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.removeOdaoNode(common.HexToAddress("0x0f030f")); err != nil {
+		t.Fatal(err)
+	}
+	odao := make([]common.Address, 0)
+	if err := k.forEachOdaoNode(func(a common.Address) bool { odao = append(odao, a); return true }); err != nil {
+		t.Fatal(err)
+	}
+	if len(odao) != 1 || odao[0] != addr {
+		t.Fatalf("expected only %s in the odao, got %v", addr, odao)
+	}
+	if member, err := k.isOdaoNode(addr); err != nil || !member {
+		t.Fatalf("expected %s to be an odao member, got %v, %v", addr, member, err)
+	}
+	if member, err := k.isOdaoNode(common.HexToAddress("0x0f030f")); err != nil || member {
+		t.Fatalf("expected 0x0f030f not to be an odao member, got %v, %v", member, err)
+	}
+
+	// Processed events come back ordered by block, then the order they were added
+	for _, block := range []uint64{10, 12, 12, 11} {
+		err := k.addProcessedEvent(&processedEvent{
+			blockNumber: block,
+			blockHash:   common.BigToHash(big.NewInt(int64(block))),
+			address:     common.BigToAddress(big.NewInt(int64(block))),
+			pubkey:      pubkey,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := k.getProcessedEvents(11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0].blockNumber != 11 || events[1].blockNumber != 12 || events[2].blockNumber != 12 {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if events[0].pubkey != pubkey || events[0].blockHash != common.BigToHash(big.NewInt(11)) {
+		t.Fatalf("event didn't round trip: %+v", events[0])
+	}
+
+	if err := k.pruneProcessedEvents(11); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.removeProcessedEvents(12); err != nil {
+		t.Fatal(err)
+	}
+	events, err = k.getProcessedEvents(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].blockNumber != 11 {
+		t.Fatalf("expected only the event at block 11 to remain, got %v", events)
+	}
+
+	// The highest block only moves forwards
+	k.setHighestBlock(big.NewInt(100))
+	k.setHighestBlock(big.NewInt(50))
+	if k.getHighestBlock().Int64() != 100 {
+		t.Fatalf("expected highest block 100, got %d", k.getHighestBlock().Int64())
+	}
+
+	// It's kept locally for a while, rather than read on every call
+	if err := store.Set(k.key("highest_block"), "200"); err != nil {
+		t.Fatal(err)
+	}
+	if k.getHighestBlock().Int64() != 100 {
+		t.Fatalf("expected the cached highest block 100, got %d", k.getHighestBlock().Int64())
+	}
+	k.highestBlockLock.Lock()
+	k.highestBlockRead = time.Time{}
+	k.highestBlockLock.Unlock()
+	if k.getHighestBlock().Int64() != 200 {
+		t.Fatalf("expected highest block 200 once read again, got %d", k.getHighestBlock().Int64())
+	}
+
+	if err := k.reset(); err != nil {
+		t.Fatal(err)
+	}
+	if k.getHighestBlock().Sign() != 0 {
+		t.Fatal("expected reset to clear the highest block")
+	}
+	if err := k.forEachMinipool(func(rptypes.ValidatorPubkey, common.Address) bool {
+		t.Fatal("expected reset to clear the minipools")
+		return false
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKVCacheIndexNodeMinipools(t *testing.T) {
+	initMetrics(t)
+	store := miniredis.RunT(t)
+	k := newTestKVCache(t, store)
+	if _, err := k.acquireLease(t.Name(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	pubkeys := make([]rptypes.ValidatorPubkey, 4)
+	for i := range pubkeys {
+		big.NewInt(int64(i + 1)).FillBytes(pubkeys[i][:])
+	}
+	nodeA := common.HexToAddress("0x0a")
+	nodeB := common.HexToAddress("0x0b")
+
+	expect := func(nodeAddr common.Address, expected ...rptypes.ValidatorPubkey) {
+		t.Helper()
+
+		got, err := k.getNodeMinipools(nodeAddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d minipools for %s, got %v", len(expected), nodeAddr.String(), got)
+		}
+		for _, pubkey := range expected {
+			if !slices.Contains(got, pubkey) {
+				t.Fatalf("expected %s to have minipool %s, got %v", nodeAddr.String(), pubkey.String(), got)
+			}
+		}
+	}
+
+	// Minipools written by a version which didn't keep per-node sets are found by scanning
+	for i, pubkey := range pubkeys[:3] {
+		nodeAddr := nodeA
+		if i == 2 {
+			nodeAddr = nodeB
+		}
+		store.HSet(k.key("minipools"), string(pubkey[:]), string(nodeAddr.Bytes()))
+	}
+	expect(nodeA, pubkeys[0], pubkeys[1])
+	if store.Exists(k.nodeMinipoolsKey(nodeA)) {
+		t.Fatal("expected no minipool set before indexing")
+	}
+
+	// Indexing builds the sets, which are used from then on
+	if err := k.indexNodeMinipools(); err != nil {
+		t.Fatal(err)
+	}
+	members, err := store.SMembers(k.nodeMinipoolsKey(nodeA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("expected 2 minipools in %s's set, got %d", nodeA.String(), len(members))
+	}
+	expect(nodeB, pubkeys[2])
+
+	// The sets follow minipools as they're added, moved and removed
+	if err := k.addMinipoolNode(pubkeys[3], nodeB); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.addMinipoolNode(pubkeys[0], nodeB); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.removeMinipoolNode(pubkeys[2]); err != nil {
+		t.Fatal(err)
+	}
+	expect(nodeA, pubkeys[1])
+	expect(nodeB, pubkeys[0], pubkeys[3])
+
+	// Resetting empties them, and leaves the empty cache indexed
+	if err := k.reset(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Exists(k.nodeMinipoolsKey(nodeB)) {
+		t.Fatal("expected the minipool sets to be removed")
+	}
+	if !store.Exists(k.key("node_minipools_indexed")) {
+		t.Fatal("expected the reset cache to be indexed")
+	}
+	expect(nodeB)
+}
+
+func TestKVCacheIndexWithdrawalAddresses(t *testing.T) {
+	initMetrics(t)
+	store := miniredis.RunT(t)
+	k := newTestKVCache(t, store)
+	if _, err := k.acquireLease(t.Name(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	nodeA := common.HexToAddress("0x0a")
+	nodeB := common.HexToAddress("0x0b")
+	nodeC := common.HexToAddress("0x0c")
+	withdrawalA := common.HexToAddress("0x0a0a")
+	withdrawalB := common.HexToAddress("0x0b0b")
+
+	expect := func(withdrawalAddr common.Address, expected ...common.Address) {
+		t.Helper()
+
+		got, err := k.getWithdrawalAddressNodes(withdrawalAddr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d nodes for %s, got %v", len(expected), withdrawalAddr.String(), got)
+		}
+		for _, nodeAddr := range expected {
+			if !slices.Contains(got, nodeAddr) {
+				t.Fatalf("expected %s to be used by %s, got %v", withdrawalAddr.String(), nodeAddr.String(), got)
+			}
+		}
+	}
+
+	// Nodes written by a version which didn't keep withdrawal address sets are found by scanning.
+	// Nodes from before withdrawal addresses were kept have none.
+	store.HSet(k.key("nodes"), string(nodeA.Bytes()), string(encodeNodeInfo(&nodeInfo{withdrawalAddress: withdrawalA})))
+	store.HSet(k.key("nodes"), string(nodeB.Bytes()),
+		string(encodeNodeInfo(&nodeInfo{withdrawalAddress: nodeB, rplWithdrawalAddress: withdrawalA})))
+	store.HSet(k.key("nodes"), string(nodeC.Bytes()), string(encodeNodeInfo(&nodeInfo{withdrawalAddressesUnknown: true})))
+	expect(withdrawalA, nodeA, nodeB)
+	if store.Exists(k.withdrawalNodesKey(withdrawalA)) {
+		t.Fatal("expected no withdrawal address set before indexing")
+	}
+
+	// Indexing builds the sets, which are used from then on
+	if err := k.indexWithdrawalAddresses(); err != nil {
+		t.Fatal(err)
+	}
+	members, err := store.SMembers(k.withdrawalNodesKey(withdrawalA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("expected 2 nodes in %s's set, got %d", withdrawalA.String(), len(members))
+	}
+	expect(nodeB, nodeB)
+	expect(common.Address{})
+
+	// The sets follow nodes as their withdrawal addresses are set, changed and unset, and as they're removed
+	if err := k.addNodeInfo(nodeC, &nodeInfo{withdrawalAddress: withdrawalB}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.addNodeInfo(nodeA, &nodeInfo{withdrawalAddress: withdrawalB, rplWithdrawalAddress: withdrawalB}); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.addNodeInfo(nodeB, &nodeInfo{withdrawalAddress: nodeB}); err != nil {
+		t.Fatal(err)
+	}
+	expect(withdrawalA)
+	expect(withdrawalB, nodeA, nodeC)
+	if err := k.removeNodeInfo(nodeA); err != nil {
+		t.Fatal(err)
+	}
+	expect(withdrawalB, nodeC)
+	expect(nodeB, nodeB)
+
+	// Only the lease holder may change them
+	if err := k.releaseLease(t.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.removeNodeInfo(nodeC); err != errNotLeaseHolder {
+		t.Fatalf("expected the write to be fenced, got %v", err)
+	}
+	expect(withdrawalB, nodeC)
+	if _, err := k.acquireLease(t.Name(), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resetting empties them, and leaves the empty cache indexed
+	if err := k.reset(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Exists(k.withdrawalNodesKey(withdrawalB)) {
+		t.Fatal("expected the withdrawal address sets to be removed")
+	}
+	if !store.Exists(k.key("withdrawal_nodes_indexed")) {
+		t.Fatal("expected the reset cache to be indexed")
+	}
+	expect(withdrawalB)
+}
+
+func TestKVCacheLease(t *testing.T) {
+	initMetrics(t)
+	store := miniredis.RunT(t)
+	k := newTestKVCache(t, store)
+
+	acquire := func(owner string, ttl time.Duration, expected bool) {
+		t.Helper()
+		acquired, err := k.acquireLease(owner, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acquired != expected {
+			t.Fatalf("expected %s acquiring the lease to be %v", owner, expected)
+		}
+	}
+	renew := func(owner string, ttl time.Duration, expected bool) {
+		t.Helper()
+		renewed, err := k.renewLease(owner, ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if renewed != expected {
+			t.Fatalf("expected %s renewing the lease to be %v", owner, expected)
+		}
+	}
+
+	acquire("a", time.Minute, true)
+	acquire("b", time.Minute, false)
+	renew("a", time.Minute, true)
+	renew("b", time.Minute, false)
+
+	// Only the holder can release it
+	if err := k.releaseLease("b"); err != nil {
+		t.Fatal(err)
+	}
+	acquire("b", time.Minute, false)
+	if err := k.releaseLease("a"); err != nil {
+		t.Fatal(err)
+	}
+	acquire("b", 50*time.Millisecond, true)
+
+	// Once it expires, anyone can take it
+	store.FastForward(100 * time.Millisecond)
+	acquire("a", time.Minute, true)
+	renew("b", time.Minute, false)
+
+	// Writes only succeed while the lease is held by whoever last acquired it
+	var pubkey rptypes.ValidatorPubkey
+	if err := k.addMinipoolNode(pubkey, common.HexToAddress("0x0f010f")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(k.key("ingestion_lease"), "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.removeMinipoolNode(pubkey); err != errNotLeaseHolder {
+		t.Fatalf("expected writes without the lease to be refused, got %v", err)
+	}
+	if err := k.addProcessedEvent(&processedEvent{blockNumber: 1}); err != errNotLeaseHolder {
+		t.Fatalf("expected writes without the lease to be refused, got %v", err)
+	}
+	if _, err := k.getMinipoolNode(pubkey); err != nil {
+		t.Fatalf("expected the minipool to remain, got %v", err)
+	}
+	if err := store.Set(k.key("ingestion_lease"), "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A holder whose lease expired without anyone taking it gets it back when renewing
+	if err := k.releaseLease("a"); err != nil {
+		t.Fatal(err)
+	}
+	renew("a", time.Minute, true)
+	acquire("b", time.Minute, false)
+}
+
+func minipoolLaunchedLog(et *elTest, minipoolAddr common.Address, nodeAddr common.Address) (types.Log, rptypes.ValidatorPubkey) {
+	h, err := hex.DecodeString(pubkeyFromMinipool(minipoolAddr))
+	if err != nil {
+		et.t.Fatal(err)
+	}
+
+	return types.Log{
+		Address: common.HexToAddress(rocketMinipoolManager),
+		Topics: []common.Hash{
+			common.BytesToHash(
+				et.ec.minipoolLaunchedTopic.Bytes(),
+			),
+			common.BytesToHash(
+				minipoolAddr.Bytes(),
+			),
+			common.BytesToHash(
+				nodeAddr.Bytes(),
+			),
+		},
+	}, rptypes.BytesToValidatorPubkey(h)
+}
+
+func TestKVELShared(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	store := miniredis.RunT(t)
+	et := setup(t, hec)
+	et.ec.CacheKVURL = kvURL(store)
+	et.ec.CacheKVPrefix = t.Name()
+	et.ec.IngestionLeaseTTL = 300 * time.Millisecond
+	key := func(name string) string {
+		return t.Name() + ":" + name
+	}
+
+	// Another instance holds the lease, and has warmed the cache up
+	a := common.HexToAddress("0x0f010f")
+	_, pubkey := minipoolLaunchedLog(et, common.HexToAddress("0x1f101f"), a)
+	if err := store.Set(key("ingestion_lease"), "other"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(key("highest_block"), "1"); err != nil {
+		t.Fatal(err)
+	}
+	store.HSet(key("nodes"), string(a.Bytes()), string(encodeNodeInfo(&nodeInfo{feeDistributor: a})))
+
+	// This one follows it
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if et.ec.ingestionOwner.Load() {
+		t.Fatal("expected the instance to follow")
+	}
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+
+	// and sees what the other instance writes
+	rpInfo, err := et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpInfo != nil {
+		t.Fatal("expected the minipool to be unknown")
+	}
+	store.HSet(key("minipools"), string(pubkey[:]), string(a.Bytes()))
+	rpInfo, err = et.ec.GetRPInfo(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpInfo == nil || rpInfo.NodeAddress != a {
+		t.Fatalf("expected the follower to see the new minipool, got %+v", rpInfo)
+	}
+
+	// Followers don't write
+	head, err := et.ec.getClient().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := common.HexToAddress("0x0f020f")
+	et.ec.handleEvent(nodeRegisteredLog(et, b, head))
+	if hasNode(t, et, b) {
+		t.Fatal("expected the follower not to ingest events")
+	}
+
+	// It takes over when the other instance goes away
+	store.Del(key("ingestion_lease"))
+	select {
+	case <-et.ec.connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the follower to take over ingestion")
+	}
+	if !et.ec.ingestionOwner.Load() {
+		t.Fatal("expected the follower to own ingestion")
+	}
+
+	et.ec.handleEvent(nodeRegisteredLog(et, b, head))
+	if !hasNode(t, et, b) {
+		t.Fatal("expected the new owner to ingest events")
+	}
+	if !hasNode(t, et, a) {
+		t.Fatal("expected the new owner to keep what the old one ingested")
+	}
+
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if store.Exists(key("ingestion_lease")) {
+		t.Fatal("expected the lease to be released")
+	}
+}
+
+func TestKVELWaitsForWarmup(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	store := miniredis.RunT(t)
+	et := setup(t, hec)
+	et.ec.CacheKVURL = kvURL(store)
+	et.ec.CacheKVPrefix = t.Name()
+	et.ec.IngestionLeaseTTL = 150 * time.Millisecond
+
+	// Another instance holds the lease, but hasn't warmed the cache up yet
+	if err := store.Set(t.Name()+":ingestion_lease", "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	initErrs := make(chan error)
+	go func() {
+		initErrs <- et.ec.Init()
+	}()
+
+	select {
+	case err := <-initErrs:
+		t.Fatalf("expected Init to wait for the cache to be warmed up, got %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// When the other instance goes away, this one takes over and warms the cache up itself
+	store.Del(t.Name() + ":ingestion_lease")
+	select {
+	case err := <-initErrs:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Init to finish once the lease was released")
+	}
+
+	if !et.ec.ingestionOwner.Load() {
+		t.Fatal("expected to own ingestion")
+	}
+	if !hasNode(t, et, hec.nodes[0].addr) {
+		t.Fatal("expected the cache to be warmed up")
+	}
+
+	errs := make(chan error)
+	go func() {
+		if err := et.ec.Start(); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+	<-et.ec.connected
+
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKVELLongWarmupKeepsLease(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	store := miniredis.RunT(t)
+	et := setup(t, &latencyEC{hec, 10 * time.Millisecond})
+	et.ec.CacheKVURL = kvURL(store)
+	et.ec.CacheKVPrefix = t.Name()
+	et.ec.IngestionLeaseTTL = 60 * time.Millisecond
+
+	// miniredis only expires keys when told time has passed
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				store.FastForward(5 * time.Millisecond)
+			}
+		}
+	}()
+
+	// The warmup outlasts the lease, which has to be renewed while it runs
+	start := time.Now()
+	errs := startEL(t, et)
+	if elapsed := time.Since(start); elapsed < et.ec.IngestionLeaseTTL {
+		t.Fatalf("expected the warmup to take longer than the lease lasts, took %v", elapsed)
+	}
+	if !et.ec.ingestionOwner.Load() {
+		t.Fatal("expected to still own ingestion")
+	}
+	if owner, err := store.Get(t.Name() + ":ingestion_lease"); err != nil || owner != et.ec.instanceID {
+		t.Fatalf("expected to still hold the lease, got %q %v", owner, err)
+	}
+	if !hasNode(t, et, hec.nodes[0].addr) {
+		t.Fatal("expected the cache to be warmed up")
+	}
+
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKVELLeaseLost(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	store := miniredis.RunT(t)
+	et := setup(t, hec)
+	et.ec.CacheKVURL = kvURL(store)
+	et.ec.CacheKVPrefix = t.Name()
+	et.ec.IngestionLeaseTTL = 150 * time.Millisecond
+	errs := startEL(t, et)
+	lease := t.Name() + ":ingestion_lease"
+
+	// Another instance takes the lease, eg after this one was partitioned from the store
+	if err := store.Set(lease, "other"); err != nil {
+		t.Fatal(err)
+	}
+
+	// This one stops ingesting and follows it, rather than exiting
+	deadline := time.Now().Add(5 * time.Second)
+	for et.ec.ingestionOwner.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected ingestion to stop when the lease was lost")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		t.Fatalf("expected to keep running as a follower, got %v", err)
+	default:
+	}
+
+	head, err := et.ec.getClient().HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.HexToAddress("0x0f010f")
+	et.ec.handleEvent(nodeRegisteredLog(et, a, head))
+	if hasNode(t, et, a) {
+		t.Fatal("expected writes to be refused without the lease")
+	}
+
+	// and takes over again once the other instance goes away
+	store.Del(lease)
+	select {
+	case <-et.ec.connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected to take over ingestion again")
+	}
+	if !et.ec.ingestionOwner.Load() {
+		t.Fatal("expected to own ingestion again")
+	}
+
+	// Stopping leaves another instance's lease alone
+	if err := store.Set(lease, "other"); err != nil {
+		t.Fatal(err)
+	}
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	holder, err := store.Get(lease)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder != "other" {
+		t.Fatalf("expected the other instance to hold the lease, got %s", holder)
+	}
+}
+
+func testKVELLegacyNodes(t *testing.T, follow bool) {
+	withdrawalAddr := common.HexToAddress("0x0a010a")
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:              common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:              true,
+				minipools:         1,
+				withdrawalAddress: withdrawalAddr,
+			},
+		},
+		[]*mockNode{},
+	}
+	store := miniredis.RunT(t)
+	et := setup(t, hec)
+	et.ec.CacheKVURL = kvURL(store)
+	et.ec.CacheKVPrefix = t.Name()
+	key := func(name string) string {
+		return t.Name() + ":" + name
+	}
+
+	// An older version warmed the cache up without withdrawal addresses
+	addr := hec.nodes[0].addr
+	legacy := encodeNodeInfo(&nodeInfo{inSmoothingPool: true})[:legacyNodeInfoLength]
+	store.HSet(key("nodes"), string(addr.Bytes()), string(legacy))
+	if err := store.Set(key("highest_block"), "1"); err != nil {
+		t.Fatal(err)
+	}
+	if follow {
+		if err := store.Set(key("ingestion_lease"), "other"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := et.ec.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer et.ec.Stop()
+
+	// Either way, the node's withdrawal addresses are read from the chain
+	addrs, err := et.ec.GetNodeWithdrawalAddresses(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs == nil || addrs.WithdrawalAddress != withdrawalAddr || addrs.RPLWithdrawalAddress != nil {
+		t.Fatalf("unexpected withdrawal addresses %+v", addrs)
+	}
+
+	// but only the lease holder writes them back
+	got, err := decodeNodeInfo([]byte(store.HGet(key("nodes"), string(addr.Bytes()))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.withdrawalAddressesUnknown != follow {
+		t.Fatalf("expected the node's withdrawal addresses to be unknown: %t, got %+v", follow, got)
+	}
+	if !follow && (got.withdrawalAddress != withdrawalAddr || !got.inSmoothingPool) {
+		t.Fatalf("unexpected backfilled node %+v", got)
+	}
+}
+
+func TestKVELLegacyNodesBackfilled(t *testing.T) {
+	testKVELLegacyNodes(t, false)
+}
+
+func TestKVELLegacyNodesFollower(t *testing.T) {
+	testKVELLegacyNodes(t, true)
+}
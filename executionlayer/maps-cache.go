@@ -16,16 +16,27 @@ type MapsCache struct {
 	// the smoothing pool, no further validation is needed, so we can exit early based
 	// on membership in this map.
 	//
-	// Since this index is expected to strictly grow, we can use sync.Map to deal with
-	// concurrent access. Elements are only inserted, never deleted.
+	// Since this index is expected to grow, we can use sync.Map to deal with
+	// concurrent access. Elements are only deleted if the block that created them
+	// is reorganized out of the chain.
 	minipoolIndex *sync.Map
 
+	// The reverse of minipoolIndex, node address -> set of pubkeys, for looking up
+	// a node's minipools. It's updated alongside minipoolIndex under nodeMinipoolsLock.
+	nodeMinipoolsLock sync.Mutex
+	nodeMinipools     map[common.Address]map[rptypes.ValidatorPubkey]struct{}
+
 	// We need to store each node's smoothing pool status and fee recipient address.
 	// We will subscribe to rocketNodeManager's events stream, which will notify us of
 	// changes- to keep map contention down, we will use pointers as elements.
 	// Ergo, this is a map of node address -> *Node
 	nodeIndex *sync.Map
 
+	// Withdrawal address -> set of node addresses using it for ETH or RPL, for looking up
+	// a withdrawal address's nodes. It's updated alongside nodeIndex under withdrawalNodesLock.
+	withdrawalNodesLock sync.Mutex
+	withdrawalNodes     map[common.Address]map[common.Address]struct{}
+
 	// We store oDAO nodes for the api. We need to be able to remove them if they're
 	// kicked or leave, so this is a map of address -> bool
 	odaoNodeIndex *sync.Map
@@ -34,14 +45,28 @@ type MapsCache struct {
 	// backfill missing data, so we keep track of the highest block for which we received
 	// an event here.
 	highestBlock *big.Int
+
+	// Recently processed events, oldest first, so that reorgs can be rolled back
+	eventsLock sync.Mutex
+	events     []*processedEvent
 }
 
 func (m *MapsCache) init() error {
 
 	m.minipoolIndex = &sync.Map{}
+	m.nodeMinipoolsLock.Lock()
+	m.nodeMinipools = make(map[common.Address]map[rptypes.ValidatorPubkey]struct{})
+	m.nodeMinipoolsLock.Unlock()
+	m.withdrawalNodesLock.Lock()
 	m.nodeIndex = &sync.Map{}
+	m.withdrawalNodes = make(map[common.Address]map[common.Address]struct{})
+	m.withdrawalNodesLock.Unlock()
 	m.odaoNodeIndex = &sync.Map{}
 	m.highestBlock = big.NewInt(0)
+
+	m.eventsLock.Lock()
+	m.events = nil
+	m.eventsLock.Unlock()
 	return nil
 }
 
@@ -61,11 +86,66 @@ func (m *MapsCache) getMinipoolNode(pubkey rptypes.ValidatorPubkey) (common.Addr
 }
 
 func (m *MapsCache) addMinipoolNode(pubkey rptypes.ValidatorPubkey, nodeAddr common.Address) error {
+	m.nodeMinipoolsLock.Lock()
+	defer m.nodeMinipoolsLock.Unlock()
+
+	// The minipool may have been attributed to another node before a reorg
+	if prev, ok := m.minipoolIndex.Swap(pubkey, nodeAddr); ok {
+		m.unindexMinipool(pubkey, prev.(common.Address))
+	}
+
+	pubkeys, ok := m.nodeMinipools[nodeAddr]
+	if !ok {
+		pubkeys = make(map[rptypes.ValidatorPubkey]struct{})
+		m.nodeMinipools[nodeAddr] = pubkeys
+	}
+	pubkeys[pubkey] = struct{}{}
+	return nil
+}
+
+func (m *MapsCache) removeMinipoolNode(pubkey rptypes.ValidatorPubkey) error {
+	m.nodeMinipoolsLock.Lock()
+	defer m.nodeMinipoolsLock.Unlock()
 
-	m.minipoolIndex.Store(pubkey, nodeAddr)
+	if prev, ok := m.minipoolIndex.LoadAndDelete(pubkey); ok {
+		m.unindexMinipool(pubkey, prev.(common.Address))
+	}
 	return nil
 }
 
+// Removes a minipool from its node's set. Must be called with nodeMinipoolsLock held.
+func (m *MapsCache) unindexMinipool(pubkey rptypes.ValidatorPubkey, nodeAddr common.Address) {
+	pubkeys, ok := m.nodeMinipools[nodeAddr]
+	if !ok {
+		return
+	}
+
+	delete(pubkeys, pubkey)
+	if len(pubkeys) == 0 {
+		delete(m.nodeMinipools, nodeAddr)
+	}
+}
+
+func (m *MapsCache) forEachMinipool(closure ForEachMinipoolClosure) error {
+	m.minipoolIndex.Range(func(k any, value any) bool {
+		return closure(k.(rptypes.ValidatorPubkey), value.(common.Address))
+	})
+
+	return nil
+}
+
+func (m *MapsCache) getNodeMinipools(nodeAddr common.Address) ([]rptypes.ValidatorPubkey, error) {
+	m.nodeMinipoolsLock.Lock()
+	defer m.nodeMinipoolsLock.Unlock()
+
+	out := make([]rptypes.ValidatorPubkey, 0, len(m.nodeMinipools[nodeAddr]))
+	for pubkey := range m.nodeMinipools[nodeAddr] {
+		out = append(out, pubkey)
+	}
+
+	return out, nil
+}
+
 func (m *MapsCache) getNodeInfo(nodeAddr common.Address) (*nodeInfo, error) {
 
 	void, ok := m.nodeIndex.Load(nodeAddr)
@@ -82,11 +162,53 @@ func (m *MapsCache) getNodeInfo(nodeAddr common.Address) (*nodeInfo, error) {
 }
 
 func (m *MapsCache) addNodeInfo(nodeAddr common.Address, node *nodeInfo) error {
+	m.withdrawalNodesLock.Lock()
+	defer m.withdrawalNodesLock.Unlock()
+
+	if prev, ok := m.nodeIndex.Swap(nodeAddr, node); ok {
+		m.unindexWithdrawalAddresses(nodeAddr, prev.(*nodeInfo))
+	}
+
+	for _, withdrawalAddr := range []common.Address{node.withdrawalAddress, node.rplWithdrawalAddress} {
+		if withdrawalAddr == (common.Address{}) {
+			continue
+		}
+
+		nodes, ok := m.withdrawalNodes[withdrawalAddr]
+		if !ok {
+			nodes = make(map[common.Address]struct{})
+			m.withdrawalNodes[withdrawalAddr] = nodes
+		}
+		nodes[nodeAddr] = struct{}{}
+	}
+	return nil
+}
 
-	m.nodeIndex.Store(nodeAddr, node)
+func (m *MapsCache) removeNodeInfo(nodeAddr common.Address) error {
+	m.withdrawalNodesLock.Lock()
+	defer m.withdrawalNodesLock.Unlock()
+
+	if prev, ok := m.nodeIndex.LoadAndDelete(nodeAddr); ok {
+		m.unindexWithdrawalAddresses(nodeAddr, prev.(*nodeInfo))
+	}
 	return nil
 }
 
+// Removes a node from its withdrawal addresses' sets. Must be called with withdrawalNodesLock held.
+func (m *MapsCache) unindexWithdrawalAddresses(nodeAddr common.Address, node *nodeInfo) {
+	for _, withdrawalAddr := range []common.Address{node.withdrawalAddress, node.rplWithdrawalAddress} {
+		nodes, ok := m.withdrawalNodes[withdrawalAddr]
+		if !ok {
+			continue
+		}
+
+		delete(nodes, nodeAddr)
+		if len(nodes) == 0 {
+			delete(m.withdrawalNodes, withdrawalAddr)
+		}
+	}
+}
+
 func (m *MapsCache) forEachNode(closure ForEachNodeClosure) error {
 	m.nodeIndex.Range(func(k any, value any) bool {
 		return closure(k.(common.Address))
@@ -95,6 +217,18 @@ func (m *MapsCache) forEachNode(closure ForEachNodeClosure) error {
 	return nil
 }
 
+func (m *MapsCache) getWithdrawalAddressNodes(withdrawalAddr common.Address) ([]common.Address, error) {
+	m.withdrawalNodesLock.Lock()
+	defer m.withdrawalNodesLock.Unlock()
+
+	out := make([]common.Address, 0, len(m.withdrawalNodes[withdrawalAddr]))
+	for nodeAddr := range m.withdrawalNodes[withdrawalAddr] {
+		out = append(out, nodeAddr)
+	}
+
+	return out, nil
+}
+
 func (m *MapsCache) addOdaoNode(addr common.Address) error {
 
 	m.odaoNodeIndex.Store(addr, true)
@@ -119,6 +253,11 @@ func (m *MapsCache) forEachOdaoNode(closure ForEachNodeClosure) error {
 	return nil
 }
 
+func (m *MapsCache) isOdaoNode(addr common.Address) (bool, error) {
+	member, ok := m.odaoNodeIndex.Load(addr)
+	return ok && member.(bool), nil
+}
+
 func (m *MapsCache) setHighestBlock(block *big.Int) {
 	if m.highestBlock.Cmp(block) >= 0 {
 		return
@@ -134,6 +273,58 @@ func (m *MapsCache) getHighestBlock() *big.Int {
 	return m.highestBlock
 }
 
+func (m *MapsCache) addProcessedEvent(event *processedEvent) error {
+	m.eventsLock.Lock()
+	defer m.eventsLock.Unlock()
+
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *MapsCache) getProcessedEvents(fromBlock uint64) ([]*processedEvent, error) {
+	m.eventsLock.Lock()
+	defer m.eventsLock.Unlock()
+
+	out := make([]*processedEvent, 0)
+	for _, event := range m.events {
+		if event.blockNumber >= fromBlock {
+			out = append(out, event)
+		}
+	}
+
+	return out, nil
+}
+
+func (m *MapsCache) removeProcessedEvents(fromBlock uint64) error {
+	m.eventsLock.Lock()
+	defer m.eventsLock.Unlock()
+
+	kept := make([]*processedEvent, 0, len(m.events))
+	for _, event := range m.events {
+		if event.blockNumber < fromBlock {
+			kept = append(kept, event)
+		}
+	}
+
+	m.events = kept
+	return nil
+}
+
+func (m *MapsCache) pruneProcessedEvents(beforeBlock uint64) error {
+	m.eventsLock.Lock()
+	defer m.eventsLock.Unlock()
+
+	kept := make([]*processedEvent, 0, len(m.events))
+	for _, event := range m.events {
+		if event.blockNumber >= beforeBlock {
+			kept = append(kept, event)
+		}
+	}
+
+	m.events = kept
+	return nil
+}
+
 func (m *MapsCache) deinit() error {
 	return nil
 }
@@ -0,0 +1,215 @@
+package executionlayer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rocket-pool/rocketpool-go/rocketpool"
+	"golang.org/x/sync/errgroup"
+)
+
+// Multicall3 is deployed at the same address on every chain we support, see https://www.multicall3.com
+var multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+const multicall3Abi = `[{"inputs":[{"components":[{"internalType":"address","name":"target","type":"address"},{"internalType":"bool","name":"allowFailure","type":"bool"},{"internalType":"bytes","name":"callData","type":"bytes"}],"internalType":"struct Multicall3.Call3[]","name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"internalType":"bool","name":"success","type":"bool"},{"internalType":"bytes","name":"returnData","type":"bytes"}],"internalType":"struct Multicall3.Result[]","name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+// How many contract calls to aggregate into a single eth_call
+const multicallSize = 500
+
+// How many eth_calls to send in a single JSON-RPC batch
+const rpcBatchSize = 8
+
+// How many JSON-RPC batches may be in flight at once
+const multicallConcurrency = 4
+
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicall3Result struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// contractCall is a view function call to be batched, whose return value is unpacked into out
+type contractCall struct {
+	contract *rocketpool.Contract
+	method   string
+	args     []interface{}
+	out      interface{}
+}
+
+// multicaller batches contract calls into Multicall3 aggregate3 calls, which are in turn
+// batched into JSON-RPC requests, with a bounded number of requests in flight.
+type multicaller struct {
+	client *rpc.Client
+	abi    abi.ABI
+	opts   *bind.CallOpts
+
+	// Called with the number of calls completed after each JSON-RPC batch
+	progress func(int)
+}
+
+func newMulticaller(client *rpc.Client, opts *bind.CallOpts, progress func(int)) (*multicaller, error) {
+	parsed, err := abi.JSON(strings.NewReader(multicall3Abi))
+	if err != nil {
+		return nil, err
+	}
+
+	return &multicaller{
+		client:   client,
+		abi:      parsed,
+		opts:     opts,
+		progress: progress,
+	}, nil
+}
+
+func (m *multicaller) context() context.Context {
+	if m.opts.Context != nil {
+		return m.opts.Context
+	}
+
+	return context.Background()
+}
+
+// Checks whether Multicall3 is deployed on the chain
+func (m *multicaller) available() (bool, error) {
+	var code hexutil.Bytes
+	err := m.client.CallContext(m.context(), &code, "eth_getCode", multicall3Address, m.blockArg())
+	if err != nil {
+		return false, err
+	}
+
+	return len(code) > 0, nil
+}
+
+func (m *multicaller) blockArg() string {
+	if m.opts.BlockNumber == nil {
+		return "latest"
+	}
+
+	return hexutil.EncodeBig(m.opts.BlockNumber)
+}
+
+// Executes every call, unpacking the results into their outputs
+func (m *multicaller) execute(calls []*contractCall) error {
+	// Split the calls into aggregate3 calls, and those into JSON-RPC batches
+	var batches [][][]*contractCall
+	for start := 0; start < len(calls); start += multicallSize * rpcBatchSize {
+		batchCalls := calls[start:min(start+multicallSize*rpcBatchSize, len(calls))]
+
+		var batch [][]*contractCall
+		for i := 0; i < len(batchCalls); i += multicallSize {
+			batch = append(batch, batchCalls[i:min(i+multicallSize, len(batchCalls))])
+		}
+		batches = append(batches, batch)
+	}
+
+	var wg errgroup.Group
+	wg.SetLimit(multicallConcurrency)
+	for _, batch := range batches {
+		batch := batch
+		wg.Go(func() error {
+			return m.executeBatch(batch)
+		})
+	}
+
+	return wg.Wait()
+}
+
+// Sends one JSON-RPC batch containing an aggregate3 call per element of batch
+func (m *multicaller) executeBatch(batch [][]*contractCall) error {
+	elems := make([]rpc.BatchElem, len(batch))
+	results := make([]hexutil.Bytes, len(batch))
+	for i, aggregate := range batch {
+		packed := make([]multicall3Call, len(aggregate))
+		for j, c := range aggregate {
+			data, err := c.contract.ABI.Pack(c.method, c.args...)
+			if err != nil {
+				return err
+			}
+
+			packed[j] = multicall3Call{
+				Target:   *c.contract.Address,
+				CallData: data,
+			}
+		}
+
+		data, err := m.abi.Pack("aggregate3", packed)
+		if err != nil {
+			return err
+		}
+
+		elems[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args: []interface{}{
+				map[string]interface{}{
+					"to":   multicall3Address,
+					"data": hexutil.Bytes(data),
+				},
+				m.blockArg(),
+			},
+			Result: &results[i],
+		}
+	}
+
+	if err := m.client.BatchCallContext(m.context(), elems); err != nil {
+		return err
+	}
+
+	completed := 0
+	for i, aggregate := range batch {
+		if elems[i].Error != nil {
+			return elems[i].Error
+		}
+
+		unpacked, err := m.abi.Unpack("aggregate3", results[i])
+		if err != nil {
+			return err
+		}
+		if len(unpacked) != 1 {
+			return fmt.Errorf("unexpected aggregate3 output length %d", len(unpacked))
+		}
+
+		returned := *abi.ConvertType(unpacked[0], new([]multicall3Result)).(*[]multicall3Result)
+		if len(returned) != len(aggregate) {
+			return fmt.Errorf("aggregate3 returned %d results for %d calls", len(returned), len(aggregate))
+		}
+
+		for j, c := range aggregate {
+			if !returned[j].Success {
+				return fmt.Errorf("call to %s failed", c.method)
+			}
+
+			if err := c.contract.ABI.UnpackIntoInterface(c.out, c.method, returned[j].ReturnData); err != nil {
+				return fmt.Errorf("error unpacking %s result: %w", c.method, err)
+			}
+		}
+		completed += len(aggregate)
+	}
+
+	if m.progress != nil {
+		m.progress(completed)
+	}
+
+	return nil
+}
+
+// Convenience for building calls whose output is a freshly allocated T
+func newCall[T any](contract *rocketpool.Contract, method string, args ...interface{}) (*contractCall, *T) {
+	out := new(T)
+	return &contractCall{
+		contract: contract,
+		method:   method,
+		args:     args,
+		out:      out,
+	}, out
+}
@@ -0,0 +1,78 @@
+package executionlayer
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+)
+
+// NodeDetails is everything the cache knows about a node, for diagnosing rejected requests
+type NodeDetails struct {
+	NodeAddress     common.Address
+	InSmoothingPool bool
+	FeeDistributor  common.Address
+	Minipools       []rptypes.ValidatorPubkey
+	OdaoMember      bool
+
+	// When each of the above last changed, or zero if it wasn't seen to.
+	// The fee distributor is set when the node registers, and never changes after.
+	SmoothingPoolChanged  time.Time
+	FeeDistributorChanged time.Time
+	MinipoolsChanged      time.Time
+	OdaoChanged           time.Time
+}
+
+// Records when a node changed on its cache entry, by calling update with a copy of it.
+// Nodes that aren't cached have nowhere to record the change.
+func (e *CachingExecutionLayer) recordNodeChange(nodeAddr common.Address, update func(*nodeInfo)) error {
+	n, err := e.cache.getNodeInfo(nodeAddr)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+
+	updated := *n
+	update(&updated)
+	return e.cache.addNodeInfo(nodeAddr, &updated)
+}
+
+// minipoolIndexCache is a cache which may hold minipools added before it indexed them by node
+type minipoolIndexCache interface {
+	indexNodeMinipools() error
+}
+
+// GetNodeDetails returns everything the cache knows about a node, or nil if it isn't a registered node
+func (e *CachingExecutionLayer) GetNodeDetails(nodeAddr common.Address) (*NodeDetails, error) {
+	n, err := e.cache.getNodeInfo(nodeAddr)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	minipools, err := e.cache.getNodeMinipools(nodeAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	odaoMember, err := e.cache.isOdaoNode(nodeAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NodeDetails{
+		NodeAddress:           nodeAddr,
+		InSmoothingPool:       n.inSmoothingPool,
+		FeeDistributor:        n.feeDistributor,
+		Minipools:             minipools,
+		OdaoMember:            odaoMember,
+		SmoothingPoolChanged:  n.smoothingPoolChanged,
+		FeeDistributorChanged: n.feeDistributorChanged,
+		MinipoolsChanged:      n.minipoolsChanged,
+		OdaoChanged:           n.odaoChanged,
+	}, nil
+}
@@ -0,0 +1,565 @@
+package executionlayer
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rocket-pool/rocketpool-go/dao/trustednode"
+	"github.com/rocket-pool/rocketpool-go/minipool"
+	"github.com/rocket-pool/rocketpool-go/node"
+	"github.com/rocket-pool/rocketpool-go/rocketpool"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// chainState is everything the cache holds, read from the chain at a single block
+type chainState struct {
+	nodes     map[common.Address]*nodeInfo
+	minipools map[rptypes.ValidatorPubkey]common.Address
+	odaoNodes map[common.Address]bool
+}
+
+// How many nodes to load at once when Multicall3 isn't available
+const serialLoadConcurrency = 32
+
+// Reads every node, minipool and odao member at the block in opts, using batched
+// Multicall3 calls where the chain supports them.
+func (e *CachingExecutionLayer) loadChainState(rp *rocketpool.RocketPool, opts *bind.CallOpts) (*chainState, error) {
+	start := time.Now()
+
+	// Progress is reported in contract calls, as their total is only known as each stage completes
+	e.m.Gauge("chain_state_calls_total").Set(0)
+	e.m.Gauge("chain_state_calls_completed").Set(0)
+	addCalls := func(n int) {
+		e.m.Gauge("chain_state_calls_total").Add(float64(n))
+	}
+	progress := func(n int) {
+		e.m.Gauge("chain_state_calls_completed").Add(float64(n))
+	}
+
+	mc, err := newMulticaller(e.getClient().Client(), opts, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := mc.available()
+	if err != nil {
+		return nil, err
+	}
+
+	var out *chainState
+	if available {
+		out, err = e.loadChainStateBatched(rp, mc, addCalls)
+	} else {
+		e.Logger.Warn("Multicall3 isn't deployed, loading chain state with individual calls")
+		out, err = e.loadChainStateSerial(rp, opts, addCalls, progress)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Get all odao nodes at the given block
+	odaoNodes, err := trustednode.GetMemberAddresses(rp, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range odaoNodes {
+		out.odaoNodes[member] = true
+	}
+	e.Logger.Info("Found odao nodes to load", zap.Int("count", len(odaoNodes)), zap.Int64("block", opts.BlockNumber.Int64()))
+
+	e.m.Gauge("chain_state_nodes").Set(float64(len(out.nodes)))
+	e.m.Gauge("chain_state_minipools").Set(float64(len(out.minipools)))
+	e.m.Gauge("chain_state_load_seconds").Set(time.Since(start).Seconds())
+	return out, nil
+}
+
+// Reads every node and minipool in a handful of stages, each a set of batched Multicall3 calls
+func (e *CachingExecutionLayer) loadChainStateBatched(rp *rocketpool.RocketPool, mc *multicaller, addCalls func(int)) (*chainState, error) {
+	out := &chainState{
+		nodes:     make(map[common.Address]*nodeInfo),
+		minipools: make(map[rptypes.ValidatorPubkey]common.Address),
+		odaoNodes: make(map[common.Address]bool),
+	}
+
+	contracts, err := rp.GetContracts(mc.opts, "rocketNodeManager", "rocketMinipoolManager", "rocketNodeDistributorFactory")
+	if err != nil {
+		return nil, err
+	}
+	nodeManager, minipoolManager, distributorFactory := contracts[0], contracts[1], contracts[2]
+
+	// Get all nodes at the given block
+	nodeCount, err := node.GetNodeCount(rp, mc.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	calls := make([]*contractCall, nodeCount)
+	nodes := make([]*common.Address, nodeCount)
+	for i := range calls {
+		calls[i], nodes[i] = newCall[common.Address](nodeManager, "getNodeAt", big.NewInt(int64(i)))
+	}
+	addCalls(len(calls))
+	if err := mc.execute(calls); err != nil {
+		return nil, err
+	}
+	e.Logger.Info("Found nodes to load", zap.Int("count", len(nodes)), zap.Int64("block", mc.opts.BlockNumber.Int64()))
+
+	// Determine their smoothing pool status, fee distributor address, withdrawal addresses, and how many minipools they have
+	calls = make([]*contractCall, 0, 6*len(nodes))
+	inSmoothingPool := make([]*bool, len(nodes))
+	feeDistributors := make([]*common.Address, len(nodes))
+	withdrawalAddresses := make([]*common.Address, len(nodes))
+	rplWithdrawalAddressesSet := make([]*bool, len(nodes))
+	rplWithdrawalAddresses := make([]*common.Address, len(nodes))
+	minipoolCounts := make([]**big.Int, len(nodes))
+	for i, addr := range nodes {
+		var c *contractCall
+		c, inSmoothingPool[i] = newCall[bool](nodeManager, "getSmoothingPoolRegistrationState", *addr)
+		calls = append(calls, c)
+		c, feeDistributors[i] = newCall[common.Address](distributorFactory, "getProxyAddress", *addr)
+		calls = append(calls, c)
+		c, withdrawalAddresses[i] = newCall[common.Address](rp.RocketStorageContract, "getNodeWithdrawalAddress", *addr)
+		calls = append(calls, c)
+		c, rplWithdrawalAddressesSet[i] = newCall[bool](nodeManager, "getNodeRPLWithdrawalAddressIsSet", *addr)
+		calls = append(calls, c)
+		c, rplWithdrawalAddresses[i] = newCall[common.Address](nodeManager, "getNodeRPLWithdrawalAddress", *addr)
+		calls = append(calls, c)
+		c, minipoolCounts[i] = newCall[*big.Int](minipoolManager, "getNodeMinipoolCount", *addr)
+		calls = append(calls, c)
+	}
+	addCalls(len(calls))
+	if err := mc.execute(calls); err != nil {
+		return nil, err
+	}
+
+	// Get the addresses of their minipools
+	calls = make([]*contractCall, 0)
+	minipoolNodes := make([]common.Address, 0)
+	minipools := make([]*common.Address, 0)
+	for i, addr := range nodes {
+		out.nodes[*addr] = &nodeInfo{
+			inSmoothingPool:   *inSmoothingPool[i],
+			feeDistributor:    *feeDistributors[i],
+			withdrawalAddress: *withdrawalAddresses[i],
+		}
+		// Unset RPL withdrawal addresses read as the withdrawal address
+		if *rplWithdrawalAddressesSet[i] {
+			out.nodes[*addr].rplWithdrawalAddress = *rplWithdrawalAddresses[i]
+		}
+
+		for j := uint64(0); j < (*minipoolCounts[i]).Uint64(); j++ {
+			c, minipool := newCall[common.Address](minipoolManager, "getNodeMinipoolAt", *addr, big.NewInt(0).SetUint64(j))
+			calls = append(calls, c)
+			minipoolNodes = append(minipoolNodes, *addr)
+			minipools = append(minipools, minipool)
+		}
+	}
+	addCalls(len(calls))
+	if err := mc.execute(calls); err != nil {
+		return nil, err
+	}
+
+	// And finally their pubkeys
+	calls = make([]*contractCall, len(minipools))
+	pubkeys := make([]*rptypes.ValidatorPubkey, len(minipools))
+	for i, minipool := range minipools {
+		calls[i], pubkeys[i] = newCall[rptypes.ValidatorPubkey](minipoolManager, "getMinipoolPubkey", *minipool)
+	}
+	addCalls(len(calls))
+	if err := mc.execute(calls); err != nil {
+		return nil, err
+	}
+
+	for i, pubkey := range pubkeys {
+		out.minipools[*pubkey] = minipoolNodes[i]
+	}
+
+	return out, nil
+}
+
+// Reads every node and minipool with individual calls, a bounded number of nodes at a time.
+// This is slow, as it makes several calls per node and one per minipool.
+func (e *CachingExecutionLayer) loadChainStateSerial(rp *rocketpool.RocketPool, opts *bind.CallOpts, addCalls func(int), progress func(int)) (*chainState, error) {
+	out := &chainState{
+		nodes:     make(map[common.Address]*nodeInfo),
+		minipools: make(map[rptypes.ValidatorPubkey]common.Address),
+		odaoNodes: make(map[common.Address]bool),
+	}
+
+	// Get all nodes at the given block
+	nodes, err := node.GetNodeAddresses(rp, opts)
+	if err != nil {
+		return nil, err
+	}
+	e.Logger.Info("Found nodes to load", zap.Int("count", len(nodes)), zap.Int64("block", opts.BlockNumber.Int64()))
+
+	var outLock sync.Mutex
+	var wg errgroup.Group
+	wg.SetLimit(serialLoadConcurrency)
+	for _, addr := range nodes {
+		addr := addr
+		wg.Go(func() error {
+			// The smoothing pool status, fee distributor, withdrawal address, whether the RPL
+			// withdrawal address is set, and minipool count, then the RPL withdrawal address if it is
+			calls := 5
+			addCalls(calls)
+
+			// Allocate a pointer for this node
+			var err error
+			nodeInfo := &nodeInfo{}
+			// Determine their smoothing pool status
+			nodeInfo.inSmoothingPool, err = node.GetSmoothingPoolRegistrationState(rp, addr, opts)
+			if err != nil {
+				return err
+			}
+
+			// Get their fee distributor address
+			nodeInfo.feeDistributor, err = node.GetDistributorAddress(rp, addr, opts)
+			if err != nil {
+				return err
+			}
+
+			// And their withdrawal addresses
+			nodeInfo.withdrawalAddress, nodeInfo.rplWithdrawalAddress, err = loadWithdrawalAddresses(rp, addr, opts)
+			if err != nil {
+				return err
+			}
+			if nodeInfo.rplWithdrawalAddress != (common.Address{}) {
+				calls++
+				addCalls(1)
+			}
+
+			// Also grab their minipools
+			minipoolAddresses, err := minipool.GetNodeMinipoolAddresses(rp, addr, opts)
+			if err != nil {
+				return err
+			}
+			progress(calls)
+
+			// Each minipool's address took a call, as its pubkey will
+			addCalls(len(minipoolAddresses))
+			progress(len(minipoolAddresses))
+
+			pubkeys := make([]rptypes.ValidatorPubkey, len(minipoolAddresses))
+			addCalls(len(minipoolAddresses))
+			for i, m := range minipoolAddresses {
+				pubkeys[i], err = minipool.GetMinipoolPubkey(rp, m, opts)
+				if err != nil {
+					return err
+				}
+			}
+			progress(len(minipoolAddresses))
+
+			outLock.Lock()
+			defer outLock.Unlock()
+			out.nodes[addr] = nodeInfo
+			for _, pubkey := range pubkeys {
+				out.minipools[pubkey] = addr
+			}
+			return nil
+		})
+	}
+
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Collects the cache entries touched by events after the given block, whose cached values may
+// legitimately differ from the chain state at that block.
+func (e *CachingExecutionLayer) touchedSince(block uint64) (map[common.Address]bool, map[rptypes.ValidatorPubkey]bool, error) {
+	events, err := e.cache.getProcessedEvents(block + 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addresses := make(map[common.Address]bool)
+	pubkeys := make(map[rptypes.ValidatorPubkey]bool)
+	for _, event := range events {
+		if event.topic == e.minipoolLaunchedTopic {
+			pubkeys[event.pubkey] = true
+			continue
+		}
+
+		addresses[event.address] = true
+	}
+
+	return addresses, pubkeys, nil
+}
+
+// Re-reads the chain state at the last block whose events have all been applied, and corrects any
+// cache entries which have drifted from it. Reading any later would "correct" entries whose events
+// have been fetched but not applied yet, which would then be applied again.
+func (e *CachingExecutionLayer) reconcile() error {
+	start := time.Now()
+
+	e.eventsLock.Lock()
+	block := e.appliedBlock
+	e.eventsLock.Unlock()
+
+	// Events after the block we read are distinguished using the processed events journal,
+	// so keep it from being pruned past that block, however long the load takes
+	e.journalPinned.Store(block + 1)
+	defer e.journalPinned.Store(0)
+
+	state, err := e.loadChainState(e.getRocketPool(), &bind.CallOpts{BlockNumber: big.NewInt(0).SetUint64(block), Context: e.ctx})
+	if err != nil {
+		return err
+	}
+
+	// Keep events from being processed while we compare and correct
+	e.eventsLock.Lock()
+	defer e.eventsLock.Unlock()
+
+	touchedAddresses, touchedPubkeys, err := e.touchedSince(block)
+	if err != nil {
+		return err
+	}
+
+	// Corrections are recorded in the history as of the block we read
+	corrections := 0
+	history := make([]*historyEntry, 0)
+	correct := func(metric string, msg string, fields ...zap.Field) {
+		corrections++
+		e.m.Counter(metric).Inc()
+		e.Logger.Warn(msg, append(fields, zap.Uint64("block", block))...)
+	}
+
+	// Changes to the node set are published once the cache has been corrected
+	addedNodes := make([]common.Address, 0)
+
+	// Nodes
+	for addr, n := range state.nodes {
+		if touchedAddresses[addr] {
+			continue
+		}
+
+		cached, err := e.cache.getNodeInfo(addr)
+		if err != nil {
+			if _, ok := err.(*NotFoundError); !ok {
+				return err
+			}
+
+			correct("reconcile_node_added", "Reconciler added missing node", zap.String("addr", addr.String()))
+			addedNodes = append(addedNodes, addr)
+			n.feeDistributorChanged = time.Now()
+		} else if cached.inSmoothingPool == n.inSmoothingPool && cached.feeDistributor == n.feeDistributor &&
+			cached.withdrawalAddress == n.withdrawalAddress && cached.rplWithdrawalAddress == n.rplWithdrawalAddress {
+			continue
+		} else {
+			n.feeDistributorChanged = cached.feeDistributorChanged
+			n.minipoolsChanged = cached.minipoolsChanged
+			n.odaoChanged = cached.odaoChanged
+
+			// We don't know when a missed smoothing pool change happened, so start its grace period now
+			if cached.inSmoothingPool != n.inSmoothingPool {
+				n.smoothingPoolChanged = time.Now()
+			} else {
+				n.smoothingPoolChanged = cached.smoothingPoolChanged
+			}
+			correct("reconcile_node_corrected", "Reconciler corrected node",
+				zap.String("addr", addr.String()),
+				zap.Bool("cached_in_sp", cached.inSmoothingPool),
+				zap.Bool("in_sp", n.inSmoothingPool),
+				zap.String("cached_fee_distributor", cached.feeDistributor.String()),
+				zap.String("fee_distributor", n.feeDistributor.String()),
+				zap.String("cached_withdrawal_address", cached.withdrawalAddress.String()),
+				zap.String("withdrawal_address", n.withdrawalAddress.String()),
+				zap.String("cached_rpl_withdrawal_address", cached.rplWithdrawalAddress.String()),
+				zap.String("rpl_withdrawal_address", n.rplWithdrawalAddress.String()))
+		}
+
+		if err := e.cache.addNodeInfo(addr, n); err != nil {
+			return err
+		}
+		history = append(history, nodeHistoryEntry(block, addr, n))
+	}
+
+	staleNodes := make([]common.Address, 0)
+	err = e.cache.forEachNode(func(addr common.Address) bool {
+		if _, ok := state.nodes[addr]; !ok && !touchedAddresses[addr] {
+			staleNodes = append(staleNodes, addr)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range staleNodes {
+		correct("reconcile_node_removed", "Reconciler removed unregistered node", zap.String("addr", addr.String()))
+		if err := e.cache.removeNodeInfo(addr); err != nil {
+			return err
+		}
+		history = append(history, &historyEntry{blockNumber: block, nodeAddress: addr, removed: true})
+	}
+
+	// Minipools, noting which nodes' minipools changed
+	changedNodes := make(map[common.Address]bool)
+	for pubkey, nodeAddr := range state.minipools {
+		if touchedPubkeys[pubkey] {
+			continue
+		}
+
+		cached, err := e.cache.getMinipoolNode(pubkey)
+		if err != nil {
+			if _, ok := err.(*NotFoundError); !ok {
+				return err
+			}
+
+			correct("reconcile_minipool_added", "Reconciler added missing minipool",
+				zap.String("pubkey", pubkey.String()), zap.String("node", nodeAddr.String()))
+			changedNodes[nodeAddr] = true
+		} else if cached == nodeAddr {
+			continue
+		} else {
+			correct("reconcile_minipool_corrected", "Reconciler corrected minipool node",
+				zap.String("pubkey", pubkey.String()),
+				zap.String("cached_node", cached.String()),
+				zap.String("node", nodeAddr.String()))
+			changedNodes[cached] = true
+			changedNodes[nodeAddr] = true
+		}
+
+		if err := e.cache.addMinipoolNode(pubkey, nodeAddr); err != nil {
+			return err
+		}
+		history = append(history, minipoolHistoryEntry(block, pubkey, nodeAddr))
+	}
+
+	staleMinipools := make(map[rptypes.ValidatorPubkey]common.Address)
+	err = e.cache.forEachMinipool(func(pubkey rptypes.ValidatorPubkey, nodeAddr common.Address) bool {
+		if _, ok := state.minipools[pubkey]; !ok && !touchedPubkeys[pubkey] {
+			staleMinipools[pubkey] = nodeAddr
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for pubkey, nodeAddr := range staleMinipools {
+		correct("reconcile_minipool_removed", "Reconciler removed nonexistent minipool", zap.String("pubkey", pubkey.String()))
+		changedNodes[nodeAddr] = true
+		if err := e.cache.removeMinipoolNode(pubkey); err != nil {
+			return err
+		}
+		history = append(history, &historyEntry{blockNumber: block, pubkey: pubkey, removed: true})
+	}
+
+	for nodeAddr := range changedNodes {
+		if err := e.recordNodeChange(nodeAddr, func(n *nodeInfo) { n.minipoolsChanged = time.Now() }); err != nil {
+			return err
+		}
+	}
+
+	// Odao members
+	cachedOdaoNodes := make(map[common.Address]bool)
+	err = e.cache.forEachOdaoNode(func(addr common.Address) bool {
+		cachedOdaoNodes[addr] = true
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	joinedOdao := make([]common.Address, 0)
+	for addr := range state.odaoNodes {
+		if cachedOdaoNodes[addr] || touchedAddresses[addr] {
+			continue
+		}
+
+		correct("reconcile_odao_node_added", "Reconciler added missing odao node", zap.String("addr", addr.String()))
+		if err := e.cache.addOdaoNode(addr); err != nil {
+			return err
+		}
+		if err := e.recordNodeChange(addr, func(n *nodeInfo) { n.odaoChanged = time.Now() }); err != nil {
+			return err
+		}
+		joinedOdao = append(joinedOdao, addr)
+	}
+
+	leftOdao := make([]common.Address, 0)
+	for addr := range cachedOdaoNodes {
+		if state.odaoNodes[addr] || touchedAddresses[addr] {
+			continue
+		}
+
+		correct("reconcile_odao_node_removed", "Reconciler removed departed odao node", zap.String("addr", addr.String()))
+		if err := e.cache.removeOdaoNode(addr); err != nil {
+			return err
+		}
+		if err := e.recordNodeChange(addr, func(n *nodeInfo) { n.odaoChanged = time.Now() }); err != nil {
+			return err
+		}
+		leftOdao = append(leftOdao, addr)
+	}
+
+	if hc, ok := e.cache.(historyCache); ok && len(history) > 0 {
+		if err := hc.addHistory(history); err != nil {
+			return err
+		}
+	}
+
+	// There's no change for a node being removed, so subscribers must start over from a snapshot
+	if len(staleNodes) > 0 {
+		e.NodeSet.Reset()
+	} else {
+		for _, addr := range addedNodes {
+			e.NodeSet.Publish(block, nodeset.NodeRegistered, addr)
+		}
+		for _, addr := range joinedOdao {
+			e.NodeSet.Publish(block, nodeset.OdaoJoined, addr)
+		}
+		for _, addr := range leftOdao {
+			e.NodeSet.Publish(block, nodeset.OdaoLeft, addr)
+		}
+	}
+
+	e.m.Counter("reconcile_completed").Inc()
+	e.m.Counter("reconcile_corrections").Add(float64(corrections))
+	e.Logger.Info("Reconciled cache with chain state",
+		zap.Uint64("block", block),
+		zap.Int("corrections", corrections),
+		zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// Periodically reconciles the cache until the execution layer is stopped
+func (e *CachingExecutionLayer) reconcileLoop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Only the instance ingesting events writes to a shared cache
+		if !e.ingestionOwner.Load() {
+			continue
+		}
+
+		if err := e.reconcile(); err != nil {
+			if e.ctx.Err() != nil {
+				return
+			}
+
+			e.m.Counter("reconcile_failed").Inc()
+			e.Logger.Warn("Error reconciling cache with chain state", zap.Error(err))
+		}
+	}
+}
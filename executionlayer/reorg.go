@@ -0,0 +1,415 @@
+package executionlayer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rocket-pool/rocketpool-go/dao/trustednode"
+	"github.com/rocket-pool/rocketpool-go/minipool"
+	"github.com/rocket-pool/rocketpool-go/node"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"go.uber.org/zap"
+)
+
+// How many blocks of history we keep to detect and roll back reorgs.
+// Two epochs is enough to reach finality, after which reorgs can't happen.
+const maxReorgDepth = 64
+
+func (e *CachingExecutionLayer) headerByNumber(number uint64) (*types.Header, error) {
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Second)
+	defer cancel()
+
+	return e.client.HeaderByNumber(ctx, big.NewInt(0).SetUint64(number))
+}
+
+// Records which cache entry an event touched, in case its block is later reorganized out
+func (e *CachingExecutionLayer) recordEvent(event types.Log, address common.Address, pubkey rptypes.ValidatorPubkey) {
+	e.recordHistory(event, address, pubkey)
+
+	// Without a block hash there's nothing to compare against the canonical chain later
+	if event.BlockHash == (common.Hash{}) {
+		return
+	}
+
+	err := e.cache.addProcessedEvent(&processedEvent{
+		blockNumber: event.BlockNumber,
+		blockHash:   event.BlockHash,
+		logIndex:    event.Index,
+		topic:       event.Topics[0],
+		address:     address,
+		pubkey:      pubkey,
+	})
+	if err != nil {
+		e.Logger.Warn("Error recording processed event", zap.Error(err))
+	}
+}
+
+// Whether the journal shows the log was already applied
+func (e *CachingExecutionLayer) alreadyProcessed(event types.Log) bool {
+	if event.BlockHash == (common.Hash{}) {
+		return false
+	}
+
+	events, err := e.cache.getProcessedEvents(event.BlockNumber)
+	if err != nil {
+		e.Logger.Warn("Error reading processed events", zap.Error(err))
+		return false
+	}
+
+	for _, processed := range events {
+		if processed.blockHash == event.BlockHash && processed.logIndex == event.Index {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Recomputes a node's smoothing pool status, fee distributor and withdrawal addresses from the chain state
+// at opts, removing it if its registration no longer exists.
+func (e *CachingExecutionLayer) refreshNode(addr common.Address, opts *bind.CallOpts) error {
+	exists, err := node.GetNodeExists(e.rp, addr, opts)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		e.Logger.Warn("Removing node whose registration was reorganized out", zap.String("addr", addr.String()))
+		return e.cache.removeNodeInfo(addr)
+	}
+
+	n := &nodeInfo{}
+	n.inSmoothingPool, err = node.GetSmoothingPoolRegistrationState(e.rp, addr, opts)
+	if err != nil {
+		return err
+	}
+
+	n.feeDistributor, err = node.GetDistributorAddress(e.rp, addr, opts)
+	if err != nil {
+		return err
+	}
+
+	n.withdrawalAddress, n.rplWithdrawalAddress, err = loadWithdrawalAddresses(e.rp, addr, opts)
+	if err != nil {
+		return err
+	}
+
+	// Keep the grace period of a smoothing pool change that survived the reorg.
+	// A node we didn't know of never had a previous fee recipient to grant a grace period for.
+	cached, err := e.cache.getNodeInfo(addr)
+	if err == nil {
+		n.feeDistributorChanged = cached.feeDistributorChanged
+		n.minipoolsChanged = cached.minipoolsChanged
+		n.odaoChanged = cached.odaoChanged
+		if cached.inSmoothingPool == n.inSmoothingPool {
+			n.smoothingPoolChanged = cached.smoothingPoolChanged
+		} else {
+			n.smoothingPoolChanged = time.Now()
+		}
+	}
+
+	return e.cache.addNodeInfo(addr, n)
+}
+
+// Removes a minipool from the index if it doesn't exist as of opts
+func (e *CachingExecutionLayer) refreshMinipool(pubkey rptypes.ValidatorPubkey, opts *bind.CallOpts) error {
+	minipoolAddr, err := minipool.GetMinipoolByPubkey(e.rp, pubkey, opts)
+	if err != nil {
+		return err
+	}
+
+	if minipoolAddr != (common.Address{}) {
+		return nil
+	}
+
+	nodeAddr, err := e.cache.getMinipoolNode(pubkey)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+
+	e.Logger.Warn("Removing minipool whose launch was reorganized out", zap.String("pubkey", pubkey.String()))
+	if err := e.cache.removeMinipoolNode(pubkey); err != nil {
+		return err
+	}
+
+	return e.recordNodeChange(nodeAddr, func(n *nodeInfo) { n.minipoolsChanged = time.Now() })
+}
+
+// Recomputes an odao member's membership from the chain state at opts
+func (e *CachingExecutionLayer) refreshOdaoNode(addr common.Address, opts *bind.CallOpts) error {
+	exists, err := trustednode.GetMemberExists(e.rp, addr, opts)
+	if err != nil {
+		return err
+	}
+
+	cached, err := e.cache.isOdaoNode(addr)
+	if err != nil {
+		return err
+	}
+	if cached == exists {
+		return nil
+	}
+
+	if exists {
+		err = e.cache.addOdaoNode(addr)
+	} else {
+		err = e.cache.removeOdaoNode(addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.recordNodeChange(addr, func(n *nodeInfo) { n.odaoChanged = time.Now() })
+}
+
+// Recomputes every cache entry touched by an event at or after the block `fork`, as of the block before it,
+// and forgets those events. Callers should backfill from `fork` afterwards to replay the events of the new
+// canonical chain. The caller must hold eventsLock.
+func (e *CachingExecutionLayer) rollbackFrom(fork uint64) error {
+	events, err := e.cache.getProcessedEvents(fork)
+	if err != nil {
+		return err
+	}
+
+	// The block before the fork is the last one both chains share
+	opts := &bind.CallOpts{BlockNumber: big.NewInt(0)}
+	if fork > 0 {
+		opts.BlockNumber.SetUint64(fork - 1)
+	}
+
+	// Only recompute each entry once, no matter how many events touched it
+	refreshed := make(map[processedEvent]bool)
+	for _, event := range events {
+		key := processedEvent{topic: event.topic, address: event.address, pubkey: event.pubkey}
+		if refreshed[key] {
+			continue
+		}
+		refreshed[key] = true
+
+		switch event.topic {
+		case e.nodeRegisteredTopic, e.smoothingPoolStatusChangedTopic,
+			e.withdrawalAddressSetTopic, e.rplWithdrawalAddressSetTopic, e.rplWithdrawalAddressUnsetTopic:
+			err = e.refreshNode(event.address, opts)
+		case e.minipoolLaunchedTopic:
+			err = e.refreshMinipool(event.pubkey, opts)
+		case e.odaoJoinedTopic, e.odaoLeftTopic, e.odaoKickedTopic:
+			err = e.refreshOdaoNode(event.address, opts)
+		default:
+			e.Logger.Warn("Processed event with unknown topic", zap.String("topic", event.topic.String()))
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+		e.m.Counter("reorg_entry_recomputed").Inc()
+	}
+
+	if hc, ok := e.cache.(historyCache); ok {
+		if err := hc.removeHistory(fork); err != nil {
+			return err
+		}
+	}
+
+	// The replayed blocks' logs haven't been applied yet
+	if fork > 0 && e.appliedBlock >= fork {
+		e.setAppliedBlock(fork - 1)
+	}
+
+	// The reorganized blocks are forgotten, so the new head isn't mistaken for a second reorg
+	for n := range e.recentBlocks {
+		if n >= fork {
+			delete(e.recentBlocks, n)
+		}
+	}
+	for n := range e.blockTimes {
+		if n >= fork {
+			delete(e.blockTimes, n)
+		}
+	}
+
+	// Nodes may have been removed, which subscribers can only learn of from a new snapshot
+	if len(events) > 0 {
+		e.NodeSet.Reset()
+	}
+
+	e.Logger.Warn("Rolled back events from reorganized blocks",
+		zap.Uint64("fork", fork), zap.Int("events", len(events)))
+	return e.cache.removeProcessedEvents(fork)
+}
+
+// Rolls back the cache to the block `fork` and replays the events of the new canonical chain.
+// The caller must hold eventsLock.
+func (e *CachingExecutionLayer) handleReorg(fork uint64) error {
+	e.m.Counter("reorg_detected").Inc()
+	e.Logger.Warn("Chain reorganization detected", zap.Uint64("fork", fork))
+
+	if err := e.rollbackFrom(fork); err != nil {
+		return err
+	}
+
+	return e.backfillEventsFrom(big.NewInt(0).SetUint64(fork))
+}
+
+// Compares the blocks of the events we've processed against the canonical chain. If any of them were
+// reorganized out, for instance while we were disconnected, the affected entries are rolled back and
+// the first such block is returned, so that events can be replayed from it. Otherwise returns nil.
+func (e *CachingExecutionLayer) checkProcessedEvents() (*big.Int, error) {
+	events, err := e.cache.getProcessedEvents(0)
+	if err != nil {
+		return nil, err
+	}
+
+	checked := make(map[uint64]bool)
+	var fork *uint64
+	for _, event := range events {
+		if checked[event.blockNumber] {
+			continue
+		}
+		checked[event.blockNumber] = true
+
+		header, err := e.headerByNumber(event.blockNumber)
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return nil, err
+		}
+
+		// The chain may now be shorter than it was
+		if header != nil && header.Hash() == event.blockHash {
+			continue
+		}
+
+		if fork == nil || event.blockNumber < *fork {
+			blockNumber := event.blockNumber
+			fork = &blockNumber
+		}
+	}
+
+	if fork == nil {
+		return nil, nil
+	}
+
+	e.m.Counter("reorg_detected").Inc()
+	e.Logger.Warn("Processed events were reorganized out of the chain", zap.Uint64("fork", *fork))
+	if err := e.rollbackFrom(*fork); err != nil {
+		return nil, err
+	}
+
+	return big.NewInt(0).SetUint64(*fork), nil
+}
+
+// Handles a log the execution client retracted because its block was reorganized out.
+// The caller must hold eventsLock.
+func (e *CachingExecutionLayer) handleRemovedEvent(event types.Log) {
+	e.m.Counter("removed_event_received").Inc()
+
+	events, err := e.cache.getProcessedEvents(event.BlockNumber)
+	if err != nil {
+		e.Logger.Error("Error reading processed events", zap.Error(err))
+		return
+	}
+
+	found := false
+	for _, processed := range events {
+		if processed.blockHash == event.BlockHash {
+			found = true
+			break
+		}
+	}
+
+	// Either we never processed it, or it was already rolled back along with the rest of its block
+	if !found {
+		return
+	}
+
+	if err := e.handleReorg(event.BlockNumber); err != nil {
+		e.Logger.Error("Error handling chain reorganization", zap.Error(err))
+	}
+}
+
+// Walks back from the block before `head` to find the first block we saw that is no longer canonical
+func (e *CachingExecutionLayer) findForkPoint(head uint64) (uint64, error) {
+	fork := head
+
+	for n := head; n > 0; n-- {
+		hash, ok := e.recentBlocks[n-1]
+		if !ok {
+			// Deeper than we remember, so roll back as far as we can
+			break
+		}
+
+		header, err := e.headerByNumber(n - 1)
+		if err != nil {
+			return 0, err
+		}
+
+		if header.Hash() == hash {
+			break
+		}
+
+		fork = n - 1
+	}
+
+	return fork, nil
+}
+
+// Tracks the hashes of recent blocks, and rolls back the cache if the new head doesn't build on them
+func (e *CachingExecutionLayer) handleNewHeader(header *types.Header) {
+	// Rolling back must not interleave with events or the reconciler
+	e.eventsLock.Lock()
+	defer e.eventsLock.Unlock()
+
+	number := header.Number.Uint64()
+
+	reorged := false
+	if hash, ok := e.recentBlocks[number]; ok && hash != header.Hash() {
+		reorged = true
+	}
+	if parent, ok := e.recentBlocks[number-1]; ok && number > 0 && parent != header.ParentHash {
+		reorged = true
+	}
+
+	if reorged {
+		fork, err := e.findForkPoint(number)
+		if err == nil {
+			err = e.handleReorg(fork)
+		}
+		if err != nil {
+			e.Logger.Error("Error handling chain reorganization", zap.Error(err))
+		}
+	}
+
+	// Forget blocks that were replaced by this one, or are too deep to be reorganized
+	for n := range e.recentBlocks {
+		if n >= number || n+maxReorgDepth < number {
+			delete(e.recentBlocks, n)
+		}
+	}
+	e.recentBlocks[number] = header.Hash()
+	e.rememberBlockTime(number, time.Unix(int64(header.Time), 0))
+	e.NodeSet.Advance(number)
+
+	// The execution client sends a block's logs before the next block's header
+	if number > 0 {
+		e.advanceAppliedBlock(number - 1)
+	}
+
+	if number > maxReorgDepth {
+		prune := number - maxReorgDepth
+		if pinned := e.journalPinned.Load(); pinned != 0 && pinned < prune {
+			prune = pinned
+		}
+
+		if err := e.cache.pruneProcessedEvents(prune); err != nil {
+			e.Logger.Warn("Error pruning processed events", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package executionlayer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.uber.org/zap"
+)
+
+// SignatureMethod is how ValidateSignature found a signature to be valid
+type SignatureMethod int
+
+const (
+	// The signature isn't valid
+	SignatureMethodNone SignatureMethod = iota
+	// Recovered from an ECDSA signature by an externally owned account
+	SignatureMethodEOA
+	// Accepted by a deployed contract's isValidSignature
+	SignatureMethodEIP1271
+	// Accepted by a contract's isValidSignature after the EIP-6492 wrapper deployed or prepared it
+	SignatureMethodEIP6492
+)
+
+func (m SignatureMethod) String() string {
+	switch m {
+	case SignatureMethodEOA:
+		return "eoa"
+	case SignatureMethodEIP1271:
+		return "eip1271"
+	case SignatureMethodEIP6492:
+		return "eip6492"
+	default:
+		return "none"
+	}
+}
+
+// EIP-6492 signatures end with this suffix
+var eip6492MagicSuffix = common.FromHex("0x6492649264926492649264926492649264926492649264926492649264926492")
+
+// The EIP-1271 magic value, bytes4(keccak256("isValidSignature(bytes32,bytes)"))
+var eip1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// eip6492Verifier is init code which is never deployed, but run by an eth_call without a `to` address,
+// which returns what it returns. The call's data is the init code followed by:
+//
+//	[0:32]     the factory's address
+//	[32:64]    the signer's address
+//	[64:96]    the length of the factory calldata, n
+//	[96:128]   the length of the isValidSignature calldata, m
+//	[128:128+n]     the factory calldata
+//	[128+n:128+n+m] the isValidSignature calldata
+//
+// It calls the factory, ignoring any failure, as the signer may already be deployed, then staticcalls the
+// signer's isValidSignature. It returns 96 bytes: the first word isValidSignature returned, whether the
+// staticcall succeeded, and how many bytes it returned.
+var eip6492Verifier = []byte{
+	0x61, 0x00, 0x3f, // PUSH2 len(eip6492Verifier)
+	0x38,             // CODESIZE
+	0x03,             // SUB
+	0x61, 0x00, 0x3f, // PUSH2 len(eip6492Verifier)
+	0x60, 0x00, // PUSH1 0
+	0x39, // CODECOPY the arguments to memory[0:]
+
+	0x60, 0x00, // PUSH1 0 (retSize)
+	0x60, 0x00, // PUSH1 0 (retOffset)
+	0x60, 0x40, // PUSH1 64
+	0x51,       // MLOAD (argsSize)
+	0x60, 0x80, // PUSH1 128 (argsOffset)
+	0x60, 0x00, // PUSH1 0 (value)
+	0x60, 0x00, // PUSH1 0
+	0x51, // MLOAD (factory)
+	0x5a, // GAS
+	0xf1, // CALL
+	0x50, // POP
+
+	0x60, 0x00, // PUSH1 0
+	0x60, 0x00, // PUSH1 0
+	0x52, // MSTORE, clearing memory[0:32] for the result
+
+	0x60, 0x20, // PUSH1 32 (retSize)
+	0x60, 0x00, // PUSH1 0 (retOffset)
+	0x60, 0x60, // PUSH1 96
+	0x51,       // MLOAD (argsSize)
+	0x60, 0x40, // PUSH1 64
+	0x51,       // MLOAD
+	0x60, 0x80, // PUSH1 128
+	0x01,       // ADD (argsOffset)
+	0x60, 0x20, // PUSH1 32
+	0x51, // MLOAD (signer)
+	0x5a, // GAS
+	0xfa, // STATICCALL
+
+	0x60, 0x20, // PUSH1 32
+	0x52,       // MSTORE the success flag to memory[32:64]
+	0x3d,       // RETURNDATASIZE
+	0x60, 0x40, // PUSH1 64
+	0x52,       // MSTORE it to memory[64:96]
+	0x60, 0x60, // PUSH1 96
+	0x60, 0x00, // PUSH1 0
+	0xf3, // RETURN memory[0:96]
+}
+
+var eip6492WrapperArgs abi.Arguments
+
+func init() {
+	addressType, _ := abi.NewType("address", "", nil)
+	bytesType, _ := abi.NewType("bytes", "", nil)
+	eip6492WrapperArgs = abi.Arguments{{Type: addressType}, {Type: bytesType}, {Type: bytesType}}
+}
+
+// Splits an EIP-6492 signature into the factory, its calldata, and the signature the signer will accept once deployed.
+// ok is false if the signature isn't wrapped.
+func unwrapEIP6492(signature []byte) (factory common.Address, factoryCalldata []byte, inner []byte, ok bool, err error) {
+	if !bytes.HasSuffix(signature, eip6492MagicSuffix) {
+		return common.Address{}, nil, nil, false, nil
+	}
+
+	values, err := eip6492WrapperArgs.Unpack(signature[:len(signature)-len(eip6492MagicSuffix)])
+	if err != nil {
+		return common.Address{}, nil, nil, true, fmt.Errorf("malformed EIP-6492 signature: %w", err)
+	}
+
+	return values[0].(common.Address), values[1].([]byte), values[2].([]byte), true, nil
+}
+
+// Recovers the signer of an ECDSA signature over dataHash, accepting v as 0/1 or 27/28
+func recoverSigner(dataHash common.Hash, signature []byte) (common.Address, bool) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, false
+	}
+
+	sig := bytes.Clone(signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	pubkey, err := crypto.SigToPub(dataHash.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, false
+	}
+
+	return crypto.PubkeyToAddress(*pubkey), true
+}
+
+// Calls isValidSignature on a deployed contract as of the given block, or the latest if nil
+func (e *CachingExecutionLayer) isValidSignature(ctx context.Context, dataHash common.Hash, signature []byte, address common.Address, block *big.Int) (bool, error) {
+	encodedData, err := getEIP1271ABI().Pack("isValidSignature", dataHash, signature)
+	if err != nil {
+		e.Logger.Warn("error packing isValidSignature call", zap.Error(err))
+		return false, InternalError
+	}
+
+	data, err := e.getClient().CallContract(ctx, ethereum.CallMsg{
+		To:   &address,
+		Data: encodedData,
+	}, block)
+	if err != nil {
+		e.Logger.Warn("error querying the execution client to validate an EIP1271 signature", zap.Error(err))
+		return false, InternalError
+	}
+
+	if len(data) == 0 {
+		return false, NoDataError
+	}
+
+	// Trim the trailing 0-bytes from the evm
+	data = bytes.TrimRight(data, "\x00")
+
+	// Check the return value, it should be exactly 4 bytes long
+	if len(data) != 4 {
+		return false, BadDataError
+	}
+
+	// Invalid signatures return 4 bytes that do not match the magic value
+	return bytes.Equal(data, eip1271MagicValue), nil
+}
+
+// Runs eip6492Verifier, deploying or preparing the signer with its factory before calling its isValidSignature
+func (e *CachingExecutionLayer) isValidSignatureDeployless(ctx context.Context, dataHash common.Hash, factory common.Address, factoryCalldata []byte, signature []byte, address common.Address, block *big.Int) (bool, error) {
+	isValidSignatureCalldata, err := getEIP1271ABI().Pack("isValidSignature", dataHash, signature)
+	if err != nil {
+		e.Logger.Warn("error packing isValidSignature call", zap.Error(err))
+		return false, InternalError
+	}
+
+	data := make([]byte, 0, len(eip6492Verifier)+128+len(factoryCalldata)+len(isValidSignatureCalldata))
+	data = append(data, eip6492Verifier...)
+	data = append(data, common.LeftPadBytes(factory.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(address.Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(binary.BigEndian.AppendUint64(nil, uint64(len(factoryCalldata))), 32)...)
+	data = append(data, common.LeftPadBytes(binary.BigEndian.AppendUint64(nil, uint64(len(isValidSignatureCalldata))), 32)...)
+	data = append(data, factoryCalldata...)
+	data = append(data, isValidSignatureCalldata...)
+
+	out, err := e.getClient().CallContract(ctx, ethereum.CallMsg{Data: data}, block)
+	if err != nil {
+		e.Logger.Warn("error querying the execution client to validate an EIP6492 signature", zap.Error(err))
+		return false, InternalError
+	}
+
+	if len(out) != 96 {
+		return false, BadDataError
+	}
+
+	success := new(big.Int).SetBytes(out[32:64]).Sign() != 0
+	returned := new(big.Int).SetBytes(out[64:96])
+	if !success || returned.Cmp(big.NewInt(4)) < 0 {
+		return false, nil
+	}
+
+	return bytes.Equal(out[:4], eip1271MagicValue), nil
+}
+
+// ValidateSignature checks a signature over dataHash by address as of the given block, or the latest if nil.
+// EIP-6492 wrapped signatures are checked against the contract they deploy, ECDSA signatures against
+// externally owned accounts, and any other signature with EIP-1271 if address is a contract.
+// It returns how the signature was found to be valid, or SignatureMethodNone if it wasn't.
+func (e *CachingExecutionLayer) ValidateSignature(ctx context.Context, dataHash common.Hash, signature []byte, address common.Address, block *big.Int) (SignatureMethod, error) {
+	factory, factoryCalldata, inner, wrapped, err := unwrapEIP6492(signature)
+	if err != nil {
+		return SignatureMethodNone, err
+	}
+
+	code, err := e.getClient().CodeAt(ctx, address, block)
+	if err != nil {
+		e.Logger.Warn("error querying the execution client for a signer's code", zap.Error(err))
+		return SignatureMethodNone, InternalError
+	}
+
+	if wrapped {
+		// A deployed signer may accept the inner signature as it is
+		if len(code) > 0 {
+			valid, err := e.isValidSignature(ctx, dataHash, inner, address, block)
+			if err != nil {
+				return SignatureMethodNone, err
+			}
+			if valid {
+				return SignatureMethodEIP1271, nil
+			}
+		}
+
+		valid, err := e.isValidSignatureDeployless(ctx, dataHash, factory, factoryCalldata, inner, address, block)
+		if err != nil || !valid {
+			return SignatureMethodNone, err
+		}
+
+		return SignatureMethodEIP6492, nil
+	}
+
+	if signer, ok := recoverSigner(dataHash, signature); ok && signer == address {
+		return SignatureMethodEOA, nil
+	}
+
+	if len(code) == 0 {
+		return SignatureMethodNone, nil
+	}
+
+	valid, err := e.isValidSignature(ctx, dataHash, signature, address, block)
+	if err != nil || !valid {
+		return SignatureMethodNone, err
+	}
+
+	return SignatureMethodEIP1271, nil
+}
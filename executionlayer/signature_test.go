@@ -0,0 +1,274 @@
+package executionlayer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var signatureFactory = common.HexToAddress("0x00000000000000000000000000000000000fac70")
+var signatureWallet = common.HexToAddress("0x000000000000000000000000000000000001a11e")
+
+// A contract whose isValidSignature calls fail
+var brokenWallet = common.HexToAddress("0x000000000000000000000000000000000000dead")
+
+// The only signature the wallets accept
+var walletSignature = common.LeftPadBytes([]byte{0x12, 0x71}, 32)
+
+// What signatureFactory is given to deploy a wallet
+var walletInitCode = []byte("wallet init code")
+
+// Where signatureFactory would deploy a wallet
+func counterfactualWallet() common.Address {
+	return crypto.CreateAddress2(signatureFactory, common.Hash{}, crypto.Keccak256(walletInitCode))
+}
+
+// What a wallet's isValidSignature returns for calldata
+func walletResult(t testing.TB, calldata []byte) []byte {
+	args, err := getEIP1271ABI().Methods["isValidSignature"].Inputs.Unpack(calldata[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Equal(args[1].([]byte), walletSignature) {
+		return common.RightPadBytes(eip1271MagicValue, 32)
+	}
+	return common.RightPadBytes([]byte{0xff, 0xff, 0xff, 0xff}, 32)
+}
+
+// signatureEC serves the same chain as happyEC, with signatureFactory, signatureWallet and brokenWallet deployed.
+// Rather than running an evm, it answers eth_calls with what the contracts and eip6492Verifier would return.
+// signatureFactory deploys a wallet at counterfactualWallet when it's given walletInitCode.
+type signatureEC struct {
+	*happyEC
+}
+
+func (e *signatureEC) Serve(mt int, data []byte) (int, []byte) {
+	m := jsonrpcMessage{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		e.t.Fatal(err)
+	}
+
+	var params []json.RawMessage
+	if m.Method == "eth_call" || m.Method == "eth_getCode" {
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			e.t.Fatal(err)
+		}
+	}
+
+	switch m.Method {
+	case "eth_getCode":
+		var addr common.Address
+		if err := json.Unmarshal(params[0], &addr); err != nil {
+			e.t.Fatal(err)
+		}
+
+		if addr == signatureFactory || addr == signatureWallet || addr == brokenWallet {
+			return mt, []byte(fmt.Sprintf(callResultFmt, m.ID, hexutil.Encode(addr.Bytes())))
+		}
+	case "eth_call":
+		var msg struct {
+			To   *common.Address `json:"to"`
+			Data hexutil.Bytes   `json:"data"`
+		}
+		if err := json.Unmarshal(params[0], &msg); err != nil {
+			e.t.Fatal(err)
+		}
+
+		if msg.To != nil && *msg.To == brokenWallet {
+			return mt, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"error":{"code":3,"message":"execution reverted"}}`, m.ID))
+		}
+		if msg.To != nil && *msg.To == signatureWallet {
+			return mt, []byte(fmt.Sprintf(callResultFmt, m.ID, hexutil.Encode(walletResult(e.t, msg.Data))))
+		}
+		if msg.To != nil {
+			break
+		}
+
+		// Decode the verifier's arguments, laid out as eip6492Verifier describes
+		if !bytes.HasPrefix(msg.Data, eip6492Verifier) {
+			e.t.Fatalf("unexpected deployless call %x", msg.Data)
+		}
+		args := msg.Data[len(eip6492Verifier):]
+		factory := common.BytesToAddress(args[0:32])
+		signer := common.BytesToAddress(args[32:64])
+		n := new(big.Int).SetBytes(args[64:96]).Uint64()
+		factoryCalldata := args[128 : 128+n]
+		isValidSignatureCalldata := args[128+n:]
+
+		deployed := signer == signatureWallet ||
+			(signer == counterfactualWallet() && factory == signatureFactory && bytes.Equal(factoryCalldata, walletInitCode))
+
+		// Staticcalls to accounts without code succeed, returning nothing
+		out := make([]byte, 96)
+		out[63] = 1
+		if deployed {
+			copy(out, walletResult(e.t, isValidSignatureCalldata))
+			out[95] = 32
+		}
+
+		return mt, []byte(fmt.Sprintf(callResultFmt, m.ID, hexutil.Encode(out)))
+	}
+
+	return e.happyEC.Serve(mt, data)
+}
+
+// Wraps a signature per EIP-6492
+func wrapEIP6492(t *testing.T, factory common.Address, factoryCalldata []byte, signature []byte) []byte {
+	out, err := eip6492WrapperArgs.Pack(factory, factoryCalldata, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return append(out, eip6492MagicSuffix...)
+}
+
+func TestEIP6492VerifierLength(t *testing.T) {
+	// The verifier copies its arguments from after itself, so it has to know its own length
+	if len(eip6492Verifier) != 0x3f {
+		t.Fatalf("the verifier is %d bytes long, but assumes it's 0x3f", len(eip6492Verifier))
+	}
+}
+
+func TestValidateSignature(t *testing.T) {
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:      true,
+				minipools: 1,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, &signatureEC{hec})
+	errs := startEL(t, et)
+
+	dataHash := crypto.Keccak256Hash([]byte("rescue node"))
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	eoa := crypto.PubkeyToAddress(key.PublicKey)
+	eoaSignature, err := crypto.Sign(dataHash.Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wallets usually produce v as 27 or 28
+	legacySignature := append([]byte{}, eoaSignature...)
+	legacySignature[crypto.RecoveryIDOffset] += 27
+
+	wallet := counterfactualWallet()
+	wrongSignature := common.LeftPadBytes([]byte{0x01}, 32)
+
+	testCases := []struct {
+		name      string
+		signature []byte
+		address   common.Address
+		block     *big.Int
+		expected  SignatureMethod
+	}{
+		{
+			name:      "EOA",
+			signature: eoaSignature,
+			address:   eoa,
+			expected:  SignatureMethodEOA,
+		},
+		{
+			name:      "EOA with a legacy v",
+			signature: legacySignature,
+			address:   eoa,
+			block:     big.NewInt(historyHead),
+			expected:  SignatureMethodEOA,
+		},
+		{
+			name:      "EOA signature from someone else",
+			signature: eoaSignature,
+			address:   common.HexToAddress("0x0f010f"),
+			expected:  SignatureMethodNone,
+		},
+		{
+			name:      "Deployed contract",
+			signature: walletSignature,
+			address:   signatureWallet,
+			block:     big.NewInt(historyHead),
+			expected:  SignatureMethodEIP1271,
+		},
+		{
+			name:      "Deployed contract rejecting the signature",
+			signature: wrongSignature,
+			address:   signatureWallet,
+			expected:  SignatureMethodNone,
+		},
+		{
+			name:      "Undeployed contract",
+			signature: wrapEIP6492(t, signatureFactory, walletInitCode, walletSignature),
+			address:   wallet,
+			expected:  SignatureMethodEIP6492,
+		},
+		{
+			name:      "Undeployed contract rejecting the signature",
+			signature: wrapEIP6492(t, signatureFactory, walletInitCode, wrongSignature),
+			address:   wallet,
+			expected:  SignatureMethodNone,
+		},
+		{
+			name:      "Undeployed contract from a factory that doesn't deploy it",
+			signature: wrapEIP6492(t, signatureFactory, []byte{0x00}, walletSignature),
+			address:   wallet,
+			expected:  SignatureMethodNone,
+		},
+		{
+			name:      "Wrapped signature for a deployed contract",
+			signature: wrapEIP6492(t, signatureFactory, walletInitCode, walletSignature),
+			address:   signatureWallet,
+			expected:  SignatureMethodEIP1271,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			method, err := et.ec.ValidateSignature(context.Background(), dataHash, tc.signature, tc.address, tc.block)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if method != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, method)
+			}
+		})
+	}
+
+	t.Run("Wrapped signature for a deployed contract that fails", func(t *testing.T) {
+		signature := wrapEIP6492(t, signatureFactory, walletInitCode, walletSignature)
+		method, err := et.ec.ValidateSignature(context.Background(), dataHash, signature, brokenWallet, nil)
+		if !errors.Is(err, InternalError) {
+			t.Fatalf("expected the contract's error, got %s and %v", method, err)
+		}
+	})
+
+	t.Run("Malformed wrapped signature", func(t *testing.T) {
+		method, err := et.ec.ValidateSignature(context.Background(), dataHash, eip6492MagicSuffix, wallet, nil)
+		if err == nil {
+			t.Fatalf("expected an error, got %s", method)
+		}
+		if errors.Is(err, InternalError) {
+			t.Fatalf("expected the signature to be blamed, got %v", err)
+		}
+	})
+
+	et.ec.Stop()
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
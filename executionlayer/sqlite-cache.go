@@ -6,11 +6,14 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
 	"github.com/ethereum/go-ethereum/common"
 	driver "github.com/mattn/go-sqlite3"
 	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"go.uber.org/zap"
 )
 
 type SqliteCache struct {
@@ -23,12 +26,44 @@ type SqliteCache struct {
 	setNodeStmt         *sql.Stmt
 	setHighestBlockStmt *sql.Stmt
 	forEachNodeStmt     *sql.Stmt
+	withdrawalNodesStmt *sql.Stmt
+	legacyNodesStmt     *sql.Stmt
 	addOdaoNodeStmt     *sql.Stmt
 	delOdaoNodeStmt     *sql.Stmt
 	forEachOdaoNodeStmt *sql.Stmt
+	isOdaoNodeStmt      *sql.Stmt
+	delMinipoolStmt     *sql.Stmt
+	forEachMinipoolStmt *sql.Stmt
+	nodeMinipoolsStmt   *sql.Stmt
+	delNodeStmt         *sql.Stmt
+	addEventStmt        *sql.Stmt
+	getEventsStmt       *sql.Stmt
+	delEventsStmt       *sql.Stmt
+	pruneEventsStmt     *sql.Stmt
+	addHistoryStmt      *sql.Stmt
+	getNodeAtStmt       *sql.Stmt
+	getMinipoolAtStmt   *sql.Stmt
+	historyStartStmt    *sql.Stmt
+	delHistoryStmt      *sql.Stmt
 
 	// Track the highest block in memory and save to db before serializing
-	highestBlock *big.Int
+	highestBlockLock sync.Mutex
+	highestBlock     *big.Int
+	// The highest block whose logs have all been applied, which is what snapshots save, so that
+	// a block isn't skipped after a crash. Until it's set, the highest block is saved.
+	appliedBlock *big.Int
+
+	// How often to save the db to disk in the background, so that it survives an unclean exit.
+	// Zero disables periodic snapshots, leaving only the one taken by deinit.
+	SnapshotInterval time.Duration
+	Logger           *zap.Logger
+
+	// Writes hold this for reading, and snapshots for writing. Writes to a shared-cache
+	// in-memory db fail outright, rather than wait, while the snapshot is reading it.
+	writeLock sync.RWMutex
+
+	stopSnapshots chan struct{}
+	wg            sync.WaitGroup
 
 	m *metrics.MetricsRegistry
 }
@@ -42,7 +77,8 @@ func (s *SqliteCache) prepareStatements() error {
 	if err != nil {
 		return err
 	}
-	s.getNodeStmt, err = s.db.Prepare("SELECT smoothing_pool_status, fee_distributor FROM nodes WHERE address = ?;")
+	s.getNodeStmt, err = s.db.Prepare(`SELECT smoothing_pool_status, fee_distributor, smoothing_pool_changed, withdrawal_address, rpl_withdrawal_address,
+		fee_distributor_changed, minipools_changed, odao_changed FROM nodes WHERE address = ?;`)
 	if err != nil {
 		return err
 	}
@@ -55,7 +91,9 @@ func (s *SqliteCache) prepareStatements() error {
 	if err != nil {
 		return err
 	}
-	s.setNodeStmt, err = s.db.Prepare("INSERT OR REPLACE INTO nodes(address, smoothing_pool_status, fee_distributor) VALUES( ?, ?, ?);")
+	s.setNodeStmt, err = s.db.Prepare(`INSERT OR REPLACE INTO nodes(address, smoothing_pool_status, fee_distributor, smoothing_pool_changed,
+		withdrawal_address, rpl_withdrawal_address, fee_distributor_changed, minipools_changed, odao_changed)
+		VALUES( ?, ?, ?, ?, ?, ?, ?, ?, ?);`)
 	if err != nil {
 		return err
 	}
@@ -69,6 +107,16 @@ func (s *SqliteCache) prepareStatements() error {
 		return err
 	}
 
+	s.withdrawalNodesStmt, err = s.db.Prepare("SELECT address FROM nodes WHERE withdrawal_address = ?1 OR rpl_withdrawal_address = ?1;")
+	if err != nil {
+		return err
+	}
+
+	s.legacyNodesStmt, err = s.db.Prepare("SELECT address FROM nodes WHERE withdrawal_address IS NULL;")
+	if err != nil {
+		return err
+	}
+
 	s.addOdaoNodeStmt, err = s.db.Prepare("INSERT OR REPLACE INTO odao_nodes(address) VALUES ( ?);")
 	if err != nil {
 		return err
@@ -84,52 +132,320 @@ func (s *SqliteCache) prepareStatements() error {
 		return err
 	}
 
+	s.isOdaoNodeStmt, err = s.db.Prepare("SELECT COUNT(*) FROM odao_nodes WHERE address = ?;")
+	if err != nil {
+		return err
+	}
+
+	s.delMinipoolStmt, err = s.db.Prepare("DELETE FROM minipools WHERE pubkey = ?;")
+	if err != nil {
+		return err
+	}
+
+	s.forEachMinipoolStmt, err = s.db.Prepare("SELECT pubkey, node_address FROM minipools;")
+	if err != nil {
+		return err
+	}
+
+	s.nodeMinipoolsStmt, err = s.db.Prepare("SELECT pubkey FROM minipools WHERE node_address = ?;")
+	if err != nil {
+		return err
+	}
+
+	s.delNodeStmt, err = s.db.Prepare("DELETE FROM nodes WHERE address = ?;")
+	if err != nil {
+		return err
+	}
+
+	s.addEventStmt, err = s.db.Prepare("INSERT INTO processed_events(block_number, block_hash, log_index, topic, address, pubkey) VALUES (?, ?, ?, ?, ?, ?);")
+	if err != nil {
+		return err
+	}
+
+	s.getEventsStmt, err = s.db.Prepare("SELECT block_number, block_hash, log_index, topic, address, pubkey FROM processed_events WHERE block_number >= ? ORDER BY id;")
+	if err != nil {
+		return err
+	}
+
+	s.delEventsStmt, err = s.db.Prepare("DELETE FROM processed_events WHERE block_number >= ?;")
+	if err != nil {
+		return err
+	}
+
+	s.pruneEventsStmt, err = s.db.Prepare("DELETE FROM processed_events WHERE block_number < ?;")
+	if err != nil {
+		return err
+	}
+
+	s.addHistoryStmt, err = s.db.Prepare(`INSERT INTO history(block_number, topic, node_address, pubkey, removed,
+		smoothing_pool_status, fee_distributor, smoothing_pool_changed) VALUES (?, ?, ?, ?, ?, ?, ?, ?);`)
+	if err != nil {
+		return err
+	}
+
+	s.getNodeAtStmt, err = s.db.Prepare(`SELECT removed, smoothing_pool_status, fee_distributor, smoothing_pool_changed FROM history
+		WHERE node_address = ? AND pubkey IS NULL AND block_number <= ? ORDER BY block_number DESC, id DESC LIMIT 1;`)
+	if err != nil {
+		return err
+	}
+
+	s.getMinipoolAtStmt, err = s.db.Prepare(`SELECT removed, node_address FROM history
+		WHERE pubkey = ? AND block_number <= ? ORDER BY block_number DESC, id DESC LIMIT 1;`)
+	if err != nil {
+		return err
+	}
+
+	s.historyStartStmt, err = s.db.Prepare("SELECT MIN(block_number) FROM history;")
+	if err != nil {
+		return err
+	}
+
+	s.delHistoryStmt, err = s.db.Prepare("DELETE FROM history WHERE block_number >= ?;")
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (s *SqliteCache) createTables() error {
-	const nodes string = `
-		CREATE TABLE IF NOT EXISTS nodes (
-			address BLOB PRIMARY KEY,
-			smoothing_pool_status TINYINT,
-			fee_distributor BLOB
-		);`
+// Schema migrations, in order. A db's schema version is the number of migrations applied to it.
+// Snapshots taken before the schema was versioned are at version 0, and may already have some of
+// the tables, so the migrations they run must tolerate that.
+var migrations = []func(tx *sql.Tx) error{
+	// 1: the original tables
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS nodes (
+				address BLOB PRIMARY KEY,
+				smoothing_pool_status TINYINT,
+				fee_distributor BLOB
+			);
+			CREATE TABLE IF NOT EXISTS minipools (
+				pubkey BLOB PRIMARY KEY,
+				node_address BLOB
+			);
+			CREATE TABLE IF NOT EXISTS highest_block (
+				id INTEGER PRIMARY KEY CHECK (id = 0),
+				value INTEGER(8)
+			);
+			CREATE TABLE IF NOT EXISTS odao_nodes (
+				address BLOB PRIMARY KEY
+			);`)
+		return err
+	},
+	// 2: the journal of processed events, used to roll back reorgs
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS processed_events (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				block_number INTEGER(8),
+				block_hash BLOB,
+				topic BLOB,
+				address BLOB,
+				pubkey BLOB
+			);
+			CREATE INDEX IF NOT EXISTS processed_events_block_number ON processed_events(block_number);`)
+		return err
+	},
+	// 3: when each node last joined or left the smoothing pool
+	func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info('nodes') WHERE name = 'smoothing_pool_changed';").Scan(&exists)
+		if err != nil || exists > 0 {
+			return err
+		}
 
-	const minipools string = `
-		CREATE TABLE IF NOT EXISTS minipools (
-			pubkey BLOB PRIMARY KEY,
-			node_address BLOB
-		);`
+		_, err = tx.Exec("ALTER TABLE nodes ADD COLUMN smoothing_pool_changed INTEGER(8) DEFAULT 0;")
+		return err
+	},
+	// 4: the history of nodes and minipools. Node entries have no pubkey.
+	// Snapshots start it off with their current state, as of their highest block.
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				block_number INTEGER(8),
+				topic BLOB,
+				node_address BLOB,
+				pubkey BLOB,
+				removed TINYINT,
+				smoothing_pool_status TINYINT,
+				fee_distributor BLOB,
+				smoothing_pool_changed INTEGER(8)
+			);
+			CREATE INDEX IF NOT EXISTS history_node ON history(node_address, block_number);
+			CREATE INDEX IF NOT EXISTS history_minipool ON history(pubkey, block_number);
+			CREATE INDEX IF NOT EXISTS history_block_number ON history(block_number);
+			INSERT INTO history(block_number, node_address, removed, smoothing_pool_status, fee_distributor, smoothing_pool_changed)
+				SELECT highest_block.value, address, 0, COALESCE(smoothing_pool_status, 0), fee_distributor, COALESCE(smoothing_pool_changed, 0)
+				FROM nodes, highest_block WHERE highest_block.id = 0;
+			INSERT INTO history(block_number, node_address, pubkey, removed)
+				SELECT highest_block.value, node_address, pubkey, 0
+				FROM minipools, highest_block WHERE highest_block.id = 0;`)
+		return err
+	},
+	// 5: nodes' withdrawal addresses. The rpl withdrawal address is null unless set.
+	// Existing snapshots don't know them, so they're null until Init reads them from the chain.
+	func(tx *sql.Tx) error {
+		for _, column := range []string{"withdrawal_address", "rpl_withdrawal_address"} {
+			var exists int
+			err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info('nodes') WHERE name = ?;", column).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if exists > 0 {
+				continue
+			}
 
-	const highestBlock string = `
-		CREATE TABLE IF NOT EXISTS highest_block (
-			id INTEGER PRIMARY KEY CHECK (id = 0),
-			value INTEGER(8)
-		);`
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE nodes ADD COLUMN %s BLOB;", column)); err != nil {
+				return err
+			}
+		}
 
-	const odaoNodes string = `
-		CREATE TABLE IF NOT EXISTS odao_nodes (
-			address BLOB PRIMARY KEY
-		);`
+		_, err := tx.Exec(`
+			CREATE INDEX IF NOT EXISTS nodes_withdrawal_address ON nodes(withdrawal_address);
+			CREATE INDEX IF NOT EXISTS nodes_rpl_withdrawal_address ON nodes(rpl_withdrawal_address);`)
+		return err
+	},
+	// 6: the reverse index of minipools, for looking up a node's minipools
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE INDEX IF NOT EXISTS minipools_node_address ON minipools(node_address);")
+		return err
+	},
+	// 7: the position of each processed event in its block, so replayed logs can be recognized.
+	// It's null for events journaled before.
+	func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info('processed_events') WHERE name = 'log_index';").Scan(&exists)
+		if err != nil || exists > 0 {
+			return err
+		}
 
-	if _, err := s.db.Exec(nodes); err != nil {
+		_, err = tx.Exec("ALTER TABLE processed_events ADD COLUMN log_index INTEGER(8);")
 		return err
+	},
+	// 8: when each node registered, and when its minipools and odao membership last changed
+	func(tx *sql.Tx) error {
+		for _, column := range []string{"fee_distributor_changed", "minipools_changed", "odao_changed"} {
+			var exists int
+			err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info('nodes') WHERE name = ?;", column).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if exists > 0 {
+				continue
+			}
+
+			if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE nodes ADD COLUMN %s INTEGER(8) DEFAULT 0;", column)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+func (s *SqliteCache) getSchemaVersion() (int, error) {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			id INTEGER PRIMARY KEY CHECK (id = 0),
+			version INTEGER
+		);`)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	err = s.db.QueryRow("SELECT version FROM schema_version WHERE id = 0;").Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
 	}
 
-	if _, err := s.db.Exec(minipools); err != nil {
+	return version, err
+}
+
+// Drops every table, leaving an empty db
+func (s *SqliteCache) dropTables() error {
+	rows, err := s.db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%';")
+	if err != nil {
 		return err
 	}
 
-	if _, err := s.db.Exec(highestBlock); err != nil {
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
 		return err
 	}
 
-	if _, err := s.db.Exec(odaoNodes); err != nil {
+	for _, table := range tables {
+		if _, err := s.db.Exec(fmt.Sprintf("DROP TABLE %q;", table)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Brings the db's schema up to date, one migration at a time
+func (s *SqliteCache) migrate() error {
+	version, err := s.getSchemaVersion()
+	if err != nil {
 		return err
 	}
 
+	// We can't know what a newer version changed, so start over.
+	// With no highest block, the execution layer warms the cache up again.
+	if version > len(migrations) {
+		s.Logger.Warn("Cache snapshot has an unknown schema version, resetting",
+			zap.Int("version", version), zap.Int("latest", len(migrations)))
+		s.m.Counter("schema_reset").Inc()
+
+		if err := s.dropTables(); err != nil {
+			return err
+		}
+
+		version, err = s.getSchemaVersion()
+		if err != nil {
+			return err
+		}
+	}
+
+	for ; version < len(migrations); version++ {
+		if err := s.applyMigration(version); err != nil {
+			return fmt.Errorf("error migrating cache schema to version %d: %w", version+1, err)
+		}
+		s.m.Counter("schema_migrated").Inc()
+	}
+
 	return nil
+}
+
+// Applies the migration from the given version to the next, atomically
+func (s *SqliteCache) applyMigration(version int) error {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	if err := migrations[version](tx); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO schema_version(id, version) VALUES (0, ?);", version+1)
+	if err != nil {
+		return err
+	}
 
+	return tx.Commit()
 }
 
 func rollback(tx *sql.Tx) {
@@ -142,11 +458,13 @@ func cloneSqlDB(dst, src *sql.DB) error {
 	if err != nil {
 		return err
 	}
+	defer srcConn.Close()
 
 	dstConn, err := dst.Conn(context.Background())
 	if err != nil {
 		return err
 	}
+	defer dstConn.Close()
 
 	err = dstConn.Raw(func(dstDConn any) error {
 		dstSQLiteConn, ok := dstDConn.(*driver.SQLiteConn)
@@ -185,6 +503,9 @@ func (s *SqliteCache) init() error {
 	var err error
 
 	s.m = metrics.NewMetricsRegistry("sqlite_cache")
+	if s.Logger == nil {
+		s.Logger = zap.NewNop()
+	}
 
 	// Set highestBlock to 0. We can load it from the snapshot later
 	s.highestBlock = big.NewInt(0)
@@ -230,7 +551,7 @@ func (s *SqliteCache) init() error {
 		}
 	}
 cont:
-	err = s.createTables()
+	err = s.migrate()
 	if err != nil {
 		return err
 	}
@@ -243,7 +564,21 @@ cont:
 	// Finally, grab the highest block from the db
 	// If there was no snapshot, this will not return anything,
 	// and the user will know they need to warm up the cache
+	if err := s.loadHighestBlock(); err != nil {
+		return err
+	}
+
+	// Only snapshot once the highest block is loaded, or the first snapshot could save a stale one
+	if s.SnapshotInterval > 0 {
+		s.stopSnapshots = make(chan struct{})
+		s.wg.Add(1)
+		go s.snapshotLoop()
+	}
 
+	return nil
+}
+
+func (s *SqliteCache) loadHighestBlock() error {
 	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted})
 	if err != nil {
 		return err
@@ -271,35 +606,109 @@ cont:
 		return err
 	}
 
+	// Every log up to the saved block was applied before it was saved
 	s.setHighestBlock(big.NewInt(block))
+	s.setAppliedBlock(big.NewInt(block))
 
 	return nil
 }
 
+// Saves the db to disk. The snapshot is written to a temporary file and renamed over
+// the previous one, so that a crash midway leaves the previous snapshot intact.
 func (s *SqliteCache) serialize() error {
-
 	snapshotPath := s.Path + "/" + snapshotFileName
-	// See if we have a sqlite snapshot we must delete
-	if _, err := os.Stat(snapshotPath); os.IsNotExist(err) {
-		// Nothing to delete
-	} else if err == nil {
-		// Something to delete
-		err = os.Remove(snapshotPath)
-		if err != nil {
-			return err
-		}
-	} else {
+	tmpPath := snapshotPath + ".tmp"
+
+	// Clear out anything left behind by an interrupted snapshot
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
 	// Now create the db and save to it
-	dst, err := sql.Open("sqlite3", "file:"+snapshotPath)
+	dst, err := sql.Open("sqlite3", "file:"+tmpPath)
+	if err != nil {
+		return err
+	}
+
+	err = cloneSqlDB(dst, s.db)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := syncPath(tmpPath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, snapshotPath); err != nil {
+		return err
+	}
+
+	// Persist the rename itself
+	return syncPath(s.Path)
+}
+
+func syncPath(path string) error {
+	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	defer dst.Close()
+	defer f.Close()
 
-	return cloneSqlDB(dst, s.db)
+	return f.Sync()
+}
+
+// Writes the highest block whose logs have all been applied into the db and saves it to disk
+func (s *SqliteCache) snapshot() error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	block := s.getAppliedBlock().Int64()
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	_, err = tx.Stmt(s.setHighestBlockStmt).Exec(block)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return s.serialize()
+}
+
+func (s *SqliteCache) snapshotLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopSnapshots:
+			return
+		case <-ticker.C:
+		}
+
+		start := time.Now()
+		if err := s.snapshot(); err != nil {
+			s.m.Counter("snapshot_failed").Inc()
+			s.Logger.Warn("Error saving cache snapshot", zap.Error(err))
+			continue
+		}
+
+		s.m.Counter("snapshot_written").Inc()
+		s.m.Gauge("snapshot_seconds").Set(time.Since(start).Seconds())
+	}
 }
 
 func (s *SqliteCache) getMinipoolNode(pubkey rptypes.ValidatorPubkey) (common.Address, error) {
@@ -336,6 +745,8 @@ func (s *SqliteCache) getMinipoolNode(pubkey rptypes.ValidatorPubkey) (common.Ad
 }
 
 func (s *SqliteCache) addMinipoolNode(pubkey rptypes.ValidatorPubkey, nodeAddr common.Address) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
 
 	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
 	if err != nil {
@@ -351,9 +762,88 @@ func (s *SqliteCache) addMinipoolNode(pubkey rptypes.ValidatorPubkey, nodeAddr c
 	return tx.Commit()
 }
 
+func (s *SqliteCache) removeMinipoolNode(pubkey rptypes.ValidatorPubkey) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	_, err = tx.Stmt(s.delMinipoolStmt).Exec(pubkey[:])
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SqliteCache) forEachMinipool(closure ForEachMinipoolClosure) error {
+	var pubkey, address []byte
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	rows, err := tx.Stmt(s.forEachMinipoolStmt).Query()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		err = rows.Scan(&pubkey, &address)
+		if err != nil {
+			return err
+		}
+
+		if !closure(rptypes.BytesToValidatorPubkey(pubkey), common.BytesToAddress(address)) {
+			break
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SqliteCache) getNodeMinipools(nodeAddr common.Address) ([]rptypes.ValidatorPubkey, error) {
+	var pubkey []byte
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(tx)
+
+	rows, err := tx.Stmt(s.nodeMinipoolsStmt).Query(nodeAddr.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]rptypes.ValidatorPubkey, 0)
+	for rows.Next() {
+		err = rows.Scan(&pubkey)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, rptypes.BytesToValidatorPubkey(pubkey))
+	}
+
+	return out, tx.Commit()
+}
+
 func (s *SqliteCache) getNodeInfo(nodeAddr common.Address) (*nodeInfo, error) {
 	var dbSPStatus int
 	var dbFeeDistributor []byte
+	var dbSPChanged int64
+	var dbWithdrawalAddress []byte
+	var dbRPLWithdrawalAddress []byte
+	var dbFeeDistributorChanged int64
+	var dbMinipoolsChanged int64
+	var dbOdaoChanged int64
 
 	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted})
 	if err != nil {
@@ -373,7 +863,8 @@ func (s *SqliteCache) getNodeInfo(nodeAddr common.Address) (*nodeInfo, error) {
 		return nil, &NotFoundError{}
 	}
 
-	err = rows.Scan(&dbSPStatus, &dbFeeDistributor)
+	err = rows.Scan(&dbSPStatus, &dbFeeDistributor, &dbSPChanged, &dbWithdrawalAddress, &dbRPLWithdrawalAddress,
+		&dbFeeDistributorChanged, &dbMinipoolsChanged, &dbOdaoChanged)
 	if err != nil {
 		return nil, err
 	}
@@ -382,26 +873,71 @@ func (s *SqliteCache) getNodeInfo(nodeAddr common.Address) (*nodeInfo, error) {
 		return nil, fmt.Errorf("retrieved more than one row for a minipool point query")
 	}
 
-	return &nodeInfo{
-		inSmoothingPool: dbSPStatus > 0,
-		feeDistributor:  common.BytesToAddress(dbFeeDistributor),
-	}, tx.Commit()
+	out := &nodeInfo{
+		inSmoothingPool:       dbSPStatus > 0,
+		feeDistributor:        common.BytesToAddress(dbFeeDistributor),
+		smoothingPoolChanged:  fromUnixTime(dbSPChanged),
+		feeDistributorChanged: fromUnixTime(dbFeeDistributorChanged),
+		minipoolsChanged:      fromUnixTime(dbMinipoolsChanged),
+		odaoChanged:           fromUnixTime(dbOdaoChanged),
+		withdrawalAddress:     common.BytesToAddress(dbWithdrawalAddress),
+		rplWithdrawalAddress:  common.BytesToAddress(dbRPLWithdrawalAddress),
+
+		// Cached before withdrawal addresses were kept
+		withdrawalAddressesUnknown: dbWithdrawalAddress == nil,
+	}
+
+	return out, tx.Commit()
 }
 
 func (s *SqliteCache) addNodeInfo(nodeAddr common.Address, node *nodeInfo) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
 	var inSP int = 0
 
 	if node.inSmoothingPool {
 		inSP = 1
 	}
 
+	var rplWithdrawalAddress []byte
+	if node.rplWithdrawalAddress != (common.Address{}) {
+		rplWithdrawalAddress = node.rplWithdrawalAddress.Bytes()
+	}
+
+	// Unknown withdrawal addresses stay null until they're backfilled
+	withdrawalAddress := node.withdrawalAddress.Bytes()
+	if node.withdrawalAddressesUnknown {
+		withdrawalAddress, rplWithdrawalAddress = nil, nil
+	}
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	_, err = tx.Stmt(s.setNodeStmt).Exec(nodeAddr.Bytes(), inSP, node.feeDistributor.Bytes(), unixTime(node.smoothingPoolChanged),
+		withdrawalAddress, rplWithdrawalAddress,
+		unixTime(node.feeDistributorChanged), unixTime(node.minipoolsChanged), unixTime(node.odaoChanged))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SqliteCache) removeNodeInfo(nodeAddr common.Address) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
 	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
 	if err != nil {
 		return err
 	}
 	defer rollback(tx)
 
-	_, err = tx.Stmt(s.setNodeStmt).Exec(nodeAddr.Bytes(), inSP, node.feeDistributor.Bytes())
+	_, err = tx.Stmt(s.delNodeStmt).Exec(nodeAddr.Bytes())
 	if err != nil {
 		return err
 	}
@@ -437,7 +973,63 @@ func (s *SqliteCache) forEachNode(closure ForEachNodeClosure) error {
 	return tx.Commit()
 }
 
+func (s *SqliteCache) getWithdrawalAddressNodes(withdrawalAddr common.Address) ([]common.Address, error) {
+	var address []byte
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(tx)
+
+	rows, err := tx.Stmt(s.withdrawalNodesStmt).Query(withdrawalAddr.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]common.Address, 0)
+	for rows.Next() {
+		err = rows.Scan(&address)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, common.BytesToAddress(address))
+	}
+
+	return out, tx.Commit()
+}
+
+func (s *SqliteCache) getLegacyNodes() ([]common.Address, error) {
+	var address []byte
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(tx)
+
+	rows, err := tx.Stmt(s.legacyNodesStmt).Query()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]common.Address, 0)
+	for rows.Next() {
+		err = rows.Scan(&address)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, common.BytesToAddress(address))
+	}
+
+	return out, tx.Commit()
+}
+
 func (s *SqliteCache) addOdaoNode(nodeAddr common.Address) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
 
 	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
 	if err != nil {
@@ -454,6 +1046,8 @@ func (s *SqliteCache) addOdaoNode(nodeAddr common.Address) error {
 }
 
 func (s *SqliteCache) removeOdaoNode(nodeAddr common.Address) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
 
 	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
 	if err != nil {
@@ -497,7 +1091,253 @@ func (s *SqliteCache) forEachOdaoNode(closure ForEachNodeClosure) error {
 	return tx.Commit()
 }
 
+func (s *SqliteCache) isOdaoNode(nodeAddr common.Address) (bool, error) {
+	var count int
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return false, err
+	}
+	defer rollback(tx)
+
+	err = tx.Stmt(s.isOdaoNodeStmt).QueryRow(nodeAddr.Bytes()).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, tx.Commit()
+}
+
+func (s *SqliteCache) addProcessedEvent(event *processedEvent) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	_, err = tx.Stmt(s.addEventStmt).Exec(
+		int64(event.blockNumber),
+		event.blockHash.Bytes(),
+		int64(event.logIndex),
+		event.topic.Bytes(),
+		event.address.Bytes(),
+		event.pubkey[:])
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SqliteCache) getProcessedEvents(fromBlock uint64) ([]*processedEvent, error) {
+	var blockNumber int64
+	var logIndex sql.NullInt64
+	var blockHash, topic, address, pubkey []byte
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return nil, err
+	}
+	defer rollback(tx)
+
+	rows, err := tx.Stmt(s.getEventsStmt).Query(int64(fromBlock))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*processedEvent, 0)
+	for rows.Next() {
+		err = rows.Scan(&blockNumber, &blockHash, &logIndex, &topic, &address, &pubkey)
+		if err != nil {
+			return nil, err
+		}
+
+		index := unknownLogIndex
+		if logIndex.Valid {
+			index = uint(logIndex.Int64)
+		}
+
+		out = append(out, &processedEvent{
+			blockNumber: uint64(blockNumber),
+			blockHash:   common.BytesToHash(blockHash),
+			logIndex:    index,
+			topic:       common.BytesToHash(topic),
+			address:     common.BytesToAddress(address),
+			pubkey:      rptypes.BytesToValidatorPubkey(pubkey),
+		})
+	}
+
+	return out, tx.Commit()
+}
+
+func (s *SqliteCache) removeProcessedEvents(fromBlock uint64) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	_, err = tx.Stmt(s.delEventsStmt).Exec(int64(fromBlock))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SqliteCache) pruneProcessedEvents(beforeBlock uint64) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	_, err = tx.Stmt(s.pruneEventsStmt).Exec(int64(beforeBlock))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SqliteCache) addHistory(entries []*historyEntry) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	stmt := tx.Stmt(s.addHistoryStmt)
+	for _, entry := range entries {
+		var topic []byte
+		if entry.topic != (common.Hash{}) {
+			topic = entry.topic.Bytes()
+		}
+
+		removed := 0
+		if entry.removed {
+			removed = 1
+		}
+
+		// Node entries have no pubkey, and minipool entries no node state
+		var pubkey, inSP, feeDistributor, spChanged any
+		if entry.pubkey != (rptypes.ValidatorPubkey{}) {
+			pubkey = entry.pubkey[:]
+		} else {
+			n := entry.node
+			if n == nil {
+				n = &nodeInfo{}
+			}
+
+			inSP, feeDistributor, spChanged = 0, n.feeDistributor.Bytes(), int64(0)
+			if n.inSmoothingPool {
+				inSP = 1
+			}
+			if !n.smoothingPoolChanged.IsZero() {
+				spChanged = n.smoothingPoolChanged.Unix()
+			}
+		}
+
+		_, err = stmt.Exec(int64(entry.blockNumber), topic, entry.nodeAddress.Bytes(), pubkey, removed, inSP, feeDistributor, spChanged)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SqliteCache) getNodeInfoAt(nodeAddr common.Address, block uint64) (*nodeInfo, error) {
+	var removed, dbSPStatus int
+	var dbFeeDistributor []byte
+	var dbSPChanged int64
+
+	err := s.getNodeAtStmt.QueryRow(nodeAddr.Bytes(), int64(block)).Scan(&removed, &dbSPStatus, &dbFeeDistributor, &dbSPChanged)
+	if err == sql.ErrNoRows {
+		return nil, &NotFoundError{}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if removed > 0 {
+		return nil, &NotFoundError{}
+	}
+
+	out := &nodeInfo{
+		inSmoothingPool: dbSPStatus > 0,
+		feeDistributor:  common.BytesToAddress(dbFeeDistributor),
+	}
+	if dbSPChanged > 0 {
+		out.smoothingPoolChanged = time.Unix(dbSPChanged, 0)
+	}
+
+	return out, nil
+}
+
+func (s *SqliteCache) getMinipoolNodeAt(pubkey rptypes.ValidatorPubkey, block uint64) (common.Address, error) {
+	var removed int
+	var addr []byte
+
+	err := s.getMinipoolAtStmt.QueryRow(pubkey[:], int64(block)).Scan(&removed, &addr)
+	if err == sql.ErrNoRows {
+		return common.Address{}, &NotFoundError{}
+	}
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if removed > 0 {
+		return common.Address{}, &NotFoundError{}
+	}
+
+	return common.BytesToAddress(addr), nil
+}
+
+func (s *SqliteCache) getHistoryStart() (uint64, bool, error) {
+	var start sql.NullInt64
+
+	if err := s.historyStartStmt.QueryRow().Scan(&start); err != nil {
+		return 0, false, err
+	}
+
+	return uint64(start.Int64), start.Valid, nil
+}
+
+func (s *SqliteCache) removeHistory(fromBlock uint64) error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer rollback(tx)
+
+	_, err = tx.Stmt(s.delHistoryStmt).Exec(int64(fromBlock))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (s *SqliteCache) setHighestBlock(block *big.Int) {
+	s.highestBlockLock.Lock()
+	defer s.highestBlockLock.Unlock()
+
 	if s.highestBlock.Cmp(block) >= 0 {
 		return
 	}
@@ -509,11 +1349,33 @@ func (s *SqliteCache) setHighestBlock(block *big.Int) {
 }
 
 func (s *SqliteCache) getHighestBlock() *big.Int {
+	s.highestBlockLock.Lock()
+	defer s.highestBlockLock.Unlock()
 
 	return s.highestBlock
 }
 
+func (s *SqliteCache) setAppliedBlock(block *big.Int) {
+	s.highestBlockLock.Lock()
+	defer s.highestBlockLock.Unlock()
+
+	s.appliedBlock = big.NewInt(0).Set(block)
+}
+
+func (s *SqliteCache) getAppliedBlock() *big.Int {
+	s.highestBlockLock.Lock()
+	defer s.highestBlockLock.Unlock()
+
+	if s.appliedBlock == nil {
+		return s.highestBlock
+	}
+	return s.appliedBlock
+}
+
 func (s *SqliteCache) reset() error {
+	s.writeLock.RLock()
+	defer s.writeLock.RUnlock()
+
 	//Just delete from each of the tables
 	_, err := s.db.Exec("DELETE FROM nodes;")
 	if err != nil {
@@ -535,36 +1397,38 @@ func (s *SqliteCache) reset() error {
 		return err
 	}
 
-	s.m.Counter("reset").Inc()
-	return nil
-}
-
-func (s *SqliteCache) deinit() error {
-	// Write the highest block into the db
-	block := s.highestBlock.Int64()
-
-	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: false, Isolation: sql.LevelReadCommitted})
+	_, err = s.db.Exec("DELETE FROM processed_events;")
 	if err != nil {
 		return err
 	}
-	defer rollback(tx)
 
-	_, err = tx.Stmt(s.setHighestBlockStmt).Exec(block)
+	_, err = s.db.Exec("DELETE FROM history;")
 	if err != nil {
 		return err
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return err
+	s.m.Counter("reset").Inc()
+	return nil
+}
+
+func (s *SqliteCache) deinit() error {
+	if s.stopSnapshots != nil {
+		close(s.stopSnapshots)
+		s.wg.Wait()
 	}
 
 	// Save the db to disk
-	err = s.serialize()
+	err := s.snapshot()
 	if err != nil {
 		return err
 	}
 
+	s.close()
+	return nil
+}
+
+// Releases the db without saving it
+func (s *SqliteCache) close() {
 	s.getMinipoolStmt.Close()
 	s.getNodeStmt.Close()
 	s.getHighestBlockStmt.Close()
@@ -572,9 +1436,24 @@ func (s *SqliteCache) deinit() error {
 	s.setNodeStmt.Close()
 	s.setHighestBlockStmt.Close()
 	s.forEachNodeStmt.Close()
+	s.withdrawalNodesStmt.Close()
+	s.legacyNodesStmt.Close()
 	s.addOdaoNodeStmt.Close()
 	s.delOdaoNodeStmt.Close()
 	s.forEachOdaoNodeStmt.Close()
+	s.isOdaoNodeStmt.Close()
+	s.delMinipoolStmt.Close()
+	s.forEachMinipoolStmt.Close()
+	s.nodeMinipoolsStmt.Close()
+	s.delNodeStmt.Close()
+	s.addEventStmt.Close()
+	s.getEventsStmt.Close()
+	s.delEventsStmt.Close()
+	s.pruneEventsStmt.Close()
+	s.addHistoryStmt.Close()
+	s.getNodeAtStmt.Close()
+	s.getMinipoolAtStmt.Close()
+	s.historyStartStmt.Close()
+	s.delHistoryStmt.Close()
 	s.db.Close()
-	return nil
 }
@@ -0,0 +1,214 @@
+package executionlayer
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rocket-pool/rocketpool-go/rocketpool"
+	"github.com/rocket-pool/rocketpool-go/storage"
+	rptypes "github.com/rocket-pool/rocketpool-go/types"
+	"go.uber.org/zap"
+)
+
+// WithdrawalAddresses are where a node's ETH and RPL are withdrawn to
+type WithdrawalAddresses struct {
+	NodeAddress       common.Address
+	WithdrawalAddress common.Address
+	// Nil unless the node set an RPL withdrawal address, otherwise RPL goes to WithdrawalAddress
+	RPLWithdrawalAddress *common.Address
+}
+
+func newWithdrawalAddresses(addr common.Address, n *nodeInfo) *WithdrawalAddresses {
+	out := &WithdrawalAddresses{
+		NodeAddress:       addr,
+		WithdrawalAddress: n.withdrawalAddress,
+	}
+	if n.rplWithdrawalAddress != (common.Address{}) {
+		rplWithdrawalAddress := n.rplWithdrawalAddress
+		out.RPLWithdrawalAddress = &rplWithdrawalAddress
+	}
+
+	return out
+}
+
+// Reads a node's withdrawal address, and its RPL withdrawal address, which is empty unless set
+func loadWithdrawalAddresses(rp *rocketpool.RocketPool, addr common.Address, opts *bind.CallOpts) (common.Address, common.Address, error) {
+	withdrawalAddress, err := storage.GetNodeWithdrawalAddress(rp, addr, opts)
+	if err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+
+	// rocketpool-go has no bindings for RPL withdrawal addresses, so call rocketNodeManager directly
+	nodeManager, err := rp.GetContract("rocketNodeManager", opts)
+	if err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+
+	// Unset RPL withdrawal addresses read as the withdrawal address, so check whether it's set first
+	isSet := new(bool)
+	if err := nodeManager.Call(opts, isSet, "getNodeRPLWithdrawalAddressIsSet", addr); err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+	if !*isSet {
+		return withdrawalAddress, common.Address{}, nil
+	}
+
+	rplWithdrawalAddress := new(common.Address)
+	if err := nodeManager.Call(opts, rplWithdrawalAddress, "getNodeRPLWithdrawalAddress", addr); err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+
+	return withdrawalAddress, *rplWithdrawalAddress, nil
+}
+
+// Handles a node setting its withdrawal address, or setting or unsetting its RPL withdrawal address
+func (e *CachingExecutionLayer) handleWithdrawalAddressEvent(event types.Log) {
+	topic := event.Topics[0]
+	if topic != e.rplWithdrawalAddressUnsetTopic && len(event.Topics) < 3 {
+		e.Logger.Warn("Withdrawal address event is missing the address", zap.String("tx", event.TxHash.String()))
+		return
+	}
+
+	nodeAddr := common.BytesToAddress(event.Topics[1].Bytes())
+	n, err := e.cache.getNodeInfo(nodeAddr)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); !ok {
+			e.Logger.Panic("Got an error from the cache while looking up a node",
+				zap.String("addr", nodeAddr.String()), zap.Error(err))
+		}
+
+		// Any address may set a withdrawal address in RocketStorage, registered node or not.
+		// Nodes that register later have theirs read when they do.
+		e.Logger.Debug("Ignoring withdrawal address change of an unregistered node", zap.String("addr", nodeAddr.String()))
+		return
+	}
+
+	// The cache may share n with readers, so change a copy
+	updated := *n
+	n = &updated
+
+	switch topic {
+	case e.withdrawalAddressSetTopic:
+		n.withdrawalAddress = common.BytesToAddress(event.Topics[2].Bytes())
+		e.Logger.Info("Node withdrawal address changed",
+			zap.String("addr", nodeAddr.String()), zap.String("withdrawal_address", n.withdrawalAddress.String()))
+		e.m.Counter("withdrawal_address_changed").Inc()
+	case e.rplWithdrawalAddressSetTopic:
+		n.rplWithdrawalAddress = common.BytesToAddress(event.Topics[2].Bytes())
+		e.Logger.Info("Node RPL withdrawal address changed",
+			zap.String("addr", nodeAddr.String()), zap.String("rpl_withdrawal_address", n.rplWithdrawalAddress.String()))
+		e.m.Counter("rpl_withdrawal_address_changed").Inc()
+	case e.rplWithdrawalAddressUnsetTopic:
+		n.rplWithdrawalAddress = common.Address{}
+		e.Logger.Info("Node RPL withdrawal address unset", zap.String("addr", nodeAddr.String()))
+		e.m.Counter("rpl_withdrawal_address_changed").Inc()
+	default:
+		e.Logger.Warn("Event with unknown topic received", zap.String("string", topic.String()))
+		return
+	}
+
+	err = e.cache.addNodeInfo(nodeAddr, n)
+	if err != nil {
+		e.Logger.Error("Failed to add nodeInfo to cache", zap.Error(err))
+	}
+	e.recordEvent(event, nodeAddr, rptypes.ValidatorPubkey{})
+}
+
+// GetNodeWithdrawalAddresses looks up a node's withdrawal addresses, returning nil if it isn't a node
+func (e *CachingExecutionLayer) GetNodeWithdrawalAddresses(nodeAddr common.Address) (*WithdrawalAddresses, error) {
+	n, err := e.cache.getNodeInfo(nodeAddr)
+	if err != nil {
+		if _, ok := err.(*NotFoundError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if n.withdrawalAddressesUnknown {
+		// Cached before withdrawal addresses were kept, and not yet backfilled, so read them from the chain
+		e.m.Counter("withdrawal_address_unknown").Inc()
+		loaded := *n
+		loaded.withdrawalAddress, loaded.rplWithdrawalAddress, err = loadWithdrawalAddresses(e.getRocketPool(), nodeAddr, nil)
+		if err != nil {
+			return nil, err
+		}
+		n = &loaded
+	}
+
+	return newWithdrawalAddresses(nodeAddr, n), nil
+}
+
+// legacyNodeCache is a cache which may hold nodes cached before withdrawal addresses were kept
+type legacyNodeCache interface {
+	// The nodes whose withdrawal addresses are unknown
+	getLegacyNodes() ([]common.Address, error)
+}
+
+// withdrawalIndexCache is a cache which may hold nodes added before it indexed them by withdrawal address
+type withdrawalIndexCache interface {
+	indexWithdrawalAddresses() error
+}
+
+// Reads the withdrawal addresses of nodes cached before they were kept from the chain state at opts
+func (e *CachingExecutionLayer) backfillWithdrawalAddresses(opts *bind.CallOpts) error {
+	lc, ok := e.cache.(legacyNodeCache)
+	if !ok {
+		return nil
+	}
+
+	nodes, err := lc.getLegacyNodes()
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	e.Logger.Info("Loading the withdrawal addresses of nodes cached without them", zap.Int("nodes", len(nodes)))
+	for _, nodeAddr := range nodes {
+		n, err := e.cache.getNodeInfo(nodeAddr)
+		if err != nil {
+			if _, ok := err.(*NotFoundError); ok {
+				continue
+			}
+			return err
+		}
+
+		updated := *n
+		updated.withdrawalAddress, updated.rplWithdrawalAddress, err = loadWithdrawalAddresses(e.rp, nodeAddr, opts)
+		if err != nil {
+			return err
+		}
+		updated.withdrawalAddressesUnknown = false
+
+		if err := e.cache.addNodeInfo(nodeAddr, &updated); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetWithdrawalAddressNodes finds every node using the address as its withdrawal address or RPL withdrawal address
+func (e *CachingExecutionLayer) GetWithdrawalAddressNodes(withdrawalAddr common.Address) ([]*WithdrawalAddresses, error) {
+	nodes, err := e.cache.getWithdrawalAddressNodes(withdrawalAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*WithdrawalAddresses, 0, len(nodes))
+	for _, nodeAddr := range nodes {
+		n, err := e.cache.getNodeInfo(nodeAddr)
+		if err != nil {
+			// The node may have been removed since it was found
+			if _, ok := err.(*NotFoundError); ok {
+				continue
+			}
+			return nil, err
+		}
+
+		out = append(out, newWithdrawalAddresses(nodeAddr, n))
+	}
+
+	return out, nil
+}
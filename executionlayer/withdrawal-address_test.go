@@ -0,0 +1,174 @@
+package executionlayer
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func withdrawalAddressLog(contract string, topic common.Hash, node common.Address, withdrawalAddr *common.Address) types.Log {
+	topics := []common.Hash{topic, common.BytesToHash(node.Bytes())}
+	if withdrawalAddr != nil {
+		topics = append(topics, common.BytesToHash(withdrawalAddr.Bytes()))
+	}
+
+	return types.Log{
+		Address: common.HexToAddress(contract),
+		Topics:  topics,
+	}
+}
+
+func withdrawalAddressNodes(t *testing.T, et *elTest, addr common.Address) []common.Address {
+	t.Helper()
+
+	nodes, err := et.ec.GetWithdrawalAddressNodes(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := make([]common.Address, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, n.NodeAddress)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return bytes.Compare(out[i].Bytes(), out[j].Bytes()) < 0
+	})
+	return out
+}
+
+func testELWithdrawalAddresses(t *testing.T, useCache func(*CachingExecutionLayer)) {
+	withdrawalAddr := common.HexToAddress("0x0a010a")
+	rplWithdrawalAddr := common.HexToAddress("0x0a020a")
+	hec := &happyEC{t,
+		[]*mockNode{
+			&mockNode{
+				addr:                 common.HexToAddress("0x0000000000000000000001234567899876543210"),
+				inSP:                 true,
+				minipools:            1,
+				withdrawalAddress:    withdrawalAddr,
+				rplWithdrawalAddress: rplWithdrawalAddr,
+			},
+			&mockNode{
+				addr:      common.HexToAddress("0x0000000000000000000002234567899876543210"),
+				minipools: 1,
+			},
+			&mockNode{
+				addr:              common.HexToAddress("0x0000000000000000000003234567899876543210"),
+				minipools:         1,
+				withdrawalAddress: withdrawalAddr,
+			},
+		},
+		[]*mockNode{},
+	}
+	et := setup(t, hec)
+	useCache(et.ec)
+	errs := startEL(t, et)
+
+	// Withdrawal addresses are loaded with the rest of the chain state
+	addrs, err := et.ec.GetNodeWithdrawalAddresses(hec.nodes[0].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs == nil || addrs.WithdrawalAddress != withdrawalAddr || addrs.RPLWithdrawalAddress == nil || *addrs.RPLWithdrawalAddress != rplWithdrawalAddr {
+		t.Fatalf("unexpected withdrawal addresses %+v", addrs)
+	}
+
+	addrs, err = et.ec.GetNodeWithdrawalAddresses(hec.nodes[1].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs == nil || addrs.WithdrawalAddress != hec.nodes[1].addr || addrs.RPLWithdrawalAddress != nil {
+		t.Fatalf("expected the node address to be the withdrawal address, got %+v", addrs)
+	}
+
+	addrs, err = et.ec.GetNodeWithdrawalAddresses(withdrawalAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs != nil {
+		t.Fatalf("expected nothing for an address that isn't a node, got %+v", addrs)
+	}
+
+	// Withdrawal addresses resolve to every node using them, for ETH or RPL
+	nodes := withdrawalAddressNodes(t, et, withdrawalAddr)
+	if len(nodes) != 2 || nodes[0] != hec.nodes[0].addr || nodes[1] != hec.nodes[2].addr {
+		t.Fatalf("unexpected nodes %v", nodes)
+	}
+	nodes = withdrawalAddressNodes(t, et, rplWithdrawalAddr)
+	if len(nodes) != 1 || nodes[0] != hec.nodes[0].addr {
+		t.Fatalf("unexpected nodes %v", nodes)
+	}
+	nodes = withdrawalAddressNodes(t, et, common.HexToAddress("0x0a030a"))
+	if len(nodes) != 0 {
+		t.Fatalf("expected no nodes, got %v", nodes)
+	}
+
+	// A node changes its withdrawal address to one another node uses for RPL
+	before, err := et.ec.cache.getNodeInfo(hec.nodes[1].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	et.ec.handleEvent(withdrawalAddressLog(rocketStorage, et.ec.withdrawalAddressSetTopic, hec.nodes[1].addr, &rplWithdrawalAddr))
+	if before.withdrawalAddress != hec.nodes[1].addr {
+		t.Fatal("expected the change not to modify a node that was already read")
+	}
+	nodes = withdrawalAddressNodes(t, et, rplWithdrawalAddr)
+	if len(nodes) != 2 || nodes[0] != hec.nodes[0].addr || nodes[1] != hec.nodes[1].addr {
+		t.Fatalf("unexpected nodes %v", nodes)
+	}
+
+	// And another sets an RPL withdrawal address, while the first unsets its own
+	et.ec.handleEvent(withdrawalAddressLog(rocketNodeManager, et.ec.rplWithdrawalAddressSetTopic, hec.nodes[2].addr, &rplWithdrawalAddr))
+	et.ec.handleEvent(withdrawalAddressLog(rocketNodeManager, et.ec.rplWithdrawalAddressUnsetTopic, hec.nodes[0].addr, nil))
+	nodes = withdrawalAddressNodes(t, et, rplWithdrawalAddr)
+	if len(nodes) != 2 || nodes[0] != hec.nodes[1].addr || nodes[1] != hec.nodes[2].addr {
+		t.Fatalf("unexpected nodes %v", nodes)
+	}
+
+	addrs, err = et.ec.GetNodeWithdrawalAddresses(hec.nodes[0].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addrs == nil || addrs.WithdrawalAddress != withdrawalAddr || addrs.RPLWithdrawalAddress != nil {
+		t.Fatalf("expected the RPL withdrawal address to be unset, got %+v", addrs)
+	}
+
+	// Withdrawal address changes don't touch the rest of the node
+	n, err := et.ec.cache.getNodeInfo(hec.nodes[0].addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.inSmoothingPool {
+		t.Fatal("expected the node to remain in the smoothing pool")
+	}
+
+	// Addresses that aren't nodes may set withdrawal addresses too, but aren't added
+	stranger := common.HexToAddress("0x0f010f")
+	et.ec.handleEvent(withdrawalAddressLog(rocketStorage, et.ec.withdrawalAddressSetTopic, stranger, &withdrawalAddr))
+	if hasNode(t, et, stranger) {
+		t.Fatal("expected a withdrawal address change not to register a node")
+	}
+
+	// Malformed events are ignored
+	et.ec.handleEvent(withdrawalAddressLog(rocketStorage, et.ec.withdrawalAddressSetTopic, hec.nodes[1].addr, nil))
+
+	et.ec.Stop()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestELWithdrawalAddresses(t *testing.T) {
+	testELWithdrawalAddresses(t, useMapsCache(t))
+}
+
+func TestSQLELWithdrawalAddresses(t *testing.T) {
+	testELWithdrawalAddresses(t, useSqliteCache(t))
+}
+
+func TestKVELWithdrawalAddresses(t *testing.T) {
+	testELWithdrawalAddresses(t, useKVCache(t))
+}
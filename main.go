@@ -21,6 +21,11 @@ func loop(signalChan chan bool, errs chan error) error {
 
 func main() {
 
+	// The cache subcommand works offline on a snapshot, so handle it before parsing the proxy's flags
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		os.Exit(runCacheCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	// Initialize config
 	config := config.InitFlags()
 
@@ -10,7 +10,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// Every metric created by any registry, so Deinit can unregister them.
+// Registries create metrics concurrently, so guard it with a lock.
 var globalCollectors []prometheus.Collector
+var globalCollectorsLock sync.Mutex
 
 // Metrics is a package-level singleton to track the state
 // of all metrics generated by the process
@@ -59,10 +62,12 @@ func Init(namespace string) (http.Handler, error) {
 
 func Deinit() {
 	DeinitEpochMetrics()
+	globalCollectorsLock.Lock()
 	for _, c := range globalCollectors {
 		prometheus.DefaultRegisterer.Unregister(c)
 	}
 	globalCollectors = nil
+	globalCollectorsLock.Unlock()
 	mtx = nil
 }
 
@@ -125,6 +130,9 @@ func (m *MetricsMap[T, O]) value(name string, opts O) T {
 
 	val = m.initializor(opts)
 	m.m[name] = val
+
+	globalCollectorsLock.Lock()
+	defer globalCollectorsLock.Unlock()
 	if globalCollectors == nil {
 		globalCollectors = make([]prometheus.Collector, 0, 1)
 	}
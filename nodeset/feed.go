@@ -0,0 +1,286 @@
+// Package nodeset publishes changes to the set of addresses rescue-api hands out credentials to:
+// rocket pool nodes, odao members and the withdrawal addresses of solo validators.
+package nodeset
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ChangeType is the kind of a Change
+type ChangeType int
+
+const (
+	NodeRegistered ChangeType = iota + 1
+	OdaoJoined
+	OdaoLeft
+	OdaoKicked
+	SoloAdded
+	SoloRemoved
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case NodeRegistered:
+		return "node_registered"
+	case OdaoJoined:
+		return "odao_joined"
+	case OdaoLeft:
+		return "odao_left"
+	case OdaoKicked:
+		return "odao_kicked"
+	case SoloAdded:
+		return "solo_added"
+	case SoloRemoved:
+		return "solo_removed"
+	}
+
+	return "unknown"
+}
+
+// Cursor identifies a point in the feed by the execution layer block a change was seen at, and
+// its position among the changes seen at that block. Changes that don't come from a block, like
+// solo validators from the consensus layer, are stamped with the latest block seen before them.
+//
+// That depends on when the feed saw them, so a cursor only means something to the feed that
+// issued it. Instance identifies that feed.
+type Cursor struct {
+	Instance uint64
+	Block    uint64
+	Index    uint64
+}
+
+// Before reports whether c comes before o
+func (c Cursor) Before(o Cursor) bool {
+	if c.Block != o.Block {
+		return c.Block < o.Block
+	}
+
+	return c.Index < o.Index
+}
+
+// Change is a single addition to or removal from the node set
+type Change struct {
+	Cursor  Cursor
+	Type    ChangeType
+	Address common.Address
+}
+
+// DefaultHistory is how many changes a Feed keeps for subscribers to resume from, by default
+const DefaultHistory = 4096
+
+// How many changes a subscriber may fall behind by before its subscription is ended
+const subscriptionBuffer = 256
+
+// Feed fans changes to the node set out to subscribers, and keeps a bounded history of them so that
+// subscribers can resume from a Cursor instead of starting over from a snapshot.
+//
+// A nil Feed discards everything published to it.
+type Feed struct {
+	lock sync.Mutex
+
+	// Cursors from other feeds, or from before a restart, don't match it
+	instance uint64
+
+	// A ring buffer of the latest changes, oldest first from historyStart
+	historySize  int
+	history      []Change
+	historyStart int
+	historyLen   int
+
+	// The cursor of the latest change, or of the latest block if there were no changes in it
+	cursor Cursor
+	// Cursors before the horizon can't be resumed from, as changes after them were dropped
+	horizon Cursor
+	started bool
+
+	subscriptions map[*Subscription]struct{}
+}
+
+// NewFeed creates a Feed that keeps the last historySize changes
+func NewFeed(historySize int) *Feed {
+	// Zero is left for cursors that don't name a feed
+	instance := rand.Uint64()
+	for instance == 0 {
+		instance = rand.Uint64()
+	}
+
+	return &Feed{
+		instance:      instance,
+		cursor:        Cursor{Instance: instance},
+		historySize:   historySize,
+		history:       make([]Change, historySize),
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Must be called with the lock held
+func (f *Feed) historyAt(i int) Change {
+	return f.history[(f.historyStart+i)%f.historySize]
+}
+
+// Adds a change to the history, dropping the oldest one if it's full. Must be called with the lock held.
+func (f *Feed) record(change Change) {
+	if f.historySize == 0 {
+		f.horizon = change.Cursor
+		return
+	}
+
+	if f.historyLen < f.historySize {
+		f.history[(f.historyStart+f.historyLen)%f.historySize] = change
+		f.historyLen++
+		return
+	}
+
+	f.horizon = f.history[f.historyStart].Cursor
+	f.history[f.historyStart] = change
+	f.historyStart = (f.historyStart + 1) % f.historySize
+}
+
+// Must be called with the lock held
+func (f *Feed) advance(block uint64) {
+	if !f.started {
+		// Changes before the first block we saw are unknown to us
+		f.started = true
+		f.cursor = Cursor{Instance: f.instance, Block: block}
+		f.horizon = f.cursor
+		return
+	}
+
+	if block > f.cursor.Block {
+		f.cursor = Cursor{Instance: f.instance, Block: block}
+	}
+}
+
+// Advance records that the execution layer reached block, so that later changes are stamped with it
+func (f *Feed) Advance(block uint64) {
+	if f == nil {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.advance(block)
+}
+
+// Publish records a change seen at block, and sends it to every subscriber.
+// Blocks older than the latest one are stamped with the latest one, so pass 0 if the block isn't known.
+func (f *Feed) Publish(block uint64, t ChangeType, addr common.Address) {
+	if f == nil {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.advance(block)
+	f.cursor.Index++
+	change := Change{
+		Cursor:  f.cursor,
+		Type:    t,
+		Address: addr,
+	}
+
+	f.record(change)
+
+	for s := range f.subscriptions {
+		select {
+		case s.changes <- change:
+		default:
+			// It fell behind, so it'll have to catch up from the history or a snapshot
+			f.end(s)
+		}
+	}
+}
+
+// Reset discards the history and ends every subscription, so that subscribers start over from a snapshot.
+// It's for changes to the node set that can't be expressed as a Change, like nodes removed by a reorg.
+func (f *Feed) Reset() {
+	if f == nil {
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// Skip an index so that the current cursor can't be resumed from either
+	f.cursor.Index++
+	f.horizon = f.cursor
+	f.historyStart = 0
+	f.historyLen = 0
+
+	for s := range f.subscriptions {
+		f.end(s)
+	}
+}
+
+// Must be called with the lock held
+func (f *Feed) end(s *Subscription) {
+	delete(f.subscriptions, s)
+	close(s.done)
+}
+
+// Subscription receives the changes published to a Feed after it was created
+type Subscription struct {
+	feed    *Feed
+	changes chan Change
+	done    chan struct{}
+}
+
+// Changes returns the channel changes are sent on
+func (s *Subscription) Changes() <-chan Change {
+	return s.changes
+}
+
+// Done is closed when the feed ends the subscription, because the subscriber fell behind or the
+// feed was Reset. Changes still buffered should be discarded, and the subscriber should resubscribe.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the subscription, if the feed hasn't already
+func (s *Subscription) Close() {
+	s.feed.lock.Lock()
+	defer s.feed.lock.Unlock()
+
+	if _, ok := s.feed.subscriptions[s]; ok {
+		s.feed.end(s)
+	}
+}
+
+// Subscribe starts following the feed.
+//
+// If after is a cursor the feed can resume from, the changes since it are returned, to be applied
+// before those sent to the subscription, and ok is true.
+// Otherwise ok is false, and the subscriber should start over from a snapshot of the node set as of
+// the returned cursor. Changes sent to the subscription will be those published after it.
+// The snapshot should be taken after subscribing, and the changes applied to it as set operations,
+// as it may already include some of them.
+func (f *Feed) Subscribe(after *Cursor) (sub *Subscription, missed []Change, cursor Cursor, ok bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	sub = &Subscription{
+		feed:    f,
+		changes: make(chan Change, subscriptionBuffer),
+		done:    make(chan struct{}),
+	}
+	f.subscriptions[sub] = struct{}{}
+
+	// Cursors from another instance, or from before a restart, don't match this one. Those from
+	// before the horizon are missing changes we no longer have.
+	if after == nil || !f.started || after.Instance != f.instance || after.Before(f.horizon) || f.cursor.Before(*after) {
+		return sub, nil, f.cursor, false
+	}
+
+	for i := 0; i < f.historyLen; i++ {
+		if change := f.historyAt(i); after.Before(change.Cursor) {
+			missed = append(missed, change)
+		}
+	}
+
+	return sub, missed, f.cursor, true
+}
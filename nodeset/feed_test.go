@@ -0,0 +1,197 @@
+package nodeset
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func addr(i byte) common.Address {
+	return common.BytesToAddress([]byte{i})
+}
+
+func receive(t *testing.T, sub *Subscription, n int) []Change {
+	t.Helper()
+
+	out := make([]Change, 0, n)
+	for len(out) < n {
+		select {
+		case change := <-sub.Changes():
+			out = append(out, change)
+		default:
+			t.Fatalf("expected %d changes, got %d", n, len(out))
+		}
+	}
+
+	return out
+}
+
+func TestFeedSnapshotThenChanges(t *testing.T) {
+	f := NewFeed(DefaultHistory)
+
+	// Before the first block, there's nothing to resume from
+	sub, missed, cursor, ok := f.Subscribe(&Cursor{Instance: f.instance})
+	if ok || missed != nil || cursor != (Cursor{Instance: f.instance}) {
+		t.Fatalf("unexpected resume before the feed started: %v %v %v", ok, missed, cursor)
+	}
+	sub.Close()
+
+	f.Advance(100)
+	sub, _, cursor, ok = f.Subscribe(nil)
+	defer sub.Close()
+	if ok {
+		t.Fatal("expected a snapshot without a cursor")
+	}
+	if cursor != (Cursor{f.instance, 100, 0}) {
+		t.Fatalf("unexpected cursor %v", cursor)
+	}
+
+	f.Publish(101, NodeRegistered, addr(1))
+	f.Publish(101, OdaoJoined, addr(1))
+	// Older blocks are stamped with the latest one
+	f.Publish(0, SoloAdded, addr(2))
+	f.Advance(99)
+	f.Publish(102, OdaoKicked, addr(1))
+
+	changes := receive(t, sub, 4)
+	expected := []Change{
+		{Cursor{f.instance, 101, 1}, NodeRegistered, addr(1)},
+		{Cursor{f.instance, 101, 2}, OdaoJoined, addr(1)},
+		{Cursor{f.instance, 101, 3}, SoloAdded, addr(2)},
+		{Cursor{f.instance, 102, 1}, OdaoKicked, addr(1)},
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Fatalf("change %d: expected %v, got %v", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestFeedResume(t *testing.T) {
+	f := NewFeed(3)
+	f.Advance(10)
+	f.Publish(11, NodeRegistered, addr(1))
+	f.Publish(12, NodeRegistered, addr(2))
+	f.Publish(12, SoloAdded, addr(3))
+
+	sub, missed, cursor, ok := f.Subscribe(&Cursor{f.instance, 11, 1})
+	sub.Close()
+	if !ok {
+		t.Fatal("expected to resume")
+	}
+	if len(missed) != 2 || missed[0].Address != addr(2) || missed[1].Address != addr(3) {
+		t.Fatalf("unexpected missed changes %v", missed)
+	}
+	if cursor != (Cursor{f.instance, 12, 2}) {
+		t.Fatalf("unexpected cursor %v", cursor)
+	}
+
+	// Resuming from the latest change misses nothing
+	sub, missed, _, ok = f.Subscribe(&cursor)
+	sub.Close()
+	if !ok || len(missed) != 0 {
+		t.Fatalf("unexpected resume %v %v", ok, missed)
+	}
+
+	// Resuming from the block the feed started at replays everything
+	sub, missed, _, ok = f.Subscribe(&Cursor{f.instance, 10, 0})
+	sub.Close()
+	if !ok || len(missed) != 3 {
+		t.Fatalf("unexpected resume %v %v", ok, missed)
+	}
+
+	// Cursors from the future, from before the feed started, or from another feed, can't be resumed from
+	other := NewFeed(3)
+	for _, after := range []Cursor{{f.instance, 12, 3}, {f.instance, 13, 0}, {f.instance, 9, 5}, {other.instance, 11, 1}, {0, 11, 1}} {
+		sub, _, _, ok = f.Subscribe(&after)
+		sub.Close()
+		if ok {
+			t.Fatalf("resumed from %v", after)
+		}
+	}
+
+	// Once the first change is dropped from the history, its predecessors can't be resumed from
+	f.Publish(13, NodeRegistered, addr(4))
+	sub, _, _, ok = f.Subscribe(&Cursor{f.instance, 10, 0})
+	sub.Close()
+	if ok {
+		t.Fatal("resumed from a dropped change")
+	}
+	sub, missed, _, ok = f.Subscribe(&Cursor{f.instance, 11, 1})
+	sub.Close()
+	if !ok || len(missed) != 3 || missed[0].Address != addr(2) || missed[2].Address != addr(4) {
+		t.Fatalf("unexpected resume %v %v", ok, missed)
+	}
+
+	// The history keeps wrapping around
+	f.Publish(14, NodeRegistered, addr(5))
+	f.Publish(15, NodeRegistered, addr(6))
+	sub, missed, _, ok = f.Subscribe(&Cursor{f.instance, 13, 1})
+	sub.Close()
+	if !ok || len(missed) != 2 || missed[0].Address != addr(5) || missed[1].Address != addr(6) {
+		t.Fatalf("unexpected resume %v %v", ok, missed)
+	}
+}
+
+func TestFeedReset(t *testing.T) {
+	f := NewFeed(DefaultHistory)
+	f.Advance(10)
+	f.Publish(11, NodeRegistered, addr(1))
+
+	sub, _, cursor, _ := f.Subscribe(nil)
+	f.Reset()
+
+	select {
+	case <-sub.Done():
+	default:
+		t.Fatal("expected the subscription to end")
+	}
+	// Closing an ended subscription is harmless
+	sub.Close()
+
+	sub, _, _, ok := f.Subscribe(&cursor)
+	sub.Close()
+	if ok {
+		t.Fatal("resumed from before a reset")
+	}
+
+	f.Publish(12, NodeRegistered, addr(2))
+	sub, _, cursor, _ = f.Subscribe(nil)
+	sub.Close()
+	sub, missed, _, ok := f.Subscribe(&cursor)
+	sub.Close()
+	if !ok || len(missed) != 0 {
+		t.Fatalf("unexpected resume after a reset %v %v", ok, missed)
+	}
+}
+
+func TestFeedSlowSubscriber(t *testing.T) {
+	f := NewFeed(DefaultHistory)
+	f.Advance(10)
+
+	sub, _, _, _ := f.Subscribe(nil)
+	for i := 0; i <= subscriptionBuffer; i++ {
+		f.Publish(11, SoloAdded, addr(byte(i)))
+	}
+
+	select {
+	case <-sub.Done():
+	default:
+		t.Fatal("expected the subscription to end")
+	}
+
+	// It can catch up from the history
+	changes := receive(t, sub, subscriptionBuffer)
+	sub, missed, _, ok := f.Subscribe(&changes[len(changes)-1].Cursor)
+	sub.Close()
+	if !ok || len(missed) != 1 {
+		t.Fatalf("unexpected resume %v %v", ok, missed)
+	}
+}
+
+func TestFeedNil(t *testing.T) {
+	var f *Feed
+	f.Advance(1)
+	f.Publish(1, NodeRegistered, addr(1))
+	f.Reset()
+}
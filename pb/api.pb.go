@@ -20,6 +20,176 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type SignatureMethod int32
+
+const (
+	SignatureMethod_SIGNATURE_METHOD_NONE    SignatureMethod = 0
+	SignatureMethod_SIGNATURE_METHOD_EOA     SignatureMethod = 1
+	SignatureMethod_SIGNATURE_METHOD_EIP1271 SignatureMethod = 2
+	SignatureMethod_SIGNATURE_METHOD_EIP6492 SignatureMethod = 3
+)
+
+// Enum value maps for SignatureMethod.
+var (
+	SignatureMethod_name = map[int32]string{
+		0: "SIGNATURE_METHOD_NONE",
+		1: "SIGNATURE_METHOD_EOA",
+		2: "SIGNATURE_METHOD_EIP1271",
+		3: "SIGNATURE_METHOD_EIP6492",
+	}
+	SignatureMethod_value = map[string]int32{
+		"SIGNATURE_METHOD_NONE":    0,
+		"SIGNATURE_METHOD_EOA":     1,
+		"SIGNATURE_METHOD_EIP1271": 2,
+		"SIGNATURE_METHOD_EIP6492": 3,
+	}
+)
+
+func (x SignatureMethod) Enum() *SignatureMethod {
+	p := new(SignatureMethod)
+	*p = x
+	return p
+}
+
+func (x SignatureMethod) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SignatureMethod) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_enumTypes[0].Descriptor()
+}
+
+func (SignatureMethod) Type() protoreflect.EnumType {
+	return &file_api_proto_enumTypes[0]
+}
+
+func (x SignatureMethod) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SignatureMethod.Descriptor instead.
+func (SignatureMethod) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{0}
+}
+
+type NodeSetChangeType int32
+
+const (
+	NodeSetChangeType_NODE_SET_CHANGE_TYPE_UNKNOWN         NodeSetChangeType = 0
+	NodeSetChangeType_NODE_SET_CHANGE_TYPE_NODE_REGISTERED NodeSetChangeType = 1
+	NodeSetChangeType_NODE_SET_CHANGE_TYPE_ODAO_JOINED     NodeSetChangeType = 2
+	NodeSetChangeType_NODE_SET_CHANGE_TYPE_ODAO_LEFT       NodeSetChangeType = 3
+	NodeSetChangeType_NODE_SET_CHANGE_TYPE_ODAO_KICKED     NodeSetChangeType = 4
+	NodeSetChangeType_NODE_SET_CHANGE_TYPE_SOLO_ADDED      NodeSetChangeType = 5
+	NodeSetChangeType_NODE_SET_CHANGE_TYPE_SOLO_REMOVED    NodeSetChangeType = 6
+)
+
+// Enum value maps for NodeSetChangeType.
+var (
+	NodeSetChangeType_name = map[int32]string{
+		0: "NODE_SET_CHANGE_TYPE_UNKNOWN",
+		1: "NODE_SET_CHANGE_TYPE_NODE_REGISTERED",
+		2: "NODE_SET_CHANGE_TYPE_ODAO_JOINED",
+		3: "NODE_SET_CHANGE_TYPE_ODAO_LEFT",
+		4: "NODE_SET_CHANGE_TYPE_ODAO_KICKED",
+		5: "NODE_SET_CHANGE_TYPE_SOLO_ADDED",
+		6: "NODE_SET_CHANGE_TYPE_SOLO_REMOVED",
+	}
+	NodeSetChangeType_value = map[string]int32{
+		"NODE_SET_CHANGE_TYPE_UNKNOWN":         0,
+		"NODE_SET_CHANGE_TYPE_NODE_REGISTERED": 1,
+		"NODE_SET_CHANGE_TYPE_ODAO_JOINED":     2,
+		"NODE_SET_CHANGE_TYPE_ODAO_LEFT":       3,
+		"NODE_SET_CHANGE_TYPE_ODAO_KICKED":     4,
+		"NODE_SET_CHANGE_TYPE_SOLO_ADDED":      5,
+		"NODE_SET_CHANGE_TYPE_SOLO_REMOVED":    6,
+	}
+)
+
+func (x NodeSetChangeType) Enum() *NodeSetChangeType {
+	p := new(NodeSetChangeType)
+	*p = x
+	return p
+}
+
+func (x NodeSetChangeType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (NodeSetChangeType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_enumTypes[1].Descriptor()
+}
+
+func (NodeSetChangeType) Type() protoreflect.EnumType {
+	return &file_api_proto_enumTypes[1]
+}
+
+func (x NodeSetChangeType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use NodeSetChangeType.Descriptor instead.
+func (NodeSetChangeType) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{1}
+}
+
+type ValidatorType int32
+
+const (
+	// Neither the execution layer nor the beacon node knows of the validator
+	ValidatorType_VALIDATOR_TYPE_UNKNOWN   ValidatorType = 0
+	ValidatorType_VALIDATOR_TYPE_MINIPOOL  ValidatorType = 1
+	ValidatorType_VALIDATOR_TYPE_SOLO_0X01 ValidatorType = 2
+	ValidatorType_VALIDATOR_TYPE_SOLO_0X02 ValidatorType = 3
+	// Validators with BLS withdrawal credentials have no fee recipient they may use
+	ValidatorType_VALIDATOR_TYPE_0X00 ValidatorType = 4
+)
+
+// Enum value maps for ValidatorType.
+var (
+	ValidatorType_name = map[int32]string{
+		0: "VALIDATOR_TYPE_UNKNOWN",
+		1: "VALIDATOR_TYPE_MINIPOOL",
+		2: "VALIDATOR_TYPE_SOLO_0X01",
+		3: "VALIDATOR_TYPE_SOLO_0X02",
+		4: "VALIDATOR_TYPE_0X00",
+	}
+	ValidatorType_value = map[string]int32{
+		"VALIDATOR_TYPE_UNKNOWN":   0,
+		"VALIDATOR_TYPE_MINIPOOL":  1,
+		"VALIDATOR_TYPE_SOLO_0X01": 2,
+		"VALIDATOR_TYPE_SOLO_0X02": 3,
+		"VALIDATOR_TYPE_0X00":      4,
+	}
+)
+
+func (x ValidatorType) Enum() *ValidatorType {
+	p := new(ValidatorType)
+	*p = x
+	return p
+}
+
+func (x ValidatorType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ValidatorType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_proto_enumTypes[2].Descriptor()
+}
+
+func (ValidatorType) Type() protoreflect.EnumType {
+	return &file_api_proto_enumTypes[2]
+}
+
+func (x ValidatorType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ValidatorType.Descriptor instead.
+func (ValidatorType) EnumDescriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{2}
+}
+
 type RocketPoolNodesRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -377,113 +547,1691 @@ func (x *ValidateEIP1271Response) GetError() string {
 	return ""
 }
 
-var File_api_proto protoreflect.FileDescriptor
+type ValidateEIP1271BatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_api_proto_rawDesc = []byte{
-	0x0a, 0x09, 0x61, 0x70, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x22,
-	0x18, 0x0a, 0x16, 0x52, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x4e, 0x6f, 0x64,
-	0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x2c, 0x0a, 0x0f, 0x52, 0x6f, 0x63,
-	0x6b, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x19, 0x0a, 0x08,
-	0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x07,
-	0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x73, 0x22, 0x12, 0x0a, 0x10, 0x4f, 0x64, 0x61, 0x6f, 0x4e,
-	0x6f, 0x64, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x26, 0x0a, 0x09, 0x4f,
-	0x64, 0x61, 0x6f, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x6e, 0x6f, 0x64, 0x65,
-	0x5f, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x07, 0x6e, 0x6f, 0x64, 0x65,
-	0x49, 0x64, 0x73, 0x22, 0x17, 0x0a, 0x15, 0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c, 0x69, 0x64,
-	0x61, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x43, 0x0a, 0x0e,
-	0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x31,
-	0x0a, 0x14, 0x77, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x5f, 0x61, 0x64, 0x64,
-	0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x13, 0x77, 0x69,
-	0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65,
-	0x73, 0x22, 0x6d, 0x0a, 0x16, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50,
-	0x31, 0x32, 0x37, 0x31, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x64,
-	0x61, 0x74, 0x61, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08,
-	0x64, 0x61, 0x74, 0x61, 0x48, 0x61, 0x73, 0x68, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e,
-	0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67,
-	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
-	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
-	0x22, 0x45, 0x0a, 0x17, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31,
-	0x32, 0x37, 0x31, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76,
-	0x61, 0x6c, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x69,
-	0x64, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x32, 0x99, 0x02, 0x0a, 0x03, 0x41, 0x70, 0x69, 0x12,
-	0x47, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x52, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c,
-	0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x6f, 0x63, 0x6b, 0x65,
-	0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x50, 0x6f, 0x6f,
-	0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x22, 0x00, 0x12, 0x35, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x4f,
-	0x64, 0x61, 0x6f, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x4f, 0x64,
-	0x61, 0x6f, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0d,
-	0x2e, 0x70, 0x62, 0x2e, 0x4f, 0x64, 0x61, 0x6f, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x22, 0x00, 0x12,
-	0x44, 0x0a, 0x11, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61,
-	0x74, 0x6f, 0x72, 0x73, 0x12, 0x19, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61,
-	0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x12, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
-	0x6f, 0x72, 0x73, 0x22, 0x00, 0x12, 0x4c, 0x0a, 0x0f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
-	0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x12, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x56, 0x61,
-	0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61,
-	0x74, 0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x22, 0x00, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x33,
+	Requests []*ValidateEIP1271Request `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
 }
 
-var (
-	file_api_proto_rawDescOnce sync.Once
-	file_api_proto_rawDescData = file_api_proto_rawDesc
-)
+func (x *ValidateEIP1271BatchRequest) Reset() {
+	*x = ValidateEIP1271BatchRequest{}
+	mi := &file_api_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-func file_api_proto_rawDescGZIP() []byte {
-	file_api_proto_rawDescOnce.Do(func() {
-		file_api_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_proto_rawDescData)
-	})
-	return file_api_proto_rawDescData
+func (x *ValidateEIP1271BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
-var file_api_proto_goTypes = []any{
-	(*RocketPoolNodesRequest)(nil),  // 0: pb.RocketPoolNodesRequest
-	(*RocketPoolNodes)(nil),         // 1: pb.RocketPoolNodes
-	(*OdaoNodesRequest)(nil),        // 2: pb.OdaoNodesRequest
-	(*OdaoNodes)(nil),               // 3: pb.OdaoNodes
-	(*SoloValidatorsRequest)(nil),   // 4: pb.SoloValidatorsRequest
-	(*SoloValidators)(nil),          // 5: pb.SoloValidators
-	(*ValidateEIP1271Request)(nil),  // 6: pb.ValidateEIP1271Request
-	(*ValidateEIP1271Response)(nil), // 7: pb.ValidateEIP1271Response
+func (*ValidateEIP1271BatchRequest) ProtoMessage() {}
+
+func (x *ValidateEIP1271BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_api_proto_depIdxs = []int32{
-	0, // 0: pb.Api.GetRocketPoolNodes:input_type -> pb.RocketPoolNodesRequest
-	2, // 1: pb.Api.GetOdaoNodes:input_type -> pb.OdaoNodesRequest
-	4, // 2: pb.Api.GetSoloValidators:input_type -> pb.SoloValidatorsRequest
-	6, // 3: pb.Api.ValidateEIP1271:input_type -> pb.ValidateEIP1271Request
-	1, // 4: pb.Api.GetRocketPoolNodes:output_type -> pb.RocketPoolNodes
-	3, // 5: pb.Api.GetOdaoNodes:output_type -> pb.OdaoNodes
-	5, // 6: pb.Api.GetSoloValidators:output_type -> pb.SoloValidators
-	7, // 7: pb.Api.ValidateEIP1271:output_type -> pb.ValidateEIP1271Response
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+
+// Deprecated: Use ValidateEIP1271BatchRequest.ProtoReflect.Descriptor instead.
+func (*ValidateEIP1271BatchRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{8}
 }
 
-func init() { file_api_proto_init() }
-func file_api_proto_init() {
-	if File_api_proto != nil {
-		return
+func (x *ValidateEIP1271BatchRequest) GetRequests() []*ValidateEIP1271Request {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+type ValidateEIP1271BatchResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// One response per request, in the same order
+	Responses []*ValidateEIP1271Response `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	Error     string                     `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *ValidateEIP1271BatchResponse) Reset() {
+	*x = ValidateEIP1271BatchResponse{}
+	mi := &file_api_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateEIP1271BatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateEIP1271BatchResponse) ProtoMessage() {}
+
+func (x *ValidateEIP1271BatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateEIP1271BatchResponse.ProtoReflect.Descriptor instead.
+func (*ValidateEIP1271BatchResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ValidateEIP1271BatchResponse) GetResponses() []*ValidateEIP1271Response {
+	if x != nil {
+		return x.Responses
+	}
+	return nil
+}
+
+func (x *ValidateEIP1271BatchResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type RPInfoAtRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pubkey []byte `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Block  uint64 `protobuf:"varint,2,opt,name=block,proto3" json:"block,omitempty"`
+}
+
+func (x *RPInfoAtRequest) Reset() {
+	*x = RPInfoAtRequest{}
+	mi := &file_api_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RPInfoAtRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RPInfoAtRequest) ProtoMessage() {}
+
+func (x *RPInfoAtRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RPInfoAtRequest.ProtoReflect.Descriptor instead.
+func (*RPInfoAtRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RPInfoAtRequest) GetPubkey() []byte {
+	if x != nil {
+		return x.Pubkey
+	}
+	return nil
+}
+
+func (x *RPInfoAtRequest) GetBlock() uint64 {
+	if x != nil {
+		return x.Block
+	}
+	return 0
+}
+
+type RPInfoAtResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// False if the validator wasn't a minipool at the block, in which case the other fields are empty
+	IsMinipool              bool     `protobuf:"varint,1,opt,name=is_minipool,json=isMinipool,proto3" json:"is_minipool,omitempty"`
+	NodeAddress             []byte   `protobuf:"bytes,2,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	ExpectedFeeRecipient    []byte   `protobuf:"bytes,3,opt,name=expected_fee_recipient,json=expectedFeeRecipient,proto3" json:"expected_fee_recipient,omitempty"`
+	AcceptableFeeRecipients [][]byte `protobuf:"bytes,4,rep,name=acceptable_fee_recipients,json=acceptableFeeRecipients,proto3" json:"acceptable_fee_recipients,omitempty"`
+	Error                   string   `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *RPInfoAtResponse) Reset() {
+	*x = RPInfoAtResponse{}
+	mi := &file_api_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RPInfoAtResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RPInfoAtResponse) ProtoMessage() {}
+
+func (x *RPInfoAtResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RPInfoAtResponse.ProtoReflect.Descriptor instead.
+func (*RPInfoAtResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RPInfoAtResponse) GetIsMinipool() bool {
+	if x != nil {
+		return x.IsMinipool
+	}
+	return false
+}
+
+func (x *RPInfoAtResponse) GetNodeAddress() []byte {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return nil
+}
+
+func (x *RPInfoAtResponse) GetExpectedFeeRecipient() []byte {
+	if x != nil {
+		return x.ExpectedFeeRecipient
+	}
+	return nil
+}
+
+func (x *RPInfoAtResponse) GetAcceptableFeeRecipients() [][]byte {
+	if x != nil {
+		return x.AcceptableFeeRecipients
+	}
+	return nil
+}
+
+func (x *RPInfoAtResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ValidateSignatureRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DataHash  []byte `protobuf:"bytes,1,opt,name=data_hash,json=dataHash,proto3" json:"data_hash,omitempty"`
+	Signature []byte `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	Address   []byte `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	// The block to validate the signature as of, or 0 for the latest
+	Block uint64 `protobuf:"varint,4,opt,name=block,proto3" json:"block,omitempty"`
+}
+
+func (x *ValidateSignatureRequest) Reset() {
+	*x = ValidateSignatureRequest{}
+	mi := &file_api_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateSignatureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateSignatureRequest) ProtoMessage() {}
+
+func (x *ValidateSignatureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateSignatureRequest.ProtoReflect.Descriptor instead.
+func (*ValidateSignatureRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ValidateSignatureRequest) GetDataHash() []byte {
+	if x != nil {
+		return x.DataHash
+	}
+	return nil
+}
+
+func (x *ValidateSignatureRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *ValidateSignatureRequest) GetAddress() []byte {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *ValidateSignatureRequest) GetBlock() uint64 {
+	if x != nil {
+		return x.Block
+	}
+	return 0
+}
+
+type ValidateSignatureResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Valid bool `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	// How the signature was found to be valid, SIGNATURE_METHOD_NONE if it wasn't
+	Method SignatureMethod `protobuf:"varint,2,opt,name=method,proto3,enum=pb.SignatureMethod" json:"method,omitempty"`
+	Error  string          `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *ValidateSignatureResponse) Reset() {
+	*x = ValidateSignatureResponse{}
+	mi := &file_api_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateSignatureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateSignatureResponse) ProtoMessage() {}
+
+func (x *ValidateSignatureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateSignatureResponse.ProtoReflect.Descriptor instead.
+func (*ValidateSignatureResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ValidateSignatureResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *ValidateSignatureResponse) GetMethod() SignatureMethod {
+	if x != nil {
+		return x.Method
+	}
+	return SignatureMethod_SIGNATURE_METHOD_NONE
+}
+
+func (x *ValidateSignatureResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type WithdrawalAddressesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Address:
+	//	*WithdrawalAddressesRequest_WithdrawalAddress
+	//	*WithdrawalAddressesRequest_NodeAddress
+	Address isWithdrawalAddressesRequest_Address `protobuf_oneof:"address"`
+}
+
+func (x *WithdrawalAddressesRequest) Reset() {
+	*x = WithdrawalAddressesRequest{}
+	mi := &file_api_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawalAddressesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawalAddressesRequest) ProtoMessage() {}
+
+func (x *WithdrawalAddressesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawalAddressesRequest.ProtoReflect.Descriptor instead.
+func (*WithdrawalAddressesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{14}
+}
+
+func (m *WithdrawalAddressesRequest) GetAddress() isWithdrawalAddressesRequest_Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (x *WithdrawalAddressesRequest) GetWithdrawalAddress() []byte {
+	if x, ok := x.GetAddress().(*WithdrawalAddressesRequest_WithdrawalAddress); ok {
+		return x.WithdrawalAddress
+	}
+	return nil
+}
+
+func (x *WithdrawalAddressesRequest) GetNodeAddress() []byte {
+	if x, ok := x.GetAddress().(*WithdrawalAddressesRequest_NodeAddress); ok {
+		return x.NodeAddress
+	}
+	return nil
+}
+
+type isWithdrawalAddressesRequest_Address interface {
+	isWithdrawalAddressesRequest_Address()
+}
+
+type WithdrawalAddressesRequest_WithdrawalAddress struct {
+	// Finds the nodes using this address as their withdrawal address or RPL withdrawal address
+	WithdrawalAddress []byte `protobuf:"bytes,1,opt,name=withdrawal_address,json=withdrawalAddress,proto3,oneof"`
+}
+
+type WithdrawalAddressesRequest_NodeAddress struct {
+	// Finds this node's withdrawal addresses
+	NodeAddress []byte `protobuf:"bytes,2,opt,name=node_address,json=nodeAddress,proto3,oneof"`
+}
+
+func (*WithdrawalAddressesRequest_WithdrawalAddress) isWithdrawalAddressesRequest_Address() {}
+
+func (*WithdrawalAddressesRequest_NodeAddress) isWithdrawalAddressesRequest_Address() {}
+
+type NodeWithdrawalAddresses struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NodeAddress       []byte `protobuf:"bytes,1,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	WithdrawalAddress []byte `protobuf:"bytes,2,opt,name=withdrawal_address,json=withdrawalAddress,proto3" json:"withdrawal_address,omitempty"`
+	// Empty unless the node set an RPL withdrawal address, otherwise RPL goes to withdrawal_address
+	RplWithdrawalAddress []byte `protobuf:"bytes,3,opt,name=rpl_withdrawal_address,json=rplWithdrawalAddress,proto3" json:"rpl_withdrawal_address,omitempty"`
+}
+
+func (x *NodeWithdrawalAddresses) Reset() {
+	*x = NodeWithdrawalAddresses{}
+	mi := &file_api_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeWithdrawalAddresses) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeWithdrawalAddresses) ProtoMessage() {}
+
+func (x *NodeWithdrawalAddresses) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeWithdrawalAddresses.ProtoReflect.Descriptor instead.
+func (*NodeWithdrawalAddresses) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *NodeWithdrawalAddresses) GetNodeAddress() []byte {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return nil
+}
+
+func (x *NodeWithdrawalAddresses) GetWithdrawalAddress() []byte {
+	if x != nil {
+		return x.WithdrawalAddress
+	}
+	return nil
+}
+
+func (x *NodeWithdrawalAddresses) GetRplWithdrawalAddress() []byte {
+	if x != nil {
+		return x.RplWithdrawalAddress
+	}
+	return nil
+}
+
+type WithdrawalAddressesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Empty if the address isn't a node, or no node uses it
+	Nodes []*NodeWithdrawalAddresses `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Error string                     `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *WithdrawalAddressesResponse) Reset() {
+	*x = WithdrawalAddressesResponse{}
+	mi := &file_api_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawalAddressesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawalAddressesResponse) ProtoMessage() {}
+
+func (x *WithdrawalAddressesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawalAddressesResponse.ProtoReflect.Descriptor instead.
+func (*WithdrawalAddressesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WithdrawalAddressesResponse) GetNodes() []*NodeWithdrawalAddresses {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *WithdrawalAddressesResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Identifies a point in the node set stream by the execution layer block of a change, and its position
+// among the changes at that block. Cursors are only meaningful to the proxy instance that sent them,
+// which instance identifies, and which changes when it restarts.
+type NodeSetCursor struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Block    uint64 `protobuf:"varint,1,opt,name=block,proto3" json:"block,omitempty"`
+	Index    uint64 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	Instance uint64 `protobuf:"varint,3,opt,name=instance,proto3" json:"instance,omitempty"`
+}
+
+func (x *NodeSetCursor) Reset() {
+	*x = NodeSetCursor{}
+	mi := &file_api_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeSetCursor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeSetCursor) ProtoMessage() {}
+
+func (x *NodeSetCursor) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeSetCursor.ProtoReflect.Descriptor instead.
+func (*NodeSetCursor) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *NodeSetCursor) GetBlock() uint64 {
+	if x != nil {
+		return x.Block
+	}
+	return 0
+}
+
+func (x *NodeSetCursor) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *NodeSetCursor) GetInstance() uint64 {
+	if x != nil {
+		return x.Instance
+	}
+	return 0
+}
+
+type NodeSetRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The cursor of the last update received, to resume from. If unset, or the proxy can't resume
+	// from it, the stream starts with a snapshot.
+	Cursor *NodeSetCursor `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+}
+
+func (x *NodeSetRequest) Reset() {
+	*x = NodeSetRequest{}
+	mi := &file_api_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeSetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeSetRequest) ProtoMessage() {}
+
+func (x *NodeSetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeSetRequest.ProtoReflect.Descriptor instead.
+func (*NodeSetRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *NodeSetRequest) GetCursor() *NodeSetCursor {
+	if x != nil {
+		return x.Cursor
+	}
+	return nil
+}
+
+type NodeSetSnapshot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NodeIds                 [][]byte `protobuf:"bytes,1,rep,name=node_ids,json=nodeIds,proto3" json:"node_ids,omitempty"`
+	OdaoNodeIds             [][]byte `protobuf:"bytes,2,rep,name=odao_node_ids,json=odaoNodeIds,proto3" json:"odao_node_ids,omitempty"`
+	SoloWithdrawalAddresses [][]byte `protobuf:"bytes,3,rep,name=solo_withdrawal_addresses,json=soloWithdrawalAddresses,proto3" json:"solo_withdrawal_addresses,omitempty"`
+}
+
+func (x *NodeSetSnapshot) Reset() {
+	*x = NodeSetSnapshot{}
+	mi := &file_api_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeSetSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeSetSnapshot) ProtoMessage() {}
+
+func (x *NodeSetSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeSetSnapshot.ProtoReflect.Descriptor instead.
+func (*NodeSetSnapshot) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *NodeSetSnapshot) GetNodeIds() [][]byte {
+	if x != nil {
+		return x.NodeIds
+	}
+	return nil
+}
+
+func (x *NodeSetSnapshot) GetOdaoNodeIds() [][]byte {
+	if x != nil {
+		return x.OdaoNodeIds
+	}
+	return nil
+}
+
+func (x *NodeSetSnapshot) GetSoloWithdrawalAddresses() [][]byte {
+	if x != nil {
+		return x.SoloWithdrawalAddresses
+	}
+	return nil
+}
+
+type NodeSetChange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type    NodeSetChangeType `protobuf:"varint,1,opt,name=type,proto3,enum=pb.NodeSetChangeType" json:"type,omitempty"`
+	Address []byte            `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+}
+
+func (x *NodeSetChange) Reset() {
+	*x = NodeSetChange{}
+	mi := &file_api_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeSetChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeSetChange) ProtoMessage() {}
+
+func (x *NodeSetChange) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeSetChange.ProtoReflect.Descriptor instead.
+func (*NodeSetChange) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *NodeSetChange) GetType() NodeSetChangeType {
+	if x != nil {
+		return x.Type
+	}
+	return NodeSetChangeType_NODE_SET_CHANGE_TYPE_UNKNOWN
+}
+
+func (x *NodeSetChange) GetAddress() []byte {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+type NodeSetUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cursor *NodeSetCursor `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// Types that are assignable to Update:
+	//	*NodeSetUpdate_Snapshot
+	//	*NodeSetUpdate_Change
+	Update isNodeSetUpdate_Update `protobuf_oneof:"update"`
+}
+
+func (x *NodeSetUpdate) Reset() {
+	*x = NodeSetUpdate{}
+	mi := &file_api_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeSetUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeSetUpdate) ProtoMessage() {}
+
+func (x *NodeSetUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeSetUpdate.ProtoReflect.Descriptor instead.
+func (*NodeSetUpdate) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *NodeSetUpdate) GetCursor() *NodeSetCursor {
+	if x != nil {
+		return x.Cursor
+	}
+	return nil
+}
+
+func (m *NodeSetUpdate) GetUpdate() isNodeSetUpdate_Update {
+	if m != nil {
+		return m.Update
+	}
+	return nil
+}
+
+func (x *NodeSetUpdate) GetSnapshot() *NodeSetSnapshot {
+	if x, ok := x.GetUpdate().(*NodeSetUpdate_Snapshot); ok {
+		return x.Snapshot
+	}
+	return nil
+}
+
+func (x *NodeSetUpdate) GetChange() *NodeSetChange {
+	if x, ok := x.GetUpdate().(*NodeSetUpdate_Change); ok {
+		return x.Change
+	}
+	return nil
+}
+
+type isNodeSetUpdate_Update interface {
+	isNodeSetUpdate_Update()
+}
+
+type NodeSetUpdate_Snapshot struct {
+	// Replaces everything received so far. Sent first, and again whenever the proxy can't
+	// express what changed as deltas.
+	Snapshot *NodeSetSnapshot `protobuf:"bytes,2,opt,name=snapshot,proto3,oneof"`
+}
+
+type NodeSetUpdate_Change struct {
+	// Applies to the last snapshot. Changes may repeat what a snapshot already includes.
+	Change *NodeSetChange `protobuf:"bytes,3,opt,name=change,proto3,oneof"`
+}
+
+func (*NodeSetUpdate_Snapshot) isNodeSetUpdate_Update() {}
+
+func (*NodeSetUpdate_Change) isNodeSetUpdate_Update() {}
+
+type ExpectedFeeRecipientRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Validator:
+	//	*ExpectedFeeRecipientRequest_Pubkey
+	//	*ExpectedFeeRecipientRequest_Index
+	Validator isExpectedFeeRecipientRequest_Validator `protobuf_oneof:"validator"`
+}
+
+func (x *ExpectedFeeRecipientRequest) Reset() {
+	*x = ExpectedFeeRecipientRequest{}
+	mi := &file_api_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpectedFeeRecipientRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpectedFeeRecipientRequest) ProtoMessage() {}
+
+func (x *ExpectedFeeRecipientRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpectedFeeRecipientRequest.ProtoReflect.Descriptor instead.
+func (*ExpectedFeeRecipientRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{22}
+}
+
+func (m *ExpectedFeeRecipientRequest) GetValidator() isExpectedFeeRecipientRequest_Validator {
+	if m != nil {
+		return m.Validator
+	}
+	return nil
+}
+
+func (x *ExpectedFeeRecipientRequest) GetPubkey() []byte {
+	if x, ok := x.GetValidator().(*ExpectedFeeRecipientRequest_Pubkey); ok {
+		return x.Pubkey
+	}
+	return nil
+}
+
+func (x *ExpectedFeeRecipientRequest) GetIndex() uint64 {
+	if x, ok := x.GetValidator().(*ExpectedFeeRecipientRequest_Index); ok {
+		return x.Index
+	}
+	return 0
+}
+
+type isExpectedFeeRecipientRequest_Validator interface {
+	isExpectedFeeRecipientRequest_Validator()
+}
+
+type ExpectedFeeRecipientRequest_Pubkey struct {
+	Pubkey []byte `protobuf:"bytes,1,opt,name=pubkey,proto3,oneof"`
+}
+
+type ExpectedFeeRecipientRequest_Index struct {
+	Index uint64 `protobuf:"varint,2,opt,name=index,proto3,oneof"`
+}
+
+func (*ExpectedFeeRecipientRequest_Pubkey) isExpectedFeeRecipientRequest_Validator() {}
+
+func (*ExpectedFeeRecipientRequest_Index) isExpectedFeeRecipientRequest_Validator() {}
+
+type ExpectedFeeRecipientResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type   ValidatorType `protobuf:"varint,1,opt,name=type,proto3,enum=pb.ValidatorType" json:"type,omitempty"`
+	Pubkey []byte        `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	// Only meaningful if in_beacon_state is set
+	Index uint64 `protobuf:"varint,3,opt,name=index,proto3" json:"index,omitempty"`
+	// Whether the beacon node has the validator in its head state
+	InBeaconState bool `protobuf:"varint,4,opt,name=in_beacon_state,json=inBeaconState,proto3" json:"in_beacon_state,omitempty"`
+	// Set for validators whose deposit hasn't been applied to the beacon state yet
+	Pending bool `protobuf:"varint,5,opt,name=pending,proto3" json:"pending,omitempty"`
+	// The validator's withdrawal address, if it has 0x01 or 0x02 credentials
+	WithdrawalAddress []byte `protobuf:"bytes,6,opt,name=withdrawal_address,json=withdrawalAddress,proto3" json:"withdrawal_address,omitempty"`
+	// Minipools only
+	NodeAddress     []byte `protobuf:"bytes,7,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	InSmoothingPool bool   `protobuf:"varint,8,opt,name=in_smoothing_pool,json=inSmoothingPool,proto3" json:"in_smoothing_pool,omitempty"`
+	// Empty for 0x00 and unknown validators
+	ExpectedFeeRecipient []byte `protobuf:"bytes,9,opt,name=expected_fee_recipient,json=expectedFeeRecipient,proto3" json:"expected_fee_recipient,omitempty"`
+	// Every fee recipient the validator may use, including expected_fee_recipient. After a node
+	// joins or leaves the smoothing pool, its previous fee recipient is accepted for a while.
+	AcceptableFeeRecipients [][]byte `protobuf:"bytes,10,rep,name=acceptable_fee_recipients,json=acceptableFeeRecipients,proto3" json:"acceptable_fee_recipients,omitempty"`
+	// Whether rETH is also accepted, as a safe default for minipools whose validator client is misconfigured
+	RethFallback bool   `protobuf:"varint,11,opt,name=reth_fallback,json=rethFallback,proto3" json:"reth_fallback,omitempty"`
+	RethAddress  []byte `protobuf:"bytes,12,opt,name=reth_address,json=rethAddress,proto3" json:"reth_address,omitempty"`
+	Error        string `protobuf:"bytes,13,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *ExpectedFeeRecipientResponse) Reset() {
+	*x = ExpectedFeeRecipientResponse{}
+	mi := &file_api_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpectedFeeRecipientResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpectedFeeRecipientResponse) ProtoMessage() {}
+
+func (x *ExpectedFeeRecipientResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpectedFeeRecipientResponse.ProtoReflect.Descriptor instead.
+func (*ExpectedFeeRecipientResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ExpectedFeeRecipientResponse) GetType() ValidatorType {
+	if x != nil {
+		return x.Type
+	}
+	return ValidatorType_VALIDATOR_TYPE_UNKNOWN
+}
+
+func (x *ExpectedFeeRecipientResponse) GetPubkey() []byte {
+	if x != nil {
+		return x.Pubkey
+	}
+	return nil
+}
+
+func (x *ExpectedFeeRecipientResponse) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ExpectedFeeRecipientResponse) GetInBeaconState() bool {
+	if x != nil {
+		return x.InBeaconState
+	}
+	return false
+}
+
+func (x *ExpectedFeeRecipientResponse) GetPending() bool {
+	if x != nil {
+		return x.Pending
+	}
+	return false
+}
+
+func (x *ExpectedFeeRecipientResponse) GetWithdrawalAddress() []byte {
+	if x != nil {
+		return x.WithdrawalAddress
+	}
+	return nil
+}
+
+func (x *ExpectedFeeRecipientResponse) GetNodeAddress() []byte {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return nil
+}
+
+func (x *ExpectedFeeRecipientResponse) GetInSmoothingPool() bool {
+	if x != nil {
+		return x.InSmoothingPool
+	}
+	return false
+}
+
+func (x *ExpectedFeeRecipientResponse) GetExpectedFeeRecipient() []byte {
+	if x != nil {
+		return x.ExpectedFeeRecipient
+	}
+	return nil
+}
+
+func (x *ExpectedFeeRecipientResponse) GetAcceptableFeeRecipients() [][]byte {
+	if x != nil {
+		return x.AcceptableFeeRecipients
+	}
+	return nil
+}
+
+func (x *ExpectedFeeRecipientResponse) GetRethFallback() bool {
+	if x != nil {
+		return x.RethFallback
+	}
+	return false
+}
+
+func (x *ExpectedFeeRecipientResponse) GetRethAddress() []byte {
+	if x != nil {
+		return x.RethAddress
+	}
+	return nil
+}
+
+func (x *ExpectedFeeRecipientResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type NodeInfoRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NodeAddress []byte `protobuf:"bytes,1,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+}
+
+func (x *NodeInfoRequest) Reset() {
+	*x = NodeInfoRequest{}
+	mi := &file_api_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeInfoRequest) ProtoMessage() {}
+
+func (x *NodeInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeInfoRequest.ProtoReflect.Descriptor instead.
+func (*NodeInfoRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *NodeInfoRequest) GetNodeAddress() []byte {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return nil
+}
+
+type NodeInfoResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NodeAddress []byte `protobuf:"bytes,1,opt,name=node_address,json=nodeAddress,proto3" json:"node_address,omitempty"`
+	// Unset if the address isn't a registered node, in which case the rest is empty
+	Registered      bool     `protobuf:"varint,2,opt,name=registered,proto3" json:"registered,omitempty"`
+	InSmoothingPool bool     `protobuf:"varint,3,opt,name=in_smoothing_pool,json=inSmoothingPool,proto3" json:"in_smoothing_pool,omitempty"`
+	FeeDistributor  []byte   `protobuf:"bytes,4,opt,name=fee_distributor,json=feeDistributor,proto3" json:"fee_distributor,omitempty"`
+	MinipoolPubkeys [][]byte `protobuf:"bytes,5,rep,name=minipool_pubkeys,json=minipoolPubkeys,proto3" json:"minipool_pubkeys,omitempty"`
+	OdaoMember      bool     `protobuf:"varint,6,opt,name=odao_member,json=odaoMember,proto3" json:"odao_member,omitempty"`
+	// Unix times at which each of the above last changed, or 0 if the proxy didn't see it change.
+	// The fee distributor is set when the node registers.
+	SmoothingPoolChanged  int64  `protobuf:"varint,7,opt,name=smoothing_pool_changed,json=smoothingPoolChanged,proto3" json:"smoothing_pool_changed,omitempty"`
+	FeeDistributorChanged int64  `protobuf:"varint,8,opt,name=fee_distributor_changed,json=feeDistributorChanged,proto3" json:"fee_distributor_changed,omitempty"`
+	MinipoolsChanged      int64  `protobuf:"varint,9,opt,name=minipools_changed,json=minipoolsChanged,proto3" json:"minipools_changed,omitempty"`
+	OdaoChanged           int64  `protobuf:"varint,10,opt,name=odao_changed,json=odaoChanged,proto3" json:"odao_changed,omitempty"`
+	Error                 string `protobuf:"bytes,11,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *NodeInfoResponse) Reset() {
+	*x = NodeInfoResponse{}
+	mi := &file_api_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeInfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeInfoResponse) ProtoMessage() {}
+
+func (x *NodeInfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeInfoResponse.ProtoReflect.Descriptor instead.
+func (*NodeInfoResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *NodeInfoResponse) GetNodeAddress() []byte {
+	if x != nil {
+		return x.NodeAddress
+	}
+	return nil
+}
+
+func (x *NodeInfoResponse) GetRegistered() bool {
+	if x != nil {
+		return x.Registered
+	}
+	return false
+}
+
+func (x *NodeInfoResponse) GetInSmoothingPool() bool {
+	if x != nil {
+		return x.InSmoothingPool
+	}
+	return false
+}
+
+func (x *NodeInfoResponse) GetFeeDistributor() []byte {
+	if x != nil {
+		return x.FeeDistributor
+	}
+	return nil
+}
+
+func (x *NodeInfoResponse) GetMinipoolPubkeys() [][]byte {
+	if x != nil {
+		return x.MinipoolPubkeys
+	}
+	return nil
+}
+
+func (x *NodeInfoResponse) GetOdaoMember() bool {
+	if x != nil {
+		return x.OdaoMember
+	}
+	return false
+}
+
+func (x *NodeInfoResponse) GetSmoothingPoolChanged() int64 {
+	if x != nil {
+		return x.SmoothingPoolChanged
+	}
+	return 0
+}
+
+func (x *NodeInfoResponse) GetFeeDistributorChanged() int64 {
+	if x != nil {
+		return x.FeeDistributorChanged
+	}
+	return 0
+}
+
+func (x *NodeInfoResponse) GetMinipoolsChanged() int64 {
+	if x != nil {
+		return x.MinipoolsChanged
+	}
+	return 0
+}
+
+func (x *NodeInfoResponse) GetOdaoChanged() int64 {
+	if x != nil {
+		return x.OdaoChanged
+	}
+	return 0
+}
+
+func (x *NodeInfoResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_api_proto protoreflect.FileDescriptor
+
+var file_api_proto_rawDesc = []byte{
+	0x0a, 0x09, 0x61, 0x70, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x22,
+	0x18, 0x0a, 0x16, 0x52, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x4e, 0x6f, 0x64,
+	0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x2c, 0x0a, 0x0f, 0x52, 0x6f, 0x63,
+	0x6b, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x19, 0x0a, 0x08,
+	0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x07,
+	0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x73, 0x22, 0x12, 0x0a, 0x10, 0x4f, 0x64, 0x61, 0x6f, 0x4e,
+	0x6f, 0x64, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x26, 0x0a, 0x09, 0x4f,
+	0x64, 0x61, 0x6f, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x6e, 0x6f, 0x64, 0x65,
+	0x5f, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x07, 0x6e, 0x6f, 0x64, 0x65,
+	0x49, 0x64, 0x73, 0x22, 0x17, 0x0a, 0x15, 0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c, 0x69, 0x64,
+	0x61, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x43, 0x0a, 0x0e,
+	0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x31,
+	0x0a, 0x14, 0x77, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x5f, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x13, 0x77, 0x69,
+	0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65,
+	0x73, 0x22, 0x6d, 0x0a, 0x16, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50,
+	0x31, 0x32, 0x37, 0x31, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x64,
+	0x61, 0x74, 0x61, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08,
+	0x64, 0x61, 0x74, 0x61, 0x48, 0x61, 0x73, 0x68, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09, 0x73, 0x69, 0x67,
+	0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x22, 0x45, 0x0a, 0x17, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31,
+	0x32, 0x37, 0x31, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x69,
+	0x64, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x55, 0x0a, 0x1b, 0x56, 0x61, 0x6c, 0x69, 0x64,
+	0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x36, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x56, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x22, 0x6f,
+	0x0a, 0x1c, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37,
+	0x31, 0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x39,
+	0x0a, 0x09, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45,
+	0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x52, 0x09,
+	0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22,
+	0x3f, 0x0a, 0x0f, 0x52, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x41, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6c,
+	0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x22, 0xde, 0x01, 0x0a, 0x10, 0x52, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x41, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x69, 0x73, 0x5f, 0x6d, 0x69, 0x6e, 0x69,
+	0x70, 0x6f, 0x6f, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69, 0x73, 0x4d, 0x69,
+	0x6e, 0x69, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x21, 0x0a, 0x0c, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x6e, 0x6f,
+	0x64, 0x65, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x34, 0x0a, 0x16, 0x65, 0x78, 0x70,
+	0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x72, 0x65, 0x63, 0x69, 0x70, 0x69,
+	0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x14, 0x65, 0x78, 0x70, 0x65, 0x63,
+	0x74, 0x65, 0x64, 0x46, 0x65, 0x65, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x12,
+	0x3a, 0x0a, 0x19, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x66, 0x65,
+	0x65, 0x5f, 0x72, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03,
+	0x28, 0x0c, 0x52, 0x17, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x46, 0x65,
+	0x65, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x22, 0x85, 0x01, 0x0a, 0x18, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x53, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b,
+	0x0a, 0x09, 0x64, 0x61, 0x74, 0x61, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x08, 0x64, 0x61, 0x74, 0x61, 0x48, 0x61, 0x73, 0x68, 0x12, 0x1c, 0x0a, 0x09, 0x73,
+	0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x09,
+	0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x74, 0x0a, 0x19, 0x56, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x65, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x12, 0x2b, 0x0a, 0x06,
+	0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x70,
+	0x62, 0x2e, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x4d, 0x65, 0x74, 0x68, 0x6f,
+	0x64, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22,
+	0x7d, 0x0a, 0x1a, 0x57, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2f, 0x0a,
+	0x12, 0x77, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x5f, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x11, 0x77, 0x69, 0x74,
+	0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x23,
+	0x0a, 0x0c, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x0b, 0x6e, 0x6f, 0x64, 0x65, 0x41, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x42, 0x09, 0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0xa1,
+	0x01, 0x0a, 0x17, 0x4e, 0x6f, 0x64, 0x65, 0x57, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61,
+	0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x6e, 0x6f,
+	0x64, 0x65, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x0b, 0x6e, 0x6f, 0x64, 0x65, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x2d, 0x0a,
+	0x12, 0x77, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x5f, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x11, 0x77, 0x69, 0x74, 0x68, 0x64,
+	0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x34, 0x0a, 0x16,
+	0x72, 0x70, 0x6c, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x5f, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x14, 0x72, 0x70,
+	0x6c, 0x57, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x22, 0x66, 0x0a, 0x1b, 0x57, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c,
+	0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x31, 0x0a, 0x05, 0x6e, 0x6f, 0x64, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x57, 0x69, 0x74, 0x68, 0x64, 0x72,
+	0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x52, 0x05, 0x6e,
+	0x6f, 0x64, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x57, 0x0a, 0x0d, 0x4e, 0x6f,
+	0x64, 0x65, 0x53, 0x65, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63,
+	0x6b, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x73, 0x74, 0x61,
+	0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x69, 0x6e, 0x73, 0x74, 0x61,
+	0x6e, 0x63, 0x65, 0x22, 0x3b, 0x0a, 0x0e, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x29, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53,
+	0x65, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72,
+	0x22, 0x8c, 0x01, 0x0a, 0x0f, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65, 0x74, 0x53, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x73, 0x12,
+	0x22, 0x0a, 0x0d, 0x6f, 0x64, 0x61, 0x6f, 0x5f, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x0b, 0x6f, 0x64, 0x61, 0x6f, 0x4e, 0x6f, 0x64, 0x65,
+	0x49, 0x64, 0x73, 0x12, 0x3a, 0x0a, 0x19, 0x73, 0x6f, 0x6c, 0x6f, 0x5f, 0x77, 0x69, 0x74, 0x68,
+	0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x17, 0x73, 0x6f, 0x6c, 0x6f, 0x57, 0x69, 0x74, 0x68,
+	0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x22,
+	0x54, 0x0a, 0x0d, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x12, 0x29, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15,
+	0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67,
+	0x65, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x64,
+	0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0xa4, 0x01, 0x0a, 0x0d, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65,
+	0x74, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x29, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f,
+	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x53, 0x65, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73,
+	0x6f, 0x72, 0x12, 0x31, 0x0a, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65,
+	0x74, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x48, 0x00, 0x52, 0x08, 0x73, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x2b, 0x0a, 0x06, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53,
+	0x65, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x48, 0x00, 0x52, 0x06, 0x63, 0x68, 0x61, 0x6e,
+	0x67, 0x65, 0x42, 0x08, 0x0a, 0x06, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x22, 0x5c, 0x0a, 0x1b,
+	0x45, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x46, 0x65, 0x65, 0x52, 0x65, 0x63, 0x69, 0x70,
+	0x69, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x06, 0x70,
+	0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x06, 0x70,
+	0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x16, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x42, 0x0b, 0x0a,
+	0x09, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x22, 0x83, 0x04, 0x0a, 0x1c, 0x45,
+	0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x46, 0x65, 0x65, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69,
+	0x65, 0x6e, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x25, 0x0a, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x56,
+	0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79,
+	0x70, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x06, 0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78,
+	0x12, 0x26, 0x0a, 0x0f, 0x69, 0x6e, 0x5f, 0x62, 0x65, 0x61, 0x63, 0x6f, 0x6e, 0x5f, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0d, 0x69, 0x6e, 0x42, 0x65, 0x61,
+	0x63, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x65, 0x6e, 0x64,
+	0x69, 0x6e, 0x67, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x70, 0x65, 0x6e, 0x64, 0x69,
+	0x6e, 0x67, 0x12, 0x2d, 0x0a, 0x12, 0x77, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c,
+	0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x11,
+	0x77, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x12, 0x21, 0x0a, 0x0c, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x6e, 0x6f, 0x64, 0x65, 0x41, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x12, 0x2a, 0x0a, 0x11, 0x69, 0x6e, 0x5f, 0x73, 0x6d, 0x6f, 0x6f, 0x74,
+	0x68, 0x69, 0x6e, 0x67, 0x5f, 0x70, 0x6f, 0x6f, 0x6c, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0f, 0x69, 0x6e, 0x53, 0x6d, 0x6f, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x50, 0x6f, 0x6f, 0x6c,
+	0x12, 0x34, 0x0a, 0x16, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x66, 0x65, 0x65,
+	0x5f, 0x72, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x14, 0x65, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x46, 0x65, 0x65, 0x52, 0x65, 0x63,
+	0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x12, 0x3a, 0x0a, 0x19, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74,
+	0x61, 0x62, 0x6c, 0x65, 0x5f, 0x66, 0x65, 0x65, 0x5f, 0x72, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65,
+	0x6e, 0x74, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x17, 0x61, 0x63, 0x63, 0x65, 0x70,
+	0x74, 0x61, 0x62, 0x6c, 0x65, 0x46, 0x65, 0x65, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e,
+	0x74, 0x73, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65, 0x74, 0x68, 0x5f, 0x66, 0x61, 0x6c, 0x6c, 0x62,
+	0x61, 0x63, 0x6b, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x72, 0x65, 0x74, 0x68, 0x46,
+	0x61, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x74, 0x68, 0x5f,
+	0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x72,
+	0x65, 0x74, 0x68, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x22, 0x34, 0x0a, 0x0f, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x6e, 0x6f, 0x64, 0x65, 0x41,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0xca, 0x03, 0x0a, 0x10, 0x4e, 0x6f, 0x64, 0x65, 0x49,
+	0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x6e,
+	0x6f, 0x64, 0x65, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x0b, 0x6e, 0x6f, 0x64, 0x65, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1e,
+	0x0a, 0x0a, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0a, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x65, 0x64, 0x12, 0x2a,
+	0x0a, 0x11, 0x69, 0x6e, 0x5f, 0x73, 0x6d, 0x6f, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x5f, 0x70,
+	0x6f, 0x6f, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0f, 0x69, 0x6e, 0x53, 0x6d, 0x6f,
+	0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x50, 0x6f, 0x6f, 0x6c, 0x12, 0x27, 0x0a, 0x0f, 0x66, 0x65,
+	0x65, 0x5f, 0x64, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x6f, 0x72, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x0e, 0x66, 0x65, 0x65, 0x44, 0x69, 0x73, 0x74, 0x72, 0x69, 0x62, 0x75,
+	0x74, 0x6f, 0x72, 0x12, 0x29, 0x0a, 0x10, 0x6d, 0x69, 0x6e, 0x69, 0x70, 0x6f, 0x6f, 0x6c, 0x5f,
+	0x70, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x0f, 0x6d,
+	0x69, 0x6e, 0x69, 0x70, 0x6f, 0x6f, 0x6c, 0x50, 0x75, 0x62, 0x6b, 0x65, 0x79, 0x73, 0x12, 0x1f,
+	0x0a, 0x0b, 0x6f, 0x64, 0x61, 0x6f, 0x5f, 0x6d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0a, 0x6f, 0x64, 0x61, 0x6f, 0x4d, 0x65, 0x6d, 0x62, 0x65, 0x72, 0x12,
+	0x34, 0x0a, 0x16, 0x73, 0x6d, 0x6f, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x5f, 0x70, 0x6f, 0x6f,
+	0x6c, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x14, 0x73, 0x6d, 0x6f, 0x6f, 0x74, 0x68, 0x69, 0x6e, 0x67, 0x50, 0x6f, 0x6f, 0x6c, 0x43, 0x68,
+	0x61, 0x6e, 0x67, 0x65, 0x64, 0x12, 0x36, 0x0a, 0x17, 0x66, 0x65, 0x65, 0x5f, 0x64, 0x69, 0x73,
+	0x74, 0x72, 0x69, 0x62, 0x75, 0x74, 0x6f, 0x72, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x15, 0x66, 0x65, 0x65, 0x44, 0x69, 0x73, 0x74, 0x72,
+	0x69, 0x62, 0x75, 0x74, 0x6f, 0x72, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x12, 0x2b, 0x0a,
+	0x11, 0x6d, 0x69, 0x6e, 0x69, 0x70, 0x6f, 0x6f, 0x6c, 0x73, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67,
+	0x65, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x10, 0x6d, 0x69, 0x6e, 0x69, 0x70, 0x6f,
+	0x6f, 0x6c, 0x73, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x12, 0x21, 0x0a, 0x0c, 0x6f, 0x64,
+	0x61, 0x6f, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0b, 0x6f, 0x64, 0x61, 0x6f, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x12, 0x14, 0x0a,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x2a, 0x82, 0x01, 0x0a, 0x0f, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72,
+	0x65, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x19, 0x0a, 0x15, 0x53, 0x49, 0x47, 0x4e, 0x41,
+	0x54, 0x55, 0x52, 0x45, 0x5f, 0x4d, 0x45, 0x54, 0x48, 0x4f, 0x44, 0x5f, 0x4e, 0x4f, 0x4e, 0x45,
+	0x10, 0x00, 0x12, 0x18, 0x0a, 0x14, 0x53, 0x49, 0x47, 0x4e, 0x41, 0x54, 0x55, 0x52, 0x45, 0x5f,
+	0x4d, 0x45, 0x54, 0x48, 0x4f, 0x44, 0x5f, 0x45, 0x4f, 0x41, 0x10, 0x01, 0x12, 0x1c, 0x0a, 0x18,
+	0x53, 0x49, 0x47, 0x4e, 0x41, 0x54, 0x55, 0x52, 0x45, 0x5f, 0x4d, 0x45, 0x54, 0x48, 0x4f, 0x44,
+	0x5f, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x10, 0x02, 0x12, 0x1c, 0x0a, 0x18, 0x53, 0x49,
+	0x47, 0x4e, 0x41, 0x54, 0x55, 0x52, 0x45, 0x5f, 0x4d, 0x45, 0x54, 0x48, 0x4f, 0x44, 0x5f, 0x45,
+	0x49, 0x50, 0x36, 0x34, 0x39, 0x32, 0x10, 0x03, 0x2a, 0x9b, 0x02, 0x0a, 0x11, 0x4e, 0x6f, 0x64,
+	0x65, 0x53, 0x65, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x20,
+	0x0a, 0x1c, 0x4e, 0x4f, 0x44, 0x45, 0x5f, 0x53, 0x45, 0x54, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x47,
+	0x45, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00,
+	0x12, 0x28, 0x0a, 0x24, 0x4e, 0x4f, 0x44, 0x45, 0x5f, 0x53, 0x45, 0x54, 0x5f, 0x43, 0x48, 0x41,
+	0x4e, 0x47, 0x45, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4e, 0x4f, 0x44, 0x45, 0x5f, 0x52, 0x45,
+	0x47, 0x49, 0x53, 0x54, 0x45, 0x52, 0x45, 0x44, 0x10, 0x01, 0x12, 0x24, 0x0a, 0x20, 0x4e, 0x4f,
+	0x44, 0x45, 0x5f, 0x53, 0x45, 0x54, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x47, 0x45, 0x5f, 0x54, 0x59,
+	0x50, 0x45, 0x5f, 0x4f, 0x44, 0x41, 0x4f, 0x5f, 0x4a, 0x4f, 0x49, 0x4e, 0x45, 0x44, 0x10, 0x02,
+	0x12, 0x22, 0x0a, 0x1e, 0x4e, 0x4f, 0x44, 0x45, 0x5f, 0x53, 0x45, 0x54, 0x5f, 0x43, 0x48, 0x41,
+	0x4e, 0x47, 0x45, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4f, 0x44, 0x41, 0x4f, 0x5f, 0x4c, 0x45,
+	0x46, 0x54, 0x10, 0x03, 0x12, 0x24, 0x0a, 0x20, 0x4e, 0x4f, 0x44, 0x45, 0x5f, 0x53, 0x45, 0x54,
+	0x5f, 0x43, 0x48, 0x41, 0x4e, 0x47, 0x45, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4f, 0x44, 0x41,
+	0x4f, 0x5f, 0x4b, 0x49, 0x43, 0x4b, 0x45, 0x44, 0x10, 0x04, 0x12, 0x23, 0x0a, 0x1f, 0x4e, 0x4f,
+	0x44, 0x45, 0x5f, 0x53, 0x45, 0x54, 0x5f, 0x43, 0x48, 0x41, 0x4e, 0x47, 0x45, 0x5f, 0x54, 0x59,
+	0x50, 0x45, 0x5f, 0x53, 0x4f, 0x4c, 0x4f, 0x5f, 0x41, 0x44, 0x44, 0x45, 0x44, 0x10, 0x05, 0x12,
+	0x25, 0x0a, 0x21, 0x4e, 0x4f, 0x44, 0x45, 0x5f, 0x53, 0x45, 0x54, 0x5f, 0x43, 0x48, 0x41, 0x4e,
+	0x47, 0x45, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x53, 0x4f, 0x4c, 0x4f, 0x5f, 0x52, 0x45, 0x4d,
+	0x4f, 0x56, 0x45, 0x44, 0x10, 0x06, 0x2a, 0x9d, 0x01, 0x0a, 0x0d, 0x56, 0x61, 0x6c, 0x69, 0x64,
+	0x61, 0x74, 0x6f, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x16, 0x56, 0x41, 0x4c, 0x49,
+	0x44, 0x41, 0x54, 0x4f, 0x52, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f,
+	0x57, 0x4e, 0x10, 0x00, 0x12, 0x1b, 0x0a, 0x17, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x41, 0x54, 0x4f,
+	0x52, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f, 0x4d, 0x49, 0x4e, 0x49, 0x50, 0x4f, 0x4f, 0x4c, 0x10,
+	0x01, 0x12, 0x1c, 0x0a, 0x18, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x41, 0x54, 0x4f, 0x52, 0x5f, 0x54,
+	0x59, 0x50, 0x45, 0x5f, 0x53, 0x4f, 0x4c, 0x4f, 0x5f, 0x30, 0x58, 0x30, 0x31, 0x10, 0x02, 0x12,
+	0x1c, 0x0a, 0x18, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x41, 0x54, 0x4f, 0x52, 0x5f, 0x54, 0x59, 0x50,
+	0x45, 0x5f, 0x53, 0x4f, 0x4c, 0x4f, 0x5f, 0x30, 0x58, 0x30, 0x32, 0x10, 0x03, 0x12, 0x17, 0x0a,
+	0x13, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x41, 0x54, 0x4f, 0x52, 0x5f, 0x54, 0x59, 0x50, 0x45, 0x5f,
+	0x30, 0x58, 0x30, 0x30, 0x10, 0x04, 0x32, 0xbb, 0x06, 0x0a, 0x03, 0x41, 0x70, 0x69, 0x12, 0x47,
+	0x0a, 0x12, 0x47, 0x65, 0x74, 0x52, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c, 0x4e,
+	0x6f, 0x64, 0x65, 0x73, 0x12, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x6f, 0x63, 0x6b, 0x65, 0x74,
+	0x50, 0x6f, 0x6f, 0x6c, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x6f, 0x63, 0x6b, 0x65, 0x74, 0x50, 0x6f, 0x6f, 0x6c,
+	0x4e, 0x6f, 0x64, 0x65, 0x73, 0x22, 0x00, 0x12, 0x35, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x4f, 0x64,
+	0x61, 0x6f, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x12, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x4f, 0x64, 0x61,
+	0x6f, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e,
+	0x70, 0x62, 0x2e, 0x4f, 0x64, 0x61, 0x6f, 0x4e, 0x6f, 0x64, 0x65, 0x73, 0x22, 0x00, 0x12, 0x44,
+	0x0a, 0x11, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
+	0x6f, 0x72, 0x73, 0x12, 0x19, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12,
+	0x2e, 0x70, 0x62, 0x2e, 0x53, 0x6f, 0x6c, 0x6f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f,
+	0x72, 0x73, 0x22, 0x00, 0x12, 0x4c, 0x0a, 0x0f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65,
+	0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x12, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x56, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
+	0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x12, 0x5b, 0x0a, 0x14, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49,
+	0x50, 0x31, 0x32, 0x37, 0x31, 0x42, 0x61, 0x74, 0x63, 0x68, 0x12, 0x1f, 0x2e, 0x70, 0x62, 0x2e,
+	0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x70, 0x62,
+	0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x45, 0x49, 0x50, 0x31, 0x32, 0x37, 0x31,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12,
+	0x3a, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x52, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x41, 0x74, 0x12, 0x13,
+	0x2e, 0x70, 0x62, 0x2e, 0x52, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x41, 0x74, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x50, 0x49, 0x6e, 0x66, 0x6f, 0x41,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x52, 0x0a, 0x11, 0x56,
+	0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65,
+	0x12, 0x1c, 0x2e, 0x70, 0x62, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x53, 0x69,
+	0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d,
+	0x2e, 0x70, 0x62, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x53, 0x69, 0x67, 0x6e,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12,
+	0x5b, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x57, 0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c,
+	0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x65, 0x73, 0x12, 0x1e, 0x2e, 0x70, 0x62, 0x2e, 0x57,
+	0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x70, 0x62, 0x2e, 0x57,
+	0x69, 0x74, 0x68, 0x64, 0x72, 0x61, 0x77, 0x61, 0x6c, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73,
+	0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x3a, 0x0a, 0x0d,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65, 0x74, 0x12, 0x12, 0x2e,
+	0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x53, 0x65, 0x74, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x5e, 0x0a, 0x17, 0x47, 0x65, 0x74, 0x45,
+	0x78, 0x70, 0x65, 0x63, 0x74, 0x65, 0x64, 0x46, 0x65, 0x65, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69,
+	0x65, 0x6e, 0x74, 0x12, 0x1f, 0x2e, 0x70, 0x62, 0x2e, 0x45, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65,
+	0x64, 0x46, 0x65, 0x65, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x70, 0x62, 0x2e, 0x45, 0x78, 0x70, 0x65, 0x63, 0x74,
+	0x65, 0x64, 0x46, 0x65, 0x65, 0x52, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e, 0x74, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x3a, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x4e,
+	0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x70,
+	0x62, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_api_proto_rawDescOnce sync.Once
+	file_api_proto_rawDescData = file_api_proto_rawDesc
+)
+
+func file_api_proto_rawDescGZIP() []byte {
+	file_api_proto_rawDescOnce.Do(func() {
+		file_api_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_proto_rawDescData)
+	})
+	return file_api_proto_rawDescData
+}
+
+var file_api_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_api_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_api_proto_goTypes = []any{
+	(SignatureMethod)(0),                 // 0: pb.SignatureMethod
+	(NodeSetChangeType)(0),               // 1: pb.NodeSetChangeType
+	(ValidatorType)(0),                   // 2: pb.ValidatorType
+	(*RocketPoolNodesRequest)(nil),       // 3: pb.RocketPoolNodesRequest
+	(*RocketPoolNodes)(nil),              // 4: pb.RocketPoolNodes
+	(*OdaoNodesRequest)(nil),             // 5: pb.OdaoNodesRequest
+	(*OdaoNodes)(nil),                    // 6: pb.OdaoNodes
+	(*SoloValidatorsRequest)(nil),        // 7: pb.SoloValidatorsRequest
+	(*SoloValidators)(nil),               // 8: pb.SoloValidators
+	(*ValidateEIP1271Request)(nil),       // 9: pb.ValidateEIP1271Request
+	(*ValidateEIP1271Response)(nil),      // 10: pb.ValidateEIP1271Response
+	(*ValidateEIP1271BatchRequest)(nil),  // 11: pb.ValidateEIP1271BatchRequest
+	(*ValidateEIP1271BatchResponse)(nil), // 12: pb.ValidateEIP1271BatchResponse
+	(*RPInfoAtRequest)(nil),              // 13: pb.RPInfoAtRequest
+	(*RPInfoAtResponse)(nil),             // 14: pb.RPInfoAtResponse
+	(*ValidateSignatureRequest)(nil),     // 15: pb.ValidateSignatureRequest
+	(*ValidateSignatureResponse)(nil),    // 16: pb.ValidateSignatureResponse
+	(*WithdrawalAddressesRequest)(nil),   // 17: pb.WithdrawalAddressesRequest
+	(*NodeWithdrawalAddresses)(nil),      // 18: pb.NodeWithdrawalAddresses
+	(*WithdrawalAddressesResponse)(nil),  // 19: pb.WithdrawalAddressesResponse
+	(*NodeSetCursor)(nil),                // 20: pb.NodeSetCursor
+	(*NodeSetRequest)(nil),               // 21: pb.NodeSetRequest
+	(*NodeSetSnapshot)(nil),              // 22: pb.NodeSetSnapshot
+	(*NodeSetChange)(nil),                // 23: pb.NodeSetChange
+	(*NodeSetUpdate)(nil),                // 24: pb.NodeSetUpdate
+	(*ExpectedFeeRecipientRequest)(nil),  // 25: pb.ExpectedFeeRecipientRequest
+	(*ExpectedFeeRecipientResponse)(nil), // 26: pb.ExpectedFeeRecipientResponse
+	(*NodeInfoRequest)(nil),              // 27: pb.NodeInfoRequest
+	(*NodeInfoResponse)(nil),             // 28: pb.NodeInfoResponse
+}
+var file_api_proto_depIdxs = []int32{
+	9,  // 0: pb.ValidateEIP1271BatchRequest.requests:type_name -> pb.ValidateEIP1271Request
+	10, // 1: pb.ValidateEIP1271BatchResponse.responses:type_name -> pb.ValidateEIP1271Response
+	0,  // 2: pb.ValidateSignatureResponse.method:type_name -> pb.SignatureMethod
+	18, // 3: pb.WithdrawalAddressesResponse.nodes:type_name -> pb.NodeWithdrawalAddresses
+	20, // 4: pb.NodeSetRequest.cursor:type_name -> pb.NodeSetCursor
+	1,  // 5: pb.NodeSetChange.type:type_name -> pb.NodeSetChangeType
+	20, // 6: pb.NodeSetUpdate.cursor:type_name -> pb.NodeSetCursor
+	22, // 7: pb.NodeSetUpdate.snapshot:type_name -> pb.NodeSetSnapshot
+	23, // 8: pb.NodeSetUpdate.change:type_name -> pb.NodeSetChange
+	2,  // 9: pb.ExpectedFeeRecipientResponse.type:type_name -> pb.ValidatorType
+	3,  // 10: pb.Api.GetRocketPoolNodes:input_type -> pb.RocketPoolNodesRequest
+	5,  // 11: pb.Api.GetOdaoNodes:input_type -> pb.OdaoNodesRequest
+	7,  // 12: pb.Api.GetSoloValidators:input_type -> pb.SoloValidatorsRequest
+	9,  // 13: pb.Api.ValidateEIP1271:input_type -> pb.ValidateEIP1271Request
+	11, // 14: pb.Api.ValidateEIP1271Batch:input_type -> pb.ValidateEIP1271BatchRequest
+	13, // 15: pb.Api.GetRPInfoAt:input_type -> pb.RPInfoAtRequest
+	15, // 16: pb.Api.ValidateSignature:input_type -> pb.ValidateSignatureRequest
+	17, // 17: pb.Api.GetWithdrawalAddresses:input_type -> pb.WithdrawalAddressesRequest
+	21, // 18: pb.Api.StreamNodeSet:input_type -> pb.NodeSetRequest
+	25, // 19: pb.Api.GetExpectedFeeRecipient:input_type -> pb.ExpectedFeeRecipientRequest
+	27, // 20: pb.Api.GetNodeInfo:input_type -> pb.NodeInfoRequest
+	4,  // 21: pb.Api.GetRocketPoolNodes:output_type -> pb.RocketPoolNodes
+	6,  // 22: pb.Api.GetOdaoNodes:output_type -> pb.OdaoNodes
+	8,  // 23: pb.Api.GetSoloValidators:output_type -> pb.SoloValidators
+	10, // 24: pb.Api.ValidateEIP1271:output_type -> pb.ValidateEIP1271Response
+	12, // 25: pb.Api.ValidateEIP1271Batch:output_type -> pb.ValidateEIP1271BatchResponse
+	14, // 26: pb.Api.GetRPInfoAt:output_type -> pb.RPInfoAtResponse
+	16, // 27: pb.Api.ValidateSignature:output_type -> pb.ValidateSignatureResponse
+	19, // 28: pb.Api.GetWithdrawalAddresses:output_type -> pb.WithdrawalAddressesResponse
+	24, // 29: pb.Api.StreamNodeSet:output_type -> pb.NodeSetUpdate
+	26, // 30: pb.Api.GetExpectedFeeRecipient:output_type -> pb.ExpectedFeeRecipientResponse
+	28, // 31: pb.Api.GetNodeInfo:output_type -> pb.NodeInfoResponse
+	21, // [21:32] is the sub-list for method output_type
+	10, // [10:21] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_api_proto_init() }
+func file_api_proto_init() {
+	if File_api_proto != nil {
+		return
+	}
+	file_api_proto_msgTypes[14].OneofWrappers = []any{
+		(*WithdrawalAddressesRequest_WithdrawalAddress)(nil),
+		(*WithdrawalAddressesRequest_NodeAddress)(nil),
+	}
+	file_api_proto_msgTypes[21].OneofWrappers = []any{
+		(*NodeSetUpdate_Snapshot)(nil),
+		(*NodeSetUpdate_Change)(nil),
+	}
+	file_api_proto_msgTypes[22].OneofWrappers = []any{
+		(*ExpectedFeeRecipientRequest_Pubkey)(nil),
+		(*ExpectedFeeRecipientRequest_Index)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_api_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      3,
+			NumMessages:   26,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_proto_goTypes,
 		DependencyIndexes: file_api_proto_depIdxs,
+		EnumInfos:         file_api_proto_enumTypes,
 		MessageInfos:      file_api_proto_msgTypes,
 	}.Build()
 	File_api_proto = out.File
@@ -19,10 +19,17 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Api_GetRocketPoolNodes_FullMethodName = "/pb.Api/GetRocketPoolNodes"
-	Api_GetOdaoNodes_FullMethodName       = "/pb.Api/GetOdaoNodes"
-	Api_GetSoloValidators_FullMethodName  = "/pb.Api/GetSoloValidators"
-	Api_ValidateEIP1271_FullMethodName    = "/pb.Api/ValidateEIP1271"
+	Api_GetRocketPoolNodes_FullMethodName      = "/pb.Api/GetRocketPoolNodes"
+	Api_GetOdaoNodes_FullMethodName            = "/pb.Api/GetOdaoNodes"
+	Api_GetSoloValidators_FullMethodName       = "/pb.Api/GetSoloValidators"
+	Api_ValidateEIP1271_FullMethodName         = "/pb.Api/ValidateEIP1271"
+	Api_ValidateEIP1271Batch_FullMethodName    = "/pb.Api/ValidateEIP1271Batch"
+	Api_GetRPInfoAt_FullMethodName             = "/pb.Api/GetRPInfoAt"
+	Api_ValidateSignature_FullMethodName       = "/pb.Api/ValidateSignature"
+	Api_GetWithdrawalAddresses_FullMethodName  = "/pb.Api/GetWithdrawalAddresses"
+	Api_StreamNodeSet_FullMethodName           = "/pb.Api/StreamNodeSet"
+	Api_GetExpectedFeeRecipient_FullMethodName = "/pb.Api/GetExpectedFeeRecipient"
+	Api_GetNodeInfo_FullMethodName             = "/pb.Api/GetNodeInfo"
 )
 
 // ApiClient is the client API for Api service.
@@ -33,6 +40,13 @@ type ApiClient interface {
 	GetOdaoNodes(ctx context.Context, in *OdaoNodesRequest, opts ...grpc.CallOption) (*OdaoNodes, error)
 	GetSoloValidators(ctx context.Context, in *SoloValidatorsRequest, opts ...grpc.CallOption) (*SoloValidators, error)
 	ValidateEIP1271(ctx context.Context, in *ValidateEIP1271Request, opts ...grpc.CallOption) (*ValidateEIP1271Response, error)
+	ValidateEIP1271Batch(ctx context.Context, in *ValidateEIP1271BatchRequest, opts ...grpc.CallOption) (*ValidateEIP1271BatchResponse, error)
+	GetRPInfoAt(ctx context.Context, in *RPInfoAtRequest, opts ...grpc.CallOption) (*RPInfoAtResponse, error)
+	ValidateSignature(ctx context.Context, in *ValidateSignatureRequest, opts ...grpc.CallOption) (*ValidateSignatureResponse, error)
+	GetWithdrawalAddresses(ctx context.Context, in *WithdrawalAddressesRequest, opts ...grpc.CallOption) (*WithdrawalAddressesResponse, error)
+	StreamNodeSet(ctx context.Context, in *NodeSetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NodeSetUpdate], error)
+	GetExpectedFeeRecipient(ctx context.Context, in *ExpectedFeeRecipientRequest, opts ...grpc.CallOption) (*ExpectedFeeRecipientResponse, error)
+	GetNodeInfo(ctx context.Context, in *NodeInfoRequest, opts ...grpc.CallOption) (*NodeInfoResponse, error)
 }
 
 type apiClient struct {
@@ -83,6 +97,85 @@ func (c *apiClient) ValidateEIP1271(ctx context.Context, in *ValidateEIP1271Requ
 	return out, nil
 }
 
+func (c *apiClient) ValidateEIP1271Batch(ctx context.Context, in *ValidateEIP1271BatchRequest, opts ...grpc.CallOption) (*ValidateEIP1271BatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateEIP1271BatchResponse)
+	err := c.cc.Invoke(ctx, Api_ValidateEIP1271Batch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetRPInfoAt(ctx context.Context, in *RPInfoAtRequest, opts ...grpc.CallOption) (*RPInfoAtResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RPInfoAtResponse)
+	err := c.cc.Invoke(ctx, Api_GetRPInfoAt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) ValidateSignature(ctx context.Context, in *ValidateSignatureRequest, opts ...grpc.CallOption) (*ValidateSignatureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateSignatureResponse)
+	err := c.cc.Invoke(ctx, Api_ValidateSignature_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetWithdrawalAddresses(ctx context.Context, in *WithdrawalAddressesRequest, opts ...grpc.CallOption) (*WithdrawalAddressesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WithdrawalAddressesResponse)
+	err := c.cc.Invoke(ctx, Api_GetWithdrawalAddresses_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) StreamNodeSet(ctx context.Context, in *NodeSetRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[NodeSetUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Api_ServiceDesc.Streams[0], Api_StreamNodeSet_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[NodeSetRequest, NodeSetUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Api_StreamNodeSetClient = grpc.ServerStreamingClient[NodeSetUpdate]
+
+func (c *apiClient) GetExpectedFeeRecipient(ctx context.Context, in *ExpectedFeeRecipientRequest, opts ...grpc.CallOption) (*ExpectedFeeRecipientResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExpectedFeeRecipientResponse)
+	err := c.cc.Invoke(ctx, Api_GetExpectedFeeRecipient_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) GetNodeInfo(ctx context.Context, in *NodeInfoRequest, opts ...grpc.CallOption) (*NodeInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NodeInfoResponse)
+	err := c.cc.Invoke(ctx, Api_GetNodeInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ApiServer is the server API for Api service.
 // All implementations must embed UnimplementedApiServer
 // for forward compatibility.
@@ -91,6 +184,13 @@ type ApiServer interface {
 	GetOdaoNodes(context.Context, *OdaoNodesRequest) (*OdaoNodes, error)
 	GetSoloValidators(context.Context, *SoloValidatorsRequest) (*SoloValidators, error)
 	ValidateEIP1271(context.Context, *ValidateEIP1271Request) (*ValidateEIP1271Response, error)
+	ValidateEIP1271Batch(context.Context, *ValidateEIP1271BatchRequest) (*ValidateEIP1271BatchResponse, error)
+	GetRPInfoAt(context.Context, *RPInfoAtRequest) (*RPInfoAtResponse, error)
+	ValidateSignature(context.Context, *ValidateSignatureRequest) (*ValidateSignatureResponse, error)
+	GetWithdrawalAddresses(context.Context, *WithdrawalAddressesRequest) (*WithdrawalAddressesResponse, error)
+	StreamNodeSet(*NodeSetRequest, grpc.ServerStreamingServer[NodeSetUpdate]) error
+	GetExpectedFeeRecipient(context.Context, *ExpectedFeeRecipientRequest) (*ExpectedFeeRecipientResponse, error)
+	GetNodeInfo(context.Context, *NodeInfoRequest) (*NodeInfoResponse, error)
 	mustEmbedUnimplementedApiServer()
 }
 
@@ -113,6 +213,27 @@ func (UnimplementedApiServer) GetSoloValidators(context.Context, *SoloValidators
 func (UnimplementedApiServer) ValidateEIP1271(context.Context, *ValidateEIP1271Request) (*ValidateEIP1271Response, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ValidateEIP1271 not implemented")
 }
+func (UnimplementedApiServer) ValidateEIP1271Batch(context.Context, *ValidateEIP1271BatchRequest) (*ValidateEIP1271BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateEIP1271Batch not implemented")
+}
+func (UnimplementedApiServer) GetRPInfoAt(context.Context, *RPInfoAtRequest) (*RPInfoAtResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRPInfoAt not implemented")
+}
+func (UnimplementedApiServer) ValidateSignature(context.Context, *ValidateSignatureRequest) (*ValidateSignatureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateSignature not implemented")
+}
+func (UnimplementedApiServer) GetWithdrawalAddresses(context.Context, *WithdrawalAddressesRequest) (*WithdrawalAddressesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWithdrawalAddresses not implemented")
+}
+func (UnimplementedApiServer) StreamNodeSet(*NodeSetRequest, grpc.ServerStreamingServer[NodeSetUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamNodeSet not implemented")
+}
+func (UnimplementedApiServer) GetExpectedFeeRecipient(context.Context, *ExpectedFeeRecipientRequest) (*ExpectedFeeRecipientResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExpectedFeeRecipient not implemented")
+}
+func (UnimplementedApiServer) GetNodeInfo(context.Context, *NodeInfoRequest) (*NodeInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeInfo not implemented")
+}
 func (UnimplementedApiServer) mustEmbedUnimplementedApiServer() {}
 func (UnimplementedApiServer) testEmbeddedByValue()             {}
 
@@ -206,6 +327,125 @@ func _Api_ValidateEIP1271_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Api_ValidateEIP1271Batch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateEIP1271BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).ValidateEIP1271Batch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Api_ValidateEIP1271Batch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).ValidateEIP1271Batch(ctx, req.(*ValidateEIP1271BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetRPInfoAt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RPInfoAtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetRPInfoAt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Api_GetRPInfoAt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetRPInfoAt(ctx, req.(*RPInfoAtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_ValidateSignature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateSignatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).ValidateSignature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Api_ValidateSignature_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).ValidateSignature(ctx, req.(*ValidateSignatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetWithdrawalAddresses_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WithdrawalAddressesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetWithdrawalAddresses(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Api_GetWithdrawalAddresses_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetWithdrawalAddresses(ctx, req.(*WithdrawalAddressesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_StreamNodeSet_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(NodeSetRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ApiServer).StreamNodeSet(m, &grpc.GenericServerStream[NodeSetRequest, NodeSetUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Api_StreamNodeSetServer = grpc.ServerStreamingServer[NodeSetUpdate]
+
+func _Api_GetExpectedFeeRecipient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpectedFeeRecipientRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetExpectedFeeRecipient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Api_GetExpectedFeeRecipient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetExpectedFeeRecipient(ctx, req.(*ExpectedFeeRecipientRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_GetNodeInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).GetNodeInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Api_GetNodeInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).GetNodeInfo(ctx, req.(*NodeInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Api_ServiceDesc is the grpc.ServiceDesc for Api service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -229,7 +469,37 @@ var Api_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ValidateEIP1271",
 			Handler:    _Api_ValidateEIP1271_Handler,
 		},
+		{
+			MethodName: "ValidateEIP1271Batch",
+			Handler:    _Api_ValidateEIP1271Batch_Handler,
+		},
+		{
+			MethodName: "GetRPInfoAt",
+			Handler:    _Api_GetRPInfoAt_Handler,
+		},
+		{
+			MethodName: "ValidateSignature",
+			Handler:    _Api_ValidateSignature_Handler,
+		},
+		{
+			MethodName: "GetWithdrawalAddresses",
+			Handler:    _Api_GetWithdrawalAddresses_Handler,
+		},
+		{
+			MethodName: "GetExpectedFeeRecipient",
+			Handler:    _Api_GetExpectedFeeRecipient_Handler,
+		},
+		{
+			MethodName: "GetNodeInfo",
+			Handler:    _Api_GetNodeInfo_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamNodeSet",
+			Handler:       _Api_StreamNodeSet_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
 }
@@ -36,6 +36,7 @@ type ProxyRouter struct {
 	EnableSoloValidators bool
 
 	gbp  *gbp.GuardedBeaconProxy
+	gate upstreamGate
 	m    *metrics.MetricsRegistry
 	gm   *metrics.MetricsRegistry
 	auth *auth
@@ -61,11 +62,10 @@ func (pr *ProxyRouter) logCredentialSharing(operatorType credentials.OperatorTyp
 			pr.m.Counter("credential_sharing_rp_to_solo").Inc()
 			return
 		}
-		// Solo validators on rv will not be looked up
+		// Validators the cl doesn't know of, eg because they were only just deposited, can't be checked
 		if validatorInfo == nil {
 			return
 		}
-		// On pbp we will, so we can check
 		chainNodeAddress = validatorInfo.WithdrawalAddress
 	} else {
 		if operatorType == pb.OperatorType_OT_SOLO {
@@ -136,9 +136,18 @@ func (pr *ProxyRouter) prepareBeaconProposerGuard(proposers gbp.PrepareBeaconPro
 	for _, proposer := range proposers {
 		validatorInfo, found := validatorMap[proposer.ValidatorIndex]
 		if !found {
-			pr.Logger.Warn("Pubkey for index not found in response from cl.",
+			// The bn doesn't have the validator yet, eg because another bn the validator client uses
+			// applied its deposit first. It can't propose until the bn has it, but its pubkey is unknown,
+			// so its fee recipient is held to what the authenticated operator's validators must use.
+			pr.m.Counter("prepare_beacon_unknown_validator").Inc()
+			pr.Logger.Debug("Pubkey for index not found in response from cl.",
 				zap.String("requested index", proposer.ValidatorIndex))
-			return gbp.BadRequest, fmt.Errorf("unknown validator index %s", proposer.ValidatorIndex)
+
+			status, err := pr.checkOperatorFeeRecipient("prepare_beacon", "validator index "+proposer.ValidatorIndex, proposer.FeeRecipient, operatorType, authedNode)
+			if status != gbp.Allowed {
+				return status, err
+			}
+			continue
 		}
 
 		pubkey := validatorInfo.Pubkey
@@ -154,7 +163,7 @@ func (pr *ProxyRouter) prepareBeaconProposerGuard(proposers gbp.PrepareBeaconPro
 
 		if rpInfo == nil {
 			// Solo validators may only use their withdrawal credential in prepare_beacon_proposer
-			if !validatorInfo.Is0x01 ||
+			if !validatorInfo.HasExecutionCredentials ||
 				!strings.EqualFold(validatorInfo.WithdrawalAddress.String(), proposer.FeeRecipient) {
 
 				pr.m.Counter("prepare_beacon_incorrect_fee_recipient_solo").Inc()
@@ -177,6 +186,14 @@ func (pr *ProxyRouter) prepareBeaconProposerGuard(proposers gbp.PrepareBeaconPro
 			continue
 		}
 
+		// The node recently joined or left the smoothing pool, and its validator client
+		// hasn't caught up yet. Allow the previous fee recipient until the grace period ends.
+		if rpInfo.AcceptsFeeRecipient(proposer.FeeRecipient) {
+			pr.m.Counter("prepare_beacon_grace_period_fee_recipient").Inc()
+			metrics.ObserveValidator(rpInfo.NodeAddress, pubkey)
+			continue
+		}
+
 		// rETH address is a 'safe' default fee recipient, and should be allowed.
 		// However, it does indicate a misconfigured node, so log it.
 		if strings.EqualFold(pr.EL.REthAddress().String(), proposer.FeeRecipient) {
@@ -201,6 +218,55 @@ func (pr *ProxyRouter) prepareBeaconProposerGuard(proposers gbp.PrepareBeaconPro
 	return gbp.Allowed, nil
 }
 
+// Checks the fee recipient of a validator the cl doesn't have against the operator the credential was
+// issued to, since the validator can't be classified by its own withdrawal credentials.
+// Metrics are counted under endpoint, and validator names it in errors.
+func (pr *ProxyRouter) checkOperatorFeeRecipient(endpoint string, validator string, feeRecipient string, operatorType credentials.OperatorType, authedNode []byte) (gbp.AuthenticationStatus, error) {
+	nodeAddr := common.BytesToAddress(authedNode)
+
+	if operatorType == pb.OperatorType_OT_SOLO {
+		// Solo credentials are issued to the validators' withdrawal address
+		if !strings.EqualFold(nodeAddr.String(), feeRecipient) {
+			pr.m.Counter(endpoint + "_incorrect_fee_recipient_solo").Inc()
+			return gbp.Forbidden,
+				fmt.Errorf("%s is unknown, and fee recipient %s differs from the withdrawal address %s the credential was issued to",
+					validator,
+					feeRecipient,
+					nodeAddr.String(),
+				)
+		}
+
+		pr.m.Counter(endpoint + "_correct_fee_recipient_solo").Inc()
+		return gbp.Allowed, nil
+	}
+
+	rpInfo, err := pr.EL.GetNodeRPInfo(nodeAddr)
+	if err != nil {
+		pr.Logger.Error("error querying cache", zap.Error(err))
+		return gbp.InternalError, fmt.Errorf("error with cache, please report it to Rescue Node maintainers")
+	}
+	if rpInfo == nil {
+		return gbp.BadRequest, fmt.Errorf("unknown %s", validator)
+	}
+
+	if rpInfo.AcceptsFeeRecipient(feeRecipient) {
+		pr.m.Counter(endpoint + "_correct_fee_recipient").Inc()
+		return gbp.Allowed, nil
+	}
+
+	if strings.EqualFold(pr.EL.REthAddress().String(), feeRecipient) {
+		pr.m.Counter(endpoint + "_reth_fee_recipient").Inc()
+		return gbp.Allowed, nil
+	}
+
+	pr.m.Counter(endpoint + "_incorrect_fee_recipient").Inc()
+	return gbp.Conflict, fmt.Errorf("%s is unknown, and fee recipient %s didn't match expected fee recipient %s",
+		validator,
+		feeRecipient,
+		rpInfo.ExpectedFeeRecipient.String(),
+	)
+}
+
 func (pr *ProxyRouter) registerValidatorGuard(validators gbp.RegisterValidatorRequest, ctx context.Context) (gbp.AuthenticationStatus, error) {
 	pr.m.Counter("register_validator").Inc()
 
@@ -210,6 +276,7 @@ func (pr *ProxyRouter) registerValidatorGuard(validators gbp.RegisterValidatorRe
 		return gbp.InternalError, nil
 	}
 
+	pubkeys := make([]rptypes.ValidatorPubkey, 0, len(validators))
 	for _, validator := range validators {
 		pubkeyStr := strings.TrimPrefix(validator.Message.Pubkey, "0x")
 
@@ -218,6 +285,18 @@ func (pr *ProxyRouter) registerValidatorGuard(validators gbp.RegisterValidatorRe
 			pr.Logger.Warn("Malformed pubkey in register_validator_request", zap.Error(err), zap.String("pubkey", pubkeyStr))
 			return gbp.BadRequest, fmt.Errorf("error parsing pubkey from request body: %v", err)
 		}
+		pubkeys = append(pubkeys, pubkey)
+	}
+
+	// Validators that haven't been deposited are held to the authenticated operator. If the lookup
+	// fails, there's no telling which those are, so carry on without it.
+	validatorMap, err := pr.CL.GetValidatorInfoByPubkey(pubkeys)
+	if err != nil {
+		pr.Logger.Warn("Error while querying CL for validator info", zap.Error(err))
+	}
+
+	for i, validator := range validators {
+		pubkey := pubkeys[i]
 
 		// Grab the expected fee recipient for the pubkey
 		rpInfo, err := pr.EL.GetRPInfo(pubkey)
@@ -226,13 +305,30 @@ func (pr *ProxyRouter) registerValidatorGuard(validators gbp.RegisterValidatorRe
 			return gbp.InternalError, fmt.Errorf("error with cache, please report it to Rescue Node maintainers")
 		}
 
-		pr.logCredentialSharing(operatorType, rpInfo, nil, common.BytesToAddress(authedNode))
+		validatorInfo := validatorMap[pubkey]
+		pr.logCredentialSharing(operatorType, rpInfo, validatorInfo, common.BytesToAddress(authedNode))
+		if rpInfo == nil && validatorInfo == nil && validatorMap != nil {
+			// Not deposited yet, so its withdrawal credentials are unknown, and its fee recipient
+			// is held to what the authenticated operator's validators must use. If the lookup
+			// failed, there's no telling, so it's treated as solo.
+			pr.m.Counter("register_validator_unknown_validator").Inc()
+
+			status, err := pr.checkOperatorFeeRecipient("register_validator", "pubkey "+pubkey.String(), validator.Message.FeeRecipient, operatorType, authedNode)
+			if status != gbp.Allowed {
+				return status, err
+			}
+			continue
+		}
+
 		if rpInfo == nil {
 			// Solo validators can do whatever they want in register_validator.
 			// The endpoint requires a signature, which the BN will validate, so
 			// we know for sure that the downstream user has custody of the BLS keys.
 
 			// The only thing to do is record some metrics
+			if validatorInfo != nil && validatorInfo.Pending {
+				pr.m.Counter("register_validator_pending_solo").Inc()
+			}
 
 			feeRecipient := common.HexToAddress(validator.Message.FeeRecipient)
 			metrics.ObserveSoloValidator(feeRecipient, pubkey)
@@ -246,6 +342,13 @@ func (pr *ProxyRouter) registerValidatorGuard(validators gbp.RegisterValidatorRe
 			continue
 		}
 
+		if rpInfo.AcceptsFeeRecipient(validator.Message.FeeRecipient) {
+			// The previous fee recipient of a node which recently joined or left the smoothing pool
+			pr.m.Counter("register_validator_grace_period_fee_recipient").Inc()
+			metrics.ObserveValidator(rpInfo.NodeAddress, pubkey)
+			continue
+		}
+
 		if strings.EqualFold(pr.EL.REthAddress().String(), validator.Message.FeeRecipient) {
 			// rETH address is a 'safe' default fee recipient, and should be allowed.
 			// However, it does indicate a misconfigured node, so log it.
@@ -380,7 +483,7 @@ func (pr *ProxyRouter) grpcAuthenticate(md metadata.MD) (gbp.AuthenticationStatu
 	return gbp.Allowed, ctx, nil
 }
 
-func (pr *ProxyRouter) Init() {
+func (pr *ProxyRouter) Init() error {
 	// Initialize the auth handler
 	pr.auth = initAuth(pr.CredentialSecrets)
 	for _, id := range pr.auth.credentialManager.PartnerIDs() {
@@ -395,10 +498,16 @@ func (pr *ProxyRouter) Init() {
 		zap.String("primary id", pr.auth.credentialManager.ID().String()),
 	)
 
+	// Requests are proxied to the bn through the upstream gate, which checks it's fit to serve them
+	gateURL, grpcGateURL, err := pr.gate.start(pr)
+	if err != nil {
+		return fmt.Errorf("couldn't start the upstream gate: %w", err)
+	}
+
 	// Create the reverse proxy.
 	pr.gbp = &gbp.GuardedBeaconProxy{
-		BeaconURL:                  pr.BeaconURL,
-		GRPCBeaconURL:              pr.GRPCBeaconURL,
+		BeaconURL:                  gateURL,
+		GRPCBeaconURL:              grpcGateURL,
 		HTTPAuthenticator:          pr.authenticate,
 		GRPCAuthenticator:          pr.grpcAuthenticate,
 		PrepareBeaconProposerGuard: pr.prepareBeaconProposerGuard,
@@ -409,6 +518,7 @@ func (pr *ProxyRouter) Init() {
 
 	pr.m = metrics.NewMetricsRegistry("http_proxy")
 	pr.gm = metrics.NewMetricsRegistry("grpc_proxy")
+	return nil
 }
 
 func (pr *ProxyRouter) Start() error {
@@ -429,4 +539,5 @@ func (pr *ProxyRouter) Serve(httpListener net.Listener, grpcListener net.Listene
 
 func (pr *ProxyRouter) Stop(ctx context.Context) {
 	pr.gbp.Stop(ctx)
+	pr.gate.stop()
 }
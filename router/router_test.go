@@ -19,19 +19,27 @@ import (
 	"github.com/Rocket-Rescue-Node/credentials/pb"
 	gbp "github.com/Rocket-Rescue-Node/guarded-beacon-proxy"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/config"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/consensuslayer"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/executionlayer"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/metrics"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/test"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum/go-ethereum/common"
 	rptypes "github.com/rocket-pool/rocketpool-go/types"
 	"go.uber.org/zap/zaptest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 type routerTest struct {
-	ctx   context.Context
-	pr    *ProxyRouter
-	start func()
+	ctx      context.Context
+	pr       *ProxyRouter
+	grpcAddr string
+	start    func()
 }
 
 type mockBeaconHandler struct {
@@ -47,6 +55,11 @@ func (m *mockBeaconHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func setup(t *testing.T, errs chan error) routerTest {
+	return setupWithGRPC(t, errs, "")
+}
+
+// Sets up a router which also proxies grpc requests to grpcBeaconURL, if it's set
+func setupWithGRPC(t *testing.T, errs chan error, grpcBeaconURL string) routerTest {
 	_, err := metrics.Init("router_test_" + t.Name())
 	if err != nil {
 		t.Fatal(err)
@@ -76,21 +89,36 @@ func setup(t *testing.T, errs chan error) routerTest {
 
 	cl.AddExecutionValidators(el, t.Name())
 
+	var grpcListener net.Listener
+	var grpcAddr string
+	if grpcBeaconURL != "" {
+		grpcListener, err = net.Listen("tcp", "127.0.0.1:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		grpcAddr = grpcListener.Addr().String()
+	}
+
 	pr := &ProxyRouter{
 		Addr:                 httpListener.Addr().String(),
 		BeaconURL:            beaconURL,
+		GRPCBeaconURL:        grpcBeaconURL,
 		CL:                   cl,
 		EL:                   el,
 		Logger:               zaptest.NewLogger(t),
 		CredentialSecrets:    config.CredentialSecrets{[]byte("test"), []byte("test2")},
 		EnableSoloValidators: true,
 	}
-	pr.Init()
+	if err := pr.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pr.gate.stop)
 	return routerTest{
-		ctx: ctx,
-		pr:  pr,
+		ctx:      ctx,
+		pr:       pr,
+		grpcAddr: grpcAddr,
 		start: func() {
-			errs <- pr.Serve(httpListener, nil)
+			errs <- pr.Serve(httpListener, grpcListener)
 		},
 	}
 }
@@ -214,6 +242,42 @@ func TestRouterGoodAuth(t *testing.T) {
 	}
 }
 
+func TestRouterUpstreamUnfit(t *testing.T) {
+	errs := make(chan error)
+	rt := setup(t, errs)
+	rt.pr.CL.(*test.MockConsensusLayer).Unfit = "the beacon node is syncing, 20 slots behind"
+
+	go rt.start()
+
+	username, pw := rt.validAuth(t, false)
+	resp, err := http.Get("http://" + username + ":" + pw + "@" + rt.pr.Addr + "/eth/v1/node/syncing")
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal("unexpected status code", resp.StatusCode)
+	}
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != http.StatusServiceUnavailable || !strings.Contains(body.Message, "syncing") {
+		t.Fatalf("unexpected response %+v", body)
+	}
+
+	rt.pr.Stop(rt.ctx)
+
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestRouterGoodAuthPartner(t *testing.T) {
 	var addr []byte
 	errs := make(chan error)
@@ -328,7 +392,7 @@ func TestRouterPBPSolo(t *testing.T) {
 	go rt.start()
 
 	// Grab the list of validators from the mock client
-	valis, err := rt.pr.CL.GetValidators()
+	valis, err := rt.pr.CL.(*test.MockConsensusLayer).GetValidators()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -390,13 +454,27 @@ func TestRouterPBPSolo(t *testing.T) {
 	}
 }
 
-func TestRouterPBPSoloUnseen(t *testing.T) {
-	errs := make(chan error)
-	rt := setup(t, errs)
+// The first mock node, which validAuth issues credentials to
+func (rt routerTest) authedNode(t *testing.T) *executionlayer.RPInfo {
+	var addr common.Address
+	err := rt.pr.EL.ForEachNode(func(a common.Address) bool {
+		addr = a
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	go rt.start()
+	rpInfo, err := rt.pr.EL.GetNodeRPInfo(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rpInfo
+}
 
-	username, pw := rt.validAuth(t, true)
+// Sends prepare_beacon_proposer for an index the cl doesn't have
+func (rt routerTest) pbpUnseen(t *testing.T, solo bool, feeRecipient string) (int, string) {
+	username, pw := rt.validAuth(t, solo)
 	resp, err := http.Post(
 		"http://"+username+":"+pw+"@"+rt.pr.Addr+"/eth/v1/validator/prepare_beacon_proposer",
 		"application/json",
@@ -404,33 +482,87 @@ func TestRouterPBPSoloUnseen(t *testing.T) {
 			[{
 				"validator_index": "%s",
 				"fee_recipient": "%s"
-			}]`, "1010101", "0xabcf8e0d4e9587369b2301d0790347320302cc09"),
+			}]`, "1010101", feeRecipient),
 		),
 	)
 	if err != nil {
 		t.Fatal("unexpected error", err)
 	}
-	if resp.StatusCode != 400 {
-		t.Fatal("unexpected status code", resp.StatusCode)
-	}
+	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	if resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
 	var eMap map[string]string
 	err = json.Unmarshal(body, &eMap)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !strings.HasPrefix(eMap["error"], "unknown validator index") {
-		t.Fatal("unexpected response", string(body))
+	return resp.StatusCode, eMap["error"]
+}
+
+func TestRouterPBPSoloUnseen(t *testing.T) {
+	errs := make(chan error)
+	rt := setup(t, errs)
+
+	go rt.start()
+
+	// Unknown validators are held to the withdrawal address solo credentials are issued to
+	status, body := rt.pbpUnseen(t, true, "0xabcf8e0d4e9587369b2301d0790347320302cc09")
+	if status != http.StatusForbidden {
+		t.Fatal("unexpected status code", status)
+	}
+	if !strings.HasPrefix(body, "validator index 1010101 is unknown") {
+		t.Fatal("unexpected response", body)
+	}
+
+	status, body = rt.pbpUnseen(t, true, rt.authedNode(t).NodeAddress.String())
+	if status != http.StatusOK || body != responseString {
+		t.Fatal("unexpected response", status, body)
 	}
 
 	rt.pr.Stop(rt.ctx)
 
-	err = <-errs
+	err := <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouterPBPRPUnseen(t *testing.T) {
+	errs := make(chan error)
+	rt := setup(t, errs)
+
+	go rt.start()
+
+	// Unknown validators are held to the fee recipient of the node rp credentials are issued to
+	status, body := rt.pbpUnseen(t, false, "0xabcf8e0d4e9587369b2301d0790347320302cc09")
+	if status != http.StatusConflict {
+		t.Fatal("unexpected status code", status)
+	}
+	if !strings.HasPrefix(body, "validator index 1010101 is unknown") {
+		t.Fatal("unexpected response", body)
+	}
+
+	status, body = rt.pbpUnseen(t, false, rt.authedNode(t).ExpectedFeeRecipient.String())
+	if status != http.StatusOK || body != responseString {
+		t.Fatal("unexpected response", status, body)
+	}
+
+	status, body = rt.pbpUnseen(t, false, rt.pr.EL.REthAddress().String())
+	if status != http.StatusOK || body != responseString {
+		t.Fatal("unexpected response", status, body)
+	}
+
+	rt.pr.Stop(rt.ctx)
+
+	err := <-errs
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -443,7 +575,7 @@ func TestRouterPBPSoloBadFeeRecipient(t *testing.T) {
 	go rt.start()
 
 	// Grab the list of validators from the mock client
-	valis, err := rt.pr.CL.GetValidators()
+	valis, err := rt.pr.CL.(*test.MockConsensusLayer).GetValidators()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -644,6 +776,52 @@ func TestRouterPBPRPCheater(t *testing.T) {
 	}
 }
 
+func TestRouterPBPRPGracePeriod(t *testing.T) {
+	errs := make(chan error)
+	rt := setup(t, errs)
+
+	go rt.start()
+
+	// Grab a validator, and pretend its node recently left the smoothing pool
+	vMap := rt.pr.EL.(*test.MockExecutionLayer).VMap
+	mockIndices := rt.pr.CL.(*test.MockConsensusLayer).Indices
+
+	previous := common.HexToAddress("0xabcf8e0d4e9587369b2301d0790347320302cc09")
+	var index string
+	for pubkey, info := range vMap {
+		info.AcceptableFeeRecipients = append(info.AcceptableFeeRecipients, previous)
+		index = mockIndices[pubkey]
+		break
+	}
+
+	username, pw := rt.validAuth(t, false)
+	resp, err := http.Post(
+		"http://"+username+":"+pw+"@"+rt.pr.Addr+"/eth/v1/validator/prepare_beacon_proposer",
+		"application/json",
+		strings.NewReader(fmt.Sprintf(`
+			[{
+				"validator_index": "%s",
+				"fee_recipient": "%s"
+			}]`,
+			index,
+			strings.ToLower(previous.String())),
+		),
+	)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatal("unexpected status code", resp.StatusCode)
+	}
+
+	rt.pr.Stop(rt.ctx)
+
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestRouterRVSolo(t *testing.T) {
 	errs := make(chan error)
 	rt := setup(t, errs)
@@ -654,7 +832,7 @@ func TestRouterRVSolo(t *testing.T) {
 	vMap := rt.pr.EL.(*test.MockExecutionLayer).VMap
 
 	// Grab all validators to pick from
-	validators, err := rt.pr.CL.GetValidators()
+	validators, err := rt.pr.CL.(*test.MockConsensusLayer).GetValidators()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -699,6 +877,78 @@ func TestRouterRVSolo(t *testing.T) {
 	}
 }
 
+func TestRouterRVPending(t *testing.T) {
+	errs := make(chan error)
+	rt := setup(t, errs)
+
+	go rt.start()
+
+	// A solo validator that's been deposited, but isn't in the beacon state yet, and one that
+	// hasn't been deposited at all
+	pending := rptypes.BytesToValidatorPubkey(bytes.Repeat([]byte{0xaa}, 48))
+	rt.pr.CL.(*test.MockConsensusLayer).Pending[pending] = &consensuslayer.ValidatorInfo{
+		Pubkey:                  pending,
+		WithdrawalAddress:       common.HexToAddress("0xabcf8e0d4e9587369b2301d0790347320302cc09"),
+		HasExecutionCredentials: true,
+		Pending:                 true,
+	}
+	unknown := rptypes.BytesToValidatorPubkey(bytes.Repeat([]byte{0xbb}, 48))
+
+	// The solo credential is issued to the first node's address
+	var credentialAddr common.Address
+	err := rt.pr.EL.(*test.MockExecutionLayer).ForEachNode(func(a common.Address) bool {
+		credentialAddr = a
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		pubkey       rptypes.ValidatorPubkey
+		feeRecipient string
+		status       int
+	}{
+		{pending, "0xabcf8e0d4e9587369b2301d0790347320302cc09", http.StatusOK},
+		// Validators that haven't been deposited are held to the address the credential was issued to
+		{unknown, "0xabcf8e0d4e9587369b2301d0790347320302cc09", http.StatusForbidden},
+		{unknown, credentialAddr.String(), http.StatusOK},
+	} {
+		body := fmt.Sprintf(`
+			[{
+				"message": {
+					"gas_limit": "1",
+					"timestamp": "1",
+					"pubkey": "%s",
+					"fee_recipient": "%s"
+				},
+				"signature": "0x1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505cc411d61252fb6cb3fa0017b679f8bb2305b26a285fa2737f175668d0dff91cc1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505"
+			}]`,
+			tc.pubkey.String(),
+			tc.feeRecipient)
+		username, pw := rt.validAuth(t, true)
+		resp, err := http.Post(
+			"http://"+username+":"+pw+"@"+rt.pr.Addr+"/eth/v1/validator/register_validator",
+			"application/json",
+			strings.NewReader(body),
+		)
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Fatalf("unexpected status code %d for %s with fee recipient %s", resp.StatusCode, tc.pubkey.String(), tc.feeRecipient)
+		}
+	}
+
+	rt.pr.Stop(rt.ctx)
+
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestRouterRVSoloMalformed(t *testing.T) {
 	errs := make(chan error)
 	rt := setup(t, errs)
@@ -887,6 +1137,53 @@ func TestRouterRVCheater(t *testing.T) {
 	}
 }
 
+func TestRouterRVGracePeriod(t *testing.T) {
+	errs := make(chan error)
+	rt := setup(t, errs)
+
+	go rt.start()
+
+	// Grab a validator, and pretend its node recently joined the smoothing pool
+	vMap := rt.pr.EL.(*test.MockExecutionLayer).VMap
+
+	previous := common.HexToAddress("0xabcf8e0d4e9587369b2301d0790347320302cc09")
+	var pubkey rptypes.ValidatorPubkey
+	for k, info := range vMap {
+		info.AcceptableFeeRecipients = append(info.AcceptableFeeRecipients, previous)
+		pubkey = k
+		break
+	}
+
+	username, pw := rt.validAuth(t, false)
+
+	body := fmt.Sprintf(`
+			[{
+				"message": {
+					"gas_limit": "1",
+					"timestamp": "1",
+					"pubkey": "%s",
+					"fee_recipient": "%s"
+				},
+				"signature": "0x1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505cc411d61252fb6cb3fa0017b679f8bb2305b26a285fa2737f175668d0dff91cc1b66ac1fb663c9bc59509846d6ec05345bd908eda73e670af888da41af171505"
+			}
+			]`,
+		pubkey.String(),
+		previous.String(),
+	)
+
+	resp, err := http.Post(
+		"http://"+username+":"+pw+"@"+rt.pr.Addr+"/eth/v1/validator/register_validator",
+		"application/json",
+		strings.NewReader(body),
+	)
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatal("unexpected status code", resp.StatusCode)
+	}
+}
+
 func TestRouterGRPCAuth(t *testing.T) {
 	errs := make(chan error)
 	rt := setup(t, errs)
@@ -967,6 +1264,55 @@ func TestRouterGRPCAuthSolo(t *testing.T) {
 	}
 }
 
+// Sends a grpc request through a router to a grpc bn with no services, so requests that reach it are Unimplemented
+func testGRPCUpstream(t *testing.T, unfit string) error {
+	upstreamListener, err := net.Listen("tcp", "127.0.0.1:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := grpc.NewServer()
+	go func() { _ = upstream.Serve(upstreamListener) }()
+	t.Cleanup(upstream.Stop)
+
+	errs := make(chan error)
+	rt := setupWithGRPC(t, errs, upstreamListener.Addr().String())
+	rt.pr.CL.(*test.MockConsensusLayer).Unfit = unfit
+
+	go rt.start()
+
+	conn, err := grpc.NewClient("passthrough:///"+rt.grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	username, pw := rt.validAuth(t, false)
+	ctx := metadata.AppendToOutgoingContext(rt.ctx, "rprnauth", fmt.Sprintf("%s:%s", username, pw))
+	out := conn.Invoke(ctx, "/ethereum.eth.v1alpha1.Node/GetSyncStatus", &emptypb.Empty{}, &emptypb.Empty{}, grpc.WaitForReady(true))
+
+	rt.pr.Stop(rt.ctx)
+
+	err = <-errs
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRouterGRPCUpstreamUnfit(t *testing.T) {
+	err := testGRPCUpstream(t, "the beacon node is optimistic")
+	if status.Code(err) != codes.Unavailable || !strings.Contains(err.Error(), "optimistic") {
+		t.Fatal("Unexpected error", err)
+	}
+}
+
+func TestRouterGRPCUpstreamFit(t *testing.T) {
+	err := testGRPCUpstream(t, "")
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatal("Unexpected error", err)
+	}
+}
+
 func TestRouterGRPCAuthMissing(t *testing.T) {
 	errs := make(chan error)
 	rt := setup(t, errs)
@@ -0,0 +1,212 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/mwitkow/grpc-proxy/proxy"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// upstreamGate turns requests that get past authentication and the guards away while the bn
+// is syncing or optimistic, so that validators don't get stale duties from it.
+//
+// gbp proxies http requests with the default transport, so the gate is a transport registered
+// for its own url scheme, which checks the bn's health before sending the request on. Those
+// requests get a 503. gbp can't return Unavailable for grpc requests, so they're proxied through
+// a loopback grpc server in front of the bn which can.
+type upstreamGate struct {
+	pr *ProxyRouter
+	id string
+
+	grpcServer   *grpc.Server
+	grpcUpstream *grpc.ClientConn
+}
+
+// The Beacon API's error response
+type beaconAPIError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// The url scheme gbp is given, so that its requests reach a gate. The host names the gate.
+const gateScheme = "rescue-proxy-gate"
+
+var (
+	gatesLock    sync.Mutex
+	gates        = make(map[string]*upstreamGate)
+	nextGate     uint64
+	registerGate sync.Once
+)
+
+type gateTransport struct{}
+
+// Starts gating requests to the bn. Returns the urls to give gbp for http and grpc.
+func (g *upstreamGate) start(pr *ProxyRouter) (*url.URL, string, error) {
+	g.pr = pr
+
+	registerGate.Do(func() {
+		http.DefaultTransport.(*http.Transport).RegisterProtocol(gateScheme, gateTransport{})
+	})
+
+	gatesLock.Lock()
+	nextGate++
+	g.id = fmt.Sprintf("gate-%d", nextGate)
+	gates[g.id] = g
+	gatesLock.Unlock()
+
+	httpURL := &url.URL{
+		Scheme: gateScheme,
+		Host:   g.id,
+	}
+
+	if pr.GRPCBeaconURL == "" {
+		return httpURL, "", nil
+	}
+
+	grpcURL, err := g.startGRPC()
+	if err != nil {
+		g.stop()
+		return nil, "", err
+	}
+
+	return httpURL, grpcURL, nil
+}
+
+// gbp dials its grpc upstream with the credentials it serves with, so the gate serves, and
+// dials the bn, with them too
+func (g *upstreamGate) grpcCredentials() (credentials.TransportCredentials, error) {
+	if g.pr.TLSCertFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	return credentials.NewServerTLSFromFile(g.pr.TLSCertFile, g.pr.TLSKeyFile)
+}
+
+func (g *upstreamGate) startGRPC() (string, error) {
+	tc, err := g.grpcCredentials()
+	if err != nil {
+		return "", err
+	}
+
+	g.grpcUpstream, err = grpc.NewClient(g.pr.GRPCBeaconURL, grpc.WithTransportCredentials(tc))
+	if err != nil {
+		return "", fmt.Errorf("couldn't connect to the beacon node's grpc endpoint: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+
+	g.grpcServer = proxy.NewProxy(g.grpcUpstream,
+		grpc.Creds(tc),
+		grpc.StreamInterceptor(g.grpcInterceptor))
+
+	go func() {
+		if err := g.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			g.pr.Logger.Error("Upstream gate stopped", zap.Error(err))
+		}
+	}()
+
+	return listener.Addr().String(), nil
+}
+
+// Explains why the bn shouldn't serve validators, or returns an empty string if it's fit to
+func (g *upstreamGate) unfit() string {
+	if g.pr.CL.IsFit() {
+		return ""
+	}
+
+	return g.pr.CL.GetBNStatus().Unfit
+}
+
+func (gateTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	gatesLock.Lock()
+	g, ok := gates[r.URL.Host]
+	gatesLock.Unlock()
+	if !ok {
+		if r.Body != nil {
+			r.Body.Close()
+		}
+		return nil, fmt.Errorf("no upstream gate %s", r.URL.Host)
+	}
+
+	return g.roundTrip(r)
+}
+
+func (g *upstreamGate) roundTrip(r *http.Request) (*http.Response, error) {
+	reason := g.unfit()
+	if reason == "" {
+		out := r.Clone(r.Context())
+		out.URL.Scheme = g.pr.BeaconURL.Scheme
+		out.URL.Host = g.pr.BeaconURL.Host
+		return http.DefaultTransport.RoundTrip(out)
+	}
+
+	if r.Body != nil {
+		r.Body.Close()
+	}
+
+	g.pr.m.Counter("upstream_unfit").Inc()
+	g.pr.Logger.Debug("Turned a request away, the beacon node is unfit to serve",
+		zap.String("uri", r.URL.RequestURI()), zap.String("reason", reason))
+
+	body, err := json.Marshal(beaconAPIError{
+		Code:    http.StatusServiceUnavailable,
+		Message: "The rescue node's beacon node can't serve validators right now: " + reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+		StatusCode:    http.StatusServiceUnavailable,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}, nil
+}
+
+func (g *upstreamGate) grpcInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	reason := g.unfit()
+	if reason == "" {
+		return handler(srv, stream)
+	}
+
+	g.pr.gm.Counter("upstream_unfit").Inc()
+	g.pr.Logger.Debug("Turned a grpc request away, the beacon node is unfit to serve",
+		zap.String("method", info.FullMethod), zap.String("reason", reason))
+
+	return status.Error(codes.Unavailable, "the rescue node's beacon node can't serve validators right now: "+reason)
+}
+
+func (g *upstreamGate) stop() {
+	gatesLock.Lock()
+	delete(gates, g.id)
+	gatesLock.Unlock()
+
+	if g.grpcServer != nil {
+		g.grpcServer.Stop()
+	}
+	if g.grpcUpstream != nil {
+		g.grpcUpstream.Close()
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Rocket-Rescue-Node/rescue-proxy/config"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/consensuslayer"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/executionlayer"
+	"github.com/Rocket-Rescue-Node/rescue-proxy/nodeset"
 	"github.com/Rocket-Rescue-Node/rescue-proxy/router"
 	"go.uber.org/zap"
 )
@@ -65,7 +66,7 @@ func (s *Service) run(ctx context.Context, errs chan error) {
 	if s.Logger == nil {
 		s.Logger = initLogger(s.Config)
 	}
-	s.Logger.Info("Starting up the rescue node proxy...")
+	s.Logger.Info("Starting up the rescue node proxy...", zap.String("network", s.Config.Network.Name))
 
 	// Create the admin-only http server
 	// This initializes metrics, so do it first.
@@ -86,12 +87,25 @@ func (s *Service) run(ctx context.Context, errs chan error) {
 		}()
 	}
 
+	// Changes to the node set are published by both layers, and streamed to rescue-api
+	nodeSet := nodeset.NewFeed(nodeset.DefaultHistory)
+
 	// Connect to and initialize the execution layer
 	el := &executionlayer.CachingExecutionLayer{
-		ECURL:             s.Config.ExecutionURL,
+		ECURLs:            s.Config.ExecutionURLs,
 		RocketStorageAddr: s.Config.RocketStorageAddr,
+		ChainID:           s.Config.Network.ChainID,
 		Logger:            s.Logger,
 		CachePath:         s.Config.CachePath,
+		ReconcileInterval: s.Config.ReconcileInterval,
+
+		CacheSnapshotInterval:    s.Config.CacheSnapshotInterval,
+		CacheKVURL:               s.Config.CacheKVURL,
+		CacheKVPrefix:            s.Config.CacheKVPrefix,
+		SmoothingPoolGraceEpochs: s.Config.SPGraceEpochs,
+		EIP1271CacheTTL:          s.Config.EIP1271CacheTTL,
+		EIP1271Concurrency:       s.Config.EIP1271Concurrency,
+		NodeSet:                  nodeSet,
 	}
 	s.el = el
 	// Init() blocks until the cache is warmed up. This is good, we don't want to
@@ -109,12 +123,19 @@ func (s *Service) run(ctx context.Context, errs chan error) {
 	}()
 
 	// Connect to and initialize the consensus layer
-	cl := consensuslayer.NewCachingConsensusLayer(s.Config.BeaconURL, s.Logger, s.Config.ForceBNJSON)
+	cl := consensuslayer.NewCachingConsensusLayer(s.Config.BeaconURL, s.Logger, s.Config.ForceBNJSON, &consensuslayer.ExpectedNetwork{
+		GenesisValidatorsRoot: s.Config.Network.GenesisValidatorsRoot,
+		DepositContractAddr:   s.Config.Network.DepositContractAddr,
+		DepositChainID:        s.Config.Network.ChainID,
+	})
+	cl.CachePath = s.Config.CachePath
+	cl.NodeSet = nodeSet
 	s.cl = cl
+	s.admin.AddStatus("beacon_node", func() any { return cl.GetBNStatus() })
 	s.Logger.Info("Starting CL monitor")
-	// Consensus Layer is non-blocking/synchronous only.
-	// On Init() it will create the client and warm the validator key cache, which
-	// is needed to serve responses to rescue-api
+	// Init blocks until the client is created and the validator registry is warmed up,
+	// from its snapshot or else by loading the whole validator set, which can take minutes.
+	// The registry is needed to serve responses to rescue-api, so the router waits for it.
 	if err := cl.Init(s.ctx); err != nil {
 		// Optimization: serialize the EL cache by stopping it now so we can recover
 		// faster.
@@ -138,7 +159,12 @@ func (s *Service) run(ctx context.Context, errs chan error) {
 		EnableSoloValidators: s.Config.EnableSoloValidators,
 		CredentialSecrets:    s.Config.CredentialSecrets,
 	}
-	s.r.Init()
+	if err := s.r.Init(); err != nil {
+		el.Stop()
+		cl.Deinit()
+		s.errs <- fmt.Errorf("unable to init router: %v", err)
+		return
+	}
 	// Spin up the rest of the servers on different goroutines, since they block.
 	go func() {
 		s.Logger.Info("Starting http server", zap.String("url", s.Config.ListenAddr))
@@ -148,9 +174,14 @@ func (s *Service) run(ctx context.Context, errs chan error) {
 	}()
 
 	s.a = &api.API{
-		EL:     s.el,
-		CL:     s.cl,
-		Logger: s.Logger,
+		EL:              s.el,
+		CL:              s.cl,
+		Logger:          s.Logger,
+		NodeSet:         nodeSet,
+		TLSCertFile:     s.Config.APITLSCertFile,
+		TLSKeyFile:      s.Config.APITLSKeyFile,
+		TLSClientCAFile: s.Config.APITLSClientCAFile,
+		Token:           s.Config.APIToken,
 	}
 	go func() {
 		s.Logger.Info("Starting rescue-api endpoint")
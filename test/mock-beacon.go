@@ -0,0 +1,199 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+const mockBeaconGenesis = `{"data":{"genesis_time":"1606824023","genesis_validators_root":"0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95","genesis_fork_version":"0x00000000"}}`
+const mockBeaconSpec = `{"data":{"CONFIG_NAME":"mainnet","PRESET_BASE":"mainnet","SECONDS_PER_SLOT":"12","SLOTS_PER_EPOCH":"32","SLOTS_PER_HISTORICAL_ROOT":"8192","EPOCHS_PER_HISTORICAL_VECTOR":"65536","EPOCHS_PER_SLASHINGS_VECTOR":"8192","GENESIS_FORK_VERSION":"0x00000000","DEPOSIT_CHAIN_ID":"1","DEPOSIT_NETWORK_ID":"1","DEPOSIT_CONTRACT_ADDRESS":"0x00000000219ab540356cbb839cbe05303d7705fa"}}`
+const mockBeaconDepositContract = `{"data":{"chain_id":"1","address":"0x00000000219ab540356cbb839cbe05303d7705fa"}}`
+const mockBeaconForkSchedule = `{"data":[{"previous_version":"0x00000000","current_version":"0x00000000","epoch":"0"}]}`
+const mockBeaconVersion = `{"data":{"version":"rescue-proxy-mock"}}`
+const mockBeaconSyncing = `{"data":{"head_slot":"0","sync_distance":"0","is_syncing":false,"is_optimistic":false,"el_offline":false}}`
+
+// The mock beacon state's slot
+const mockBeaconSlot = 32 * 1000
+
+// mockBeacon serves a MockConsensusLayer's validators as a beacon node would, from files
+type mockBeacon struct {
+	stateSSZ       string
+	stateJSON      string
+	validatorsJSON string
+}
+
+// stateValidators returns the mock validators as a list ordered by index, as they are in a
+// beacon state. The mock's indices don't start at 0 and may have gaps, which are filled with
+// placeholders.
+func (m *MockConsensusLayer) stateValidators() []*apiv1.Validator {
+	count := 0
+	for _, v := range m.validators {
+		if int(v.Index) >= count {
+			count = int(v.Index) + 1
+		}
+	}
+
+	out := make([]*apiv1.Validator, count)
+	for _, v := range m.validators {
+		out[v.Index] = v
+	}
+
+	for i, v := range out {
+		if v != nil {
+			continue
+		}
+
+		out[i] = &apiv1.Validator{
+			Index:  phase0.ValidatorIndex(i),
+			Status: apiv1.ValidatorStateActiveOngoing,
+			Validator: &phase0.Validator{
+				WithdrawalCredentials: make([]byte, 32),
+				ExitEpoch:             phase0.Epoch(^uint64(0)),
+				WithdrawableEpoch:     phase0.Epoch(^uint64(0)),
+			},
+		}
+	}
+
+	return out
+}
+
+func (m *MockConsensusLayer) beaconState() *phase0.BeaconState {
+	validators := m.stateValidators()
+
+	out := &phase0.BeaconState{
+		Slot:              mockBeaconSlot,
+		Fork:              &phase0.Fork{},
+		LatestBlockHeader: &phase0.BeaconBlockHeader{},
+		BlockRoots:        make([]phase0.Root, 8192),
+		StateRoots:        make([]phase0.Root, 8192),
+		// The validators follow the variable length fields before them, so include some
+		HistoricalRoots: make([]phase0.Root, 3),
+		ETH1Data: &phase0.ETH1Data{
+			BlockHash: make([]byte, 32),
+		},
+		ETH1DataVotes: []*phase0.ETH1Data{{
+			BlockHash: make([]byte, 32),
+		}},
+		Validators:                  make([]*phase0.Validator, len(validators)),
+		Balances:                    make([]phase0.Gwei, len(validators)),
+		RANDAOMixes:                 make([]phase0.Root, 65536),
+		Slashings:                   make([]phase0.Gwei, 8192),
+		JustificationBits:           []byte{0},
+		PreviousJustifiedCheckpoint: &phase0.Checkpoint{},
+		CurrentJustifiedCheckpoint:  &phase0.Checkpoint{},
+		FinalizedCheckpoint:         &phase0.Checkpoint{},
+	}
+
+	for i, v := range validators {
+		out.Validators[i] = v.Validator
+		out.Balances[i] = v.Balance
+	}
+
+	return out
+}
+
+// BeaconHandler returns a http.Handler that serves the mock validators as a beacon node would,
+// both in a beacon state and from the validators endpoint. It serves just enough of the beacon
+// node api for a consensus layer client to connect.
+//
+// The responses are encoded up front and written to dir, so that they don't count towards the
+// memory used by whatever is reading them.
+func (m *MockConsensusLayer) BeaconHandler(dir string) (http.Handler, error) {
+	state := m.beaconState()
+
+	stateSSZ, err := state.MarshalSSZ()
+	if err != nil {
+		return nil, err
+	}
+
+	stateJSON, err := json.Marshal(struct {
+		Version string              `json:"version"`
+		Data    *phase0.BeaconState `json:"data"`
+	}{
+		Version: "phase0",
+		Data:    state,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	validatorsJSON, err := json.Marshal(struct {
+		ExecutionOptimistic bool               `json:"execution_optimistic"`
+		Finalized           bool               `json:"finalized"`
+		Data                []*apiv1.Validator `json:"data"`
+	}{
+		Data: m.stateValidators(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &mockBeacon{
+		stateSSZ:       filepath.Join(dir, "state.ssz"),
+		stateJSON:      filepath.Join(dir, "state.json"),
+		validatorsJSON: filepath.Join(dir, "validators.json"),
+	}
+	for path, body := range map[string][]byte{
+		out.stateSSZ:       stateSSZ,
+		out.stateJSON:      stateJSON,
+		out.validatorsJSON: validatorsJSON,
+	} {
+		if err := os.WriteFile(path, body, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+func (b *mockBeacon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeJSON := func(body string) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, body)
+	}
+
+	switch r.URL.Path {
+	case "/eth/v1/beacon/genesis":
+		writeJSON(mockBeaconGenesis)
+	case "/eth/v1/config/spec":
+		writeJSON(mockBeaconSpec)
+	case "/eth/v1/config/deposit_contract":
+		writeJSON(mockBeaconDepositContract)
+	case "/eth/v1/config/fork_schedule":
+		writeJSON(mockBeaconForkSchedule)
+	case "/eth/v1/node/version":
+		writeJSON(mockBeaconVersion)
+	case "/eth/v1/node/syncing":
+		writeJSON(mockBeaconSyncing)
+	case "/eth/v1/events":
+		w.Header().Set("Content-Type", "text/event-stream")
+	case "/eth/v2/debug/beacon/states/head":
+		w.Header().Set("Eth-Consensus-Version", "phase0")
+		if strings.Contains(r.Header.Get("Accept"), "application/octet-stream") {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			http.ServeFile(w, r, b.stateSSZ)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFile(w, r, b.stateJSON)
+	case "/eth/v1/beacon/states/head/validators":
+		if r.URL.RawQuery != "" {
+			// Only the full list is served
+			http.Error(w, "unsupported query", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		http.ServeFile(w, r, b.validatorsJSON)
+	default:
+		http.NotFound(w, r)
+	}
+}
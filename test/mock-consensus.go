@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math/rand"
+	"time"
 
 	apiv1 "github.com/attestantio/go-eth2-client/api/v1"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
@@ -18,6 +19,12 @@ import (
 type MockConsensusLayer struct {
 	validators map[string]*apiv1.Validator
 	Indices    map[rptypes.ValidatorPubkey]string
+
+	// Validators that have been deposited, but aren't in the beacon state yet
+	Pending map[rptypes.ValidatorPubkey]*consensuslayer.ValidatorInfo
+
+	// Set to make the mock bn unfit to serve, for the given reason
+	Unfit string
 }
 
 func NewMockConsensusLayer(numValidators int, seed string) *MockConsensusLayer {
@@ -30,6 +37,7 @@ func NewMockConsensusLayer(numValidators int, seed string) *MockConsensusLayer {
 	out := new(MockConsensusLayer)
 	out.validators = make(map[string]*apiv1.Validator, numValidators)
 	out.Indices = make(map[rptypes.ValidatorPubkey]string)
+	out.Pending = make(map[rptypes.ValidatorPubkey]*consensuslayer.ValidatorInfo)
 
 	for i := 0; i < numValidators; i++ {
 		pubkey := randPubkey(gen)
@@ -80,6 +88,24 @@ func (m *MockConsensusLayer) AddExecutionValidators(e *MockExecutionLayer, seed
 	}
 }
 
+func validatorInfo(v *apiv1.Validator) *consensuslayer.ValidatorInfo {
+	out := &consensuslayer.ValidatorInfo{
+		Index:     v.Index,
+		Pubkey:    rptypes.BytesToValidatorPubkey(v.Validator.PublicKey[:]),
+		ExitEpoch: v.Validator.ExitEpoch,
+	}
+
+	// 0x02 credentials have a withdrawal address too
+	credentials := v.Validator.WithdrawalCredentials
+	if bytes.HasPrefix(credentials, []byte{0x01}) || bytes.HasPrefix(credentials, []byte{0x02}) {
+		out.HasExecutionCredentials = true
+		out.Compounding = credentials[0] == 0x02
+		out.WithdrawalAddress = common.BytesToAddress(v.Validator.WithdrawalCredentials)
+	}
+
+	return out
+}
+
 func (m *MockConsensusLayer) GetValidatorInfo(idx []string) (map[string]*consensuslayer.ValidatorInfo, error) {
 	out := make(map[string]*consensuslayer.ValidatorInfo)
 	for _, k := range idx {
@@ -88,19 +114,66 @@ func (m *MockConsensusLayer) GetValidatorInfo(idx []string) (map[string]*consens
 			continue
 		}
 
-		out[k] = &consensuslayer.ValidatorInfo{
-			Pubkey: rptypes.BytesToValidatorPubkey(v.Validator.PublicKey[:]),
-		}
+		out[k] = validatorInfo(v)
+	}
+
+	return out, nil
+}
 
-		if bytes.HasPrefix(v.Validator.WithdrawalCredentials, []byte{0x01}) {
-			out[k].Is0x01 = true
-			out[k].WithdrawalAddress = common.BytesToAddress(v.Validator.WithdrawalCredentials)
+func (m *MockConsensusLayer) GetValidatorInfoByPubkey(pubkeys []rptypes.ValidatorPubkey) (map[rptypes.ValidatorPubkey]*consensuslayer.ValidatorInfo, error) {
+	out := make(map[rptypes.ValidatorPubkey]*consensuslayer.ValidatorInfo)
+	for _, pubkey := range pubkeys {
+		idx, ok := m.Indices[pubkey]
+		if !ok {
+			if pending, ok := m.Pending[pubkey]; ok {
+				out[pubkey] = pending
+			}
+			continue
 		}
+
+		out[pubkey] = validatorInfo(m.validators[idx])
 	}
 
 	return out, nil
 }
 
+func (m *MockConsensusLayer) GetWithdrawalAddresses() []common.Address {
+	addrs := make(map[common.Address]struct{})
+	for _, v := range m.validators {
+		if v.Status == apiv1.ValidatorStateUnknown ||
+			v.Status == apiv1.ValidatorStateExitedUnslashed ||
+			v.Status == apiv1.ValidatorStateExitedSlashed ||
+			v.Status == apiv1.ValidatorStateWithdrawalPossible ||
+			v.Status == apiv1.ValidatorStateWithdrawalDone {
+			continue
+		}
+
+		info := validatorInfo(v)
+		if info.HasExecutionCredentials {
+			addrs[info.WithdrawalAddress] = struct{}{}
+		}
+	}
+
+	out := make([]common.Address, 0, len(addrs))
+	for addr := range addrs {
+		out = append(out, addr)
+	}
+
+	return out
+}
+
+func (m *MockConsensusLayer) GetBNStatus() *consensuslayer.BNStatus {
+	return &consensuslayer.BNStatus{
+		CheckedAt: time.Now(),
+		Unfit:     m.Unfit,
+	}
+}
+
+func (m *MockConsensusLayer) IsFit() bool {
+	return m.Unfit == ""
+}
+
+// GetValidators returns every mock validator, so tests can pick some to use
 func (m *MockConsensusLayer) GetValidators() ([]*apiv1.Validator, error) {
 	out := make([]*apiv1.Validator, 0, len(m.validators))
 
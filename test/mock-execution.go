@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/binary"
+	"math/big"
 	"math/rand"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -17,6 +18,9 @@ type MockExecutionLayer struct {
 	odaoNodes []common.Address
 	VMap      map[rptypes.ValidatorPubkey]*executionlayer.RPInfo
 	REth      common.Address
+
+	// Each node's withdrawal addresses, which are initially just the node address
+	WithdrawalAddresses map[common.Address]*executionlayer.WithdrawalAddresses
 }
 
 func NewMockExecutionLayer(numNodes int, numOdaoNodes int, numValidators int, seed string) *MockExecutionLayer {
@@ -30,6 +34,7 @@ func NewMockExecutionLayer(numNodes int, numOdaoNodes int, numValidators int, se
 	out.nodes = make([]*executionlayer.RPInfo, 0, numNodes)
 	out.odaoNodes = make([]common.Address, 0, numOdaoNodes)
 	out.VMap = make(map[rptypes.ValidatorPubkey]*executionlayer.RPInfo, numValidators)
+	out.WithdrawalAddresses = make(map[common.Address]*executionlayer.WithdrawalAddresses, numNodes)
 
 	// Create a fake rETH address
 	out.REth = randAddress(gen)
@@ -38,11 +43,16 @@ func NewMockExecutionLayer(numNodes int, numOdaoNodes int, numValidators int, se
 	for i := 0; i < numNodes; i++ {
 		fr := randAddress(gen)
 		info := &executionlayer.RPInfo{
-			NodeAddress:          randAddress(gen),
-			ExpectedFeeRecipient: &fr,
+			NodeAddress:             randAddress(gen),
+			ExpectedFeeRecipient:    &fr,
+			AcceptableFeeRecipients: []common.Address{fr},
 		}
 
 		out.nodes = append(out.nodes, info)
+		out.WithdrawalAddresses[info.NodeAddress] = &executionlayer.WithdrawalAddresses{
+			NodeAddress:       info.NodeAddress,
+			WithdrawalAddress: info.NodeAddress,
+		}
 	}
 
 	// Generate numOdaoNodes random addresses
@@ -89,6 +99,15 @@ func (m *MockExecutionLayer) GetRPInfo(k rptypes.ValidatorPubkey) (*executionlay
 	return out, nil
 }
 
+func (m *MockExecutionLayer) GetNodeRPInfo(nodeAddr common.Address) (*executionlayer.RPInfo, error) {
+	for _, node := range m.nodes {
+		if node.NodeAddress == nodeAddr {
+			return node, nil
+		}
+	}
+	return nil, nil
+}
+
 func (m *MockExecutionLayer) REthAddress() *common.Address {
 	return &m.REth
 }
@@ -96,3 +115,61 @@ func (m *MockExecutionLayer) REthAddress() *common.Address {
 func (m *MockExecutionLayer) ValidateEIP1271(ctx context.Context, dataHash common.Hash, signature []byte, address common.Address) (bool, error) {
 	return true, nil
 }
+
+func (m *MockExecutionLayer) ValidateEIP1271Batch(ctx context.Context, requests []executionlayer.EIP1271Request) []executionlayer.EIP1271Result {
+	out := make([]executionlayer.EIP1271Result, len(requests))
+	for i := range requests {
+		out[i].Valid = true
+	}
+	return out
+}
+
+func (m *MockExecutionLayer) ValidateSignature(ctx context.Context, dataHash common.Hash, signature []byte, address common.Address, block *big.Int) (executionlayer.SignatureMethod, error) {
+	return executionlayer.SignatureMethodEIP1271, nil
+}
+
+func (m *MockExecutionLayer) GetRPInfoAt(k rptypes.ValidatorPubkey, block uint64) (*executionlayer.RPInfo, error) {
+	return m.GetRPInfo(k)
+}
+
+func (m *MockExecutionLayer) GetNodeWithdrawalAddresses(nodeAddr common.Address) (*executionlayer.WithdrawalAddresses, error) {
+	return m.WithdrawalAddresses[nodeAddr], nil
+}
+
+func (m *MockExecutionLayer) GetWithdrawalAddressNodes(withdrawalAddr common.Address) ([]*executionlayer.WithdrawalAddresses, error) {
+	out := make([]*executionlayer.WithdrawalAddresses, 0)
+	for _, node := range m.nodes {
+		w := m.WithdrawalAddresses[node.NodeAddress]
+		if w.WithdrawalAddress == withdrawalAddr || (w.RPLWithdrawalAddress != nil && *w.RPLWithdrawalAddress == withdrawalAddr) {
+			out = append(out, w)
+		}
+	}
+
+	return out, nil
+}
+
+func (m *MockExecutionLayer) GetNodeDetails(nodeAddr common.Address) (*executionlayer.NodeDetails, error) {
+	info, _ := m.GetNodeRPInfo(nodeAddr)
+	if info == nil {
+		return nil, nil
+	}
+
+	out := &executionlayer.NodeDetails{
+		NodeAddress:     nodeAddr,
+		InSmoothingPool: info.InSmoothingPool,
+		FeeDistributor:  *info.ExpectedFeeRecipient,
+		Minipools:       make([]rptypes.ValidatorPubkey, 0),
+	}
+	for pubkey, v := range m.VMap {
+		if v == info {
+			out.Minipools = append(out.Minipools, pubkey)
+		}
+	}
+	for _, odaoNode := range m.odaoNodes {
+		if odaoNode == nodeAddr {
+			out.OdaoMember = true
+		}
+	}
+
+	return out, nil
+}